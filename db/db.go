@@ -41,16 +41,47 @@ func InitDB() {
 		&models.Like{},
 		&models.Report{},
 		&models.Comment{},
+		&models.CommentEdit{},
 		&models.Category{},
 		&models.ContentCreatorInfo{},
 		&models.PrivateMessage{},
 		&models.Subscription{},
 		&models.SubscriptionPayment{},
+		&models.PaymentRefund{},
+		&models.PaymentDispute{},
+		&models.Invoice{},
+		&models.SubscriptionPlan{},
+		&models.Coupon{},
+		&models.CouponRedemption{},
+		&models.Tip{},
+		&models.PostPurchase{},
+		&models.LedgerTransaction{},
+		&models.LedgerEntry{},
+		&models.PayoutBatch{},
+		&models.Payout{},
+		&models.StripeEvent{},
+		&models.Follow{},
+		&models.UserSession{},
+		&models.RefreshToken{},
+		&models.StreamTicket{},
+		&models.RealtimePayload{},
+		&models.RecoveryCode{},
+		&models.AuthThrottle{},
 	)
 	if err != nil {
 		utils.LogError(err, "Error migrating database")
 		panic("Could not migrate database")
 	}
 
+	// Les numéros de facture étaient uniques sur toute la plateforme, ils le sont désormais par créateur
+	for _, index := range []string{"idx_invoices_sequence", "idx_invoices_number"} {
+		if DB.Migrator().HasIndex(&models.Invoice{}, index) {
+			if err := DB.Migrator().DropIndex(&models.Invoice{}, index); err != nil {
+				utils.LogError(err, "Error dropping index "+index)
+				panic("Could not migrate database")
+			}
+		}
+	}
+
 	utils.LogSuccess("Database connection successful")
 }
\ No newline at end of file
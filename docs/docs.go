@@ -15,282 +15,185 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/categories": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Retrieve all categories",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "categories"
-                ],
-                "summary": "Get all categories",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Category"
-                            }
-                        }
-                    },
-                    "401": {
-                        "description": "error: Unauthorized",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "500": {
-                        "description": "error: Error message",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    }
-                }
-            },
+        "/2fa/confirm": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new category with the provided information",
+                "description": "Enable 2FA after checking a first TOTP code, and return one-time recovery codes (shown only once)",
                 "consumes": [
-                    "multipart/form-data",
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "categories"
+                    "auth"
                 ],
-                "summary": "Create a new category",
+                "summary": "Confirm 2FA enrolment",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Category name",
-                        "name": "name",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "file",
-                        "description": "Category picture",
-                        "name": "picture",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "description": "Category data (for JSON requests)",
-                        "name": "body",
+                        "description": "TOTP code",
+                        "name": "code",
                         "in": "body",
+                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.CategoryCreate"
+                            "$ref": "#/definitions/auth.TwoFactorCodeRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "message: Two-factor authentication enabled, recoveryCodes: list of codes",
                         "schema": {
-                            "$ref": "#/definitions/models.Category"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "error: Invalid input",
+                        "description": "error: Invalid code or enrolment not started",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
                     "401": {
                         "description": "error: Unauthorized",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "error: Two-factor authentication already enabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "500": {
                         "description": "error: Error message",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     }
                 }
             }
         },
-        "/categories/{id}": {
-            "put": {
+        "/2fa/disable": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update a category with the provided information",
+                "description": "Disable 2FA with a valid TOTP code or recovery code",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "categories"
+                    "auth"
                 ],
-                "summary": "Update a category",
+                "summary": "Disable 2FA",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Category ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Category name",
-                        "name": "name",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "file",
-                        "description": "Category picture",
-                        "name": "picture",
-                        "in": "formData",
-                        "required": true
+                        "description": "TOTP code or recovery code",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.TwoFactorCodeRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "message: Two-factor authentication disabled",
                         "schema": {
-                            "$ref": "#/definitions/models.Category"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "error: Invalid input",
+                        "description": "error: Invalid code or 2FA not enabled",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
                     "401": {
                         "description": "error: Unauthorized",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "404": {
-                        "description": "error: Category not found",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
                     "500": {
                         "description": "error: Error message",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/2fa/enroll": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete a category by its ID",
+                "description": "Generate a new TOTP secret for the current user. 2FA is only enabled after /2fa/confirm.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "categories"
-                ],
-                "summary": "Delete a category",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Category ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "auth"
                 ],
+                "summary": "Start 2FA enrolment",
                 "responses": {
                     "200": {
-                        "description": "message: Category deleted successfully",
+                        "description": "secret: base32 secret, otpauthUri: URI for authenticator apps",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
                     "401": {
                         "description": "error: Unauthorized",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
-                    "404": {
-                        "description": "error: Category not found",
+                    "409": {
+                        "description": "error: Two-factor authentication already enabled",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
                     "500": {
                         "description": "error: Error message",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     }
                 }
             }
         },
-        "/contact": {
+        "/2fa/verify": {
             "post": {
-                "description": "Submit a new contact request with the provided information",
+                "description": "Exchange the challenge token returned by /login and a TOTP code (or recovery code) for the real tokens",
                 "consumes": [
                     "application/json"
                 ],
@@ -298,23 +201,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "contacts"
+                    "auth"
                 ],
-                "summary": "Create a new contact request",
+                "summary": "Verify 2FA login",
                 "parameters": [
                     {
-                        "description": "Contact information",
-                        "name": "contact",
+                        "description": "Challenge token and code",
+                        "name": "verify",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.ContactCreate"
+                            "$ref": "#/definitions/auth.TwoFactorVerifyRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "message: Contact request submitted successfully, id: contact ID",
+                    "200": {
+                        "description": "token: JWT token, refreshToken: refresh token",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -327,6 +230,20 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
+                    "401": {
+                        "description": "error: Invalid challenge or code",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "429": {
+                        "description": "error: Too many failed attempts, retryAfter: seconds before retry",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
                     "500": {
                         "description": "error: Error message",
                         "schema": {
@@ -337,34 +254,58 @@ const docTemplate = `{
                 }
             }
         },
-        "/contacts": {
+        "/analytics/creators": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a list of all contact requests (Admin access only)",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the revenue generated by each content creator between two dates from the ledger, in cents, highest gross first: payments collected, refunds and disputes, commission kept by the platform and share owed to the creator (admin only). Defaults to the last 30 days. Add format=csv to download the report, amounts in euros",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv"
                 ],
                 "tags": [
-                    "contacts"
+                    "analytics"
+                ],
+                "summary": "Get the revenue per creator report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date, included (YYYY-MM-DD)",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv to download the report",
+                        "name": "format",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get all contact requests (Admin)",
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.CreatorRevenue"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid dates",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "array",
-                                "items": {
-                                    "$ref": "#/definitions/models.Contact"
-                                }
+                                "type": "string"
                             }
                         }
                     },
@@ -378,7 +319,7 @@ const docTemplate = `{
                         }
                     },
                     "403": {
-                        "description": "error: Forbidden - Admin access required",
+                        "description": "error: Access denied",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -398,52 +339,61 @@ const docTemplate = `{
                 }
             }
         },
-        "/contacts/{id}/status": {
-            "patch": {
+        "/analytics/payments": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update the status of a contact request (Admin access only)",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the subscription payments attempted per day, week (starting on Monday) or month with their failure rate (failed / attempts) and refund rate (payments refunded at least partially / payments succeeded), counted on the final state of each payment (admin only). Defaults to the last 30 days, 12 weeks or 12 months. Add format=csv to download the report",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv"
                 ],
                 "tags": [
-                    "contacts"
+                    "analytics"
                 ],
-                "summary": "Update contact status (Admin)",
+                "summary": "Get the payment rates report",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Contact ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "default": "day",
+                        "description": "day, week or month",
+                        "name": "period",
+                        "in": "query"
                     },
                     {
-                        "description": "Status update information",
-                        "name": "statusUpdate",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.ContactStatusUpdate"
-                        }
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date, included (YYYY-MM-DD)",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv to download the report",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "message: Contact status updated successfully",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PaymentRatesPoint"
+                            }
                         }
                     },
                     "400": {
-                        "description": "error: Invalid input",
+                        "description": "error: Invalid period or dates",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -451,8 +401,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "error: Contact not found",
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Access denied",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -472,317 +431,286 @@ const docTemplate = `{
                 }
             }
         },
-        "/content-creators": {
-            "put": {
+        "/analytics/revenue": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update an existing content creator application (rejected or approved)",
-                "consumes": [
-                    "multipart/form-data"
-                ],
+                "description": "Returns the revenue of the platform per day, week (starting on Monday) or month from the ledger, in cents: payments collected by product, refunds, disputes, commission kept by the platform and share owed to the creators (admin only). Defaults to the last 30 days, 12 weeks or 12 months. Add format=csv to download the report, amounts in euros",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv"
                 ],
                 "tags": [
-                    "content-creators"
+                    "analytics"
                 ],
-                "summary": "Update a content creator application",
+                "summary": "Get the platform revenue report",
                 "parameters": [
                     {
                         "type": "string",
-                        "default": "My Creative Company",
-                        "description": "Company name",
-                        "name": "companyName",
-                        "in": "formData",
-                        "required": true
+                        "default": "day",
+                        "description": "day, week or month",
+                        "name": "period",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "default": "SARL",
-                        "description": "Company type",
-                        "name": "companyType",
-                        "in": "formData",
-                        "required": true
+                        "description": "Start date (YYYY-MM-DD)",
+                        "name": "start_date",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "default": "12345678901234",
-                        "description": "SIRET number",
-                        "name": "siretNumber",
-                        "in": "formData",
-                        "required": true
+                        "description": "End date, included (YYYY-MM-DD)",
+                        "name": "end_date",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "default": "FR12345678901",
-                        "description": "VAT number",
-                        "name": "vatNumber",
-                        "in": "formData"
+                        "description": "csv to download the report",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PlatformRevenuePoint"
+                            }
+                        }
                     },
-                    {
-                        "type": "string",
-                        "default": "123 Business Street",
-                        "description": "Street address",
-                        "name": "streetAddress",
-                        "in": "formData",
-                        "required": true
+                    "400": {
+                        "description": "error: Invalid period or dates",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     },
-                    {
-                        "type": "string",
-                        "default": "75001",
-                        "description": "Postal code",
-                        "name": "postalCode",
-                        "in": "formData",
-                        "required": true
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     },
-                    {
-                        "type": "string",
-                        "default": "Paris",
-                        "description": "City",
-                        "name": "city",
-                        "in": "formData",
-                        "required": true
+                    "403": {
+                        "description": "error: Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     },
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/analytics/subscriptions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns per day, week (starting on Monday) or month the subscriptions of the whole platform: active subscriptions and MRR (cents, trials excluded) at the end of the period, new and churned subscriptions, and churn rate (churned / active at the start of the period) (admin only). Defaults to the last 30 days, 12 weeks or 12 months. Add format=csv to download the report, amounts in euros",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Get the subscriptions report",
+                "parameters": [
                     {
                         "type": "string",
-                        "default": "France",
-                        "description": "Country",
-                        "name": "country",
-                        "in": "formData",
-                        "required": true
+                        "default": "day",
+                        "description": "day, week or month",
+                        "name": "period",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "default": "FR7630006000011234567890189",
-                        "description": "IBAN",
-                        "name": "iban",
-                        "in": "formData",
-                        "required": true
+                        "description": "Start date (YYYY-MM-DD)",
+                        "name": "start_date",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "default": "BNPAFRPP",
-                        "description": "BIC",
-                        "name": "bic",
-                        "in": "formData",
-                        "required": true
+                        "description": "End date, included (YYYY-MM-DD)",
+                        "name": "end_date",
+                        "in": "query"
                     },
                     {
-                        "type": "file",
-                        "description": "Document proof (PDF, image)",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
+                        "type": "string",
+                        "description": "csv to download the report",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "message: Application updated successfully",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SubscriptionsPoint"
+                            }
                         }
                     },
                     "400": {
-                        "description": "error: Invalid input",
+                        "description": "error: Invalid period or dates",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "403": {
-                        "description": "error: Application cannot be updated",
+                    "401": {
+                        "description": "error: Unauthorized",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "error: No application found",
+                    "403": {
+                        "description": "error: Access denied",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
                         "description": "error: Error message",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/categories": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Submit an application to become a content creator",
-                "consumes": [
-                    "multipart/form-data"
-                ],
+                "description": "Retrieve all categories",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "content-creators"
+                    "categories"
                 ],
-                "summary": "Apply to become a content creator",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "default": "My Creative Company",
-                        "description": "Company name",
-                        "name": "companyName",
-                        "in": "formData",
-                        "required": true
+                "summary": "Get all categories",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Category"
+                            }
+                        }
                     },
-                    {
-                        "type": "string",
-                        "default": "SARL",
-                        "description": "Company type",
-                        "name": "companyType",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "default": "12345678901234",
-                        "description": "SIRET number",
-                        "name": "siretNumber",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "default": "FR12345678901",
-                        "description": "VAT number",
-                        "name": "vatNumber",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "string",
-                        "default": "123 Business Street",
-                        "description": "Street address",
-                        "name": "streetAddress",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "default": "75001",
-                        "description": "Postal code",
-                        "name": "postalCode",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "default": "Paris",
-                        "description": "City",
-                        "name": "city",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "default": "France",
-                        "description": "Country",
-                        "name": "country",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "default": "FR7630006000011234567890189",
-                        "description": "IBAN",
-                        "name": "iban",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "default": "BNPAFRPP",
-                        "description": "BIC",
-                        "name": "bic",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "file",
-                        "description": "Document proof (PDF, image)",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "message: Application submitted successfully",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    },
-                    "400": {
-                        "description": "error: Invalid input",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    },
-                    "409": {
-                        "description": "error: Application already exists",
+                    "401": {
+                        "description": "error: Unauthorized",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
                         "description": "error: Error message",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            }
-        },
-        "/content-creators/all": {
-            "get": {
+            },
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a list of all content creator applications (Admin access only)",
+                "description": "Create a new category with the provided information",
                 "consumes": [
+                    "multipart/form-data",
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "content-creators"
+                    "categories"
+                ],
+                "summary": "Create a new category",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Category name",
+                        "name": "name",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Category picture",
+                        "name": "picture",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "description": "Category data (for JSON requests)",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.CategoryCreate"
+                        }
+                    }
                 ],
-                "summary": "Get all content creator applications (Admin)",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.ContentCreatorInfo"
-                            }
+                            "$ref": "#/definitions/models.Category"
                         }
                     },
-                    "401": {
-                        "description": "error: Unauthorized",
+                    "400": {
+                        "description": "error: Invalid input",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -790,8 +718,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "403": {
-                        "description": "error: Forbidden - Admin access required",
+                    "401": {
+                        "description": "error: Unauthorized",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -811,50 +739,52 @@ const docTemplate = `{
                 }
             }
         },
-        "/content-creators/{id}/status": {
+        "/categories/{id}": {
             "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update the status of a content creator application (Admin access only)",
+                "description": "Update a category with the provided information",
                 "consumes": [
-                    "application/json"
+                    "multipart/form-data"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "content-creators"
+                    "categories"
                 ],
-                "summary": "Update content creator application status (Admin)",
+                "summary": "Update a category",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Content Creator Application ID",
+                        "description": "Category ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "New status",
-                        "name": "status",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.ContentCreatorStatusUpdate"
-                        }
+                        "type": "string",
+                        "description": "Category name",
+                        "name": "name",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Category picture",
+                        "name": "picture",
+                        "in": "formData",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "message: Status updated successfully",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.Category"
                         }
                     },
                     "400": {
@@ -875,17 +805,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "403": {
-                        "description": "error: Forbidden - Admin access required",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
                     "404": {
-                        "description": "error: Content creator application not found",
+                        "description": "error: Category not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -903,33 +824,42 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/insee/{siret}": {
-            "get": {
-                "description": "Get Entreprise Info",
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a category by its ID",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "insee"
+                    "categories"
                 ],
-                "summary": "Get Entreprise Info",
+                "summary": "Delete a category",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Siret",
-                        "name": "siret",
+                        "description": "Category ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "message: Category deleted successfully",
                         "schema": {
-                            "$ref": "#/definitions/insee.EntrepriseInfo"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "error: Unauthorized",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -938,7 +868,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "error: Category not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -947,7 +877,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -958,9 +888,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/login": {
+        "/contact": {
             "post": {
-                "description": "user login with credential",
+                "description": "Submit a new contact request with the provided information",
                 "consumes": [
                     "application/json"
                 ],
@@ -968,23 +898,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "contacts"
                 ],
-                "summary": "user login",
+                "summary": "Create a new contact request",
                 "parameters": [
                     {
-                        "description": "User credentials",
-                        "name": "user",
+                        "description": "Contact information",
+                        "name": "contact",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/auth.LoginRequest"
+                            "$ref": "#/definitions/models.ContactCreate"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "token: JWT token",
+                    "201": {
+                        "description": "message: Contact request submitted successfully, id: contact ID",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -997,15 +927,8 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
-                    "401": {
-                        "description": "error: Wrong credentials or email not verified",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    },
-                    "422": {
-                        "description": "error: JWT not generated",
+                    "500": {
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -1014,164 +937,34 @@ const docTemplate = `{
                 }
             }
         },
-        "/posts": {
+        "/contacts": {
             "get": {
-                "description": "Retrieve all posts with optional filtering",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "posts"
-                ],
-                "summary": "Get all posts",
-                "parameters": [
-                    {
-                        "type": "boolean",
-                        "description": "Filter by free posts",
-                        "name": "isFree",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Filter by category ID",
-                        "name": "category",
-                        "in": "query"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.PostResponse"
-                            }
-                        }
-                    },
-                    "500": {
-                        "description": "error: Error message",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    }
-                }
-            },
-            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new post with the provided information",
+                "description": "Retrieves a list of all contact requests (Admin access only)",
                 "consumes": [
-                    "multipart/form-data"
-                ],
-                "produces": [
                     "application/json"
                 ],
-                "tags": [
-                    "posts"
-                ],
-                "summary": "Create a new post",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Post name",
-                        "name": "name",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "Is the post free",
-                        "name": "isFree",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "Is the post enabled",
-                        "name": "enable",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "csv",
-                        "description": "Category IDs",
-                        "name": "categories",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "file",
-                        "description": "Post picture",
-                        "name": "file",
-                        "in": "formData"
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/models.Post"
-                        }
-                    },
-                    "400": {
-                        "description": "error: Invalid input",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "401": {
-                        "description": "error: Unauthorized",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "500": {
-                        "description": "error: Error message",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    }
-                }
-            }
-        },
-        "/posts/reports": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Get all reports with optional filtering",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "admin"
+                    "contacts"
                 ],
-                "summary": "Get all reports (Admin only)",
+                "summary": "Get all contact requests (Admin)",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Report"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/models.Contact"
+                                }
                             }
                         }
                     },
@@ -1185,7 +978,7 @@ const docTemplate = `{
                         }
                     },
                     "403": {
-                        "description": "error: Forbidden",
+                        "description": "error: Forbidden - Admin access required",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1205,34 +998,61 @@ const docTemplate = `{
                 }
             }
         },
-        "/posts/{id}": {
-            "get": {
-                "description": "Retrieve a post by its ID",
+        "/contacts/{id}/status": {
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update the status of a contact request (Admin access only)",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "posts"
+                    "contacts"
                 ],
-                "summary": "Get a post by ID",
+                "summary": "Update contact status (Admin)",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Post ID",
+                        "description": "Contact ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Status update information",
+                        "name": "statusUpdate",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ContactStatusUpdate"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "message: Contact status updated successfully",
                         "schema": {
-                            "$ref": "#/definitions/models.PostResponse"
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
-                        "description": "error: Post not found",
+                        "description": "error: Contact not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1250,14 +1070,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/content-creators": {
             "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update a post with the provided information",
+                "description": "Update an existing content creator application (rejected or approved)",
                 "consumes": [
                     "multipart/form-data"
                 ],
@@ -1265,168 +1087,280 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "posts"
+                    "content-creators"
                 ],
-                "summary": "Update a post",
+                "summary": "Update a content creator application",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Post ID",
-                        "name": "id",
-                        "in": "path",
+                        "default": "My Creative Company",
+                        "description": "Company name",
+                        "name": "companyName",
+                        "in": "formData",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Post name",
-                        "name": "name",
-                        "in": "formData"
+                        "default": "SARL",
+                        "description": "Company type",
+                        "name": "companyType",
+                        "in": "formData",
+                        "required": true
                     },
                     {
-                        "type": "boolean",
-                        "description": "Is the post free",
-                        "name": "isFree",
-                        "in": "formData"
+                        "type": "string",
+                        "default": "12345678901234",
+                        "description": "SIRET number",
+                        "name": "siretNumber",
+                        "in": "formData",
+                        "required": true
                     },
                     {
-                        "type": "boolean",
-                        "description": "Is the post enabled",
-                        "name": "enable",
+                        "type": "string",
+                        "default": "FR12345678901",
+                        "description": "VAT number",
+                        "name": "vatNumber",
                         "in": "formData"
                     },
                     {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "csv",
-                        "description": "Category IDs",
-                        "name": "categories",
-                        "in": "formData"
+                        "type": "string",
+                        "default": "123 Business Street",
+                        "description": "Street address",
+                        "name": "streetAddress",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "75001",
+                        "description": "Postal code",
+                        "name": "postalCode",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "Paris",
+                        "description": "City",
+                        "name": "city",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "France",
+                        "description": "Country",
+                        "name": "country",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "FR7630006000011234567890189",
+                        "description": "IBAN",
+                        "name": "iban",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "BNPAFRPP",
+                        "description": "BIC",
+                        "name": "bic",
+                        "in": "formData",
+                        "required": true
                     },
                     {
                         "type": "file",
-                        "description": "Post picture",
+                        "description": "Document proof (PDF, image)",
                         "name": "file",
-                        "in": "formData"
+                        "in": "formData",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "message: Application updated successfully",
                         "schema": {
-                            "$ref": "#/definitions/models.Post"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
                         "description": "error: Invalid input",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
-                    "401": {
-                        "description": "error: Unauthorized",
+                    "403": {
+                        "description": "error: Application cannot be updated",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
                     "404": {
-                        "description": "error: Post not found",
+                        "description": "error: No application found",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
                     "500": {
                         "description": "error: Error message",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     }
                 }
             },
-            "delete": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete a post by its ID",
+                "description": "Submit an application to become a content creator",
+                "consumes": [
+                    "multipart/form-data"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "posts"
+                    "content-creators"
                 ],
-                "summary": "Delete a post",
+                "summary": "Apply to become a content creator",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Post ID",
-                        "name": "id",
-                        "in": "path",
+                        "default": "My Creative Company",
+                        "description": "Company name",
+                        "name": "companyName",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "SARL",
+                        "description": "Company type",
+                        "name": "companyType",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "12345678901234",
+                        "description": "SIRET number",
+                        "name": "siretNumber",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "FR12345678901",
+                        "description": "VAT number",
+                        "name": "vatNumber",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "default": "123 Business Street",
+                        "description": "Street address",
+                        "name": "streetAddress",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "75001",
+                        "description": "Postal code",
+                        "name": "postalCode",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "Paris",
+                        "description": "City",
+                        "name": "city",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "France",
+                        "description": "Country",
+                        "name": "country",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "FR7630006000011234567890189",
+                        "description": "IBAN",
+                        "name": "iban",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "BNPAFRPP",
+                        "description": "BIC",
+                        "name": "bic",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Document proof (PDF, image)",
+                        "name": "file",
+                        "in": "formData",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "message: Post deleted successfully",
+                    "201": {
+                        "description": "message: Application submitted successfully",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
-                    "401": {
-                        "description": "error: Unauthorized",
+                    "400": {
+                        "description": "error: Invalid input",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
-                    "404": {
-                        "description": "error: Post not found",
+                    "409": {
+                        "description": "error: Application already exists",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
                     "500": {
                         "description": "error: Error message",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     }
                 }
             }
         },
-        "/posts/{id}/comments": {
-            "post": {
+        "/content-creators/all": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new comment and broadcast it via SSE",
+                "description": "Retrieves a list of all content creator applications (Admin access only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1434,39 +1368,30 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "comments"
+                    "content-creators"
                 ],
-                "summary": "Create a new comment for a post",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Post ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "JWT Token for web clients (optional)",
-                        "name": "token",
-                        "in": "query"
+                "summary": "Get all content creator applications (Admin)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ContentCreatorInfo"
+                            }
+                        }
                     },
-                    {
-                        "description": "Comment content",
-                        "name": "comment",
-                        "in": "body",
-                        "required": true,
+                    "401": {
+                        "description": "error: Unauthorized",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Comment created",
+                    },
+                    "403": {
+                        "description": "error: Forbidden - Admin access required",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1474,17 +1399,51 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "error: Invalid request",
+                    "500": {
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
+                    }
+                }
+            }
+        },
+        "/content-creators/me/analytics": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the current MRR (monthly recurring revenue in cents, multi-month bundles spread over their months, trials excluded) and the number of active, trialing and past due subscribers of the connected content creator",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Get my analytics overview",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CreatorAnalyticsOverview"
+                        }
                     },
                     "401": {
-                        "description": "error: Unauthorized",
+                        "description": "error: User not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Only content creators have analytics",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1493,7 +1452,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error: Server error",
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1504,45 +1463,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/posts/{id}/comments/sse": {
+        "/content-creators/me/analytics/retention": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Connect to SSE to receive comments in real-time for a specific post",
+                "description": "Groups the subscriptions of the connected content creator by the month they started and returns, for each month elapsed since, how many of them were still running. The last cohort is the current month",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "comments"
+                    "analytics"
                 ],
-                "summary": "Handle SSE connection for comments",
+                "summary": "Get my retention cohorts",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Post ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "JWT Token for web clients (optional)",
-                        "name": "token",
+                        "type": "integer",
+                        "default": 6,
+                        "description": "Number of cohorts (1-24)",
+                        "name": "months",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Connected to SSE",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.RetentionCohort"
                             }
                         }
                     },
                     "400": {
-                        "description": "error: Invalid post ID",
+                        "description": "error: Invalid months",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1550,8 +1506,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "error: Unauthorized",
+                    "403": {
+                        "description": "error: Only content creators have analytics",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1560,7 +1516,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error: Error setting up SSE",
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1571,42 +1527,51 @@ const docTemplate = `{
                 }
             }
         },
-        "/posts/{id}/like": {
-            "post": {
+        "/content-creators/me/analytics/revenue": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Add or remove a like on a post",
+                "description": "Returns the subscription revenue of the connected content creator per day, week (starting on Monday) or month, in cents before commission: payments collected, refunds issued and disputes lost during each period. Defaults to the last 30 days, 12 weeks or 12 months",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "posts"
+                    "analytics"
                 ],
-                "summary": "Toggle like on a post",
+                "summary": "Get my revenue over time",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Post ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "default": "day",
+                        "description": "day, week or month",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date, included (YYYY-MM-DD)",
+                        "name": "end_date",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "message: Like added/removed successfully",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.RevenueSeries"
                         }
                     },
-                    "401": {
-                        "description": "error: Unauthorized",
+                    "400": {
+                        "description": "error: Invalid period or dates",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1614,8 +1579,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "error: Post not found",
+                    "403": {
+                        "description": "error: Only content creators have analytics",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1635,51 +1600,51 @@ const docTemplate = `{
                 }
             }
         },
-        "/posts/{id}/report": {
-            "post": {
+        "/content-creators/me/analytics/subscribers": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Report a post for inappropriate content",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the new and churned (canceled or expired) subscribers of the connected content creator per day, week (starting on Monday) or month. Defaults to the last 30 days, 12 weeks or 12 months",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "posts"
+                    "analytics"
                 ],
-                "summary": "Report a post",
+                "summary": "Get my subscribers over time",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Post ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "default": "day",
+                        "description": "day, week or month",
+                        "name": "period",
+                        "in": "query"
                     },
                     {
-                        "description": "Report reason",
-                        "name": "report",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.ReportCreate"
-                        }
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date, included (YYYY-MM-DD)",
+                        "name": "end_date",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Report"
+                            "$ref": "#/definitions/models.SubscriberSeries"
                         }
                     },
                     "400": {
-                        "description": "error: Invalid input",
+                        "description": "error: Invalid period or dates",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1687,8 +1652,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "error: Unauthorized",
+                    "403": {
+                        "description": "error: Only content creators have analytics",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1696,8 +1661,63 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "error: Post not found",
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/content-creators/me/analytics/top-posts": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the posts of the connected content creator with the most likes, then the most comments",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Get my top posts",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of posts (1-50)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PostEngagement"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid limit",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Only content creators have analytics",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1717,36 +1737,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/private-messages": {
+        "/content-creators/me/coupons": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get all messages sent and received by the authenticated user",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the promo codes of the connected content creator with the number of times each one has been redeemed, newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "private-messages"
+                    "plans"
                 ],
-                "summary": "Get user messages",
+                "summary": "Get my promo codes",
                 "responses": {
                     "200": {
-                        "description": "List of messages",
+                        "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.PrivateMessage"
+                                "$ref": "#/definitions/models.Coupon"
                             }
                         }
                     },
                     "401": {
-                        "description": "error: Unauthorized",
+                        "description": "error: User not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Only content creators can manage subscription plans",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1755,7 +1781,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error: Error retrieving messages",
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1771,7 +1797,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Send a private message from the authenticated user to another user",
+                "description": "Create a promo code for the subscriptions of the connected content creator: a percentage or a fixed amount (in cents) off the first payment, with an optional expiry date and redemption limit. Each user can redeem a code once",
                 "consumes": [
                     "application/json"
                 ],
@@ -1779,29 +1805,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "private-messages"
+                    "plans"
                 ],
-                "summary": "Create a private message",
+                "summary": "Create a promo code",
                 "parameters": [
                     {
-                        "description": "Message information",
-                        "name": "message",
+                        "description": "Promo code",
+                        "name": "coupon",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.PrivateMessageCreate"
+                            "$ref": "#/definitions/models.CouponCreate"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Created message",
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.PrivateMessage"
+                            "$ref": "#/definitions/models.Coupon"
                         }
                     },
                     "400": {
-                        "description": "error: Invalid request data",
+                        "description": "error: Invalid input",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1809,8 +1835,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "error: Unauthorized",
+                    "403": {
+                        "description": "error: Only content creators can manage subscription plans",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1818,8 +1844,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "error: Receiver not found",
+                    "409": {
+                        "description": "error: This promo code already exists",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1828,7 +1854,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error: Error creating message",
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1839,36 +1865,51 @@ const docTemplate = `{
                 }
             }
         },
-        "/private-messages/received": {
-            "get": {
+        "/content-creators/me/coupons/{couponId}": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get all messages received by the authenticated user",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Deactivate a promo code: it can no longer be redeemed, the discounts already granted are kept",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "private-messages"
+                    "plans"
+                ],
+                "summary": "Deactivate a promo code",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Coupon ID",
+                        "name": "couponId",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get received messages",
                 "responses": {
                     "200": {
-                        "description": "List of received messages",
+                        "description": "message: Promo code deactivated",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "object"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
-                    "401": {
-                        "description": "error: Unauthorized",
+                    "403": {
+                        "description": "error: Only content creators can manage subscription plans",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: Promo code not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1877,7 +1918,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error: Error retrieving messages",
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1888,36 +1929,64 @@ const docTemplate = `{
                 }
             }
         },
-        "/private-messages/sent": {
+        "/content-creators/me/earnings": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get all messages sent by the authenticated user",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the balance owed to the connected content creator and the history of the ledger transactions (payments, refunds and payouts), newest first. Amounts are in cents, commission already deducted",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "private-messages"
+                    "payouts"
+                ],
+                "summary": "Get my earnings",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Number of transactions (1-200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of transactions to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get sent messages",
                 "responses": {
                     "200": {
-                        "description": "List of sent messages",
+                        "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "object"
+                            "$ref": "#/definitions/models.CreatorEarnings"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid limit or offset",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
                     "401": {
-                        "description": "error: Unauthorized",
+                        "description": "error: User not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Only content creators have earnings",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1926,7 +1995,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error: Error retrieving messages",
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1937,54 +2006,33 @@ const docTemplate = `{
                 }
             }
         },
-        "/private-messages/{id}/read": {
-            "patch": {
+        "/content-creators/me/plans": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Mark a specific private message as read",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns all the plans of the connected content creator, including inactive ones",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "private-messages"
-                ],
-                "summary": "Mark message as read",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Message ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "plans"
                 ],
+                "summary": "Get my subscription plans",
                 "responses": {
                     "200": {
-                        "description": "message: Message marked as read",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "400": {
-                        "description": "error: Bad request",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SubscriptionPlan"
                             }
                         }
                     },
                     "401": {
-                        "description": "error: Unauthorized",
+                        "description": "error: User not authenticated",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1993,16 +2041,7 @@ const docTemplate = `{
                         }
                     },
                     "403": {
-                        "description": "error: Forbidden",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "404": {
-                        "description": "error: Message not found",
+                        "description": "error: Only content creators can manage subscription plans",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2011,7 +2050,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error: Error updating message",
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2020,11 +2059,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/register": {
+            },
             "post": {
-                "description": "Create a new user with the provided information",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a monthly subscription plan for the connected content creator (one plan per tier), with an optional free trial for first-time subscribers and discounted 3, 6 or 12 month bundles",
                 "consumes": [
                     "application/json"
                 ],
@@ -2032,82 +2074,56 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "plans"
                 ],
-                "summary": "Create a new user",
+                "summary": "Create a subscription plan",
                 "parameters": [
                     {
-                        "description": "User information",
-                        "name": "user",
+                        "description": "Plan information (price in cents)",
+                        "name": "plan",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UserCreate"
+                            "$ref": "#/definitions/models.SubscriptionPlanCreate"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "message: User created successfully, email: user email",
+                        "description": "Created",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/models.SubscriptionPlan"
                         }
                     },
                     "400": {
                         "description": "error: Invalid input",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "409": {
-                        "description": "error: Email already exists",
+                    "403": {
+                        "description": "error: Only content creators can manage subscription plans",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "500": {
-                        "description": "error: Error message",
+                    "409": {
+                        "description": "error: A plan already exists for this tier",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
-                        }
-                    }
-                }
-            }
-        },
-        "/subscriptions": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Return all the subscriptions (active, canceled, history) of the connected user",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "subscriptions"
-                ],
-                "summary": "List the user's subscriptions",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Subscription"
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
-                    "401": {
-                        "description": "error: Unauthorized",
+                    "500": {
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2118,14 +2134,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/subscriptions/checkout/{contentCreatorId}": {
-            "post": {
+        "/content-creators/me/plans/{planId}": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Start a Stripe payment to subscribe to a content creator (verified role). Returns the Stripe session ID to use on the frontend.",
+                "description": "Update a plan of the connected content creator. A new price only applies to new subscribers.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2133,30 +2149,36 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "plans"
                 ],
-                "summary": "Create a Stripe Checkout session for subscription",
+                "summary": "Update a subscription plan",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID of the content creator",
-                        "name": "contentCreatorId",
+                        "description": "Plan ID",
+                        "name": "planId",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Fields to update (price in cents)",
+                        "name": "plan",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SubscriptionPlanUpdate"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "sessionId: ID of the Stripe Checkout session, url: Stripe Checkout URL",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.SubscriptionPlan"
                         }
                     },
-                    "401": {
-                        "description": "error: Unauthorized",
+                    "400": {
+                        "description": "error: Invalid input",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2165,7 +2187,7 @@ const docTemplate = `{
                         }
                     },
                     "403": {
-                        "description": "error: Can only subscribe to a content creator",
+                        "description": "error: Only content creators can manage subscription plans",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2174,7 +2196,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "error: User not found",
+                        "description": "error: Plan not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2183,7 +2205,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error: Stripe error or server error",
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2192,52 +2214,33 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/subscriptions/revenue": {
-            "get": {
+            },
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Returns the total amount of successful subscription payments between two dates (admin only)",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Deactivate a plan: it is no longer offered, existing subscriptions are kept",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "plans"
                 ],
-                "summary": "Get the total revenue of the site",
+                "summary": "Deactivate a subscription plan",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Start date (YYYY-MM-DD)",
-                        "name": "start_date",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "End date (YYYY-MM-DD)",
-                        "name": "end_date",
-                        "in": "query",
+                        "description": "Plan ID",
+                        "name": "planId",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "total: total amount in cents",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    },
-                    "400": {
-                        "description": "error: Invalid input",
+                        "description": "message: Plan deactivated",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2245,8 +2248,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "error: Unauthorized",
+                    "403": {
+                        "description": "error: Only content creators can manage subscription plans",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2254,8 +2257,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "403": {
-                        "description": "error: Access denied",
+                    "404": {
+                        "description": "error: Plan not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2264,7 +2267,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error: Server error",
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2275,55 +2278,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/subscriptions/top-creators": {
+        "/content-creators/{id}/plans": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Returns the top 3 content creators with the most active subscriptions (admin only)",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the active plans of a content creator, cheapest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "plans"
+                ],
+                "summary": "Get the subscription plans of a content creator",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Content creator ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get top 3 content creators by active subscriptions",
                 "responses": {
                     "200": {
-                        "description": "Top creators with their subscription count",
+                        "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "type": "object",
-                                "additionalProperties": true
-                            }
-                        }
-                    },
-                    "401": {
-                        "description": "error: Unauthorized",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                                "$ref": "#/definitions/models.SubscriptionPlan"
                             }
                         }
                     },
-                    "403": {
-                        "description": "error: Access denied",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "500": {
-                        "description": "error: Server error",
+                    "500": {
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2334,14 +2324,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/subscriptions/{subscriptionId}": {
-            "get": {
+        "/content-creators/{id}/status": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Return the detailed information of a subscription",
+                "description": "Update the status of a content creator application (Admin access only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -2349,23 +2339,44 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "content-creators"
                 ],
-                "summary": "Details of a subscription",
+                "summary": "Update content creator application status (Admin)",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID of the subscription",
-                        "name": "subscriptionId",
+                        "description": "Content Creator Application ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "New status",
+                        "name": "status",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ContentCreatorStatusUpdate"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "message: Status updated successfully",
                         "schema": {
-                            "$ref": "#/definitions/models.Subscription"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "401": {
@@ -2378,7 +2389,7 @@ const docTemplate = `{
                         }
                     },
                     "403": {
-                        "description": "error: You are not authorized to view this subscription",
+                        "description": "error: Forbidden - Admin access required",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2387,7 +2398,16 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "error: Subscription not found",
+                        "description": "error: Content creator application not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2396,36 +2416,46 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/feed": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Cancel a Stripe subscription and update its status in the database",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Retrieve, newest first, the posts of the creators the user has an active subscription to, plus the free posts of the creators the user follows and the posts the user bought",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "subscriptions"
+                    "posts"
                 ],
-                "summary": "Cancel a subscription",
+                "summary": "Get the personalized feed",
                 "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of posts per page (default 20, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "ID of the subscription to cancel",
-                        "name": "subscriptionId",
-                        "in": "path",
-                        "required": true
+                        "description": "Opaque cursor returned as nextCursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "message: Subscription canceled successfully",
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PostPage"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid query parameter",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2442,8 +2472,43 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "403": {
-                        "description": "error: You are not authorized to cancel this subscription",
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/insee/{siret}": {
+            "get": {
+                "description": "Get Entreprise Info",
+                "tags": [
+                    "insee"
+                ],
+                "summary": "Get Entreprise Info",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Siret",
+                        "name": "siret",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/insee.EntrepriseInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2452,7 +2517,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "error: Subscription not found",
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2461,7 +2526,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error: Error when canceling the Stripe subscription",
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2472,34 +2537,79 @@ const docTemplate = `{
                 }
             }
         },
-        "/users": {
+        "/invoices": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a list of all users (Admin access only)",
-                "consumes": [
+                "description": "Returns the invoices of the subscription payments of the connected user, newest first",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "invoices"
+                ],
+                "summary": "Get my invoices",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Invoice"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: User not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/invoices/received": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the invoices issued on behalf of the connected content creator for the subscription payments they received, newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "invoices"
                 ],
-                "summary": "Get all users (Admin)",
+                "summary": "Get received invoices",
                 "responses": {
                     "200": {
-                        "description": "users: array of user objects",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Invoice"
+                            }
                         }
                     },
                     "401": {
-                        "description": "error: Unauthorized",
+                        "description": "error: User not authenticated",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2508,7 +2618,7 @@ const docTemplate = `{
                         }
                     },
                     "403": {
-                        "description": "error: Forbidden - Admin access required",
+                        "description": "error: Only content creators receive payments",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2517,7 +2627,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error: error message",
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2528,47 +2638,39 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/password": {
-            "put": {
+        "/invoices/{id}/pdf": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update user's password by verifying the old password and setting a new one",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Download an invoice as a PDF. Only the buyer and the content creator of the invoice can download it",
                 "produces": [
-                    "application/json"
+                    "application/pdf"
                 ],
                 "tags": [
-                    "users"
+                    "invoices"
                 ],
-                "summary": "Update user password",
+                "summary": "Download an invoice",
                 "parameters": [
                     {
-                        "description": "Password update information",
-                        "name": "password",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.PasswordUpdate"
-                        }
+                        "type": "string",
+                        "description": "Invoice ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "message: Password updated successfully",
+                        "description": "PDF invoice",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "file"
                         }
                     },
-                    "400": {
-                        "description": "error: Invalid request",
+                    "401": {
+                        "description": "error: User not authenticated",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2576,8 +2678,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "error: Invalid old password",
+                    "403": {
+                        "description": "error: You don't have access to this invoice",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2586,7 +2688,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "error: User not found",
+                        "description": "error: Invoice not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2595,7 +2697,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error: Error updating password",
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2606,9 +2708,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/password/reset/confirm": {
+        "/login": {
             "post": {
-                "description": "Change the password if the code is correct and not expired",
+                "description": "user login with credential",
                 "consumes": [
                     "application/json"
                 ],
@@ -2616,112 +2718,162 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "auth"
                 ],
-                "summary": "Reset password with a code",
+                "summary": "user login",
                 "parameters": [
                     {
-                        "description": "Email, code, new password",
-                        "name": "data",
+                        "description": "User credentials",
+                        "name": "user",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/users.PasswordResetConfirm"
+                            "$ref": "#/definitions/auth.LoginRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "message: Password reset",
+                        "description": "token: JWT token (15 min), refreshToken: refresh token, or twoFactorRequired and challengeToken when 2FA is enabled",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "error: Invalid data or code incorrect/expired",
+                        "description": "error: Invalid input",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "error: Wrong credentials or email not verified",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "422": {
+                        "description": "error: JWT not generated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "429": {
+                        "description": "error: Too many failed attempts, retryAfter: seconds before retry",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     }
                 }
             }
         },
-        "/users/password/reset/request": {
+        "/logout": {
             "post": {
-                "description": "send a reset password code to the email if the user exists",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Revoke the current session: its access and refresh tokens stop working",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "auth"
                 ],
-                "summary": "send a reset password code",
-                "parameters": [
-                    {
-                        "description": "Email of the user",
-                        "name": "data",
-                        "in": "body",
-                        "required": true,
+                "summary": "Logout",
+                "responses": {
+                    "200": {
+                        "description": "message: Logged out successfully",
                         "schema": {
-                            "$ref": "#/definitions/users.PasswordResetRequest"
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     }
+                }
+            }
+        },
+        "/logout-all": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revoke every active session of the current user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
                 ],
+                "summary": "Logout from all devices",
                 "responses": {
                     "200": {
-                        "description": "message: Code sent",
+                        "description": "message: Logged out from all sessions, revoked: number of sessions",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
-                    "404": {
-                        "description": "error: User not found",
+                    "401": {
+                        "description": "error: Unauthorized",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     }
                 }
             }
         },
-        "/users/profile": {
+        "/payouts/batches": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get the current authenticated user's profile information",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the payout batches, newest first (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "payouts"
                 ],
-                "summary": "Get user profile",
+                "summary": "List the payout batches",
                 "responses": {
                     "200": {
-                        "description": "user: user object",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PayoutBatch"
+                            }
                         }
                     },
                     "401": {
@@ -2733,8 +2885,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "error: User not found",
+                    "403": {
+                        "description": "error: Access denied",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2743,7 +2895,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error: Error retrieving profile",
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2753,83 +2905,29 @@ const docTemplate = `{
                     }
                 }
             },
-            "put": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update the current authenticated user's profile information with optional profile picture",
-                "consumes": [
-                    "multipart/form-data"
-                ],
+                "description": "Pays out the whole positive balance of every approved content creator: one payout per creator, debited from their balance in the ledger. Creators without approved bank details are skipped and keep their balance (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
-                ],
-                "summary": "Update user profile",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "UserName",
-                        "name": "userName",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "string",
-                        "description": "First name",
-                        "name": "firstName",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Last name",
-                        "name": "lastName",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Biography",
-                        "name": "bio",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Email address",
-                        "name": "email",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Sexe",
-                        "name": "sexe",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "string",
-                        "description": "BirthDayDate",
-                        "name": "birthDayDate",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "file",
-                        "description": "Profile picture image file",
-                        "name": "profilePicture",
-                        "in": "formData"
-                    }
+                    "payouts"
                 ],
+                "summary": "Create a payout batch",
                 "responses": {
-                    "200": {
-                        "description": "message: Profile updated successfully, user: updated user object",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/models.PayoutBatch"
                         }
                     },
-                    "400": {
-                        "description": "error: Invalid request data",
+                    "401": {
+                        "description": "error: Unauthorized",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2837,8 +2935,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "error: Unauthorized",
+                    "403": {
+                        "description": "error: Access denied",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2846,8 +2944,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "error: User not found",
+                    "409": {
+                        "description": "error: No balance to pay out",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2856,7 +2954,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error: Error updating profile",
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2867,62 +2965,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/statistics": {
+        "/payouts/batches/{id}/sepa": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get count of users by month or year",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Download the SEPA credit transfer file (pain.001.001.03) of a payout batch, to upload to the bank of the platform (admin only)",
                 "produces": [
-                    "application/json"
+                    "text/xml"
                 ],
                 "tags": [
-                    "users"
+                    "payouts"
                 ],
-                "summary": "Get user statistics (Admin)",
+                "summary": "Export a payout batch as a SEPA credit transfer",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Filter type: 'month' or 'year'",
-                        "name": "filter",
-                        "in": "query",
+                        "description": "Payout batch ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Year to filter by (default is current year)",
-                        "name": "year",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Month to filter by (1-12, only used with 'month' filter)",
-                        "name": "month",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/users.UserStatsResponse"
-                            }
-                        }
-                    },
-                    "400": {
-                        "description": "error: Invalid filter parameter",
+                        "description": "pain.001 XML file",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "file"
                         }
                     },
                     "401": {
@@ -2934,48 +3005,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "error: Error retrieving statistics",
+                    "403": {
+                        "description": "error: Access denied",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    }
-                }
-            }
-        },
-        "/users/stats/gender": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Get the count of users for each gender (Admin access only)",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "users"
-                ],
-                "summary": "Get user gender statistics (Admin)",
-                "responses": {
-                    "200": {
-                        "description": "Gender counts",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "integer"
-                            }
-                        }
                     },
-                    "401": {
-                        "description": "error: Unauthorized",
+                    "404": {
+                        "description": "error: Payout batch not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2984,7 +3024,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error: Internal server error",
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -2995,31 +3035,165 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/stats/roles": {
+        "/posts": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get the count of users for each role (Admin access only)",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Retrieve posts newest first with keyset pagination and optional filtering. Paid posts are returned as locked teasers (without pictureUrl) unless the caller is the author, an admin or has an active subscription to the author",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "posts"
                 ],
-                "summary": "Get user role statistics (Admin)",
-                "responses": {
-                    "200": {
-                        "description": "Role counts",
+                "summary": "Get all posts",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Filter by free posts",
+                        "name": "isFree",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by category ID",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by author user ID",
+                        "name": "author",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only posts created on or after this date (YYYY-MM-DD or RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only posts created before this date: a YYYY-MM-DD date includes the whole day, an RFC3339 timestamp is exclusive",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of posts per page (default 20, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor returned as nextCursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PostPage"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid query parameter",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "integer"
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new post with the provided information",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Create a new post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Post name",
+                        "name": "name",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Is the post free",
+                        "name": "isFree",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Pay-per-view price in cents (0 = included in the subscription)",
+                        "name": "price",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Is the post enabled",
+                        "name": "enable",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Category IDs",
+                        "name": "categories",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Post picture",
+                        "name": "file",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Post"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -3033,7 +3207,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error: Internal server error",
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -3044,133 +3218,4335 @@ const docTemplate = `{
                 }
             }
         },
-        "/valid-email/{token}": {
+        "/posts/reports": {
             "get": {
-                "description": "Resend validation email for users who loose their code or code is expired",
-                "consumes": [
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all reports with optional filtering",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get all reports (Admin only)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Report"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a post by its ID. A paid post is returned as a locked teaser (without pictureUrl) unless the caller is the author, an admin or has an active subscription to the author",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "posts"
                 ],
-                "summary": "Resend Validation email",
+                "summary": "Get a post by ID",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "user send email for received new mail",
-                        "name": "email",
+                        "description": "Post ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "message\": \"send email at user email address",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/models.PostResponse"
                         }
                     },
-                    "400": {
-                        "description": "error: User already validated account",
+                    "404": {
+                        "description": "error: Post not found",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "error: User not found",
+                    "500": {
+                        "description": "error: Error message",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "auth.LoginRequest": {
-            "description": "model for user login",
-            "type": "object",
-            "required": [
-                "email",
-                "password"
-            ],
-            "properties": {
-                "email": {
-                    "type": "string",
-                    "example": "utilisateur@exemple.com"
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update a post with the provided information",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Update a post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Post name",
+                        "name": "name",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Is the post free",
+                        "name": "isFree",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Pay-per-view price in cents (0 = included in the subscription)",
+                        "name": "price",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Is the post enabled",
+                        "name": "enable",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Category IDs",
+                        "name": "categories",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Post picture",
+                        "name": "file",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Post"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: Post not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a post by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Delete a post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Post deleted successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: Post not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/comments": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the top-level comments of a post with their number of replies, or with parent_id the replies to one comment, oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "Get the comments of a post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comment ID to get the replies of",
+                        "name": "parent_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "comments: list of comments",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid parent comment ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Failed to retrieve comments",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new comment, or a reply to a comment of the same post with parentId, and broadcast it via SSE",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "Create a new comment for a post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Comment content and optional parentId",
+                        "name": "comment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Comment created",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: Parent comment not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/comments/sse": {
+            "get": {
+                "description": "Connect to SSE to receive comments in real-time for a specific post: \"comment\" events for new comments, \"reply\" events for replies (nested under their parentId), \"comment_updated\" and \"comment_deleted\" events when a comment is edited or removed, \"like\" events with the new likesCount and \"presence\" events with the number of viewers currently connected to the post. Every event except presence has an id: when the stream is opened with the lastEventId parameter (or the Last-Event-ID header) only the missed events are replayed, otherwise all the comments are sent again. A heartbeat comment is sent every 15 seconds, and a client that does not read fast enough is disconnected. The stream ticket is single-use, so the automatic reconnection of EventSource is refused: after any disconnection, request a new ticket and open a new stream with lastEventId set to the id of the last event received",
+                "tags": [
+                    "comments"
+                ],
+                "summary": "Handle SSE connection for comments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Stream ticket from POST /stream-tickets, can also be sent in the stream_ticket cookie",
+                        "name": "ticket",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Id of the last event received, to resume the stream with a new ticket",
+                        "name": "lastEventId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Id of the last event received, to resume the stream",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Connected to SSE",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid post ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error setting up SSE",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "error: Too many clients connected to this stream",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/comments/{commentId}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Edit a comment (author only). The previous content is kept in the edit history and the comment is flagged as edited. Broadcast as a \"comment_updated\" SSE event",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "Edit a comment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comment ID",
+                        "name": "commentId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New content",
+                        "name": "comment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CommentUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "comment: edited comment",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid comment data",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Only the author can edit this comment",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: Comment not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a comment. Authors can delete their comments, post owners and admins can remove any comment on the post. A deleted comment with replies stays in the thread without its content. Broadcast as a \"comment_deleted\" SSE event",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "Delete a comment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comment ID",
+                        "name": "commentId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Comment deleted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Not authorized to delete this comment",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: Comment not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/comments/{commentId}/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the previous versions of an edited comment, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "Get the edit history of a comment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comment ID",
+                        "name": "commentId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "edits: previous versions of the comment",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: Comment not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/like": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add or remove a like on a post, the new likes count is broadcast to the viewers of the post as a \"like\" event",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Toggle like on a post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Like added/removed successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: Post not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/purchase": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a one-off Stripe Checkout session to buy a post sold at a fixed price. Once paid, the post is unlocked for the buyer, subscribed or not",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Buy a pay-per-view post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "sessionId: ID of the Stripe Checkout session, url: Stripe Checkout URL, purchaseId: ID of the purchase",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error: This post is not for sale",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: User or post not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "error: You already bought this post, or a purchase of this post is already in progress",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Stripe error or server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/report": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Report a post for inappropriate content",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "Report a post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Report reason",
+                        "name": "report",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ReportCreate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Report"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: Post not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/private-messages": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all messages sent and received by the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "private-messages"
+                ],
+                "summary": "Get user messages",
+                "responses": {
+                    "200": {
+                        "description": "List of messages",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PrivateMessage"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error retrieving messages",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Send a private message from the authenticated user to another user. If the receiver is connected to the WebSocket the message is pushed to them and delivered is true",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "private-messages"
+                ],
+                "summary": "Create a private message",
+                "parameters": [
+                    {
+                        "description": "Message information",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PrivateMessageCreate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created message",
+                        "schema": {
+                            "$ref": "#/definitions/models.PrivateMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid request data",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: Receiver not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error creating message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/private-messages/received": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all messages received by the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "private-messages"
+                ],
+                "summary": "Get received messages",
+                "responses": {
+                    "200": {
+                        "description": "List of received messages",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "object"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error retrieving messages",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/private-messages/sent": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all messages sent by the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "private-messages"
+                ],
+                "summary": "Get sent messages",
+                "responses": {
+                    "200": {
+                        "description": "List of sent messages",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "object"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error retrieving messages",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/private-messages/ws": {
+            "get": {
+                "description": "Open a WebSocket that pushes the private messages of the connected user as JSON frames {\"type\",\"payload\"}: \"unread\" with the number of unread messages on connection (to fetch with GET /private-messages/received what arrived while offline), \"message\" for a new message, \"read\" when a sent message is read and \"typing\" when a user is writing to you. Send {\"type\":\"typing\",\"payload\":{\"receiverId\":\"...\"}} to show you are writing. Authenticated with a ticket from POST /stream-tickets with stream \"messages\"",
+                "tags": [
+                    "private-messages"
+                ],
+                "summary": "Private messages WebSocket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Stream ticket, can also be sent in the stream_ticket cookie",
+                        "name": "ticket",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "error: Invalid or expired stream ticket",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/private-messages/{id}/read": {
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Mark a specific private message as read, the sender receives a \"read\" frame on the WebSocket",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "private-messages"
+                ],
+                "summary": "Mark message as read",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Message marked as read",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error: Bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: Message not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error updating message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/purchases": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return all the pay-per-view purchases of the connected user, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "posts"
+                ],
+                "summary": "List my post purchases",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PostPurchase"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error fetching purchases",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/register": {
+            "post": {
+                "description": "Create a new user with the provided information",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Create a new user",
+                "parameters": [
+                    {
+                        "description": "User information",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserCreate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "message: User created successfully, email: user email",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "error: Email already exists",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/stream-tickets": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Exchange the JWT for a single-use ticket valid for one minute, to open the comments SSE stream of a post (stream \"comments\", the default) or the private messages WebSocket (stream \"messages\"): browsers cannot send the Authorization header on these connections. The ticket is returned in the body, to pass as the ticket query parameter, and in an HttpOnly cookie restricted to the stream URL",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "comments"
+                ],
+                "summary": "Get a stream ticket",
+                "parameters": [
+                    {
+                        "description": "Stream to open",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.StreamTicketRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.StreamTicketResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: User not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: Post not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/stripe/events": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the received Stripe events, failed ones by default (admin only)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stripe"
+                ],
+                "summary": "List Stripe webhook events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "FAILED",
+                        "description": "Event status (PROCESSING, PROCESSED, FAILED, IGNORED)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Maximum number of events",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.StripeEvent"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid limit",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Access denied: admin role required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error fetching events",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/stripe/events/{id}/replay": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Process a failed Stripe event again, even if its automatic retries are exhausted (admin only)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stripe"
+                ],
+                "summary": "Replay a failed Stripe event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Stripe event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Result of the processing",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Access denied: admin role required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: Event not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "error: Event is not in a replayable state",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Replay failed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return all the subscriptions (active, canceled, history) of the connected user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "List the user's subscriptions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Subscription"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/checkout/{contentCreatorId}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Start a Stripe payment to subscribe to a content creator (verified role). A 3, 6 or 12 month bundle offered by the plan can be chosen, a promo code of the creator discounts the first payment and first-time subscribers get the free trial of the plan. Returns the Stripe session ID to use on the frontend.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Create a Stripe Checkout session for subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the content creator",
+                        "name": "contentCreatorId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Chosen plan (defaults to the cheapest active plan), bundle and promo code",
+                        "name": "checkout",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.SubscriptionCheckoutRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "sessionId: ID of the Stripe Checkout session, url: Stripe Checkout URL",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Can only subscribe to a content creator",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: User, content creator or plan not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "error: Already subscribed, promo code already used or redemption limit reached",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Stripe error or server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/disputes": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the chargebacks opened by fans on subscription payments, tips and post purchases, newest first (admin only)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "List the payment disputes",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PaymentDispute"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/payments/{paymentId}/refund": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Issue a full or partial refund of a subscription payment through the payment provider (admin only). The refund is recorded when the provider confirms it by webhook: a full refund ends the subscription immediately",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Refund a subscription payment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the subscription payment",
+                        "name": "paymentId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Amount to refund in cents (the whole remaining amount if empty)",
+                        "name": "refund",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.PaymentRefundRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "message: Refund requested, refundId, amount",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: Payment not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "error: Only succeeded payments can be refunded",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error when refunding the payment",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/revenue": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the net revenue between two dates, both included, from the ledger (admin only): subscription payments, tips and post purchases collected over the period, minus the refunds issued and the disputes withdrawn over the period, whatever the payment they apply to",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Get the total revenue of the site",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD)",
+                        "name": "start_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date, included (YYYY-MM-DD)",
+                        "name": "end_date",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "total: net amount in cents, subscriptions, tips and purchases: payments collected in cents, refunds and disputes: amounts taken back in cents",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/top-creators": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the top 3 content creators with the most active subscriptions (admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Get top 3 content creators by active subscriptions",
+                "responses": {
+                    "200": {
+                        "description": "Top creators with their subscription count",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "object",
+                                "additionalProperties": true
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Access denied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{subscriptionId}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return the detailed information of a subscription",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Details of a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the subscription",
+                        "name": "subscriptionId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Subscription"
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: You are not authorized to view this subscription",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: Subscription not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Schedule the cancellation of a Stripe subscription at the end of the paid period (pending subscriptions are canceled immediately)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Cancel a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the subscription to cancel",
+                        "name": "subscriptionId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Subscription will be canceled at the end of the current period, endDate",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: You are not authorized to cancel this subscription",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: Subscription not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "error: Subscription already ended",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error when canceling the Stripe subscription",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tips/received": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return the paid tips received by the connected content creator, newest first, with the total amount in cents",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tips"
+                ],
+                "summary": "List the tips I received",
+                "responses": {
+                    "200": {
+                        "description": "tips: list of tips, total: total amount in cents",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error fetching tips",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tips/sent": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return all the tips sent by the connected user, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tips"
+                ],
+                "summary": "List the tips I sent",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Tip"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error fetching tips",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tips/{contentCreatorId}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a one-off Stripe Checkout session to send a tip (with an optional message) to a content creator, from their profile or on one of their posts",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tips"
+                ],
+                "summary": "Tip a content creator",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the content creator",
+                        "name": "contentCreatorId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Amount in cents, optional message and post",
+                        "name": "tip",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TipCreate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "sessionId: ID of the Stripe Checkout session, url: Stripe Checkout URL, tipId: ID of the tip",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Can only tip a content creator",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: User, content creator or post not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Stripe error or server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/token/refresh": {
+            "post": {
+                "description": "Exchange a refresh token for a new access token and a new refresh token (rotation). Reusing an already rotated refresh token revokes the whole session.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh access token",
+                "parameters": [
+                    {
+                        "description": "Refresh token",
+                        "name": "refresh",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RefreshTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "token: JWT token, refreshToken: new refresh token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "error: Invalid, expired or revoked refresh token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a list of all users (Admin access only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get all users (Admin)",
+                "responses": {
+                    "200": {
+                        "description": "users: array of user objects",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Forbidden - Admin access required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/following": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the content creators followed by the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get followed content creators",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.UserInfo"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/password": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update user's password by verifying the old password and setting a new one. Every session of the user is revoked, including the current one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update user password",
+                "parameters": [
+                    {
+                        "description": "Password update information",
+                        "name": "password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PasswordUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Password updated successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Invalid old password",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: User not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error updating password",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/password/reset/confirm": {
+            "post": {
+                "description": "Change the password if the code is correct and not expired. Every session of the user is revoked",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Reset password with a code",
+                "parameters": [
+                    {
+                        "description": "Email, code, new password",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/users.PasswordResetConfirm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Password reset",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid data or code incorrect/expired",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "error: Too many attempts, request a new code, or too many failed attempts on the account or IP",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/password/reset/request": {
+            "post": {
+                "description": "send a reset password code to the email if the user exists",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "send a reset password code",
+                "parameters": [
+                    {
+                        "description": "Email of the user",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/users.PasswordResetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Code sent",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: User not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "error: Too many failed attempts or a code was sent recently, retryAfter: seconds before retry",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/profile": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the current authenticated user's profile information",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user profile",
+                "responses": {
+                    "200": {
+                        "description": "user: user object",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: User not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error retrieving profile",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update the current authenticated user's profile information with optional profile picture",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update user profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UserName",
+                        "name": "userName",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "First name",
+                        "name": "firstName",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last name",
+                        "name": "lastName",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Biography",
+                        "name": "bio",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email address",
+                        "name": "email",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sexe",
+                        "name": "sexe",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "BirthDayDate",
+                        "name": "birthDayDate",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Profile picture image file",
+                        "name": "profilePicture",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Profile updated successfully, user: updated user object",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid request data",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: User not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error updating profile",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/statistics": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get count of users by month or year",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user statistics (Admin)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter type: 'month' or 'year'",
+                        "name": "filter",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Year to filter by (default is current year)",
+                        "name": "year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Month to filter by (1-12, only used with 'month' filter)",
+                        "name": "month",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/users.UserStatsResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid filter parameter",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error retrieving statistics",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/stats/gender": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the count of users for each gender (Admin access only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user gender statistics (Admin)",
+                "responses": {
+                    "200": {
+                        "description": "Gender counts",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/stats/roles": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the count of users for each role (Admin access only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user role statistics (Admin)",
+                "responses": {
+                    "200": {
+                        "description": "Role counts",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/follow": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Follow or unfollow a content creator. Free posts of followed creators appear in the personalized feed",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Toggle follow on a content creator",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Content creator user ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Follow added/removed successfully, action: added/removed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "error: You cannot follow yourself",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error: Can only follow a content creator",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: User not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error: Error message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/valid-email/{code}": {
+            "get": {
+                "description": "After create account, user valid it email. Each code accepts a limited number of attempts, and wrong codes count towards the lockout of the account and of the IP. The email is optional so that clients sending only the code keep working: without it the code alone identifies the account and wrong codes only count for the IP. Sending it is recommended.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Validation email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "user code received by mail",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "user email, recommended",
+                        "name": "email",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message\": \"User validate account",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "error: User already validated account or invalid code",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "error: Confirmation code expired",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "error: User not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "429": {
+                        "description": "error: Too many attempts, request a new code",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/valid-email/{token}": {
+            "get": {
+                "description": "Resend validation email for users who loose their code or code is expired",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Resend Validation email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "user send email for received new mail",
+                        "name": "email",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message\": \"send email at user email address",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "error: User already validated account",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "error: User not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "429": {
+                        "description": "error: Too many failed attempts or a code was sent recently, retryAfter: seconds before retry",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "auth.LoginRequest": {
+            "description": "model for user login",
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "utilisateur@exemple.com"
+                },
+                "password": {
+                    "type": "string",
+                    "minLength": 6,
+                    "example": "Motdepasse123"
+                }
+            }
+        },
+        "auth.TwoFactorCodeRequest": {
+            "description": "code TOTP à 6 chiffres ou code de secours",
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "auth.TwoFactorVerifyRequest": {
+            "description": "challenge token renvoyé par /login et code TOTP ou code de secours",
+            "type": "object",
+            "required": [
+                "challengeToken",
+                "code"
+            ],
+            "properties": {
+                "challengeToken": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "insee.EntrepriseInfo": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "city": {
+                    "type": "string"
+                },
+                "company_name": {
+                    "type": "string"
+                },
+                "company_type": {
+                    "type": "string"
+                },
+                "postal_code": {
+                    "type": "string"
+                },
+                "siret": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Category": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "pictureUrl": {
+                    "type": "string"
+                },
+                "posts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Post"
+                    }
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CategoryCreate": {
+            "type": "object",
+            "required": [
+                "name",
+                "pictureUrl"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "pictureUrl": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CommentUpdate": {
+            "type": "object",
+            "required": [
+                "content"
+            ],
+            "properties": {
+                "content": {
+                    "type": "string",
+                    "example": "Nouveau contenu"
+                }
+            }
+        },
+        "models.Contact": {
+            "description": "Modèle complet d'une demande de contact",
+            "type": "object",
+            "required": [
+                "email",
+                "firstName",
+                "lastName",
+                "message",
+                "subject"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "firstName": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lastName": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/models.StatusType"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "submittedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ContactCreate": {
+            "description": "modèle pour créer une demande de contact",
+            "type": "object",
+            "required": [
+                "email",
+                "firstName",
+                "lastName",
+                "message",
+                "subject"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "jean.dupont@exemple.com"
+                },
+                "firstName": {
+                    "type": "string",
+                    "example": "Jean"
+                },
+                "lastName": {
+                    "type": "string",
+                    "example": "Dupont"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "J'aimerais avoir plus d'informations sur vos services."
+                },
+                "subject": {
+                    "type": "string",
+                    "example": "Demande d'information"
+                }
+            }
+        },
+        "models.ContactStatusUpdate": {
+            "description": "modèle pour mettre à jour le statut d'une demande de contact",
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.StatusType"
+                        }
+                    ],
+                    "example": "processing"
+                }
+            }
+        },
+        "models.ContentCreatorInfo": {
+            "type": "object",
+            "required": [
+                "bic",
+                "city",
+                "companyName",
+                "companyType",
+                "country",
+                "documentProofUrl",
+                "iban",
+                "postalCode",
+                "siretNumber",
+                "streetAddress"
+            ],
+            "properties": {
+                "bic": {
+                    "type": "string"
+                },
+                "city": {
+                    "type": "string"
+                },
+                "companyName": {
+                    "type": "string"
+                },
+                "companyType": {
+                    "type": "string"
+                },
+                "country": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "documentProofUrl": {
+                    "type": "string"
+                },
+                "iban": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "postalCode": {
+                    "type": "string"
+                },
+                "siretNumber": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/models.ContentCreatorStatusType"
+                },
+                "streetAddress": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                },
+                "vatNumber": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ContentCreatorStatusType": {
+            "type": "string",
+            "enum": [
+                "PENDING",
+                "APPROVED",
+                "REJECTED"
+            ],
+            "x-enum-varnames": [
+                "ContentCreatorStatusPending",
+                "ContentCreatorStatusApproved",
+                "ContentCreatorStatusRejected"
+            ]
+        },
+        "models.ContentCreatorStatusUpdate": {
+            "description": "modèle pour mettre à jour le statut d'une demande de content creator",
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ContentCreatorStatusType"
+                        }
+                    ],
+                    "example": "APPROVED"
+                }
+            }
+        },
+        "models.Coupon": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "amountOff": {
+                    "type": "integer"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "contentCreatorId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "maxRedemptions": {
+                    "description": "0 = nombre d'utilisations illimité",
+                    "type": "integer"
+                },
+                "percentOff": {
+                    "type": "integer"
+                },
+                "timesRedeemed": {
+                    "type": "integer"
+                },
+                "type": {
+                    "$ref": "#/definitions/models.CouponType"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CouponCreate": {
+            "description": "code promo en pourcentage (percentOff) ou en montant fixe en centimes (amountOff), maxRedemptions à 0 pour illimité",
+            "type": "object",
+            "required": [
+                "code",
+                "type"
+            ],
+            "properties": {
+                "amountOff": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 200
+                },
+                "code": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "minLength": 3,
+                    "example": "WELCOME20"
+                },
+                "expiresAt": {
+                    "type": "string",
+                    "example": "2026-12-31T23:59:59Z"
+                },
+                "maxRedemptions": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 100
+                },
+                "percentOff": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 1,
+                    "example": 20
+                },
+                "type": {
+                    "enum": [
+                        "PERCENT",
+                        "FIXED"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.CouponType"
+                        }
+                    ],
+                    "example": "PERCENT"
+                }
+            }
+        },
+        "models.CouponType": {
+            "type": "string",
+            "enum": [
+                "PERCENT",
+                "FIXED"
+            ],
+            "x-enum-varnames": [
+                "CouponPercent",
+                "CouponFixed"
+            ]
+        },
+        "models.CreatorAnalyticsOverview": {
+            "description": "MRR en centimes : revenu mensuel récurrent des abonnements payants en cours, les formules de plusieurs mois ramenées au mois",
+            "type": "object",
+            "properties": {
+                "activeSubscribers": {
+                    "type": "integer",
+                    "example": 50
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "eur"
+                },
+                "mrr": {
+                    "type": "integer",
+                    "example": 24950
+                },
+                "pastDueSubscribers": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "trialingSubscribers": {
+                    "type": "integer",
+                    "example": 4
+                }
+            }
+        },
+        "models.CreatorEarnings": {
+            "description": "Solde et historique des gains d'un créateur",
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "integer",
+                    "example": 12450
+                },
+                "commissionPercent": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "eur"
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "transactions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LedgerTransaction"
+                    }
+                }
+            }
+        },
+        "models.CreatorRevenue": {
+            "type": "object",
+            "properties": {
+                "commission": {
+                    "type": "integer",
+                    "example": 4092
+                },
+                "contentCreatorId": {
+                    "type": "string"
+                },
+                "creatorShare": {
+                    "type": "integer",
+                    "example": 16367
+                },
+                "gross": {
+                    "type": "integer",
+                    "example": 20958
+                },
+                "net": {
+                    "type": "integer",
+                    "example": 20459
+                },
+                "payments": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "refunds": {
+                    "type": "integer",
+                    "example": 499
+                },
+                "userName": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Invoice": {
+            "type": "object",
+            "properties": {
+                "amountExcludingTax": {
+                    "description": "Montants en centimes : le prix payé est TTC",
+                    "type": "integer"
+                },
+                "amountIncludingTax": {
+                    "type": "integer"
+                },
+                "buyerEmail": {
+                    "type": "string"
+                },
+                "buyerName": {
+                    "type": "string"
+                },
+                "contentCreatorId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "issuedAt": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                },
+                "sellerAddress": {
+                    "type": "string"
+                },
+                "sellerName": {
+                    "type": "string"
+                },
+                "sellerSiret": {
+                    "type": "string"
+                },
+                "sellerVatNumber": {
+                    "type": "string"
+                },
+                "sequence": {
+                    "description": "Numérotation continue, sans trou, attribuée dans l'ordre d'émission. Le créateur étant le vendeur,\nchaque créateur a sa propre série",
+                    "type": "integer"
+                },
+                "subscriptionId": {
+                    "type": "string"
+                },
+                "subscriptionPaymentId": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                },
+                "vatAmount": {
+                    "type": "integer"
+                },
+                "vatRate": {
+                    "description": "Taux de TVA en points de base (2000 = 20 %)",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.LedgerSourceType": {
+            "type": "string",
+            "enum": [
+                "SUBSCRIPTION_PAYMENT",
+                "TIP",
+                "POST_PURCHASE",
+                "PAYOUT",
+                "PAYMENT_REFUND",
+                "PAYMENT_DISPUTE"
+            ],
+            "x-enum-varnames": [
+                "LedgerSourceSubscriptionPayment",
+                "LedgerSourceTip",
+                "LedgerSourcePostPurchase",
+                "LedgerSourcePayout",
+                "LedgerSourcePaymentRefund",
+                "LedgerSourcePaymentDispute"
+            ]
+        },
+        "models.LedgerTransaction": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Montant brut payé par le client, en centimes",
+                    "type": "integer"
+                },
+                "commission": {
+                    "description": "Part conservée par la plateforme, en centimes",
+                    "type": "integer"
+                },
+                "contentCreatorId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "$ref": "#/definitions/models.LedgerTransactionKind"
+                },
+                "net": {
+                    "description": "Effet sur le solde du créateur, négatif pour un remboursement ou un versement",
+                    "type": "integer"
+                },
+                "reversedTransactionId": {
+                    "description": "Paiement repris (remboursement, contestation) ou rétabli (contestation gagnée) par cette transaction,\npour cumuler les reprises d'un même paiement quelle que soit leur origine",
+                    "type": "string"
+                },
+                "sourceId": {
+                    "type": "string"
+                },
+                "sourceType": {
+                    "$ref": "#/definitions/models.LedgerSourceType"
+                }
+            }
+        },
+        "models.LedgerTransactionKind": {
+            "type": "string",
+            "enum": [
+                "PAYMENT",
+                "REFUND",
+                "PAYOUT"
+            ],
+            "x-enum-varnames": [
+                "LedgerTransactionPayment",
+                "LedgerTransactionRefund",
+                "LedgerTransactionPayout"
+            ]
+        },
+        "models.Like": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "postId": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MessageStatusType": {
+            "type": "string",
+            "enum": [
+                "UNREAD",
+                "READ",
+                "ARCHIVED",
+                "DELETED"
+            ],
+            "x-enum-varnames": [
+                "MessageStatusUnread",
+                "MessageStatusRead",
+                "MessageStatusArchived",
+                "MessageStatusDeleted"
+            ]
+        },
+        "models.PasswordUpdate": {
+            "description": "modèle pour mettre à jour le mot de passe d'un utilisateur",
+            "type": "object",
+            "required": [
+                "newPassword",
+                "oldPassword"
+            ],
+            "properties": {
+                "newPassword": {
+                    "type": "string",
+                    "minLength": 6,
+                    "example": "NouveauMotdepasse123"
+                },
+                "oldPassword": {
+                    "type": "string",
+                    "example": "AncienMotdepasse123"
+                }
+            }
+        },
+        "models.PaymentDispute": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "closedAt": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "fundsWithdrawn": {
+                    "description": "Les fonds ont été repris par la banque : le créateur a été débité au grand livre",
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "postPurchaseId": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "stripeDisputeId": {
+                    "type": "string"
+                },
+                "subscriptionPaymentId": {
+                    "type": "string"
+                },
+                "tipId": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PaymentRatesPoint": {
+            "description": "failureRate : échecs / tentatives, refundRate : paiements remboursés (même partiellement) / paiements réussis",
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "disputed": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 8
+                },
+                "failureRate": {
+                    "type": "number",
+                    "example": 0.067
+                },
+                "period": {
+                    "type": "string"
+                },
+                "refundRate": {
+                    "type": "number",
+                    "example": 0.027
+                },
+                "refunded": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "succeeded": {
+                    "type": "integer",
+                    "example": 112
+                }
+            }
+        },
+        "models.PaymentRefundRequest": {
+            "description": "montant à rembourser en centimes, la totalité du montant restant si absent",
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 499
+                }
+            }
+        },
+        "models.Payout": {
+            "type": "object",
+            "properties": {
+                "accountHolder": {
+                    "type": "string"
+                },
+                "amount": {
+                    "type": "integer"
+                },
+                "batchId": {
+                    "type": "string"
+                },
+                "bic": {
+                    "type": "string"
+                },
+                "contentCreatorId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "endToEndId": {
+                    "description": "Identifiant de bout en bout du virement (EndToEndId, 35 caractères maximum)",
+                    "type": "string"
+                },
+                "iban": {
+                    "type": "string"
                 },
-                "password": {
-                    "type": "string",
-                    "minLength": 6,
-                    "example": "Motdepasse123"
+                "id": {
+                    "type": "string"
                 }
             }
         },
-        "insee.EntrepriseInfo": {
+        "models.PayoutBatch": {
             "type": "object",
             "properties": {
-                "address": {
+                "createdAt": {
                     "type": "string"
                 },
-                "city": {
+                "createdById": {
                     "type": "string"
                 },
-                "company_name": {
+                "currency": {
                     "type": "string"
                 },
-                "company_type": {
+                "id": {
                     "type": "string"
                 },
-                "postal_code": {
+                "payouts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Payout"
+                    }
+                },
+                "payoutsCount": {
+                    "type": "integer"
+                },
+                "reference": {
+                    "description": "Identifiant du message SEPA (MsgId, 35 caractères maximum)",
                     "type": "string"
                 },
-                "siret": {
+                "totalAmount": {
+                    "type": "integer"
+                },
+                "updatedAt": {
                     "type": "string"
                 }
             }
         },
-        "models.Category": {
+        "models.PlanBundle": {
+            "type": "object",
+            "properties": {
+                "discountPercent": {
+                    "type": "integer",
+                    "maximum": 90,
+                    "minimum": 1,
+                    "example": 15
+                },
+                "months": {
+                    "type": "integer",
+                    "enum": [
+                        3,
+                        6,
+                        12
+                    ],
+                    "example": 6
+                }
+            }
+        },
+        "models.PlatformRevenuePoint": {
+            "description": "gross : paiements encaissés (abonnements, pourboires, achats), refunds et disputes : sommes reprises, commission : part de la plateforme",
+            "type": "object",
+            "properties": {
+                "commission": {
+                    "type": "integer",
+                    "example": 23558
+                },
+                "creatorShare": {
+                    "type": "integer",
+                    "example": 94234
+                },
+                "disputes": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "gross": {
+                    "type": "integer",
+                    "example": 119790
+                },
+                "net": {
+                    "type": "integer",
+                    "example": 117792
+                },
+                "period": {
+                    "type": "string"
+                },
+                "purchases": {
+                    "type": "integer",
+                    "example": 4990
+                },
+                "refunds": {
+                    "type": "integer",
+                    "example": 1998
+                },
+                "subscriptions": {
+                    "type": "integer",
+                    "example": 99800
+                },
+                "tips": {
+                    "type": "integer",
+                    "example": 15000
+                }
+            }
+        },
+        "models.Post": {
             "type": "object",
             "required": [
                 "name"
             ],
             "properties": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Category"
+                    }
+                },
                 "createdAt": {
                     "type": "string"
                 },
+                "deletedAt": {
+                    "type": "string"
+                },
+                "enable": {
+                    "type": "boolean"
+                },
                 "id": {
                     "type": "string"
                 },
+                "isFree": {
+                    "type": "boolean"
+                },
+                "likes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Like"
+                    }
+                },
                 "name": {
                     "type": "string"
                 },
                 "pictureUrl": {
                     "type": "string"
                 },
-                "posts": {
+                "price": {
+                    "type": "integer"
+                },
+                "reports": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.Post"
+                        "$ref": "#/definitions/models.Report"
                     }
                 },
                 "updatedAt": {
                     "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/models.User"
+                },
+                "userId": {
+                    "type": "string"
                 }
             }
         },
-        "models.CategoryCreate": {
+        "models.PostEngagement": {
             "type": "object",
-            "required": [
-                "name",
-                "pictureUrl"
-            ],
             "properties": {
+                "commentsCount": {
+                    "type": "integer",
+                    "example": 14
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "likesCount": {
+                    "type": "integer",
+                    "example": 120
+                },
                 "name": {
                     "type": "string"
                 },
@@ -3179,189 +7555,203 @@ const docTemplate = `{
                 }
             }
         },
-        "models.Contact": {
-            "description": "Modèle complet d'une demande de contact",
+        "models.PostPage": {
             "type": "object",
-            "required": [
-                "email",
-                "firstName",
-                "lastName",
-                "message",
-                "subject"
-            ],
             "properties": {
-                "email": {
+                "nextCursor": {
                     "type": "string"
                 },
-                "firstName": {
+                "posts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PostResponse"
+                    }
+                }
+            }
+        },
+        "models.PostPurchase": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "buyerId": {
                     "type": "string"
                 },
-                "id": {
+                "contentCreatorId": {
                     "type": "string"
                 },
-                "lastName": {
+                "createdAt": {
                     "type": "string"
                 },
-                "message": {
+                "currency": {
                     "type": "string"
                 },
-                "status": {
-                    "$ref": "#/definitions/models.StatusType"
+                "id": {
+                    "type": "string"
                 },
-                "subject": {
+                "paidAt": {
                     "type": "string"
                 },
-                "submittedAt": {
+                "postId": {
                     "type": "string"
-                }
-            }
-        },
-        "models.ContactCreate": {
-            "description": "modèle pour créer une demande de contact",
-            "type": "object",
-            "required": [
-                "email",
-                "firstName",
-                "lastName",
-                "message",
-                "subject"
-            ],
-            "properties": {
-                "email": {
-                    "type": "string",
-                    "example": "jean.dupont@exemple.com"
                 },
-                "firstName": {
-                    "type": "string",
-                    "example": "Jean"
+                "refundedAmount": {
+                    "description": "Montant déjà remboursé, en centimes (remboursements partiels cumulés)",
+                    "type": "integer"
                 },
-                "lastName": {
-                    "type": "string",
-                    "example": "Dupont"
+                "status": {
+                    "$ref": "#/definitions/models.PostPurchaseStatus"
                 },
-                "message": {
-                    "type": "string",
-                    "example": "J'aimerais avoir plus d'informations sur vos services."
+                "stripePaymentIntentId": {
+                    "type": "string"
                 },
-                "subject": {
-                    "type": "string",
-                    "example": "Demande d'information"
+                "updatedAt": {
+                    "type": "string"
                 }
             }
         },
-        "models.ContactStatusUpdate": {
-            "description": "modèle pour mettre à jour le statut d'une demande de contact",
-            "type": "object",
-            "required": [
-                "status"
+        "models.PostPurchaseStatus": {
+            "type": "string",
+            "enum": [
+                "PENDING",
+                "SUCCEEDED",
+                "FAILED",
+                "CANCELED",
+                "REFUNDED",
+                "DISPUTED"
             ],
-            "properties": {
-                "status": {
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/models.StatusType"
-                        }
-                    ],
-                    "example": "processing"
-                }
-            }
+            "x-enum-varnames": [
+                "PostPurchasePending",
+                "PostPurchaseSucceeded",
+                "PostPurchaseFailed",
+                "PostPurchaseCanceled",
+                "PostPurchaseRefunded",
+                "PostPurchaseDisputed"
+            ]
         },
-        "models.ContentCreatorInfo": {
+        "models.PostResponse": {
             "type": "object",
-            "required": [
-                "bic",
-                "city",
-                "companyName",
-                "companyType",
-                "country",
-                "documentProofUrl",
-                "iban",
-                "postalCode",
-                "siretNumber",
-                "streetAddress"
-            ],
             "properties": {
-                "bic": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Category"
+                    }
+                },
+                "commentsCount": {
+                    "type": "integer"
+                },
+                "createdAt": {
                     "type": "string"
                 },
-                "city": {
+                "enable": {
+                    "type": "boolean"
+                },
+                "id": {
                     "type": "string"
                 },
-                "companyName": {
+                "isFree": {
+                    "type": "boolean"
+                },
+                "isLiked": {
+                    "type": "boolean"
+                },
+                "isLocked": {
+                    "type": "boolean"
+                },
+                "isPurchased": {
+                    "type": "boolean"
+                },
+                "likesCount": {
+                    "type": "integer"
+                },
+                "name": {
                     "type": "string"
                 },
-                "companyType": {
+                "pictureUrl": {
                     "type": "string"
                 },
-                "country": {
+                "price": {
+                    "type": "integer"
+                },
+                "reportsCount": {
+                    "type": "integer"
+                },
+                "updatedAt": {
                     "type": "string"
                 },
-                "createdAt": {
+                "user": {
+                    "$ref": "#/definitions/models.UserInfo"
+                }
+            }
+        },
+        "models.PrivateMessage": {
+            "type": "object",
+            "required": [
+                "content"
+            ],
+            "properties": {
+                "content": {
                     "type": "string"
                 },
-                "documentProofUrl": {
+                "createdAt": {
                     "type": "string"
                 },
-                "iban": {
+                "deletedAt": {
                     "type": "string"
                 },
+                "delivered": {
+                    "description": "Vrai si le destinataire était connecté au WebSocket et a reçu le message en direct",
+                    "type": "boolean"
+                },
                 "id": {
                     "type": "string"
                 },
-                "postalCode": {
+                "receiverId": {
                     "type": "string"
                 },
-                "siretNumber": {
+                "senderId": {
                     "type": "string"
                 },
                 "status": {
-                    "$ref": "#/definitions/models.ContentCreatorStatusType"
-                },
-                "streetAddress": {
-                    "type": "string"
+                    "$ref": "#/definitions/models.MessageStatusType"
                 },
                 "updatedAt": {
                     "type": "string"
-                },
-                "userId": {
+                }
+            }
+        },
+        "models.PrivateMessageCreate": {
+            "description": "model for creating a private message",
+            "type": "object",
+            "required": [
+                "content",
+                "receiverUserName"
+            ],
+            "properties": {
+                "content": {
                     "type": "string"
                 },
-                "vatNumber": {
+                "receiverUserName": {
                     "type": "string"
                 }
             }
         },
-        "models.ContentCreatorStatusType": {
-            "type": "string",
-            "enum": [
-                "PENDING",
-                "APPROVED",
-                "REJECTED"
-            ],
-            "x-enum-varnames": [
-                "ContentCreatorStatusPending",
-                "ContentCreatorStatusApproved",
-                "ContentCreatorStatusRejected"
-            ]
-        },
-        "models.ContentCreatorStatusUpdate": {
-            "description": "modèle pour mettre à jour le statut d'une demande de content creator",
+        "models.RefreshTokenRequest": {
+            "description": "modèle pour renouveler un access token à partir d'un refresh token",
             "type": "object",
             "required": [
-                "status"
+                "refreshToken"
             ],
             "properties": {
-                "status": {
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/models.ContentCreatorStatusType"
-                        }
-                    ],
-                    "example": "APPROVED"
+                "refreshToken": {
+                    "type": "string",
+                    "example": "4kY1b0..."
                 }
             }
         },
-        "models.Like": {
+        "models.Report": {
             "type": "object",
             "properties": {
                 "createdAt": {
@@ -3373,333 +7763,664 @@ const docTemplate = `{
                 "postId": {
                     "type": "string"
                 },
-                "userId": {
+                "reason": {
+                    "$ref": "#/definitions/models.ReportReason"
+                },
+                "reportedBy": {
                     "type": "string"
                 }
             }
         },
-        "models.MessageStatusType": {
+        "models.ReportCreate": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "reason": {
+                    "$ref": "#/definitions/models.ReportReason"
+                }
+            }
+        },
+        "models.ReportReason": {
             "type": "string",
             "enum": [
-                "UNREAD",
-                "READ",
-                "ARCHIVED",
-                "DELETED"
+                "DISLIKE",
+                "HARASSMENT",
+                "SELF_HARM",
+                "VIOLENCE",
+                "RESTRICTED_ITEMS",
+                "NUDITY",
+                "SCAM",
+                "MISINFORMATION",
+                "ILLEGAL_CONTENT"
             ],
             "x-enum-varnames": [
-                "MessageStatusUnread",
-                "MessageStatusRead",
-                "MessageStatusArchived",
-                "MessageStatusDeleted"
+                "DISLIKE",
+                "HARASSMENT",
+                "SELF_HARM",
+                "VIOLENCE",
+                "RESTRICTED_ITEMS",
+                "NUDITY",
+                "SCAM",
+                "MISINFORMATION",
+                "ILLEGAL_CONTENT"
             ]
         },
-        "models.PasswordUpdate": {
-            "description": "modèle pour mettre à jour le mot de passe d'un utilisateur",
+        "models.RetentionCohort": {
+            "description": "retained[k] : abonnements toujours en cours k mois après leur début, rates[k] : retained[k] / size",
             "type": "object",
-            "required": [
-                "newPassword",
-                "oldPassword"
-            ],
             "properties": {
-                "newPassword": {
-                    "type": "string",
-                    "minLength": 6,
-                    "example": "NouveauMotdepasse123"
+                "cohort": {
+                    "type": "string"
                 },
-                "oldPassword": {
-                    "type": "string",
-                    "example": "AncienMotdepasse123"
+                "rates": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "retained": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 40
                 }
             }
         },
-        "models.Post": {
+        "models.RevenuePoint": {
             "type": "object",
-            "required": [
-                "name"
-            ],
             "properties": {
-                "categories": {
+                "disputes": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "gross": {
+                    "type": "integer",
+                    "example": 12475
+                },
+                "net": {
+                    "type": "integer",
+                    "example": 11976
+                },
+                "payments": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "period": {
+                    "type": "string"
+                },
+                "refunds": {
+                    "type": "integer",
+                    "example": 499
+                }
+            }
+        },
+        "models.RevenueSeries": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "eur"
+                },
+                "period": {
+                    "type": "string",
+                    "example": "month"
+                },
+                "points": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.Category"
+                        "$ref": "#/definitions/models.RevenuePoint"
                     }
+                }
+            }
+        },
+        "models.Role": {
+            "type": "string",
+            "enum": [
+                "ADMIN",
+                "USER",
+                "CONTENT_CREATOR"
+            ],
+            "x-enum-varnames": [
+                "AdminRole",
+                "UserRole",
+                "ContentCreator"
+            ]
+        },
+        "models.Sexe": {
+            "type": "string",
+            "enum": [
+                "MAN",
+                "WOMAN",
+                "OTHER"
+            ],
+            "x-enum-varnames": [
+                "Male",
+                "Female",
+                "Other"
+            ]
+        },
+        "models.StatusType": {
+            "type": "string",
+            "enum": [
+                "open",
+                "processing",
+                "closed",
+                "rejected"
+            ],
+            "x-enum-varnames": [
+                "StatusOpen",
+                "StatusProcessing",
+                "StatusClosed",
+                "StatusRejected"
+            ]
+        },
+        "models.StreamTicketRequest": {
+            "description": "flux à ouvrir : les commentaires d'un post (postId obligatoire) ou les messages privés",
+            "type": "object",
+            "properties": {
+                "postId": {
+                    "type": "string",
+                    "example": "3f1c2b4a-8d6e-4c1f-9a7b-2e5d6f8a9b0c"
                 },
-                "createdAt": {
+                "stream": {
+                    "enum": [
+                        "comments",
+                        "messages"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.StreamType"
+                        }
+                    ],
+                    "example": "comments"
+                }
+            }
+        },
+        "models.StreamTicketResponse": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
                     "type": "string"
                 },
-                "deletedAt": {
-                    "type": "string"
+                "ticket": {
+                    "type": "string",
+                    "example": "4kY1b0..."
+                }
+            }
+        },
+        "models.StreamType": {
+            "type": "string",
+            "enum": [
+                "comments",
+                "messages"
+            ],
+            "x-enum-varnames": [
+                "StreamComments",
+                "StreamMessages"
+            ]
+        },
+        "models.StripeEvent": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
                 },
-                "enable": {
-                    "type": "boolean"
+                "createdAt": {
+                    "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
-                "isFree": {
-                    "type": "boolean"
-                },
-                "likes": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.Like"
-                    }
+                "lastError": {
+                    "type": "string"
                 },
-                "name": {
+                "nextAttemptAt": {
                     "type": "string"
                 },
-                "pictureUrl": {
+                "processedAt": {
                     "type": "string"
                 },
-                "reports": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.Report"
-                    }
+                "status": {
+                    "$ref": "#/definitions/models.StripeEventStatus"
+                },
+                "type": {
+                    "type": "string"
                 },
                 "updatedAt": {
                     "type": "string"
+                }
+            }
+        },
+        "models.StripeEventStatus": {
+            "type": "string",
+            "enum": [
+                "PROCESSING",
+                "PROCESSED",
+                "FAILED",
+                "IGNORED"
+            ],
+            "x-enum-varnames": [
+                "StripeEventProcessing",
+                "StripeEventProcessed",
+                "StripeEventFailed",
+                "StripeEventIgnored"
+            ]
+        },
+        "models.SubscriberPoint": {
+            "type": "object",
+            "properties": {
+                "churned": {
+                    "type": "integer",
+                    "example": 3
                 },
-                "user": {
-                    "$ref": "#/definitions/models.User"
+                "net": {
+                    "type": "integer",
+                    "example": 9
                 },
-                "userId": {
+                "new": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "period": {
                     "type": "string"
                 }
             }
         },
-        "models.PostResponse": {
+        "models.SubscriberSeries": {
             "type": "object",
             "properties": {
-                "categories": {
+                "period": {
+                    "type": "string",
+                    "example": "week"
+                },
+                "points": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.Category"
+                        "$ref": "#/definitions/models.SubscriberPoint"
                     }
-                },
-                "commentsCount": {
+                }
+            }
+        },
+        "models.Subscription": {
+            "type": "object",
+            "properties": {
+                "billingMonths": {
+                    "description": "Nombre de mois couverts par chaque échéance (formules de 3, 6 ou 12 mois)",
                     "type": "integer"
                 },
+                "cancelAtPeriodEnd": {
+                    "type": "boolean"
+                },
+                "contentCreatorId": {
+                    "type": "string"
+                },
                 "createdAt": {
                     "type": "string"
                 },
-                "enable": {
-                    "type": "boolean"
+                "endDate": {
+                    "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
-                "isFree": {
-                    "type": "boolean"
+                "planId": {
+                    "type": "string"
                 },
-                "likesCount": {
+                "price": {
                     "type": "integer"
                 },
-                "name": {
+                "startDate": {
                     "type": "string"
                 },
-                "pictureUrl": {
+                "status": {
+                    "$ref": "#/definitions/models.SubscriptionStatus"
+                },
+                "stripeSubscriptionId": {
                     "type": "string"
                 },
-                "reportsCount": {
-                    "type": "integer"
+                "tier": {
+                    "$ref": "#/definitions/models.SubscriptionTier"
+                },
+                "trialEndsAt": {
+                    "type": "string"
                 },
                 "updatedAt": {
                     "type": "string"
                 },
-                "user": {
-                    "$ref": "#/definitions/models.UserInfo"
+                "userId": {
+                    "type": "string"
                 }
             }
         },
-        "models.PrivateMessage": {
+        "models.SubscriptionCheckoutRequest": {
+            "description": "plan choisi lors du paiement (le moins cher du créateur si absent), durée de la formule et code promo éventuel",
             "type": "object",
-            "required": [
-                "content"
-            ],
             "properties": {
-                "content": {
+                "bundleMonths": {
+                    "type": "integer",
+                    "enum": [
+                        1,
+                        3,
+                        6,
+                        12
+                    ],
+                    "example": 3
+                },
+                "planId": {
+                    "type": "string",
+                    "example": "0f8fad5b-d9cb-469f-a165-70867728950e"
+                },
+                "promoCode": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "example": "WELCOME20"
+                }
+            }
+        },
+        "models.SubscriptionPlan": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "bundles": {
+                    "description": "Formules de plusieurs mois payées d'avance avec une remise",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PlanBundle"
+                    }
+                },
+                "contentCreatorId": {
                     "type": "string"
                 },
                 "createdAt": {
                     "type": "string"
                 },
-                "deletedAt": {
+                "currency": {
                     "type": "string"
                 },
-                "id": {
+                "description": {
                     "type": "string"
                 },
-                "receiverId": {
+                "id": {
                     "type": "string"
                 },
-                "senderId": {
+                "name": {
                     "type": "string"
                 },
-                "status": {
-                    "$ref": "#/definitions/models.MessageStatusType"
+                "perks": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "tier": {
+                    "$ref": "#/definitions/models.SubscriptionTier"
+                },
+                "trialDays": {
+                    "description": "Jours d'essai gratuit offerts à un premier abonnement chez ce créateur",
+                    "type": "integer"
                 },
                 "updatedAt": {
                     "type": "string"
                 }
             }
         },
-        "models.PrivateMessageCreate": {
-            "description": "model for creating a private message",
+        "models.SubscriptionPlanCreate": {
+            "description": "modèle pour créer un plan d'abonnement (prix mensuel en centimes)",
             "type": "object",
             "required": [
-                "content",
-                "receiverUserName"
+                "name",
+                "price",
+                "tier"
             ],
             "properties": {
-                "content": {
-                    "type": "string"
+                "bundles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PlanBundle"
+                    }
                 },
-                "receiverUserName": {
-                    "type": "string"
-                }
-            }
-        },
-        "models.Report": {
-            "type": "object",
-            "properties": {
-                "createdAt": {
-                    "type": "string"
+                "description": {
+                    "type": "string",
+                    "example": "Accès à tous mes contenus"
                 },
-                "id": {
-                    "type": "string"
+                "name": {
+                    "type": "string",
+                    "example": "VIP"
                 },
-                "postId": {
-                    "type": "string"
+                "perks": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Lives privés",
+                        "Messages prioritaires"
+                    ]
                 },
-                "reason": {
-                    "$ref": "#/definitions/models.ReportReason"
+                "price": {
+                    "type": "integer",
+                    "maximum": 100000,
+                    "minimum": 100,
+                    "example": 999
                 },
-                "reportedBy": {
-                    "type": "string"
+                "tier": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.SubscriptionTier"
+                        }
+                    ],
+                    "example": "VIP"
+                },
+                "trialDays": {
+                    "type": "integer",
+                    "maximum": 90,
+                    "minimum": 0,
+                    "example": 7
                 }
             }
         },
-        "models.ReportCreate": {
+        "models.SubscriptionPlanUpdate": {
+            "description": "modèle pour modifier un plan d'abonnement, le nouveau prix ne s'applique qu'aux nouveaux abonnés",
             "type": "object",
-            "required": [
-                "reason"
-            ],
             "properties": {
-                "reason": {
-                    "$ref": "#/definitions/models.ReportReason"
+                "active": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "bundles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PlanBundle"
+                    }
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Accès à tous mes contenus"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "VIP"
+                },
+                "perks": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "price": {
+                    "type": "integer",
+                    "maximum": 100000,
+                    "minimum": 100,
+                    "example": 1499
+                },
+                "trialDays": {
+                    "type": "integer",
+                    "maximum": 90,
+                    "minimum": 0,
+                    "example": 7
                 }
             }
         },
-        "models.ReportReason": {
-            "type": "string",
-            "enum": [
-                "DISLIKE",
-                "HARASSMENT",
-                "SELF_HARM",
-                "VIOLENCE",
-                "RESTRICTED_ITEMS",
-                "NUDITY",
-                "SCAM",
-                "MISINFORMATION",
-                "ILLEGAL_CONTENT"
-            ],
-            "x-enum-varnames": [
-                "DISLIKE",
-                "HARASSMENT",
-                "SELF_HARM",
-                "VIOLENCE",
-                "RESTRICTED_ITEMS",
-                "NUDITY",
-                "SCAM",
-                "MISINFORMATION",
-                "ILLEGAL_CONTENT"
-            ]
-        },
-        "models.Role": {
+        "models.SubscriptionStatus": {
             "type": "string",
             "enum": [
-                "ADMIN",
-                "USER",
-                "CONTENT_CREATOR"
+                "ACTIVE",
+                "CANCELED",
+                "PENDING",
+                "PAST_DUE",
+                "EXPIRED"
             ],
             "x-enum-varnames": [
-                "AdminRole",
-                "UserRole",
-                "ContentCreator"
+                "SubscriptionActive",
+                "SubscriptionCanceled",
+                "SubscriptionPending",
+                "SubscriptionPastDue",
+                "SubscriptionExpired"
             ]
         },
-        "models.Sexe": {
+        "models.SubscriptionTier": {
             "type": "string",
             "enum": [
-                "MAN",
-                "WOMAN",
-                "OTHER"
+                "BASIC",
+                "PREMIUM",
+                "VIP"
             ],
             "x-enum-varnames": [
-                "Male",
-                "Female",
-                "Other"
+                "TierBasic",
+                "TierPremium",
+                "TierVIP"
             ]
         },
-        "models.StatusType": {
-            "type": "string",
-            "enum": [
-                "open",
-                "processing",
-                "closed",
-                "rejected"
-            ],
-            "x-enum-varnames": [
-                "StatusOpen",
-                "StatusProcessing",
-                "StatusClosed",
-                "StatusRejected"
-            ]
+        "models.SubscriptionsPoint": {
+            "description": "active et mrr : situation en fin de période, churnRate : abonnements perdus / abonnements actifs en début de période",
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "integer",
+                    "example": 830
+                },
+                "churnRate": {
+                    "type": "number",
+                    "example": 0.026
+                },
+                "churned": {
+                    "type": "integer",
+                    "example": 21
+                },
+                "mrr": {
+                    "type": "integer",
+                    "example": 414170
+                },
+                "new": {
+                    "type": "integer",
+                    "example": 64
+                },
+                "period": {
+                    "type": "string"
+                }
+            }
         },
-        "models.Subscription": {
+        "models.Tip": {
             "type": "object",
             "properties": {
+                "amount": {
+                    "type": "integer"
+                },
                 "contentCreatorId": {
                     "type": "string"
                 },
                 "createdAt": {
                     "type": "string"
                 },
-                "endDate": {
+                "currency": {
                     "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
-                "startDate": {
+                "message": {
+                    "type": "string"
+                },
+                "paidAt": {
+                    "type": "string"
+                },
+                "postId": {
+                    "type": "string"
+                },
+                "refundedAmount": {
+                    "description": "Montant déjà remboursé, en centimes (remboursements partiels cumulés)",
+                    "type": "integer"
+                },
+                "sender": {
+                    "description": "Profil public de l'auteur, renseigné pour les pourboires reçus par le créateur",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.UserInfo"
+                        }
+                    ]
+                },
+                "senderId": {
                     "type": "string"
                 },
                 "status": {
-                    "$ref": "#/definitions/models.SubscriptionStatus"
+                    "$ref": "#/definitions/models.TipStatus"
                 },
-                "stripeSubscriptionId": {
+                "stripePaymentIntentId": {
                     "type": "string"
                 },
                 "updatedAt": {
                     "type": "string"
+                }
+            }
+        },
+        "models.TipCreate": {
+            "type": "object",
+            "required": [
+                "amount"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "integer",
+                    "maximum": 50000,
+                    "minimum": 100
                 },
-                "userId": {
+                "message": {
+                    "type": "string",
+                    "maxLength": 500
+                },
+                "postId": {
                     "type": "string"
                 }
             }
         },
-        "models.SubscriptionStatus": {
+        "models.TipStatus": {
             "type": "string",
             "enum": [
-                "ACTIVE",
+                "PENDING",
+                "SUCCEEDED",
+                "FAILED",
                 "CANCELED",
-                "PENDING"
+                "REFUNDED",
+                "DISPUTED"
             ],
             "x-enum-varnames": [
-                "SubscriptionActive",
-                "SubscriptionCanceled",
-                "SubscriptionPending"
+                "TipPending",
+                "TipSucceeded",
+                "TipFailed",
+                "TipCanceled",
+                "TipRefunded",
+                "TipDisputed"
             ]
         },
         "models.User": {
@@ -3726,9 +8447,6 @@ const docTemplate = `{
                 "commentsEnable": {
                     "type": "boolean"
                 },
-                "confirmationCode": {
-                    "type": "string"
-                },
                 "createdAt": {
                     "type": "string"
                 },
@@ -3763,9 +8481,6 @@ const docTemplate = `{
                 "profilePicture": {
                     "type": "string"
                 },
-                "resetPasswordCode": {
-                    "type": "string"
-                },
                 "resetPasswordCodeEnd": {
                     "type": "string"
                 },
@@ -3784,6 +8499,9 @@ const docTemplate = `{
                 "subscriptionEnable": {
                     "type": "boolean"
                 },
+                "twoFactorEnabled": {
+                    "type": "boolean"
+                },
                 "updatedAt": {
                     "type": "string"
                 },
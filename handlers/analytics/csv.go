@@ -0,0 +1,60 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// wantsCSV indique si le rapport est demandé au format CSV (format=csv) plutôt qu'en JSON
+func wantsCSV(c *gin.Context) bool {
+	return c.Query("format") == "csv"
+}
+
+// writeCSV envoie un rapport en pièce jointe CSV, une ligne d'en-tête puis une ligne par élément
+func writeCSV(c *gin.Context, filename string, header []string, rows [][]string) error {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	return nil
+}
+
+// reportFilename nom du fichier d'un rapport couvrant [from, to[
+func reportFilename(report string, from time.Time, to time.Time) string {
+	return fmt.Sprintf("%s-%s-%s.csv", report, from.Format("2006-01-02"), to.AddDate(0, 0, -1).Format("2006-01-02"))
+}
+
+// csvAmount montant en centimes écrit en euros pour la comptabilité : 1234 → 12.34
+func csvAmount(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+func csvRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', 4, 64)
+}
+
+func csvCount(count int64) string {
+	return strconv.FormatInt(count, 10)
+}
+
+func csvDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
@@ -0,0 +1,400 @@
+package analytics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stripe/stripe-go/v82"
+)
+
+// Abonnements ayant réellement commencé, par opposition aux paiements jamais aboutis
+var startedStatuses = []models.SubscriptionStatus{models.SubscriptionActive, models.SubscriptionPastDue, models.SubscriptionCanceled, models.SubscriptionExpired}
+
+var endedStatuses = []models.SubscriptionStatus{models.SubscriptionCanceled, models.SubscriptionExpired}
+
+// Paiements encaissés : les remboursements et litiges perdus sont déduits à leur date
+var collectedPaymentStatuses = []models.SubscriptionPaymentStatus{models.SubscriptionPaymentSucceeded, models.SubscriptionPaymentRefunded, models.SubscriptionPaymentDisputed}
+
+// bucketRow agrégat d'une période renvoyé par PostgreSQL
+type bucketRow struct {
+	Period time.Time
+	Amount int64
+	Count  int64
+}
+
+func currentCreator(c *gin.Context, handlerName string) (models.User, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(errors.New("user_id manquant"), "User not authenticated in "+handlerName)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return models.User{}, false
+	}
+
+	var user models.User
+	if err := db.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "User not found in "+handlerName)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return models.User{}, false
+	}
+
+	if user.Role != models.ContentCreator {
+		utils.LogErrorWithUser(userID, errors.New("pas créateur"), "Only content creators have analytics in "+handlerName)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only content creators have analytics"})
+		return models.User{}, false
+	}
+
+	return user, true
+}
+
+// @Summary Get my analytics overview
+// @Description Returns the current MRR (monthly recurring revenue in cents, multi-month bundles spread over their months, trials excluded) and the number of active, trialing and past due subscribers of the connected content creator
+// @Tags analytics
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.CreatorAnalyticsOverview
+// @Failure 401 {object} map[string]string "error: User not authenticated"
+// @Failure 403 {object} map[string]string "error: Only content creators have analytics"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /content-creators/me/analytics [get]
+func GetOverview(c *gin.Context) {
+	creator, ok := currentCreator(c, "GetOverview")
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	var overview models.CreatorAnalyticsOverview
+	err := db.DB.Model(&models.Subscription{}).
+		Select(`COALESCE(ROUND(SUM(price::numeric / GREATEST(billing_months, 1)) FILTER (WHERE status = ? AND (trial_ends_at IS NULL OR trial_ends_at <= ?))), 0) AS mrr,
+			COUNT(*) FILTER (WHERE status = ? AND (trial_ends_at IS NULL OR trial_ends_at <= ?)) AS active_subscribers,
+			COUNT(*) FILTER (WHERE status = ? AND trial_ends_at > ?) AS trialing_subscribers,
+			COUNT(*) FILTER (WHERE status = ?) AS past_due_subscribers`,
+			models.SubscriptionActive, now, models.SubscriptionActive, now, models.SubscriptionActive, now, models.SubscriptionPastDue).
+		Where("content_creator_id = ?", creator.ID).
+		Scan(&overview).Error
+	if err != nil {
+		utils.LogErrorWithUser(creator.ID, err, "Error computing overview in GetOverview")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing analytics"})
+		return
+	}
+
+	overview.Currency = "eur"
+
+	utils.LogSuccessWithUser(creator.ID, "Analytics overview computed successfully in GetOverview")
+	c.JSON(http.StatusOK, overview)
+}
+
+// @Summary Get my subscribers over time
+// @Description Returns the new and churned (canceled or expired) subscribers of the connected content creator per day, week (starting on Monday) or month. Defaults to the last 30 days, 12 weeks or 12 months
+// @Tags analytics
+// @Produce json
+// @Param period query string false "day, week or month" default(day)
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date, included (YYYY-MM-DD)"
+// @Security BearerAuth
+// @Success 200 {object} models.SubscriberSeries
+// @Failure 400 {object} map[string]string "error: Invalid period or dates"
+// @Failure 403 {object} map[string]string "error: Only content creators have analytics"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /content-creators/me/analytics/subscribers [get]
+func GetSubscribers(c *gin.Context) {
+	creator, ok := currentCreator(c, "GetSubscribers")
+	if !ok {
+		return
+	}
+	period, from, to, ok := seriesRange(c, creator.ID, "GetSubscribers")
+	if !ok {
+		return
+	}
+
+	started, err := startedByPeriod(creator.ID, period, from, to)
+	if err != nil {
+		utils.LogErrorWithUser(creator.ID, err, "Error counting new subscribers in GetSubscribers")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing analytics"})
+		return
+	}
+
+	churned, err := endedByPeriod(creator.ID, period, from, to)
+	if err != nil {
+		utils.LogErrorWithUser(creator.ID, err, "Error counting churned subscribers in GetSubscribers")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing analytics"})
+		return
+	}
+
+	newByPeriod := byPeriod(started)
+	churnedByPeriod := byPeriod(churned)
+	series := models.SubscriberSeries{Period: period, Points: []models.SubscriberPoint{}}
+	for _, bucket := range buckets(from, to, period) {
+		point := models.SubscriberPoint{
+			Period:  bucket,
+			New:     newByPeriod[bucketKey(bucket)].Count,
+			Churned: churnedByPeriod[bucketKey(bucket)].Count,
+		}
+		point.Net = point.New - point.Churned
+		series.Points = append(series.Points, point)
+	}
+
+	utils.LogSuccessWithUser(creator.ID, "Subscribers series computed successfully in GetSubscribers")
+	c.JSON(http.StatusOK, series)
+}
+
+// @Summary Get my revenue over time
+// @Description Returns the subscription revenue of the connected content creator per day, week (starting on Monday) or month, in cents before commission: payments collected, refunds issued and disputes lost during each period. Defaults to the last 30 days, 12 weeks or 12 months
+// @Tags analytics
+// @Produce json
+// @Param period query string false "day, week or month" default(day)
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date, included (YYYY-MM-DD)"
+// @Security BearerAuth
+// @Success 200 {object} models.RevenueSeries
+// @Failure 400 {object} map[string]string "error: Invalid period or dates"
+// @Failure 403 {object} map[string]string "error: Only content creators have analytics"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /content-creators/me/analytics/revenue [get]
+func GetRevenue(c *gin.Context) {
+	creator, ok := currentCreator(c, "GetRevenue")
+	if !ok {
+		return
+	}
+	period, from, to, ok := seriesRange(c, creator.ID, "GetRevenue")
+	if !ok {
+		return
+	}
+
+	var collected []bucketRow
+	err := db.DB.Model(&models.SubscriptionPayment{}).
+		Select("date_trunc(?, subscription_payments.paid_at AT TIME ZONE 'UTC') AS period, COALESCE(SUM(subscription_payments.amount),0) AS amount, COUNT(*) AS count", period).
+		Joins("JOIN subscriptions ON subscriptions.id = subscription_payments.subscription_id").
+		Where("subscriptions.content_creator_id = ? AND subscription_payments.status IN ? AND subscription_payments.paid_at >= ? AND subscription_payments.paid_at < ?",
+			creator.ID, collectedPaymentStatuses, from, to).
+		Group("period").
+		Scan(&collected).Error
+	if err != nil {
+		utils.LogErrorWithUser(creator.ID, err, "Error summing payments in GetRevenue")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing analytics"})
+		return
+	}
+
+	var refunds []bucketRow
+	err = db.DB.Model(&models.PaymentRefund{}).
+		Select("date_trunc(?, payment_refunds.created_at AT TIME ZONE 'UTC') AS period, COALESCE(SUM(payment_refunds.amount),0) AS amount, COUNT(*) AS count", period).
+		Joins("JOIN subscription_payments ON subscription_payments.id = payment_refunds.subscription_payment_id").
+		Joins("JOIN subscriptions ON subscriptions.id = subscription_payments.subscription_id").
+		Where("subscriptions.content_creator_id = ? AND payment_refunds.created_at >= ? AND payment_refunds.created_at < ?", creator.ID, from, to).
+		Group("period").
+		Scan(&refunds).Error
+	if err != nil {
+		utils.LogErrorWithUser(creator.ID, err, "Error summing refunds in GetRevenue")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing analytics"})
+		return
+	}
+
+	var disputes []bucketRow
+	err = db.DB.Model(&models.PaymentDispute{}).
+		Select("date_trunc(?, payment_disputes.closed_at AT TIME ZONE 'UTC') AS period, COALESCE(SUM(payment_disputes.amount),0) AS amount, COUNT(*) AS count", period).
+		Joins("JOIN subscription_payments ON subscription_payments.id = payment_disputes.subscription_payment_id").
+		Joins("JOIN subscriptions ON subscriptions.id = subscription_payments.subscription_id").
+		Where("subscriptions.content_creator_id = ? AND payment_disputes.funds_withdrawn = ? AND payment_disputes.status = ? AND payment_disputes.closed_at >= ? AND payment_disputes.closed_at < ?",
+			creator.ID, true, string(stripe.DisputeStatusLost), from, to).
+		Group("period").
+		Scan(&disputes).Error
+	if err != nil {
+		utils.LogErrorWithUser(creator.ID, err, "Error summing disputes in GetRevenue")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing analytics"})
+		return
+	}
+
+	collectedByPeriod := byPeriod(collected)
+	refundsByPeriod := byPeriod(refunds)
+	disputesByPeriod := byPeriod(disputes)
+	series := models.RevenueSeries{Period: period, Currency: "eur", Points: []models.RevenuePoint{}}
+	for _, bucket := range buckets(from, to, period) {
+		key := bucketKey(bucket)
+		point := models.RevenuePoint{
+			Period:   bucket,
+			Payments: collectedByPeriod[key].Count,
+			Gross:    collectedByPeriod[key].Amount,
+			Refunds:  refundsByPeriod[key].Amount,
+			Disputes: disputesByPeriod[key].Amount,
+		}
+		point.Net = point.Gross - point.Refunds - point.Disputes
+		series.Points = append(series.Points, point)
+	}
+
+	utils.LogSuccessWithUser(creator.ID, "Revenue series computed successfully in GetRevenue")
+	c.JSON(http.StatusOK, series)
+}
+
+// @Summary Get my top posts
+// @Description Returns the posts of the connected content creator with the most likes, then the most comments
+// @Tags analytics
+// @Produce json
+// @Param limit query int false "Number of posts (1-50)" default(10)
+// @Security BearerAuth
+// @Success 200 {array} models.PostEngagement
+// @Failure 400 {object} map[string]string "error: Invalid limit"
+// @Failure 403 {object} map[string]string "error: Only content creators have analytics"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /content-creators/me/analytics/top-posts [get]
+func GetTopPosts(c *gin.Context) {
+	creator, ok := currentCreator(c, "GetTopPosts")
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 50 {
+		utils.LogErrorWithUser(creator.ID, err, "Invalid limit in GetTopPosts")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	var posts []models.PostEngagement
+	err = db.DB.Model(&models.Post{}).
+		Select(`posts.id, posts.name, posts.picture_url, posts.created_at,
+			(SELECT COUNT(*) FROM likes WHERE likes.post_id = posts.id) AS likes_count,
+			(SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id AND comments.deleted_at IS NULL) AS comments_count`).
+		Where("posts.user_id = ? AND posts.deleted_at IS NULL", creator.ID).
+		Order("likes_count DESC, comments_count DESC, posts.created_at DESC").
+		Limit(limit).
+		Scan(&posts).Error
+	if err != nil {
+		utils.LogErrorWithUser(creator.ID, err, "Error fetching top posts in GetTopPosts")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing analytics"})
+		return
+	}
+	if posts == nil {
+		posts = []models.PostEngagement{}
+	}
+
+	utils.LogSuccessWithUser(creator.ID, "Top posts fetched successfully in GetTopPosts")
+	c.JSON(http.StatusOK, posts)
+}
+
+// @Summary Get my retention cohorts
+// @Description Groups the subscriptions of the connected content creator by the month they started and returns, for each month elapsed since, how many of them were still running. The last cohort is the current month
+// @Tags analytics
+// @Produce json
+// @Param months query int false "Number of cohorts (1-24)" default(6)
+// @Security BearerAuth
+// @Success 200 {array} models.RetentionCohort
+// @Failure 400 {object} map[string]string "error: Invalid months"
+// @Failure 403 {object} map[string]string "error: Only content creators have analytics"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /content-creators/me/analytics/retention [get]
+func GetRetention(c *gin.Context) {
+	creator, ok := currentCreator(c, "GetRetention")
+	if !ok {
+		return
+	}
+
+	months, err := strconv.Atoi(c.DefaultQuery("months", "6"))
+	if err != nil || months < 1 || months > 24 {
+		utils.LogErrorWithUser(creator.ID, err, "Invalid months in GetRetention")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "months must be between 1 and 24"})
+		return
+	}
+
+	now := time.Now().UTC()
+	currentMonth := truncate(now, "month")
+	firstCohort := currentMonth.AddDate(0, -(months - 1), 0)
+
+	// Durée de vie en mois entiers : jusqu'à maintenant pour un abonnement en cours, jusqu'à sa fin sinon
+	lifetime := "age(CASE WHEN status IN ? THEN ? ELSE COALESCE(end_date, updated_at) END, start_date)"
+	running := []models.SubscriptionStatus{models.SubscriptionActive, models.SubscriptionPastDue}
+
+	var rows []struct {
+		Cohort time.Time
+		Months int
+		Count  int64
+	}
+	err = db.DB.Model(&models.Subscription{}).
+		Select("date_trunc('month', start_date AT TIME ZONE 'UTC') AS cohort, (EXTRACT(YEAR FROM "+lifetime+") * 12 + EXTRACT(MONTH FROM "+lifetime+"))::int AS months, COUNT(*) AS count",
+			running, now, running, now).
+		Where("content_creator_id = ? AND status IN ? AND start_date >= ?", creator.ID, startedStatuses, firstCohort).
+		Group("cohort, months").
+		Scan(&rows).Error
+	if err != nil {
+		utils.LogErrorWithUser(creator.ID, err, "Error computing cohorts in GetRetention")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing analytics"})
+		return
+	}
+
+	lifetimes := make(map[string]map[int]int64)
+	for _, row := range rows {
+		key := bucketKey(row.Cohort)
+		if lifetimes[key] == nil {
+			lifetimes[key] = make(map[int]int64)
+		}
+		lifetimes[key][row.Months] += row.Count
+	}
+
+	cohorts := []models.RetentionCohort{}
+	for i, cohortStart := range buckets(firstCohort, currentMonth.AddDate(0, 1, 0), "month") {
+		// Seuls les mois déjà écoulés pour la cohorte sont mesurables
+		elapsed := months - 1 - i
+		cohort := models.RetentionCohort{
+			Cohort:   cohortStart,
+			Retained: make([]int64, elapsed+1),
+			Rates:    make([]float64, elapsed+1),
+		}
+		for lived, count := range lifetimes[bucketKey(cohortStart)] {
+			cohort.Size += count
+			for k := 0; k <= elapsed && k <= lived; k++ {
+				cohort.Retained[k] += count
+			}
+		}
+		for k, retained := range cohort.Retained {
+			cohort.Rates[k] = ratio(retained, cohort.Size)
+		}
+		cohorts = append(cohorts, cohort)
+	}
+
+	utils.LogSuccessWithUser(creator.ID, "Retention cohorts computed successfully in GetRetention")
+	c.JSON(http.StatusOK, cohorts)
+}
+
+// startedByPeriod nombre d'abonnements commencés par période, pour un créateur ou toute la plateforme (creatorID vide)
+func startedByPeriod(creatorID string, period string, from time.Time, to time.Time) ([]bucketRow, error) {
+	query := db.DB.Model(&models.Subscription{}).
+		Select("date_trunc(?, start_date AT TIME ZONE 'UTC') AS period, COUNT(*) AS count", period)
+	if creatorID != "" {
+		query = query.Where("content_creator_id = ?", creatorID)
+	}
+
+	var rows []bucketRow
+	err := query.Where("status IN ? AND start_date >= ? AND start_date < ?", startedStatuses, from, to).
+		Group("period").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// endedByPeriod nombre d'abonnements résiliés ou expirés par période, pour un créateur ou toute la plateforme (creatorID vide)
+func endedByPeriod(creatorID string, period string, from time.Time, to time.Time) ([]bucketRow, error) {
+	query := db.DB.Model(&models.Subscription{}).
+		Select("date_trunc(?, end_date AT TIME ZONE 'UTC') AS period, COUNT(*) AS count", period)
+	if creatorID != "" {
+		query = query.Where("content_creator_id = ?", creatorID)
+	}
+
+	var rows []bucketRow
+	err := query.Where("status IN ? AND end_date >= ? AND end_date < ?", endedStatuses, from, to).
+		Group("period").
+		Scan(&rows).Error
+	return rows, err
+}
+
+func byPeriod(rows []bucketRow) map[string]bucketRow {
+	result := make(map[string]bucketRow, len(rows))
+	for _, row := range rows {
+		result[bucketKey(row.Period)] = row
+	}
+	return result
+}
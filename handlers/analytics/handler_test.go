@@ -0,0 +1,264 @@
+package analytics
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"pec2-backend/models"
+	"pec2-backend/testutils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	testutils.InitTestMain()
+
+	log.SetOutput(io.Discard)
+
+	exitCode := m.Run()
+
+	log.SetOutput(os.Stdout)
+
+	os.Exit(exitCode)
+}
+
+const creatorID = "creator-uuid"
+
+func expectUser(mock sqlmock.Sqlmock, userID string, role string) {
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs(userID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(userID, "creator", role))
+}
+
+func serve(handler gin.HandlerFunc, userID string, url string) *httptest.ResponseRecorder {
+	r := testutils.SetupTestRouter()
+	r.GET("/analytics", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		handler(c)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// Test que le MRR et les compteurs d'abonnés sont renvoyés
+func TestGetOverview_Success(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	expectUser(mock, creatorID, string(models.ContentCreator))
+	mock.ExpectQuery(`SELECT COALESCE\(ROUND\(SUM\(price::numeric / GREATEST\(billing_months, 1\)\) FILTER (.+) FROM "subscriptions" WHERE content_creator_id = \$8`).
+		WithArgs(models.SubscriptionActive, sqlmock.AnyArg(), models.SubscriptionActive, sqlmock.AnyArg(), models.SubscriptionActive, sqlmock.AnyArg(), models.SubscriptionPastDue, creatorID).
+		WillReturnRows(mock.NewRows([]string{"mrr", "active_subscribers", "trialing_subscribers", "past_due_subscribers"}).AddRow(2496, 4, 1, 0))
+
+	w := serve(GetOverview, creatorID, "/analytics")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response models.CreatorAnalyticsOverview
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2496), response.Mrr)
+	assert.Equal(t, int64(4), response.ActiveSubscribers)
+	assert.Equal(t, int64(1), response.TrialingSubscribers)
+	assert.Equal(t, "eur", response.Currency)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test que les semaines sans mouvement apparaissent dans la série
+func TestGetSubscribers_Weekly(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	// Du lundi 2 mars au dimanche 22 mars 2026 : trois semaines
+	firstWeek := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	thirdWeek := time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 3, 23, 0, 0, 0, 0, time.UTC)
+
+	expectUser(mock, creatorID, string(models.ContentCreator))
+	mock.ExpectQuery(`SELECT date_trunc\(\$1, start_date AT TIME ZONE 'UTC'\) AS period, COUNT\(\*\) AS count FROM "subscriptions" WHERE (.+) GROUP BY "period"`).
+		WithArgs("week", creatorID, models.SubscriptionActive, models.SubscriptionPastDue, models.SubscriptionCanceled, models.SubscriptionExpired, firstWeek, end).
+		WillReturnRows(mock.NewRows([]string{"period", "count"}).AddRow(firstWeek, 5).AddRow(thirdWeek, 2))
+	mock.ExpectQuery(`SELECT date_trunc\(\$1, end_date AT TIME ZONE 'UTC'\) AS period, COUNT\(\*\) AS count FROM "subscriptions" WHERE (.+) GROUP BY "period"`).
+		WithArgs("week", creatorID, models.SubscriptionCanceled, models.SubscriptionExpired, firstWeek, end).
+		WillReturnRows(mock.NewRows([]string{"period", "count"}).AddRow(thirdWeek, 3))
+
+	w := serve(GetSubscribers, creatorID, "/analytics?period=week&start_date=2026-03-02&end_date=2026-03-22")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response models.SubscriberSeries
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "week", response.Period)
+	assert.Len(t, response.Points, 3)
+	assert.Equal(t, int64(5), response.Points[0].New)
+	assert.Equal(t, int64(0), response.Points[1].New)
+	assert.Equal(t, int64(2), response.Points[2].New)
+	assert.Equal(t, int64(3), response.Points[2].Churned)
+	assert.Equal(t, int64(-1), response.Points[2].Net)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'une granularité inconnue est refusée
+func TestGetRevenue_InvalidPeriod(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	expectUser(mock, creatorID, string(models.ContentCreator))
+
+	w := serve(GetRevenue, creatorID, "/analytics?period=year")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test que la rétention est cumulée sur les mois écoulés de chaque cohorte
+func TestGetRetention_Success(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	currentMonth := truncate(time.Now().UTC(), "month")
+	previousMonth := currentMonth.AddDate(0, -1, 0)
+
+	expectUser(mock, creatorID, string(models.ContentCreator))
+	mock.ExpectQuery(`SELECT date_trunc\('month', start_date AT TIME ZONE 'UTC'\) AS cohort, (.+) FROM "subscriptions" WHERE (.+) GROUP BY cohort, months`).
+		WillReturnRows(mock.NewRows([]string{"cohort", "months", "count"}).
+			AddRow(previousMonth, 0, 1).
+			AddRow(previousMonth, 1, 3).
+			AddRow(currentMonth, 0, 2))
+
+	w := serve(GetRetention, creatorID, "/analytics?months=2")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response []models.RetentionCohort
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response, 2)
+	assert.Equal(t, int64(4), response[0].Size)
+	assert.Equal(t, []int64{4, 3}, response[0].Retained)
+	assert.Equal(t, []float64{1, 0.75}, response[0].Rates)
+	assert.Equal(t, int64(2), response[1].Size)
+	assert.Equal(t, []int64{2}, response[1].Retained)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un simple utilisateur n'a pas accès aux statistiques
+func TestGetTopPosts_NotCreator(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	expectUser(mock, "user-uuid", string(models.UserRole))
+
+	w := serve(GetTopPosts, "user-uuid", "/analytics")
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func expectRevenueRows(mock sqlmock.Sqlmock, from time.Time, to time.Time, rows *sqlmock.Rows) {
+	mock.ExpectQuery(`SELECT date_trunc\(\$1, created_at AT TIME ZONE 'UTC'\) AS period, (.+) FROM "ledger_transactions" WHERE kind <> \$11 AND created_at >= \$12 AND created_at < \$13 GROUP BY "period"`).
+		WithArgs("month",
+			models.LedgerTransactionPayment, models.LedgerSourceSubscriptionPayment,
+			models.LedgerTransactionPayment, models.LedgerSourceTip,
+			models.LedgerTransactionPayment, models.LedgerSourcePostPurchase,
+			models.LedgerTransactionRefund, models.LedgerSourcePaymentDispute,
+			models.LedgerSourcePaymentDispute,
+			models.LedgerTransactionPayout, from, to).
+		WillReturnRows(rows)
+}
+
+// Test que le rapport de revenus couvre chaque mois, y compris ceux sans écriture
+func TestGetRevenueReport_Monthly(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	january := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	expectRevenueRows(mock, january, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		mock.NewRows([]string{"period", "subscriptions", "tips", "purchases", "refunds", "disputes", "commission", "creator_share"}).
+			AddRow(january, 9980, 1500, 499, 998, 0, 2196, 8785).
+			AddRow(march, 4990, 0, 0, 0, 499, 898, 3593))
+
+	w := serve(GetRevenueReport, "admin-uuid", "/analytics?period=month&start_date=2026-01-01&end_date=2026-03-31")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response []models.PlatformRevenuePoint
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response, 3)
+	assert.Equal(t, int64(11979), response[0].Gross)
+	assert.Equal(t, int64(10981), response[0].Net)
+	assert.Equal(t, int64(0), response[1].Gross)
+	assert.Equal(t, int64(4491), response[2].Net)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test que le rapport de revenus est téléchargeable en CSV, montants en euros
+func TestGetRevenueReport_CSV(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	january := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	expectRevenueRows(mock, january, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		mock.NewRows([]string{"period", "subscriptions", "tips", "purchases", "refunds", "disputes", "commission", "creator_share"}).
+			AddRow(january, 9980, 1500, 499, 998, 0, 2196, 8785))
+
+	w := serve(GetRevenueReport, "admin-uuid", "/analytics?period=month&start_date=2026-01-01&end_date=2026-01-31&format=csv")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=revenue-2026-01-01-2026-01-31.csv", w.Header().Get("Content-Disposition"))
+	assert.Equal(t,
+		"period,subscriptions_eur,tips_eur,purchases_eur,gross_eur,refunds_eur,disputes_eur,net_eur,commission_eur,creator_share_eur\n"+
+			"2026-01-01,99.80,15.00,4.99,119.79,9.98,0.00,109.81,21.96,87.85\n",
+		w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test que le taux d'attrition rapporte les abonnements perdus aux actifs du début de période
+func TestGetSubscriptionsReport_Weekly(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	firstWeek := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	secondWeek := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT b.boundary AS period, COUNT\(s.id\) AS active, (.+) FROM \(SELECT generate_series\(\$1::timestamp, \$2::timestamp, \$3::interval\) AT TIME ZONE 'UTC' AS boundary\) AS b LEFT JOIN subscriptions s`).
+		WithArgs("2026-03-02", "2026-03-16", "1 week",
+			models.SubscriptionActive, models.SubscriptionPastDue, models.SubscriptionCanceled, models.SubscriptionExpired,
+			models.SubscriptionActive, models.SubscriptionPastDue).
+		WillReturnRows(mock.NewRows([]string{"period", "active", "mrr"}).
+			AddRow(firstWeek, 100, 49900).
+			AddRow(secondWeek, 110, 54890).
+			AddRow(end, 105, 52395))
+	mock.ExpectQuery(`SELECT date_trunc\(\$1, start_date AT TIME ZONE 'UTC'\) AS period, COUNT\(\*\) AS count FROM "subscriptions" WHERE status IN (.+) GROUP BY "period"`).
+		WithArgs("week", models.SubscriptionActive, models.SubscriptionPastDue, models.SubscriptionCanceled, models.SubscriptionExpired, firstWeek, end).
+		WillReturnRows(mock.NewRows([]string{"period", "count"}).AddRow(firstWeek, 15).AddRow(secondWeek, 6))
+	mock.ExpectQuery(`SELECT date_trunc\(\$1, end_date AT TIME ZONE 'UTC'\) AS period, COUNT\(\*\) AS count FROM "subscriptions" WHERE status IN (.+) GROUP BY "period"`).
+		WithArgs("week", models.SubscriptionCanceled, models.SubscriptionExpired, firstWeek, end).
+		WillReturnRows(mock.NewRows([]string{"period", "count"}).AddRow(firstWeek, 5).AddRow(secondWeek, 11))
+
+	w := serve(GetSubscriptionsReport, "admin-uuid", "/analytics?period=week&start_date=2026-03-02&end_date=2026-03-15")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response []models.SubscriptionsPoint
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response, 2)
+	assert.Equal(t, int64(110), response[0].Active)
+	assert.Equal(t, int64(54890), response[0].Mrr)
+	assert.Equal(t, 0.05, response[0].ChurnRate)
+	assert.Equal(t, int64(105), response[1].Active)
+	assert.Equal(t, 0.1, response[1].ChurnRate)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
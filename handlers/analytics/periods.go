@@ -0,0 +1,112 @@
+package analytics
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"pec2-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Plage couverte par défaut selon la granularité demandée
+var defaultRanges = map[string]func(time.Time) time.Time{
+	"day":   func(end time.Time) time.Time { return end.AddDate(0, 0, -29) },
+	"week":  func(end time.Time) time.Time { return end.AddDate(0, 0, -7*11) },
+	"month": func(end time.Time) time.Time { return end.AddDate(0, -11, 0) },
+}
+
+// Au-delà, les séries deviennent trop longues pour être affichées
+const maxRange = 3 * 366 * 24 * time.Hour
+
+// seriesRange lit la granularité (period) et la plage de dates (start_date, end_date) d'une série.
+// La borne de fin renvoyée est exclue
+func seriesRange(c *gin.Context, userID string, handlerName string) (string, time.Time, time.Time, bool) {
+	period := c.DefaultQuery("period", "day")
+	defaultStart, ok := defaultRanges[period]
+	if !ok {
+		utils.LogErrorWithUser(userID, errors.New("période invalide"), "Invalid period in "+handlerName)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be day, week or month"})
+		return "", time.Time{}, time.Time{}, false
+	}
+
+	now := time.Now().UTC()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			utils.LogErrorWithUser(userID, err, "Invalid end_date format in "+handlerName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format (YYYY-MM-DD)"})
+			return "", time.Time{}, time.Time{}, false
+		}
+		end = parsed
+	}
+
+	start := truncate(defaultStart(end), period)
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			utils.LogErrorWithUser(userID, err, "Invalid start_date format in "+handlerName)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format (YYYY-MM-DD)"})
+			return "", time.Time{}, time.Time{}, false
+		}
+		start = parsed
+	}
+
+	if end.Before(start) {
+		utils.LogErrorWithUser(userID, errors.New("dates inversées"), "end_date before start_date in "+handlerName)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must be after start_date"})
+		return "", time.Time{}, time.Time{}, false
+	}
+	if end.Sub(start) > maxRange {
+		utils.LogErrorWithUser(userID, errors.New("plage trop longue"), "Date range too long in "+handlerName)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The date range cannot exceed 3 years"})
+		return "", time.Time{}, time.Time{}, false
+	}
+
+	return period, start, end.AddDate(0, 0, 1), true
+}
+
+// truncate ramène une date au début de sa période, comme date_trunc de PostgreSQL (semaines commençant le lundi)
+func truncate(t time.Time, period string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case "week":
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+// buckets renvoie le début de chaque période entre from (inclus) et to (exclu)
+func buckets(from time.Time, to time.Time, period string) []time.Time {
+	var result []time.Time
+	for bucket := truncate(from, period); bucket.Before(to); bucket = next(bucket, period) {
+		result = append(result, bucket)
+	}
+	return result
+}
+
+// Pas de generate_series pour chaque granularité
+var intervals = map[string]string{
+	"day":   "1 day",
+	"week":  "1 week",
+	"month": "1 month",
+}
+
+func next(bucket time.Time, period string) time.Time {
+	switch period {
+	case "week":
+		return bucket.AddDate(0, 0, 7)
+	case "month":
+		return bucket.AddDate(0, 1, 0)
+	}
+	return bucket.AddDate(0, 0, 1)
+}
+
+// bucketKey clé commune aux périodes renvoyées par PostgreSQL et à celles calculées ici
+func bucketKey(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
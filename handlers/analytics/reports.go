@@ -0,0 +1,350 @@
+package analytics
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get the platform revenue report
+// @Description Returns the revenue of the platform per day, week (starting on Monday) or month from the ledger, in cents: payments collected by product, refunds, disputes, commission kept by the platform and share owed to the creators (admin only). Defaults to the last 30 days, 12 weeks or 12 months. Add format=csv to download the report, amounts in euros
+// @Tags analytics
+// @Produce json,text/csv
+// @Param period query string false "day, week or month" default(day)
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date, included (YYYY-MM-DD)"
+// @Param format query string false "csv to download the report"
+// @Security BearerAuth
+// @Success 200 {array} models.PlatformRevenuePoint
+// @Failure 400 {object} map[string]string "error: Invalid period or dates"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 403 {object} map[string]string "error: Access denied"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /analytics/revenue [get]
+func GetRevenueReport(c *gin.Context) {
+	userID := c.GetString("user_id")
+	period, from, to, ok := seriesRange(c, userID, "GetRevenueReport")
+	if !ok {
+		return
+	}
+
+	var rows []models.PlatformRevenuePoint
+	err := db.DB.Model(&models.LedgerTransaction{}).
+		Select(`date_trunc(?, created_at AT TIME ZONE 'UTC') AS period,
+			COALESCE(SUM(amount) FILTER (WHERE kind = ? AND source_type = ?), 0) AS subscriptions,
+			COALESCE(SUM(amount) FILTER (WHERE kind = ? AND source_type = ?), 0) AS tips,
+			COALESCE(SUM(amount) FILTER (WHERE kind = ? AND source_type = ?), 0) AS purchases,
+			COALESCE(SUM(-amount) FILTER (WHERE kind = ? AND source_type <> ?), 0) AS refunds,
+			COALESCE(SUM(-amount) FILTER (WHERE source_type = ?), 0) AS disputes,
+			COALESCE(SUM(commission), 0) AS commission,
+			COALESCE(SUM(net), 0) AS creator_share`,
+			period,
+			models.LedgerTransactionPayment, models.LedgerSourceSubscriptionPayment,
+			models.LedgerTransactionPayment, models.LedgerSourceTip,
+			models.LedgerTransactionPayment, models.LedgerSourcePostPurchase,
+			models.LedgerTransactionRefund, models.LedgerSourcePaymentDispute,
+			models.LedgerSourcePaymentDispute).
+		Where("kind <> ? AND created_at >= ? AND created_at < ?", models.LedgerTransactionPayout, from, to).
+		Group("period").
+		Scan(&rows).Error
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Error computing revenue in GetRevenueReport")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing report"})
+		return
+	}
+
+	byKey := make(map[string]models.PlatformRevenuePoint, len(rows))
+	for _, row := range rows {
+		byKey[bucketKey(row.Period)] = row
+	}
+	points := []models.PlatformRevenuePoint{}
+	for _, bucket := range buckets(from, to, period) {
+		point := byKey[bucketKey(bucket)]
+		point.Period = bucket
+		point.Gross = point.Subscriptions + point.Tips + point.Purchases
+		point.Net = point.Gross - point.Refunds - point.Disputes
+		points = append(points, point)
+	}
+
+	if wantsCSV(c) {
+		csvRows := make([][]string, 0, len(points))
+		for _, point := range points {
+			csvRows = append(csvRows, []string{
+				csvDate(point.Period), csvAmount(point.Subscriptions), csvAmount(point.Tips), csvAmount(point.Purchases), csvAmount(point.Gross),
+				csvAmount(point.Refunds), csvAmount(point.Disputes), csvAmount(point.Net), csvAmount(point.Commission), csvAmount(point.CreatorShare),
+			})
+		}
+		header := []string{"period", "subscriptions_eur", "tips_eur", "purchases_eur", "gross_eur", "refunds_eur", "disputes_eur", "net_eur", "commission_eur", "creator_share_eur"}
+		if err := writeCSV(c, reportFilename("revenue", from, to), header, csvRows); err != nil {
+			utils.LogErrorWithUser(userID, err, "Error writing CSV in GetRevenueReport")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error writing report"})
+			return
+		}
+		utils.LogSuccessWithUser(userID, "Revenue report exported in GetRevenueReport")
+		return
+	}
+
+	utils.LogSuccessWithUser(userID, "Revenue report computed successfully in GetRevenueReport")
+	c.JSON(http.StatusOK, points)
+}
+
+// @Summary Get the revenue per creator report
+// @Description Returns the revenue generated by each content creator between two dates from the ledger, in cents, highest gross first: payments collected, refunds and disputes, commission kept by the platform and share owed to the creator (admin only). Defaults to the last 30 days. Add format=csv to download the report, amounts in euros
+// @Tags analytics
+// @Produce json,text/csv
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date, included (YYYY-MM-DD)"
+// @Param format query string false "csv to download the report"
+// @Security BearerAuth
+// @Success 200 {array} models.CreatorRevenue
+// @Failure 400 {object} map[string]string "error: Invalid dates"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 403 {object} map[string]string "error: Access denied"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /analytics/creators [get]
+func GetCreatorsRevenueReport(c *gin.Context) {
+	userID := c.GetString("user_id")
+	_, from, to, ok := seriesRange(c, userID, "GetCreatorsRevenueReport")
+	if !ok {
+		return
+	}
+
+	creators := []models.CreatorRevenue{}
+	err := db.DB.Model(&models.LedgerTransaction{}).
+		Select(`ledger_transactions.content_creator_id, users.user_name,
+			COUNT(*) FILTER (WHERE ledger_transactions.kind = ? AND ledger_transactions.source_type <> ?) AS payments,
+			COALESCE(SUM(ledger_transactions.amount) FILTER (WHERE ledger_transactions.kind = ? AND ledger_transactions.source_type <> ?), 0) AS gross,
+			COALESCE(SUM(-ledger_transactions.amount) FILTER (WHERE ledger_transactions.kind = ? OR ledger_transactions.source_type = ?), 0) AS refunds,
+			COALESCE(SUM(ledger_transactions.amount), 0) AS net,
+			COALESCE(SUM(ledger_transactions.commission), 0) AS commission,
+			COALESCE(SUM(ledger_transactions.net), 0) AS creator_share`,
+			models.LedgerTransactionPayment, models.LedgerSourcePaymentDispute,
+			models.LedgerTransactionPayment, models.LedgerSourcePaymentDispute,
+			models.LedgerTransactionRefund, models.LedgerSourcePaymentDispute).
+		Joins("JOIN users ON users.id = ledger_transactions.content_creator_id").
+		Where("ledger_transactions.kind <> ? AND ledger_transactions.created_at >= ? AND ledger_transactions.created_at < ?", models.LedgerTransactionPayout, from, to).
+		Group("ledger_transactions.content_creator_id, users.user_name").
+		Order("gross DESC").
+		Scan(&creators).Error
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Error computing revenue per creator in GetCreatorsRevenueReport")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing report"})
+		return
+	}
+
+	if wantsCSV(c) {
+		csvRows := make([][]string, 0, len(creators))
+		for _, creator := range creators {
+			csvRows = append(csvRows, []string{
+				creator.ContentCreatorID, creator.UserName, csvCount(creator.Payments), csvAmount(creator.Gross),
+				csvAmount(creator.Refunds), csvAmount(creator.Net), csvAmount(creator.Commission), csvAmount(creator.CreatorShare),
+			})
+		}
+		header := []string{"content_creator_id", "user_name", "payments", "gross_eur", "refunds_eur", "net_eur", "commission_eur", "creator_share_eur"}
+		if err := writeCSV(c, reportFilename("creators-revenue", from, to), header, csvRows); err != nil {
+			utils.LogErrorWithUser(userID, err, "Error writing CSV in GetCreatorsRevenueReport")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error writing report"})
+			return
+		}
+		utils.LogSuccessWithUser(userID, "Revenue per creator report exported in GetCreatorsRevenueReport")
+		return
+	}
+
+	utils.LogSuccessWithUser(userID, "Revenue per creator report computed successfully in GetCreatorsRevenueReport")
+	c.JSON(http.StatusOK, creators)
+}
+
+// @Summary Get the payment rates report
+// @Description Returns the subscription payments attempted per day, week (starting on Monday) or month with their failure rate (failed / attempts) and refund rate (payments refunded at least partially / payments succeeded), counted on the final state of each payment (admin only). Defaults to the last 30 days, 12 weeks or 12 months. Add format=csv to download the report
+// @Tags analytics
+// @Produce json,text/csv
+// @Param period query string false "day, week or month" default(day)
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date, included (YYYY-MM-DD)"
+// @Param format query string false "csv to download the report"
+// @Security BearerAuth
+// @Success 200 {array} models.PaymentRatesPoint
+// @Failure 400 {object} map[string]string "error: Invalid period or dates"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 403 {object} map[string]string "error: Access denied"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /analytics/payments [get]
+func GetPaymentRatesReport(c *gin.Context) {
+	userID := c.GetString("user_id")
+	period, from, to, ok := seriesRange(c, userID, "GetPaymentRatesReport")
+	if !ok {
+		return
+	}
+
+	attempted := append([]models.SubscriptionPaymentStatus{models.SubscriptionPaymentFailed}, collectedPaymentStatuses...)
+
+	var rows []models.PaymentRatesPoint
+	err := db.DB.Model(&models.SubscriptionPayment{}).
+		Select(`date_trunc(?, created_at AT TIME ZONE 'UTC') AS period,
+			COUNT(*) AS attempts,
+			COUNT(*) FILTER (WHERE status IN ?) AS succeeded,
+			COUNT(*) FILTER (WHERE status = ?) AS failed,
+			COUNT(*) FILTER (WHERE refunded_amount > 0) AS refunded,
+			COUNT(*) FILTER (WHERE id IN (SELECT subscription_payment_id FROM payment_disputes)) AS disputed`,
+			period, collectedPaymentStatuses, models.SubscriptionPaymentFailed).
+		Where("status IN ? AND created_at >= ? AND created_at < ?", attempted, from, to).
+		Group("period").
+		Scan(&rows).Error
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Error computing payment rates in GetPaymentRatesReport")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing report"})
+		return
+	}
+
+	byKey := make(map[string]models.PaymentRatesPoint, len(rows))
+	for _, row := range rows {
+		byKey[bucketKey(row.Period)] = row
+	}
+	points := []models.PaymentRatesPoint{}
+	for _, bucket := range buckets(from, to, period) {
+		point := byKey[bucketKey(bucket)]
+		point.Period = bucket
+		point.FailureRate = ratio(point.Failed, point.Attempts)
+		point.RefundRate = ratio(point.Refunded, point.Succeeded)
+		points = append(points, point)
+	}
+
+	if wantsCSV(c) {
+		csvRows := make([][]string, 0, len(points))
+		for _, point := range points {
+			csvRows = append(csvRows, []string{
+				csvDate(point.Period), csvCount(point.Attempts), csvCount(point.Succeeded), csvCount(point.Failed),
+				csvCount(point.Refunded), csvCount(point.Disputed), csvRate(point.FailureRate), csvRate(point.RefundRate),
+			})
+		}
+		header := []string{"period", "attempts", "succeeded", "failed", "refunded", "disputed", "failure_rate", "refund_rate"}
+		if err := writeCSV(c, reportFilename("payments", from, to), header, csvRows); err != nil {
+			utils.LogErrorWithUser(userID, err, "Error writing CSV in GetPaymentRatesReport")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error writing report"})
+			return
+		}
+		utils.LogSuccessWithUser(userID, "Payment rates report exported in GetPaymentRatesReport")
+		return
+	}
+
+	utils.LogSuccessWithUser(userID, "Payment rates report computed successfully in GetPaymentRatesReport")
+	c.JSON(http.StatusOK, points)
+}
+
+// @Summary Get the subscriptions report
+// @Description Returns per day, week (starting on Monday) or month the subscriptions of the whole platform: active subscriptions and MRR (cents, trials excluded) at the end of the period, new and churned subscriptions, and churn rate (churned / active at the start of the period) (admin only). Defaults to the last 30 days, 12 weeks or 12 months. Add format=csv to download the report, amounts in euros
+// @Tags analytics
+// @Produce json,text/csv
+// @Param period query string false "day, week or month" default(day)
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date, included (YYYY-MM-DD)"
+// @Param format query string false "csv to download the report"
+// @Security BearerAuth
+// @Success 200 {array} models.SubscriptionsPoint
+// @Failure 400 {object} map[string]string "error: Invalid period or dates"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 403 {object} map[string]string "error: Access denied"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /analytics/subscriptions [get]
+func GetSubscriptionsReport(c *gin.Context) {
+	userID := c.GetString("user_id")
+	period, from, to, ok := seriesRange(c, userID, "GetSubscriptionsReport")
+	if !ok {
+		return
+	}
+
+	periods := buckets(from, to, period)
+	last := next(periods[len(periods)-1], period)
+	running := []models.SubscriptionStatus{models.SubscriptionActive, models.SubscriptionPastDue}
+
+	// Situation à chaque borne de période : un abonnement compte s'il avait commencé et n'était pas encore terminé
+	var snapshots []struct {
+		Period time.Time
+		Active int64
+		Mrr    int64
+	}
+	err := db.DB.Raw(`SELECT b.boundary AS period, COUNT(s.id) AS active,
+			COALESCE(ROUND(SUM(s.price::numeric / GREATEST(s.billing_months, 1)) FILTER (WHERE s.trial_ends_at IS NULL OR s.trial_ends_at <= b.boundary)), 0) AS mrr
+		FROM (SELECT generate_series(?::timestamp, ?::timestamp, ?::interval) AT TIME ZONE 'UTC' AS boundary) AS b
+		LEFT JOIN subscriptions s ON s.status IN ? AND s.start_date < b.boundary
+			AND (s.status IN ? OR COALESCE(s.end_date, s.updated_at) >= b.boundary)
+		GROUP BY b.boundary
+		ORDER BY b.boundary`,
+		csvDate(periods[0]), csvDate(last), intervals[period], startedStatuses, running).
+		Scan(&snapshots).Error
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Error computing active subscriptions in GetSubscriptionsReport")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing report"})
+		return
+	}
+
+	started, err := startedByPeriod("", period, from, to)
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Error counting new subscriptions in GetSubscriptionsReport")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing report"})
+		return
+	}
+	churned, err := endedByPeriod("", period, from, to)
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Error counting churned subscriptions in GetSubscriptionsReport")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing report"})
+		return
+	}
+
+	activeAt := make(map[string]int64, len(snapshots))
+	mrrAt := make(map[string]int64, len(snapshots))
+	for _, snapshot := range snapshots {
+		activeAt[bucketKey(snapshot.Period)] = snapshot.Active
+		mrrAt[bucketKey(snapshot.Period)] = snapshot.Mrr
+	}
+	newByPeriod := byPeriod(started)
+	churnedByPeriod := byPeriod(churned)
+
+	points := []models.SubscriptionsPoint{}
+	for _, bucket := range periods {
+		end := bucketKey(next(bucket, period))
+		point := models.SubscriptionsPoint{
+			Period:  bucket,
+			Active:  activeAt[end],
+			Mrr:     mrrAt[end],
+			New:     newByPeriod[bucketKey(bucket)].Count,
+			Churned: churnedByPeriod[bucketKey(bucket)].Count,
+		}
+		point.ChurnRate = ratio(point.Churned, activeAt[bucketKey(bucket)])
+		points = append(points, point)
+	}
+
+	if wantsCSV(c) {
+		csvRows := make([][]string, 0, len(points))
+		for _, point := range points {
+			csvRows = append(csvRows, []string{
+				csvDate(point.Period), csvCount(point.Active), csvAmount(point.Mrr), csvCount(point.New), csvCount(point.Churned), csvRate(point.ChurnRate),
+			})
+		}
+		header := []string{"period", "active", "mrr_eur", "new", "churned", "churn_rate"}
+		if err := writeCSV(c, reportFilename("subscriptions", from, to), header, csvRows); err != nil {
+			utils.LogErrorWithUser(userID, err, "Error writing CSV in GetSubscriptionsReport")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error writing report"})
+			return
+		}
+		utils.LogSuccessWithUser(userID, "Subscriptions report exported in GetSubscriptionsReport")
+		return
+	}
+
+	utils.LogSuccessWithUser(userID, "Subscriptions report computed successfully in GetSubscriptionsReport")
+	c.JSON(http.StatusOK, points)
+}
+
+// ratio part arrondie au millième, 0 si le dénominateur est nul
+func ratio(part int64, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*1000) / 1000
+}
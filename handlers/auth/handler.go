@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"pec2-backend/db"
 	"pec2-backend/models"
+	"pec2-backend/throttle"
 	"pec2-backend/utils"
 	mailsmodels "pec2-backend/utils/mails-models"
 	"strings"
@@ -159,7 +160,12 @@ func CreateUser(c *gin.Context) {
 	}
 
 	now := time.Now()
-	code := utils.GenerateCode()
+	code, err := utils.GenerateCode()
+	if err != nil {
+		utils.LogError(err, "Error when generating confirmation code in CreateUser")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error when generating confirmation code"})
+		return
+	}
 
 	user := models.User{
 		Email:               userCreate.Email,
@@ -179,8 +185,8 @@ func CreateUser(c *gin.Context) {
 		MessageEnable:       true,
 		EmailVerifiedAt:     nil,
 		Siret:               "",
-		ConfirmationCode:    code,
-		ConfirmationCodeEnd: now.Add(1 * time.Hour),
+		ConfirmationCode:    utils.HashCode(code),
+		ConfirmationCodeEnd: now.Add(utils.ConfirmationCodeTTL),
 	}
 
 	result := db.DB.Create(&user)
@@ -226,10 +232,11 @@ type LoginRequest struct {
 // @Accept json
 // @Produce json
 // @Param user body LoginRequest true "User credentials"
-// @Success 200 {object} map[string]interface{} "token: JWT token"
+// @Success 200 {object} map[string]interface{} "token: JWT token (15 min), refreshToken: refresh token, or twoFactorRequired and challengeToken when 2FA is enabled"
 // @Failure 400 {object} map[string]interface{} "error: Invalid input"
 // @Failure 401 {object} map[string]interface{} "error: Wrong credentials or email not verified"
 // @Failure 422 {object} map[string]interface{} "error: JWT not generated"
+// @Failure 429 {object} map[string]interface{} "error: Too many failed attempts, retryAfter: seconds before retry"
 // @Router /login [post]
 func Login(c *gin.Context) {
 	var inputLogin LoginRequest
@@ -266,11 +273,16 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	if throttle.AbortIfThrottled(c, inputLogin.Email, "Login") {
+		return
+	}
+
 	var user models.User
 	result := db.DB.Where("email = ?", inputLogin.Email).First(&user)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			throttle.RegisterFailure(c, inputLogin.Email)
 			utils.LogError(result.Error, "User not found in Login")
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "User not found",
@@ -287,6 +299,7 @@ func Login(c *gin.Context) {
 	isSamePassword := samePassword(inputLogin.Password, user.Password)
 
 	if !isSamePassword {
+		throttle.RegisterFailure(c, inputLogin.Email)
 		utils.LogError(errors.New("mauvais mot de passe"), "Wrong password in Login")
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "Wrong credentials",
@@ -302,12 +315,29 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	token, err := utils.GenerateJWT(user, 72)
+	if user.TwoFactorEnabled {
+		challengeToken, err := utils.GenerateTwoFactorChallenge(user)
+		if err != nil {
+			utils.LogError(err, "Error when generating 2FA challenge in Login")
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Error when generating JWT"})
+			return
+		}
+
+		utils.LogSuccessWithUser(user.ID, "Two-factor challenge issued in Login")
+		c.JSON(http.StatusOK, gin.H{
+			"twoFactorRequired": true,
+			"challengeToken":    challengeToken,
+		})
+		return
+	}
+
+	token, refreshToken, err := issueSession(c, user)
 	if err != nil {
 		utils.LogError(err, "Error when generating JWT in Login")
 		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Error when generating JWT"})
 		return
 	}
+	throttle.ResetAccount(inputLogin.Email)
 
 	userID := user.ID
 	if userID == "" {
@@ -315,28 +345,48 @@ func Login(c *gin.Context) {
 	}
 	utils.LogSuccessWithUser(userID, "User login successfully in Login")
 	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user":  user,
+		"token":        token,
+		"refreshToken": refreshToken,
+		"user":         user,
 	})
 }
 
 // @Summary Validation email
-// @Description After create account, user valid it email
+// @Description After create account, user valid it email. Each code accepts a limited number of attempts, and wrong codes count towards the lockout of the account and of the IP. The email is optional so that clients sending only the code keep working: without it the code alone identifies the account and wrong codes only count for the IP. Sending it is recommended.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param code path string true "user code received by mail"
+// @Param email query string false "user email, recommended"
 // @Success 200 {object} map[string]interface{} "message": "User validate account"
-// @Failure 400 {object} map[string]interface{} "error: User already validated account"
-// @Router /valid-email/{token} [get]
+// @Failure 400 {object} map[string]interface{} "error: User already validated account or invalid code"
+// @Failure 401 {object} map[string]interface{} "error: Confirmation code expired"
+// @Failure 404 {object} map[string]interface{} "error: User not found"
+// @Failure 429 {object} map[string]interface{} "error: Too many attempts, request a new code"
+// @Router /valid-email/{code} [get]
 func ValidEmail(c *gin.Context) {
 	code := c.Param("code")
-	var user models.User
+	email := c.Query("email")
+
+	if throttle.AbortIfThrottled(c, email, "ValidEmail") {
+		return
+	}
 
-	result := db.DB.Where("confirmation_code = ?", code).First(&user)
+	// Sans email, le compte est retrouvé par l'empreinte du code comme avant l'ajout du paramètre
+	query := db.DB.Where("email = ?", email)
+	if email == "" {
+		query = db.DB.Where("confirmation_code = ?", utils.HashCode(code))
+	}
+
+	var user models.User
+	result := query.First(&user)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			// Un code qui ne correspond à aucun compte est un essai manqué pour l'IP
+			if email == "" {
+				throttle.RegisterFailure(c, "")
+			}
 			utils.LogError(result.Error, "User not found in ValidEmail")
 			c.JSON(http.StatusNotFound, gin.H{
 				"error": "User not found",
@@ -350,6 +400,14 @@ func ValidEmail(c *gin.Context) {
 		return
 	}
 
+	if user.EmailVerifiedAt != nil {
+		utils.LogError(errors.New("déjà validé"), "User already validated in ValidEmail")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "User already validated account",
+		})
+		return
+	}
+
 	if time.Now().After(user.ConfirmationCodeEnd) {
 		utils.LogError(errors.New("code expiré"), "Confirmation code expired in ValidEmail")
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -358,10 +416,34 @@ func ValidEmail(c *gin.Context) {
 		return
 	}
 
-	if user.EmailVerifiedAt != nil {
-		utils.LogError(errors.New("déjà validé"), "User already validated in ValidEmail")
+	// L'essai est décompté avant la comparaison pour que des requêtes concurrentes ne dépassent pas la limite
+	attempt := db.DB.Model(&models.User{}).
+		Where("id = ? AND confirmation_attempts < ?", user.ID, utils.MaxCodeAttempts).
+		UpdateColumn("confirmation_attempts", gorm.Expr("confirmation_attempts + 1"))
+	if attempt.Error != nil {
+		utils.LogError(attempt.Error, "Database error in ValidEmail")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Database error: " + attempt.Error.Error(),
+		})
+		return
+	}
+	if attempt.RowsAffected == 0 {
+		throttle.RegisterFailure(c, email)
+		utils.LogError(errors.New("trop de tentatives"), "Too many attempts in ValidEmail")
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": "Too many attempts, request a new code",
+		})
+		return
+	}
+
+	if !utils.CheckCode(code, user.ConfirmationCode) {
+		// Les échecs comptent aussi dans le verrouillage du compte et de l'IP : demander un nouveau code
+		// remet les essais du code à zéro mais pas ce compteur
+		throttle.RegisterFailure(c, email)
+		utils.LogError(errors.New("code invalide"), "Invalid confirmation code in ValidEmail")
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "User already validated account",
+			"error":             "Invalid confirmation code",
+			"remainingAttempts": utils.MaxCodeAttempts - user.ConfirmationAttempts - 1,
 		})
 		return
 	}
@@ -369,6 +451,7 @@ func ValidEmail(c *gin.Context) {
 	now := time.Now()
 	user.EmailVerifiedAt = &now
 	user.ConfirmationCode = ""
+	user.ConfirmationAttempts = 0
 
 	resultSaveUser := db.DB.Save(&user)
 	if resultSaveUser.Error != nil {
@@ -398,10 +481,15 @@ func ValidEmail(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "message": "send email at user email address"
 // @Failure 400 {object} map[string]interface{} "error: User already validated account"
 // @Failure 404 {object} map[string]interface{} "error: User not found"
+// @Failure 429 {object} map[string]interface{} "error: Too many failed attempts or a code was sent recently, retryAfter: seconds before retry"
 // @Router /valid-email/{token} [get]
 func ResendValidEmail(c *gin.Context) {
 	email := c.Param("email")
 
+	if throttle.AbortIfThrottled(c, email, "ResendValidEmail") {
+		return
+	}
+
 	var user models.User
 	result := db.DB.Where("email = ?", email).First(&user)
 
@@ -428,11 +516,21 @@ func ResendValidEmail(c *gin.Context) {
 		return
 	}
 
+	if throttle.AbortIfCodeRecentlySent(c, user.ConfirmationCodeEnd.Add(-utils.ConfirmationCodeTTL), "ResendValidEmail") {
+		return
+	}
+
 	now := time.Now()
-	code := utils.GenerateCode()
+	code, err := utils.GenerateCode()
+	if err != nil {
+		utils.LogError(err, "Error when generating confirmation code in ResendValidEmail")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error when generating confirmation code"})
+		return
+	}
 
-	user.ConfirmationCode = code
-	user.ConfirmationCodeEnd = now.Add(1 * time.Hour)
+	user.ConfirmationCode = utils.HashCode(code)
+	user.ConfirmationCodeEnd = now.Add(utils.ConfirmationCodeTTL)
+	user.ConfirmationAttempts = 0
 
 	if result := db.DB.Save(&user); result.Error != nil {
 		utils.LogError(result.Error, "Error when saving user in ResendValidEmail")
@@ -9,10 +9,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"pec2-backend/models"
 	"pec2-backend/testutils"
+	"pec2-backend/utils"
 	"testing"
 	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -355,10 +359,18 @@ func TestLogin_Success(t *testing.T) {
 	defer cleanup()
 
 	now := time.Now()
+	expectThrottleCheck(mock)
 	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
 		WithArgs("user@example.com", 1).
 		WillReturnRows(mock.NewRows([]string{"id", "email", "password", "email_verified_at"}).
 			AddRow("user-uuid", "user@example.com", "$2a$10$8b9qfHvbQVnP1IgEyd/AX.X5PCNGO/ZVE13NZS8xg3wDo6f4rWpiW", sql.NullTime{Time: now, Valid: true}))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "user_sessions"`).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("session-uuid"))
+	mock.ExpectQuery(`INSERT INTO "refresh_tokens"`).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("refresh-uuid"))
+	mock.ExpectCommit()
+	expectThrottleReset(mock)
 
 	r := testutils.SetupTestRouter()
 	r.POST("/login", Login)
@@ -380,12 +392,15 @@ func TestLogin_Success(t *testing.T) {
 	var respBody map[string]string
 	json.Unmarshal(resp.Body.Bytes(), &respBody)
 	assert.NotEmpty(t, respBody["token"])
+	assert.NotEmpty(t, respBody["refreshToken"])
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestLogin_EmailNotVerified(t *testing.T) {
 	_, mock, cleanup := testutils.SetupTestDB(t)
 	defer cleanup()
 
+	expectThrottleCheck(mock)
 	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
 		WithArgs("user@example.com", 1).
 		WillReturnRows(mock.NewRows([]string{"id", "email", "password", "email_verified_at"}).
@@ -418,11 +433,15 @@ func TestLogin_InvalidPassword(t *testing.T) {
 	defer cleanup()
 
 	now := time.Now()
+	expectThrottleCheck(mock)
 	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
 		WithArgs("user@example.com", 1).
 		WillReturnRows(mock.NewRows([]string{"id", "email", "password", "email_verified_at"}).
 			AddRow("user-uuid", "user@example.com", "$2a$10$8b9qfHvbQVnP1IgEyd/AX.X5PCNGO/ZVE13NZS8xg3wDo6f4rWpiW", sql.NullTime{Time: now, Valid: true}))
 
+	expectLoginFailure(mock)
+	expectLoginFailure(mock)
+
 	r := testutils.SetupTestRouter()
 	r.POST("/login", Login)
 
@@ -443,16 +462,21 @@ func TestLogin_InvalidPassword(t *testing.T) {
 	var respBody map[string]string
 	json.Unmarshal(resp.Body.Bytes(), &respBody)
 	assert.Equal(t, "Wrong credentials", respBody["error"])
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestLogin_UserNotFound(t *testing.T) {
 	_, mock, cleanup := testutils.SetupTestDB(t)
 	defer cleanup()
 
+	expectThrottleCheck(mock)
 	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
 		WithArgs("nonexistent@example.com", 1).
 		WillReturnError(gorm.ErrRecordNotFound)
 
+	expectLoginFailure(mock)
+	expectLoginFailure(mock)
+
 	r := testutils.SetupTestRouter()
 	r.POST("/login", Login)
 
@@ -473,19 +497,29 @@ func TestLogin_UserNotFound(t *testing.T) {
 	var respBody map[string]string
 	json.Unmarshal(resp.Body.Bytes(), &respBody)
 	assert.Equal(t, "User not found", respBody["error"])
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+var validEmailColumns = []string{"id", "email", "email_verified_at", "confirmation_code", "confirmation_code_end", "confirmation_attempts"}
+
 func TestValidEmail_Success(t *testing.T) {
 	_, mock, cleanup := testutils.SetupTestDB(t)
 	defer cleanup()
 
-	confirmationCode := "test-confirmation-code"
+	confirmationCode := "12345"
 	futureTime := time.Now().Add(time.Hour * 24)
 
-	mock.ExpectQuery(`SELECT \* FROM "users" WHERE confirmation_code = \$1 ORDER BY "users"."id" LIMIT \$2`).
-		WithArgs(confirmationCode, 1).
-		WillReturnRows(mock.NewRows([]string{"id", "email", "email_verified_at", "confirmation_code", "confirmation_code_end"}).
-			AddRow("test-uuid", "test@example.com", sql.NullTime{Valid: false}, confirmationCode, futureTime))
+	expectThrottleCheck(mock)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs("test@example.com", 1).
+		WillReturnRows(mock.NewRows(validEmailColumns).
+			AddRow("test-uuid", "test@example.com", sql.NullTime{Valid: false}, utils.HashCode(confirmationCode), futureTime, 0))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "confirmation_attempts"=confirmation_attempts \+ 1 WHERE id = \$1 AND confirmation_attempts < \$2`).
+		WithArgs("test-uuid", utils.MaxCodeAttempts).
+		WillReturnResult(testutils.NewResult(0, 1))
+	mock.ExpectCommit()
 
 	mock.ExpectBegin()
 	mock.ExpectExec(`UPDATE "users" SET (.+) WHERE (.+)`).
@@ -495,7 +529,7 @@ func TestValidEmail_Success(t *testing.T) {
 	r := testutils.SetupTestRouter()
 	r.GET("/valid-email/:code", ValidEmail)
 
-	req, _ := http.NewRequest(http.MethodGet, "/valid-email/"+confirmationCode, nil)
+	req, _ := http.NewRequest(http.MethodGet, "/valid-email/"+confirmationCode+"?email=test@example.com", nil)
 	resp := httptest.NewRecorder()
 
 	r.ServeHTTP(resp, req)
@@ -506,19 +540,125 @@ func TestValidEmail_Success(t *testing.T) {
 	err := json.Unmarshal(resp.Body.Bytes(), &respBody)
 	assert.NoError(t, err, "Erreur lors de la désérialisation de la réponse JSON: %s", resp.Body.String())
 	assert.Equal(t, "User validate account", respBody["message"])
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestValidEmail_CodeExpired(t *testing.T) {
+func TestValidEmail_InvalidCode(t *testing.T) {
 	_, mock, cleanup := testutils.SetupTestDB(t)
 	defer cleanup()
 
-	confirmationCode := "test-confirmation-code"
-	pastTime := time.Now().Add(-time.Hour * 24)
+	futureTime := time.Now().Add(time.Hour * 24)
+
+	expectThrottleCheck(mock)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs("test@example.com", 1).
+		WillReturnRows(mock.NewRows(validEmailColumns).
+			AddRow("test-uuid", "test@example.com", sql.NullTime{Valid: false}, utils.HashCode("12345"), futureTime, 1))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "confirmation_attempts"=confirmation_attempts \+ 1 WHERE id = \$1 AND confirmation_attempts < \$2`).
+		WillReturnResult(testutils.NewResult(0, 1))
+	mock.ExpectCommit()
+	// L'échec compte pour le compte et pour l'IP
+	expectLoginFailure(mock)
+	expectLoginFailure(mock)
+
+	r := testutils.SetupTestRouter()
+	r.GET("/valid-email/:code", ValidEmail)
+
+	req, _ := http.NewRequest(http.MethodGet, "/valid-email/54321?email=test@example.com", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	var respBody map[string]interface{}
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "Invalid confirmation code", respBody["error"])
+	assert.Equal(t, float64(utils.MaxCodeAttempts-2), respBody["remainingAttempts"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestValidEmail_TooManyAttempts(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	confirmationCode := "12345"
+	futureTime := time.Now().Add(time.Hour * 24)
+
+	expectThrottleCheck(mock)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs("test@example.com", 1).
+		WillReturnRows(mock.NewRows(validEmailColumns).
+			AddRow("test-uuid", "test@example.com", sql.NullTime{Valid: false}, utils.HashCode(confirmationCode), futureTime, utils.MaxCodeAttempts))
+
+	// Même le bon code est refusé une fois la limite atteinte
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "confirmation_attempts"=confirmation_attempts \+ 1 WHERE id = \$1 AND confirmation_attempts < \$2`).
+		WillReturnResult(testutils.NewResult(0, 0))
+	mock.ExpectCommit()
+	// L'échec compte pour le compte et pour l'IP
+	expectLoginFailure(mock)
+	expectLoginFailure(mock)
+
+	r := testutils.SetupTestRouter()
+	r.GET("/valid-email/:code", ValidEmail)
+
+	req, _ := http.NewRequest(http.MethodGet, "/valid-email/"+confirmationCode+"?email=test@example.com", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un nouveau code n'est pas envoyé moins d'une minute après le précédent
+func TestResendValidEmail_CodeRecentlySent(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	expectThrottleCheck(mock)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs("test@example.com", 1).
+		WillReturnRows(mock.NewRows(validEmailColumns).
+			AddRow("test-uuid", "test@example.com", sql.NullTime{Valid: false}, utils.HashCode("12345678"), time.Now().Add(utils.ConfirmationCodeTTL-10*time.Second), 0))
+
+	r := testutils.SetupTestRouter()
+	r.GET("/resend-valid-email/:email", ResendValidEmail)
+
+	req, _ := http.NewRequest(http.MethodGet, "/resend-valid-email/test@example.com", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test que l'ancien lien sans email valide toujours le compte retrouvé par son code
+func TestValidEmail_WithoutEmail(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	confirmationCode := "12345678"
 
+	mock.ExpectQuery(`SELECT \* FROM "auth_throttles" WHERE throttle_key IN \(\$1\) AND locked_until > \$2`).
+		WillReturnRows(mock.NewRows([]string{"throttle_key", "failures", "locked_until"}))
 	mock.ExpectQuery(`SELECT \* FROM "users" WHERE confirmation_code = \$1 ORDER BY "users"."id" LIMIT \$2`).
-		WithArgs(confirmationCode, 1).
-		WillReturnRows(mock.NewRows([]string{"id", "email", "email_verified_at", "confirmation_code", "confirmation_code_end"}).
-			AddRow("test-uuid", "test@example.com", sql.NullTime{Valid: false}, confirmationCode, pastTime))
+		WithArgs(utils.HashCode(confirmationCode), 1).
+		WillReturnRows(mock.NewRows(validEmailColumns).
+			AddRow("test-uuid", "test@example.com", sql.NullTime{Valid: false}, utils.HashCode(confirmationCode), time.Now().Add(time.Hour), 0))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "confirmation_attempts"=confirmation_attempts \+ 1 WHERE id = \$1 AND confirmation_attempts < \$2`).
+		WillReturnResult(testutils.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET (.+) WHERE (.+)`).
+		WillReturnResult(testutils.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	r := testutils.SetupTestRouter()
 	r.GET("/valid-email/:code", ValidEmail)
@@ -528,6 +668,56 @@ func TestValidEmail_CodeExpired(t *testing.T) {
 
 	r.ServeHTTP(resp, req)
 
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un code inconnu envoyé sans email compte comme un échec pour l'IP
+func TestValidEmail_WithoutEmail_UnknownCode(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT \* FROM "auth_throttles" WHERE throttle_key IN \(\$1\) AND locked_until > \$2`).
+		WillReturnRows(mock.NewRows([]string{"throttle_key", "failures", "locked_until"}))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE confirmation_code = \$1`).
+		WillReturnRows(mock.NewRows(validEmailColumns))
+	mock.ExpectQuery(`INSERT INTO auth_throttles`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"failures"}).AddRow(1))
+
+	r := testutils.SetupTestRouter()
+	r.GET("/valid-email/:code", ValidEmail)
+
+	req, _ := http.NewRequest(http.MethodGet, "/valid-email/00000000", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestValidEmail_CodeExpired(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	confirmationCode := "12345"
+	pastTime := time.Now().Add(-time.Hour * 24)
+
+	expectThrottleCheck(mock)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs("test@example.com", 1).
+		WillReturnRows(mock.NewRows(validEmailColumns).
+			AddRow("test-uuid", "test@example.com", sql.NullTime{Valid: false}, utils.HashCode(confirmationCode), pastTime, 0))
+
+	r := testutils.SetupTestRouter()
+	r.GET("/valid-email/:code", ValidEmail)
+
+	req, _ := http.NewRequest(http.MethodGet, "/valid-email/"+confirmationCode+"?email=test@example.com", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
 	assert.Equal(t, http.StatusUnauthorized, resp.Code)
 
 	var respBody map[string]string
@@ -540,19 +730,20 @@ func TestValidEmail_AlreadyVerified(t *testing.T) {
 	_, mock, cleanup := testutils.SetupTestDB(t)
 	defer cleanup()
 
-	confirmationCode := "test-confirmation-code"
+	confirmationCode := "12345"
 	futureTime := time.Now().Add(time.Hour * 24)
 	now := time.Now()
 
-	mock.ExpectQuery(`SELECT \* FROM "users" WHERE confirmation_code = \$1 ORDER BY "users"."id" LIMIT \$2`).
-		WithArgs(confirmationCode, 1).
-		WillReturnRows(mock.NewRows([]string{"id", "email", "email_verified_at", "confirmation_code", "confirmation_code_end"}).
-			AddRow("test-uuid", "test@example.com", sql.NullTime{Time: now, Valid: true}, confirmationCode, futureTime))
+	expectThrottleCheck(mock)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs("test@example.com", 1).
+		WillReturnRows(mock.NewRows(validEmailColumns).
+			AddRow("test-uuid", "test@example.com", sql.NullTime{Time: now, Valid: true}, "", futureTime, 0))
 
 	r := testutils.SetupTestRouter()
 	r.GET("/valid-email/:code", ValidEmail)
 
-	req, _ := http.NewRequest(http.MethodGet, "/valid-email/"+confirmationCode, nil)
+	req, _ := http.NewRequest(http.MethodGet, "/valid-email/"+confirmationCode+"?email=test@example.com", nil)
 	resp := httptest.NewRecorder()
 
 	r.ServeHTTP(resp, req)
@@ -747,3 +938,315 @@ func TestCreateUser_UserNameAlreadyExists(t *testing.T) {
 	json.Unmarshal(resp.Body.Bytes(), &respBody)
 	assert.Contains(t, respBody["error"], "This username is already taken")
 }
+
+var refreshTokenColumns = []string{"id", "session_id", "user_id", "token_hash", "expires_at", "used_at"}
+
+// Test la rotation d'un refresh token valide
+func TestRefreshToken_Success(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	rawToken := "valid-refresh-token"
+	mock.ExpectQuery(`SELECT \* FROM "refresh_tokens" WHERE token_hash = \$1 ORDER BY "refresh_tokens"."id" LIMIT \$2`).
+		WithArgs(utils.HashToken(rawToken), 1).
+		WillReturnRows(mock.NewRows(refreshTokenColumns).
+			AddRow("refresh-uuid", "session-uuid", "user-uuid", utils.HashToken(rawToken), time.Now().Add(time.Hour), nil))
+	mock.ExpectQuery(`SELECT \* FROM "user_sessions" WHERE id = \$1 ORDER BY "user_sessions"."id" LIMIT \$2`).
+		WithArgs("session-uuid", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_id", "revoked_at"}).AddRow("session-uuid", "user-uuid", nil))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs("user-uuid", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "email", "role"}).AddRow("user-uuid", "user@example.com", "USER"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "refresh_tokens" SET "used_at"=\$1 WHERE id = \$2 AND used_at IS NULL`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "refresh_tokens"`).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("refresh-uuid-2"))
+	mock.ExpectCommit()
+
+	r := testutils.SetupTestRouter()
+	r.POST("/token/refresh", RefreshToken)
+
+	jsonData, _ := json.Marshal(map[string]string{"refreshToken": rawToken})
+	req, _ := http.NewRequest(http.MethodPost, "/token/refresh", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var respBody map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.NotEmpty(t, respBody["token"])
+	assert.NotEmpty(t, respBody["refreshToken"])
+	assert.NotEqual(t, rawToken, respBody["refreshToken"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test que la réutilisation d'un refresh token déjà utilisé révoque toute la session
+func TestRefreshToken_ReuseRevokesSession(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	rawToken := "rotated-refresh-token"
+	mock.ExpectQuery(`SELECT \* FROM "refresh_tokens" WHERE token_hash = \$1`).
+		WithArgs(utils.HashToken(rawToken), 1).
+		WillReturnRows(mock.NewRows(refreshTokenColumns).
+			AddRow("refresh-uuid", "session-uuid", "user-uuid", utils.HashToken(rawToken), time.Now().Add(time.Hour), time.Now().Add(-time.Minute)))
+	mock.ExpectQuery(`SELECT \* FROM "user_sessions" WHERE id = \$1`).
+		WithArgs("session-uuid", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_id", "revoked_at"}).AddRow("session-uuid", "user-uuid", nil))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "user_sessions" SET "revoked_at"=\$1,"updated_at"=\$2 WHERE id = \$3 AND revoked_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "session-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	r := testutils.SetupTestRouter()
+	r.POST("/token/refresh", RefreshToken)
+
+	jsonData, _ := json.Marshal(map[string]string{"refreshToken": rawToken})
+	req, _ := http.NewRequest(http.MethodPost, "/token/refresh", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test le refus d'un refresh token inconnu
+func TestRefreshToken_Unknown(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT \* FROM "refresh_tokens" WHERE token_hash = \$1`).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	r := testutils.SetupTestRouter()
+	r.POST("/token/refresh", RefreshToken)
+
+	jsonData, _ := json.Marshal(map[string]string{"refreshToken": "unknown"})
+	req, _ := http.NewRequest(http.MethodPost, "/token/refresh", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+// Test la déconnexion de la session courante
+func TestLogout_Success(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "user_sessions" SET "revoked_at"=\$1,"updated_at"=\$2 WHERE id = \$3 AND user_id = \$4 AND revoked_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "session-uuid", "user-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	r := testutils.SetupTestRouter()
+	r.POST("/logout", func(c *gin.Context) {
+		c.Set("user_id", "user-uuid")
+		c.Set("session_id", "session-uuid")
+		Logout(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "/logout", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// Test que le login renvoie un challenge lorsque la 2FA est activée
+func TestLogin_TwoFactorChallenge(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	expectThrottleCheck(mock)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs("user@example.com", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "email", "password", "email_verified_at", "two_factor_enabled"}).
+			AddRow("user-uuid", "user@example.com", "$2a$10$8b9qfHvbQVnP1IgEyd/AX.X5PCNGO/ZVE13NZS8xg3wDo6f4rWpiW", sql.NullTime{Time: now, Valid: true}, true))
+
+	r := testutils.SetupTestRouter()
+	r.POST("/login", Login)
+
+	jsonData, _ := json.Marshal(map[string]string{
+		"email":    "user@example.com",
+		"password": "Test123!",
+	})
+	req, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var respBody map[string]interface{}
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, true, respBody["twoFactorRequired"])
+	assert.NotEmpty(t, respBody["challengeToken"])
+	assert.Nil(t, respBody["token"])
+
+	claims, err := utils.DecodeJWT(respBody["challengeToken"].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, utils.TwoFactorChallengeType, claims["type"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test la finalisation du login avec un code TOTP valide
+func TestVerifyTwoFactor_Success(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	challenge, _ := utils.GenerateTwoFactorChallenge(models.User{ID: "user-uuid"})
+	code, _ := utils.TOTPCodeAt(testTOTPSecret, utils.TOTPStep(time.Now()))
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs("user-uuid", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "email", "two_factor_enabled", "two_factor_secret", "two_factor_last_step"}).
+			AddRow("user-uuid", "user@example.com", true, testTOTPSecret, 0))
+	expectThrottleCheck(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "two_factor_last_step"=\$1,"updated_at"=\$2 WHERE id = \$3 AND two_factor_last_step < \$4`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "user_sessions"`).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("session-uuid"))
+	mock.ExpectQuery(`INSERT INTO "refresh_tokens"`).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("refresh-uuid"))
+	mock.ExpectCommit()
+	expectThrottleReset(mock)
+
+	r := testutils.SetupTestRouter()
+	r.POST("/2fa/verify", VerifyTwoFactor)
+
+	jsonData, _ := json.Marshal(map[string]string{"challengeToken": challenge, "code": code})
+	req, _ := http.NewRequest(http.MethodPost, "/2fa/verify", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var respBody map[string]interface{}
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.NotEmpty(t, respBody["token"])
+	assert.NotEmpty(t, respBody["refreshToken"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un access token ne peut pas être utilisé comme challenge 2FA
+func TestVerifyTwoFactor_WrongTokenType(t *testing.T) {
+	_, _, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	accessToken, _ := utils.GenerateJWT(models.User{ID: "user-uuid"}, "session-uuid", utils.AccessTokenTTL)
+
+	r := testutils.SetupTestRouter()
+	r.POST("/2fa/verify", VerifyTwoFactor)
+
+	jsonData, _ := json.Marshal(map[string]string{"challengeToken": accessToken, "code": "123456"})
+	req, _ := http.NewRequest(http.MethodPost, "/2fa/verify", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+// Test le refus d'un code invalide lors de la confirmation de l'enrôlement
+func TestConfirmTwoFactor_InvalidCode(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs("user-uuid", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "email", "two_factor_enabled", "two_factor_secret"}).
+			AddRow("user-uuid", "user@example.com", false, testTOTPSecret))
+
+	r := testutils.SetupTestRouter()
+	r.POST("/2fa/confirm", func(c *gin.Context) {
+		c.Set("user_id", "user-uuid")
+		ConfirmTwoFactor(c)
+	})
+
+	jsonData, _ := json.Marshal(map[string]string{"code": "abcdef"})
+	req, _ := http.NewRequest(http.MethodPost, "/2fa/confirm", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectThrottleCheck simule la vérification du verrouillage (aucun verrou actif)
+func expectThrottleCheck(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT \* FROM "auth_throttles" WHERE throttle_key IN \(\$1,\$2\) AND locked_until > \$3`).
+		WillReturnRows(mock.NewRows([]string{"throttle_key", "failures", "locked_until"}))
+}
+
+// expectLoginFailure simule l'enregistrement d'un échec pour une clé (compte ou IP)
+func expectLoginFailure(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`INSERT INTO auth_throttles`).
+		WillReturnRows(mock.NewRows([]string{"failures"}).AddRow(1))
+}
+
+func expectThrottleReset(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "auth_throttles" WHERE throttle_key = \$1`).
+		WithArgs("account:user@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+}
+
+// Test le refus de connexion (429) lorsque le compte est verrouillé
+func TestLogin_LockedOut(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	lockedUntil := time.Now().Add(2 * time.Minute)
+	mock.ExpectQuery(`SELECT \* FROM "auth_throttles" WHERE throttle_key IN \(\$1,\$2\) AND locked_until > \$3`).
+		WithArgs("account:user@example.com", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"throttle_key", "failures", "locked_until"}).
+			AddRow("account:user@example.com", 6, lockedUntil))
+
+	r := testutils.SetupTestRouter()
+	r.POST("/login", Login)
+
+	jsonData, _ := json.Marshal(map[string]string{
+		"email":    "User@Example.com",
+		"password": "Test123!",
+	})
+	req, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+
+	var respBody map[string]interface{}
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "Too many failed attempts, try again later", respBody["error"])
+	assert.InDelta(t, 120, respBody["retryAfter"], 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
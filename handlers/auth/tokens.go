@@ -0,0 +1,240 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/sessions"
+	"pec2-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errRefreshTokenReused = errors.New("refresh token reused")
+
+// issueSession ouvre une nouvelle session pour l'utilisateur et renvoie
+// un access token de courte durée ainsi que le premier refresh token de la famille
+func issueSession(c *gin.Context, user models.User) (string, string, error) {
+	session := models.UserSession{
+		UserID:    user.ID,
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+
+	var refreshToken string
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		var err error
+		refreshToken, err = createRefreshToken(tx, session.ID, user.ID)
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	accessToken, err := utils.GenerateJWT(user, session.ID, utils.AccessTokenTTL)
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+func createRefreshToken(tx *gorm.DB, sessionID string, userID string) (string, error) {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	refresh := models.RefreshToken{
+		SessionID: sessionID,
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL),
+	}
+	if err := tx.Create(&refresh).Error; err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token and a new refresh token (rotation). Reusing an already rotated refresh token revokes the whole session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param refresh body models.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} map[string]interface{} "token: JWT token, refreshToken: new refresh token"
+// @Failure 400 {object} map[string]interface{} "error: Invalid input"
+// @Failure 401 {object} map[string]interface{} "error: Invalid, expired or revoked refresh token"
+// @Failure 500 {object} map[string]interface{} "error: Error message"
+// @Router /token/refresh [post]
+func RefreshToken(c *gin.Context) {
+	var input models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.LogError(err, "Error when binding JSON in RefreshToken")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	var stored models.RefreshToken
+	if err := db.DB.Where("token_hash = ?", utils.HashToken(input.RefreshToken)).First(&stored).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.LogError(err, "Refresh token not found in RefreshToken")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+		utils.LogError(err, "Database error in RefreshToken")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + err.Error()})
+		return
+	}
+
+	var session models.UserSession
+	if err := db.DB.Where("id = ?", stored.SessionID).First(&session).Error; err != nil {
+		utils.LogError(err, "Session not found in RefreshToken")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	if session.RevokedAt != nil {
+		utils.LogError(errors.New("session révoquée"), "Revoked session in RefreshToken")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session revoked"})
+		return
+	}
+
+	if stored.UsedAt != nil {
+		handleRefreshTokenReuse(c, stored)
+		return
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		utils.LogError(errors.New("refresh token expiré"), "Expired refresh token in RefreshToken")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token expired"})
+		return
+	}
+
+	var user models.User
+	if err := db.DB.Where("id = ?", stored.UserID).First(&user).Error; err != nil {
+		utils.LogError(err, "User not found in RefreshToken")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	var newRefreshToken string
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// La condition used_at IS NULL protège contre deux rotations concurrentes du même token
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", stored.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshTokenReused
+		}
+
+		var err error
+		newRefreshToken, err = createRefreshToken(tx, stored.SessionID, stored.UserID)
+		return err
+	})
+	if errors.Is(err, errRefreshTokenReused) {
+		handleRefreshTokenReuse(c, stored)
+		return
+	}
+	if err != nil {
+		utils.LogError(err, "Error when rotating refresh token in RefreshToken")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error when rotating refresh token"})
+		return
+	}
+
+	accessToken, err := utils.GenerateJWT(user, stored.SessionID, utils.AccessTokenTTL)
+	if err != nil {
+		utils.LogError(err, "Error when generating JWT in RefreshToken")
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Error when generating JWT"})
+		return
+	}
+
+	utils.LogSuccessWithUser(user.ID, "Access token refreshed in RefreshToken")
+	c.JSON(http.StatusOK, gin.H{
+		"token":        accessToken,
+		"refreshToken": newRefreshToken,
+	})
+}
+
+// handleRefreshTokenReuse révoque toute la famille lorsqu'un token déjà utilisé est présenté à nouveau
+func handleRefreshTokenReuse(c *gin.Context, stored models.RefreshToken) {
+	utils.LogError(errRefreshTokenReused, "Refresh token reuse detected, revoking session "+stored.SessionID)
+	if err := sessions.Revoke(db.DB, stored.SessionID); err != nil {
+		utils.LogError(err, "Error when revoking session in RefreshToken")
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token already used, session revoked"})
+}
+
+// @Summary Logout
+// @Description Revoke the current session: its access and refresh tokens stop working
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "message: Logged out successfully"
+// @Failure 401 {object} map[string]interface{} "error: Unauthorized"
+// @Failure 500 {object} map[string]interface{} "error: Error message"
+// @Router /logout [post]
+func Logout(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		utils.LogError(errors.New("session_id manquant"), "Session not found in Logout")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found in token"})
+		return
+	}
+
+	err := db.DB.Model(&models.UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		utils.LogError(err, "Error when revoking session in Logout")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error when revoking session"})
+		return
+	}
+
+	utils.LogSuccessWithUser(userID, "User logged out in Logout")
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// @Summary Logout from all devices
+// @Description Revoke every active session of the current user
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "message: Logged out from all sessions, revoked: number of sessions"
+// @Failure 401 {object} map[string]interface{} "error: Unauthorized"
+// @Failure 500 {object} map[string]interface{} "error: Error message"
+// @Router /logout-all [post]
+func LogoutAll(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(errors.New("user_id manquant"), "User not found in LogoutAll")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	revoked, err := sessions.RevokeAll(db.DB, userID.(string))
+	if err != nil {
+		utils.LogError(err, "Error when revoking sessions in LogoutAll")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error when revoking sessions"})
+		return
+	}
+
+	utils.LogSuccessWithUser(userID, "User logged out from all sessions in LogoutAll")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logged out from all sessions",
+		"revoked": revoked,
+	})
+}
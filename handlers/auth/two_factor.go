@@ -0,0 +1,362 @@
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"net/http"
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/throttle"
+	"pec2-backend/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const recoveryCodesCount = 10
+
+// alphabet sans caractères ambigus (0/O, 1/l/I)
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+var errInvalidTwoFactorCode = errors.New("invalid two-factor code")
+
+// TwoFactorCodeRequest modèle pour confirmer ou désactiver la 2FA
+// @Description code TOTP à 6 chiffres ou code de secours
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required" example:"123456"`
+}
+
+// TwoFactorVerifyRequest modèle pour finaliser un login avec 2FA
+// @Description challenge token renvoyé par /login et code TOTP ou code de secours
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required" example:"123456"`
+}
+
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = recoveryCodeAlphabet[n.Int64()]
+	}
+	return string(b[:5]) + "-" + string(b[5:]), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+}
+
+// replaceRecoveryCodes supprime les anciens codes de secours et en génère de nouveaux
+func replaceRecoveryCodes(tx *gorm.DB, userID string) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodesCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(code),
+		})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// checkTwoFactorCode accepte un code TOTP (non rejoué) ou un code de secours non utilisé
+func checkTwoFactorCode(user models.User, code string) error {
+	if step, ok := utils.ValidateTOTP(user.TwoFactorSecret, code, time.Now()); ok {
+		// La condition sur le dernier pas utilisé empêche de rejouer un code déjà accepté
+		result := db.DB.Model(&models.User{}).
+			Where("id = ? AND two_factor_last_step < ?", user.ID, step).
+			Update("two_factor_last_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	result := db.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, utils.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// @Summary Start 2FA enrolment
+// @Description Generate a new TOTP secret for the current user. 2FA is only enabled after /2fa/confirm.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "secret: base32 secret, otpauthUri: URI for authenticator apps"
+// @Failure 401 {object} map[string]interface{} "error: Unauthorized"
+// @Failure 409 {object} map[string]interface{} "error: Two-factor authentication already enabled"
+// @Failure 500 {object} map[string]interface{} "error: Error message"
+// @Router /2fa/enroll [post]
+func EnrollTwoFactor(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var user models.User
+	if err := db.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		utils.LogError(err, "User not found in EnrollTwoFactor")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.TwoFactorEnabled {
+		utils.LogError(errors.New("2FA déjà activée"), "Two-factor already enabled in EnrollTwoFactor")
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication already enabled"})
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		utils.LogError(err, "Error when generating secret in EnrollTwoFactor")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error when generating secret"})
+		return
+	}
+
+	if err := db.DB.Model(&user).Update("two_factor_secret", secret).Error; err != nil {
+		utils.LogError(err, "Error when saving secret in EnrollTwoFactor")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error when saving secret"})
+		return
+	}
+
+	utils.LogSuccessWithUser(user.ID, "Two-factor enrolment started in EnrollTwoFactor")
+	c.JSON(http.StatusOK, gin.H{
+		"secret":     secret,
+		"otpauthUri": utils.TOTPURI(user.Email, secret),
+	})
+}
+
+// @Summary Confirm 2FA enrolment
+// @Description Enable 2FA after checking a first TOTP code, and return one-time recovery codes (shown only once)
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code body TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} map[string]interface{} "message: Two-factor authentication enabled, recoveryCodes: list of codes"
+// @Failure 400 {object} map[string]interface{} "error: Invalid code or enrolment not started"
+// @Failure 401 {object} map[string]interface{} "error: Unauthorized"
+// @Failure 409 {object} map[string]interface{} "error: Two-factor authentication already enabled"
+// @Failure 500 {object} map[string]interface{} "error: Error message"
+// @Router /2fa/confirm [post]
+func ConfirmTwoFactor(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var input TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.LogError(err, "Error when binding JSON in ConfirmTwoFactor")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := db.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		utils.LogError(err, "User not found in ConfirmTwoFactor")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.TwoFactorEnabled {
+		utils.LogError(errors.New("2FA déjà activée"), "Two-factor already enabled in ConfirmTwoFactor")
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication already enabled"})
+		return
+	}
+
+	if user.TwoFactorSecret == "" {
+		utils.LogError(errors.New("enrôlement non démarré"), "Enrolment not started in ConfirmTwoFactor")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor enrolment not started"})
+		return
+	}
+
+	step, ok := utils.ValidateTOTP(user.TwoFactorSecret, input.Code, time.Now())
+	if !ok {
+		utils.LogError(errInvalidTwoFactorCode, "Invalid code in ConfirmTwoFactor")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+
+	var recoveryCodes []string
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"two_factor_enabled":   true,
+			"two_factor_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		recoveryCodes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		utils.LogError(err, "Error when enabling two-factor in ConfirmTwoFactor")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error when enabling two-factor authentication"})
+		return
+	}
+
+	utils.LogSuccessWithUser(user.ID, "Two-factor enabled in ConfirmTwoFactor")
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Two-factor authentication enabled",
+		"recoveryCodes": recoveryCodes,
+	})
+}
+
+// @Summary Disable 2FA
+// @Description Disable 2FA with a valid TOTP code or recovery code
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code body TwoFactorCodeRequest true "TOTP code or recovery code"
+// @Success 200 {object} map[string]interface{} "message: Two-factor authentication disabled"
+// @Failure 400 {object} map[string]interface{} "error: Invalid code or 2FA not enabled"
+// @Failure 401 {object} map[string]interface{} "error: Unauthorized"
+// @Failure 500 {object} map[string]interface{} "error: Error message"
+// @Router /2fa/disable [post]
+func DisableTwoFactor(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var input TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.LogError(err, "Error when binding JSON in DisableTwoFactor")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := db.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		utils.LogError(err, "User not found in DisableTwoFactor")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !user.TwoFactorEnabled {
+		utils.LogError(errors.New("2FA non activée"), "Two-factor not enabled in DisableTwoFactor")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	if err := checkTwoFactorCode(user, input.Code); err != nil {
+		utils.LogError(err, "Invalid code in DisableTwoFactor")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"two_factor_enabled":   false,
+			"two_factor_secret":    "",
+			"two_factor_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		utils.LogError(err, "Error when disabling two-factor in DisableTwoFactor")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error when disabling two-factor authentication"})
+		return
+	}
+
+	utils.LogSuccessWithUser(user.ID, "Two-factor disabled in DisableTwoFactor")
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// @Summary Verify 2FA login
+// @Description Exchange the challenge token returned by /login and a TOTP code (or recovery code) for the real tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param verify body TwoFactorVerifyRequest true "Challenge token and code"
+// @Success 200 {object} map[string]interface{} "token: JWT token, refreshToken: refresh token"
+// @Failure 400 {object} map[string]interface{} "error: Invalid input"
+// @Failure 401 {object} map[string]interface{} "error: Invalid challenge or code"
+// @Failure 429 {object} map[string]interface{} "error: Too many failed attempts, retryAfter: seconds before retry"
+// @Failure 500 {object} map[string]interface{} "error: Error message"
+// @Router /2fa/verify [post]
+func VerifyTwoFactor(c *gin.Context) {
+	var input TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.LogError(err, "Error when binding JSON in VerifyTwoFactor")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	claims, err := utils.DecodeJWT(input.ChallengeToken)
+	if err != nil {
+		utils.LogError(err, "Invalid challenge token in VerifyTwoFactor")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge token"})
+		return
+	}
+	if tokenType, _ := claims["type"].(string); tokenType != utils.TwoFactorChallengeType {
+		utils.LogError(errors.New("mauvais type de token"), "Wrong token type in VerifyTwoFactor")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge token"})
+		return
+	}
+
+	var user models.User
+	if err := db.DB.Where("id = ?", claims["user_id"]).First(&user).Error; err != nil {
+		utils.LogError(err, "User not found in VerifyTwoFactor")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !user.TwoFactorEnabled {
+		utils.LogError(errors.New("2FA non activée"), "Two-factor not enabled in VerifyTwoFactor")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	if throttle.AbortIfThrottled(c, user.Email, "VerifyTwoFactor") {
+		return
+	}
+
+	if err := checkTwoFactorCode(user, input.Code); err != nil {
+		throttle.RegisterFailure(c, user.Email)
+		utils.LogError(err, "Invalid code in VerifyTwoFactor")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+
+	token, refreshToken, err := issueSession(c, user)
+	if err != nil {
+		utils.LogError(err, "Error when generating JWT in VerifyTwoFactor")
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Error when generating JWT"})
+		return
+	}
+	throttle.ResetAccount(user.Email)
+
+	utils.LogSuccessWithUser(user.ID, "User login with two-factor successfully in VerifyTwoFactor")
+	c.JSON(http.StatusOK, gin.H{
+		"token":        token,
+		"refreshToken": refreshToken,
+		"user":         user,
+	})
+}
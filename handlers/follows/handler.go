@@ -0,0 +1,122 @@
+package follows
+
+import (
+	"net/http"
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Toggle follow on a content creator
+// @Description Follow or unfollow a content creator. Free posts of followed creators appear in the personalized feed
+// @Tags users
+// @Produce json
+// @Param id path string true "Content creator user ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "message: Follow added/removed successfully, action: added/removed"
+// @Failure 400 {object} map[string]string "error: You cannot follow yourself"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 403 {object} map[string]string "error: Can only follow a content creator"
+// @Failure 404 {object} map[string]string "error: User not found"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /users/{id}/follow [post]
+func ToggleFollow(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(nil, "User not found in token in ToggleFollow")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	creatorID := c.Param("id")
+	if creatorID == userID.(string) {
+		utils.LogError(nil, "User tried to follow himself in ToggleFollow")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot follow yourself"})
+		return
+	}
+
+	var creator models.User
+	if err := db.DB.First(&creator, "id = ?", creatorID).Error; err != nil {
+		utils.LogError(err, "User not found in ToggleFollow")
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if creator.Role != models.ContentCreator {
+		utils.LogError(nil, "Can only follow a content creator in ToggleFollow")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Can only follow a content creator"})
+		return
+	}
+
+	var follow models.Follow
+	result := db.DB.Where("follower_id = ? AND creator_id = ?", userID, creatorID).First(&follow)
+
+	if result.Error == nil {
+		// Le follow existe déjà, on le supprime
+		if err := db.DB.Delete(&follow).Error; err != nil {
+			utils.LogError(err, "Error removing follow in ToggleFollow")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error removing follow: " + err.Error()})
+			return
+		}
+
+		utils.LogSuccessWithUser(userID, "Follow removed successfully in ToggleFollow")
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Follow removed successfully",
+			"action":  "removed",
+		})
+		return
+	}
+
+	follow = models.Follow{
+		FollowerID: userID.(string),
+		CreatorID:  creatorID,
+	}
+
+	if err := db.DB.Create(&follow).Error; err != nil {
+		utils.LogError(err, "Error adding follow in ToggleFollow")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error adding follow: " + err.Error()})
+		return
+	}
+
+	utils.LogSuccessWithUser(userID, "Follow added successfully in ToggleFollow")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Follow added successfully",
+		"action":  "added",
+	})
+}
+
+// @Summary Get followed content creators
+// @Description Get the content creators followed by the authenticated user
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.UserInfo
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /users/following [get]
+func GetFollowing(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(nil, "User not found in token in GetFollowing")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	following := make([]models.UserInfo, 0)
+	err := db.DB.Table("follows").
+		Select("users.id, users.user_name, users.profile_picture").
+		Joins("JOIN users ON users.id = follows.creator_id").
+		Where("follows.follower_id = ?", userID).
+		Order("follows.created_at DESC").
+		Scan(&following).Error
+	if err != nil {
+		utils.LogError(err, "Error retrieving followed creators in GetFollowing")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving followed creators: " + err.Error()})
+		return
+	}
+
+	utils.LogSuccessWithUser(userID, "Followed creators retrieved successfully in GetFollowing")
+	c.JSON(http.StatusOK, following)
+}
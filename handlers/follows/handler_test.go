@@ -0,0 +1,105 @@
+package follows
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"pec2-backend/testutils"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	testutils.InitTestMain()
+
+	log.SetOutput(io.Discard)
+
+	exitCode := m.Run()
+
+	log.SetOutput(os.Stdout)
+
+	os.Exit(exitCode)
+}
+
+// Test le suivi d'un créateur de contenu
+func TestToggleFollow_Add(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	userID := "user-uuid"
+	creatorID := "creator-uuid"
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs(creatorID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(creatorID, "creator", "CONTENT_CREATOR"))
+	mock.ExpectQuery(`SELECT \* FROM "follows" WHERE follower_id = \$1 AND creator_id = \$2 ORDER BY "follows"."id" LIMIT \$3`).
+		WithArgs(userID, creatorID, 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "follows" \("follower_id","creator_id","created_at"\) VALUES \(\$1,\$2,\$3\) RETURNING "id"`).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("follow-uuid"))
+	mock.ExpectCommit()
+
+	r := testutils.SetupTestRouter()
+	r.POST("/users/:id/follow", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		ToggleFollow(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "/users/"+creatorID+"/follow", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &response)
+	assert.Equal(t, "added", response["action"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test le suivi d'un utilisateur qui n'est pas créateur de contenu
+func TestToggleFollow_NotContentCreator(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs("other-uuid", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow("other-uuid", "other", "USER"))
+
+	r := testutils.SetupTestRouter()
+	r.POST("/users/:id/follow", func(c *gin.Context) {
+		c.Set("user_id", "user-uuid")
+		ToggleFollow(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "/users/other-uuid/follow", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+// Test qu'un utilisateur ne peut pas se suivre lui-même
+func TestToggleFollow_Self(t *testing.T) {
+	r := testutils.SetupTestRouter()
+	r.POST("/users/:id/follow", func(c *gin.Context) {
+		c.Set("user_id", "user-uuid")
+		ToggleFollow(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "/users/user-uuid/follow", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -0,0 +1,133 @@
+package invoices
+
+import (
+	"errors"
+	"net/http"
+
+	"pec2-backend/db"
+	"pec2-backend/invoices"
+	"pec2-backend/models"
+	"pec2-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Summary Get my invoices
+// @Description Returns the invoices of the subscription payments of the connected user, newest first
+// @Tags invoices
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Invoice
+// @Failure 401 {object} map[string]string "error: User not authenticated"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /invoices [get]
+func GetMyInvoices(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(errors.New("user_id manquant"), "User not authenticated in GetMyInvoices")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var list []models.Invoice
+	if err := db.DB.Where("user_id = ?", userID).Order("issued_at DESC").Find(&list).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "Error fetching invoices in GetMyInvoices")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching invoices"})
+		return
+	}
+
+	utils.LogSuccessWithUser(userID, "Invoices fetched successfully in GetMyInvoices")
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary Get received invoices
+// @Description Returns the invoices issued on behalf of the connected content creator for the subscription payments they received, newest first
+// @Tags invoices
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Invoice
+// @Failure 401 {object} map[string]string "error: User not authenticated"
+// @Failure 403 {object} map[string]string "error: Only content creators receive payments"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /invoices/received [get]
+func GetReceivedInvoices(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(errors.New("user_id manquant"), "User not authenticated in GetReceivedInvoices")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var user models.User
+	if err := db.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "User not found in GetReceivedInvoices")
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.Role != models.ContentCreator {
+		utils.LogErrorWithUser(userID, errors.New("pas créateur"), "Only content creators receive payments in GetReceivedInvoices")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only content creators receive payments"})
+		return
+	}
+
+	var list []models.Invoice
+	if err := db.DB.Where("content_creator_id = ?", user.ID).Order("sequence DESC").Find(&list).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "Error fetching invoices in GetReceivedInvoices")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching invoices"})
+		return
+	}
+
+	utils.LogSuccessWithUser(userID, "Received invoices fetched successfully in GetReceivedInvoices")
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary Download an invoice
+// @Description Download an invoice as a PDF. Only the buyer and the content creator of the invoice can download it
+// @Tags invoices
+// @Produce application/pdf
+// @Param id path string true "Invoice ID"
+// @Security BearerAuth
+// @Success 200 {file} file "PDF invoice"
+// @Failure 401 {object} map[string]string "error: User not authenticated"
+// @Failure 403 {object} map[string]string "error: You don't have access to this invoice"
+// @Failure 404 {object} map[string]string "error: Invoice not found"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /invoices/{id}/pdf [get]
+func DownloadInvoicePdf(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(errors.New("user_id manquant"), "User not authenticated in DownloadInvoicePdf")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var invoice models.Invoice
+	if err := db.DB.First(&invoice, "id = ?", c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.LogErrorWithUser(userID, err, "Invoice not found in DownloadInvoicePdf")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+			return
+		}
+		utils.LogErrorWithUser(userID, err, "Error fetching invoice in DownloadInvoicePdf")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching invoice"})
+		return
+	}
+
+	if invoice.UserID != userID && invoice.ContentCreatorID != userID {
+		utils.LogErrorWithUser(userID, errors.New("accès refusé"), "Access to invoice denied in DownloadInvoicePdf")
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this invoice"})
+		return
+	}
+
+	file, err := invoices.RenderPDF(invoice)
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Error rendering invoice in DownloadInvoicePdf")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating invoice"})
+		return
+	}
+
+	utils.LogSuccessWithUser(userID, "Invoice "+invoice.Number+" downloaded in DownloadInvoicePdf")
+	c.Header("Content-Disposition", "attachment; filename="+invoice.Number+".pdf")
+	c.Data(http.StatusOK, "application/pdf", file)
+}
@@ -0,0 +1,121 @@
+package invoices
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"pec2-backend/models"
+	"pec2-backend/testutils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	testutils.InitTestMain()
+
+	log.SetOutput(io.Discard)
+
+	exitCode := m.Run()
+
+	log.SetOutput(os.Stdout)
+
+	os.Exit(exitCode)
+}
+
+var invoiceColumns = []string{"id", "sequence", "number", "subscription_payment_id", "subscription_id", "user_id", "content_creator_id",
+	"seller_name", "buyer_name", "description", "amount_excluding_tax", "vat_rate", "vat_amount", "amount_including_tax", "currency", "issued_at"}
+
+func expectInvoice(mock sqlmock.Sqlmock, invoiceID string, userID string, creatorID string) {
+	mock.ExpectQuery(`SELECT \* FROM "invoices" WHERE id = \$1 ORDER BY "invoices"."id" LIMIT \$2`).
+		WithArgs(invoiceID, 1).
+		WillReturnRows(mock.NewRows(invoiceColumns).
+			AddRow(invoiceID, 7, "F2026-000007", "payment-uuid", "sub-uuid", userID, creatorID,
+				"Studio SAS", "Jean Dupont", "Abonnement - 1 mois", 833, 2000, 166, 999, "eur", time.Now()))
+}
+
+// Test que l'utilisateur liste ses factures
+func TestGetMyInvoices_Success(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	userID := "user-uuid"
+	mock.ExpectQuery(`SELECT \* FROM "invoices" WHERE user_id = \$1 ORDER BY issued_at DESC`).
+		WithArgs(userID).
+		WillReturnRows(mock.NewRows(invoiceColumns).
+			AddRow("invoice-uuid", 7, "F2026-000007", "payment-uuid", "sub-uuid", userID, "creator-uuid",
+				"Studio SAS", "Jean Dupont", "Abonnement - 1 mois", 833, 2000, 166, 999, "eur", time.Now()))
+
+	r := testutils.SetupTestRouter()
+	r.GET("/invoices", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		GetMyInvoices(c)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/invoices", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response []models.Invoice
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response, 1)
+	assert.Equal(t, "F2026-000007", response[0].Number)
+	assert.Equal(t, 166, response[0].VatAmount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test que le créateur télécharge en PDF une facture émise en son nom
+func TestDownloadInvoicePdf_Creator(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	creatorID := "creator-uuid"
+	expectInvoice(mock, "invoice-uuid", "user-uuid", creatorID)
+
+	r := testutils.SetupTestRouter()
+	r.GET("/invoices/:id/pdf", func(c *gin.Context) {
+		c.Set("user_id", creatorID)
+		DownloadInvoicePdf(c)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/invoices/invoice-uuid/pdf", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "F2026-000007.pdf")
+	assert.True(t, strings.HasPrefix(w.Body.String(), "%PDF-"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un utilisateur étranger à la facture ne peut pas la télécharger
+func TestDownloadInvoicePdf_Forbidden(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	expectInvoice(mock, "invoice-uuid", "user-uuid", "creator-uuid")
+
+	r := testutils.SetupTestRouter()
+	r.GET("/invoices/:id/pdf", func(c *gin.Context) {
+		c.Set("user_id", "other-uuid")
+		DownloadInvoicePdf(c)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/invoices/invoice-uuid/pdf", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
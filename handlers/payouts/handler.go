@@ -0,0 +1,269 @@
+package payouts
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pec2-backend/db"
+	"pec2-backend/ledger"
+	"pec2-backend/models"
+	"pec2-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Verrou applicatif (pg_advisory_xact_lock) : deux lots simultanés ne doivent pas verser deux fois le même solde
+const payoutLockKey = 4242001
+
+// errNothingToPay aucun créateur n'a de solde versable
+var errNothingToPay = errors.New("nothing to pay")
+
+// @Summary Get my earnings
+// @Description Returns the balance owed to the connected content creator and the history of the ledger transactions (payments, refunds and payouts), newest first. Amounts are in cents, commission already deducted
+// @Tags payouts
+// @Produce json
+// @Param limit query int false "Number of transactions (1-200)" default(50)
+// @Param offset query int false "Number of transactions to skip" default(0)
+// @Security BearerAuth
+// @Success 200 {object} models.CreatorEarnings
+// @Failure 400 {object} map[string]string "error: Invalid limit or offset"
+// @Failure 401 {object} map[string]string "error: User not authenticated"
+// @Failure 403 {object} map[string]string "error: Only content creators have earnings"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /content-creators/me/earnings [get]
+func GetMyEarnings(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(errors.New("user_id manquant"), "User not authenticated in GetMyEarnings")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		utils.LogErrorWithUser(userID, err, "Invalid limit in GetMyEarnings")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		utils.LogErrorWithUser(userID, err, "Invalid offset in GetMyEarnings")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+		return
+	}
+
+	var user models.User
+	if err := db.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "User not found in GetMyEarnings")
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.Role != models.ContentCreator {
+		utils.LogErrorWithUser(userID, errors.New("pas créateur"), "Only content creators have earnings in GetMyEarnings")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only content creators have earnings"})
+		return
+	}
+
+	balance, err := ledger.CreatorBalance(db.DB, user.ID)
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Error computing balance in GetMyEarnings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing balance"})
+		return
+	}
+
+	earnings := models.CreatorEarnings{
+		Balance:           balance,
+		Currency:          "eur",
+		CommissionPercent: ledger.CommissionPercent(),
+		Transactions:      []models.LedgerTransaction{},
+	}
+
+	if err := db.DB.Model(&models.LedgerTransaction{}).
+		Where("content_creator_id = ?", user.ID).
+		Count(&earnings.Total).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "Error counting transactions in GetMyEarnings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching transactions"})
+		return
+	}
+	if err := db.DB.Where("content_creator_id = ?", user.ID).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&earnings.Transactions).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "Error fetching transactions in GetMyEarnings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching transactions"})
+		return
+	}
+
+	utils.LogSuccessWithUser(userID, "Earnings fetched successfully in GetMyEarnings")
+	c.JSON(http.StatusOK, earnings)
+}
+
+// @Summary Create a payout batch
+// @Description Pays out the whole positive balance of every approved content creator: one payout per creator, debited from their balance in the ledger. Creators without approved bank details are skipped and keep their balance (admin only)
+// @Tags payouts
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} models.PayoutBatch
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 403 {object} map[string]string "error: Access denied"
+// @Failure 409 {object} map[string]string "error: No balance to pay out"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /payouts/batches [post]
+func CreatePayoutBatch(c *gin.Context) {
+	adminID := c.GetString("user_id")
+
+	var batch models.PayoutBatch
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", payoutLockKey).Error; err != nil {
+			return err
+		}
+
+		balances, err := ledger.PositiveBalances(tx)
+		if err != nil {
+			return err
+		}
+		if len(balances) == 0 {
+			return errNothingToPay
+		}
+
+		creatorIDs := make([]string, 0, len(balances))
+		for _, balance := range balances {
+			creatorIDs = append(creatorIDs, balance.ContentCreatorID)
+		}
+		var infos []models.ContentCreatorInfo
+		if err := tx.Where("user_id IN ? AND status = ?", creatorIDs, models.ContentCreatorStatusApproved).
+			Find(&infos).Error; err != nil {
+			return err
+		}
+		infoByCreator := make(map[string]models.ContentCreatorInfo, len(infos))
+		for _, info := range infos {
+			infoByCreator[info.UserID] = info
+		}
+
+		now := time.Now()
+		batch = models.PayoutBatch{
+			Reference:   "PAYOUT-" + now.UTC().Format("20060102150405"),
+			Currency:    "eur",
+			CreatedByID: adminID,
+		}
+		for _, balance := range balances {
+			info, ok := infoByCreator[balance.ContentCreatorID]
+			if !ok || info.Iban == "" || info.Bic == "" {
+				utils.LogInfo("Creator " + balance.ContentCreatorID + " skipped: no approved bank details in CreatePayoutBatch")
+				continue
+			}
+			batch.Payouts = append(batch.Payouts, models.Payout{
+				ContentCreatorID: balance.ContentCreatorID,
+				EndToEndID:       fmt.Sprintf("%s-%04d", batch.Reference, len(batch.Payouts)+1),
+				Amount:           int(balance.Balance),
+				Currency:         "eur",
+				AccountHolder:    info.CompanyName,
+				Iban:             ledger.NormalizeBankID(info.Iban),
+				Bic:              ledger.NormalizeBankID(info.Bic),
+			})
+			batch.TotalAmount += int(balance.Balance)
+		}
+		if len(batch.Payouts) == 0 {
+			return errNothingToPay
+		}
+		batch.PayoutsCount = len(batch.Payouts)
+
+		if err := tx.Create(&batch).Error; err != nil {
+			return err
+		}
+		for _, payout := range batch.Payouts {
+			if err := ledger.RecordPayout(tx, payout); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errNothingToPay) {
+		utils.LogInfo("No balance to pay out in CreatePayoutBatch")
+		c.JSON(http.StatusConflict, gin.H{"error": "No balance to pay out"})
+		return
+	}
+	if err != nil {
+		utils.LogErrorWithUser(adminID, err, "Error creating payout batch in CreatePayoutBatch")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating payout batch"})
+		return
+	}
+
+	utils.LogSuccessWithUser(adminID, "Payout batch "+batch.Reference+" created in CreatePayoutBatch")
+	c.JSON(http.StatusCreated, batch)
+}
+
+// @Summary List the payout batches
+// @Description Returns the payout batches, newest first (admin only)
+// @Tags payouts
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.PayoutBatch
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 403 {object} map[string]string "error: Access denied"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /payouts/batches [get]
+func GetPayoutBatches(c *gin.Context) {
+	var batches []models.PayoutBatch
+	if err := db.DB.Order("created_at DESC").Find(&batches).Error; err != nil {
+		utils.LogError(err, "Error fetching payout batches in GetPayoutBatches")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching payout batches"})
+		return
+	}
+
+	utils.LogSuccess("Payout batches fetched successfully in GetPayoutBatches")
+	c.JSON(http.StatusOK, batches)
+}
+
+// @Summary Export a payout batch as a SEPA credit transfer
+// @Description Download the SEPA credit transfer file (pain.001.001.03) of a payout batch, to upload to the bank of the platform (admin only)
+// @Tags payouts
+// @Produce xml
+// @Param id path string true "Payout batch ID"
+// @Security BearerAuth
+// @Success 200 {file} file "pain.001 XML file"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 403 {object} map[string]string "error: Access denied"
+// @Failure 404 {object} map[string]string "error: Payout batch not found"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /payouts/batches/{id}/sepa [get]
+func ExportPayoutBatchSepa(c *gin.Context) {
+	batchID := c.Param("id")
+
+	var batch models.PayoutBatch
+	if err := db.DB.Preload("Payouts", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("end_to_end_id ASC")
+	}).First(&batch, "id = ?", batchID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.LogError(err, "Payout batch not found in ExportPayoutBatchSepa")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payout batch not found"})
+			return
+		}
+		utils.LogError(err, "Error fetching payout batch in ExportPayoutBatchSepa")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching payout batch"})
+		return
+	}
+
+	debtor, err := ledger.DebtorFromEnv()
+	if err != nil {
+		utils.LogError(err, "SEPA debtor account not configured in ExportPayoutBatchSepa")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "SEPA debtor account not configured"})
+		return
+	}
+
+	file, err := ledger.BuildSepaCreditTransfer(batch, debtor)
+	if err != nil {
+		utils.LogError(err, "Error building SEPA file in ExportPayoutBatchSepa")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error building SEPA file: " + err.Error()})
+		return
+	}
+
+	utils.LogSuccess("SEPA file of payout batch " + batch.Reference + " exported in ExportPayoutBatchSepa")
+	c.Header("Content-Disposition", "attachment; filename="+batch.Reference+".xml")
+	c.Data(http.StatusOK, "application/xml", file)
+}
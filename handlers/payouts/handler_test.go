@@ -0,0 +1,176 @@
+package payouts
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"pec2-backend/models"
+	"pec2-backend/testutils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	testutils.InitTestMain()
+
+	log.SetOutput(io.Discard)
+
+	exitCode := m.Run()
+
+	log.SetOutput(os.Stdout)
+
+	os.Exit(exitCode)
+}
+
+func expectUser(mock sqlmock.Sqlmock, userID string, role string) {
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs(userID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(userID, "creator", role))
+}
+
+// Test que le créateur voit son solde et l'historique de ses gains
+func TestGetMyEarnings_Success(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	creatorID := "creator-uuid"
+	expectUser(mock, creatorID, string(models.ContentCreator))
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(credit - debit\),0\) FROM "ledger_entries" WHERE account = \$1 AND content_creator_id = \$2`).
+		WithArgs(models.LedgerAccountCreatorBalance, creatorID).
+		WillReturnRows(mock.NewRows([]string{"balance"}).AddRow(720))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "ledger_transactions" WHERE content_creator_id = \$1`).
+		WithArgs(creatorID).
+		WillReturnRows(mock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(`SELECT \* FROM "ledger_transactions" WHERE content_creator_id = \$1 ORDER BY created_at DESC LIMIT \$2`).
+		WithArgs(creatorID, 50).
+		WillReturnRows(mock.NewRows([]string{"id", "kind", "source_type", "source_id", "content_creator_id", "amount", "commission", "net"}).
+			AddRow("tx-2", models.LedgerTransactionPayment, models.LedgerSourceTip, "tip-uuid", creatorID, 500, 100, 400).
+			AddRow("tx-1", models.LedgerTransactionPayment, models.LedgerSourceSubscriptionPayment, "payment-uuid", creatorID, 400, 80, 320))
+
+	r := testutils.SetupTestRouter()
+	r.GET("/content-creators/me/earnings", func(c *gin.Context) {
+		c.Set("user_id", creatorID)
+		GetMyEarnings(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/content-creators/me/earnings", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var earnings models.CreatorEarnings
+	json.Unmarshal(resp.Body.Bytes(), &earnings)
+	assert.Equal(t, int64(720), earnings.Balance)
+	assert.Equal(t, int64(2), earnings.Total)
+	if assert.Len(t, earnings.Transactions, 2) {
+		assert.Equal(t, 400, earnings.Transactions[0].Net)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un utilisateur qui n'est pas créateur n'a pas de gains
+func TestGetMyEarnings_NotCreator(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	expectUser(mock, "user-uuid", string(models.UserRole))
+
+	r := testutils.SetupTestRouter()
+	r.GET("/content-creators/me/earnings", func(c *gin.Context) {
+		c.Set("user_id", "user-uuid")
+		GetMyEarnings(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/content-creators/me/earnings", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un lot verse le solde des créateurs validés et ignore ceux sans coordonnées bancaires
+func TestCreatePayoutBatch_Success(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1\)`).
+		WithArgs(payoutLockKey).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT content_creator_id, SUM\(credit - debit\) AS balance FROM "ledger_entries" WHERE account = \$1 GROUP BY "content_creator_id" HAVING SUM\(credit - debit\) > 0`).
+		WithArgs(models.LedgerAccountCreatorBalance).
+		WillReturnRows(mock.NewRows([]string{"content_creator_id", "balance"}).
+			AddRow("creator-1", 1200).
+			AddRow("creator-2", 300))
+	mock.ExpectQuery(`SELECT \* FROM "content_creator_info" WHERE user_id IN \(\$1,\$2\) AND status = \$3`).
+		WithArgs("creator-1", "creator-2", models.ContentCreatorStatusApproved).
+		WillReturnRows(mock.NewRows([]string{"id", "user_id", "company_name", "iban", "bic", "status"}).
+			AddRow("info-1", "creator-1", "Studio", "fr76 3000 6000 0112 3456 7890 189", "bnpafrpp", models.ContentCreatorStatusApproved))
+	mock.ExpectQuery(`INSERT INTO "payout_batches"`).
+		WithArgs(sqlmock.AnyArg(), 1200, "eur", 1, "admin-uuid", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("batch-uuid"))
+	mock.ExpectQuery(`INSERT INTO "payouts"`).
+		WithArgs("batch-uuid", "creator-1", sqlmock.AnyArg(), 1200, "eur", "Studio", "FR7630006000011234567890189", "BNPAFRPP", sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("payout-uuid"))
+	mock.ExpectQuery(`INSERT INTO "ledger_transactions" (.+) ON CONFLICT DO NOTHING RETURNING "id"`).
+		WithArgs(models.LedgerTransactionPayout, models.LedgerSourcePayout, "payout-uuid", "creator-1", -1200, 0, -1200, "eur", nil, sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("ledger-payout"))
+	mock.ExpectQuery(`INSERT INTO "ledger_entries"`).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("entry-1").AddRow("entry-2"))
+	mock.ExpectCommit()
+
+	r := testutils.SetupTestRouter()
+	r.POST("/payouts/batches", func(c *gin.Context) {
+		c.Set("user_id", "admin-uuid")
+		CreatePayoutBatch(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "/payouts/batches", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+
+	var batch models.PayoutBatch
+	json.Unmarshal(resp.Body.Bytes(), &batch)
+	assert.Equal(t, 1200, batch.TotalAmount)
+	if assert.Len(t, batch.Payouts, 1) {
+		assert.Equal(t, batch.Reference+"-0001", batch.Payouts[0].EndToEndID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'aucun lot n'est créé sans solde à verser
+func TestCreatePayoutBatch_NothingToPay(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT content_creator_id, SUM\(credit - debit\) AS balance FROM "ledger_entries"`).
+		WillReturnRows(mock.NewRows([]string{"content_creator_id", "balance"}))
+	mock.ExpectRollback()
+
+	r := testutils.SetupTestRouter()
+	r.POST("/payouts/batches", func(c *gin.Context) {
+		c.Set("user_id", "admin-uuid")
+		CreatePayoutBatch(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "/payouts/batches", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,187 @@
+package plans
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Summary Get my promo codes
+// @Description Returns the promo codes of the connected content creator with the number of times each one has been redeemed, newest first
+// @Tags plans
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Coupon
+// @Failure 401 {object} map[string]string "error: User not authenticated"
+// @Failure 403 {object} map[string]string "error: Only content creators can manage subscription plans"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /content-creators/me/coupons [get]
+func GetMyCoupons(c *gin.Context) {
+	creator, ok := currentCreator(c, "GetMyCoupons")
+	if !ok {
+		return
+	}
+
+	var coupons []models.Coupon
+	if err := db.DB.Where("content_creator_id = ?", creator.ID).
+		Order("created_at DESC").
+		Find(&coupons).Error; err != nil {
+		utils.LogErrorWithUser(creator.ID, err, "Error fetching coupons in GetMyCoupons")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching promo codes"})
+		return
+	}
+
+	if len(coupons) > 0 {
+		couponIDs := make([]string, 0, len(coupons))
+		for _, coupon := range coupons {
+			couponIDs = append(couponIDs, coupon.ID)
+		}
+
+		var counts []struct {
+			CouponID string
+			Count    int64
+		}
+		if err := db.DB.Model(&models.CouponRedemption{}).
+			Select("coupon_id, COUNT(*) AS count").
+			Where("coupon_id IN ? AND status = ?", couponIDs, models.CouponRedemptionRedeemed).
+			Group("coupon_id").
+			Scan(&counts).Error; err != nil {
+			utils.LogErrorWithUser(creator.ID, err, "Error counting redemptions in GetMyCoupons")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching promo codes"})
+			return
+		}
+
+		redeemed := make(map[string]int64, len(counts))
+		for _, count := range counts {
+			redeemed[count.CouponID] = count.Count
+		}
+		for i := range coupons {
+			coupons[i].TimesRedeemed = redeemed[coupons[i].ID]
+		}
+	}
+
+	utils.LogSuccessWithUser(creator.ID, "Coupons fetched successfully in GetMyCoupons")
+	c.JSON(http.StatusOK, coupons)
+}
+
+// @Summary Create a promo code
+// @Description Create a promo code for the subscriptions of the connected content creator: a percentage or a fixed amount (in cents) off the first payment, with an optional expiry date and redemption limit. Each user can redeem a code once
+// @Tags plans
+// @Accept json
+// @Produce json
+// @Param coupon body models.CouponCreate true "Promo code"
+// @Security BearerAuth
+// @Success 201 {object} models.Coupon
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 403 {object} map[string]string "error: Only content creators can manage subscription plans"
+// @Failure 409 {object} map[string]string "error: This promo code already exists"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /content-creators/me/coupons [post]
+func CreateCoupon(c *gin.Context) {
+	var input models.CouponCreate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.LogError(err, "Error when binding JSON in CreateCoupon")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	if input.Type == models.CouponPercent && (input.PercentOff == 0 || input.AmountOff != 0) {
+		utils.LogError(errors.New("remise invalide"), "Invalid percentage coupon in CreateCoupon")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A PERCENT promo code needs percentOff only"})
+		return
+	}
+	if input.Type == models.CouponFixed && (input.AmountOff == 0 || input.PercentOff != 0) {
+		utils.LogError(errors.New("remise invalide"), "Invalid fixed amount coupon in CreateCoupon")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A FIXED promo code needs amountOff only"})
+		return
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		utils.LogError(errors.New("date passée"), "Expiry date in the past in CreateCoupon")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The expiry date must be in the future"})
+		return
+	}
+
+	creator, ok := currentCreator(c, "CreateCoupon")
+	if !ok {
+		return
+	}
+
+	// Les codes sont insensibles à la casse
+	code := strings.ToUpper(input.Code)
+
+	var existing models.Coupon
+	if err := db.DB.Where("content_creator_id = ? AND code = ?", creator.ID, code).First(&existing).Error; err == nil {
+		utils.LogErrorWithUser(creator.ID, errors.New("code déjà existant"), "Coupon already exists in CreateCoupon")
+		c.JSON(http.StatusConflict, gin.H{"error": "This promo code already exists"})
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.LogErrorWithUser(creator.ID, err, "Error checking existing coupon in CreateCoupon")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating promo code"})
+		return
+	}
+
+	coupon := models.Coupon{
+		ContentCreatorID: creator.ID,
+		Code:             code,
+		Type:             input.Type,
+		PercentOff:       input.PercentOff,
+		AmountOff:        input.AmountOff,
+		ExpiresAt:        input.ExpiresAt,
+		MaxRedemptions:   input.MaxRedemptions,
+		Active:           true,
+	}
+
+	if err := db.DB.Create(&coupon).Error; err != nil {
+		utils.LogErrorWithUser(creator.ID, err, "Error creating coupon in CreateCoupon")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating promo code"})
+		return
+	}
+
+	utils.LogSuccessWithUser(creator.ID, "Coupon "+coupon.Code+" created successfully in CreateCoupon")
+	c.JSON(http.StatusCreated, coupon)
+}
+
+// @Summary Deactivate a promo code
+// @Description Deactivate a promo code: it can no longer be redeemed, the discounts already granted are kept
+// @Tags plans
+// @Produce json
+// @Param couponId path string true "Coupon ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "message: Promo code deactivated"
+// @Failure 403 {object} map[string]string "error: Only content creators can manage subscription plans"
+// @Failure 404 {object} map[string]string "error: Promo code not found"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /content-creators/me/coupons/{couponId} [delete]
+func DeactivateCoupon(c *gin.Context) {
+	couponID := c.Param("couponId")
+
+	creator, ok := currentCreator(c, "DeactivateCoupon")
+	if !ok {
+		return
+	}
+
+	result := db.DB.Model(&models.Coupon{}).
+		Where("id = ? AND content_creator_id = ?", couponID, creator.ID).
+		Update("active", false)
+	if result.Error != nil {
+		utils.LogErrorWithUser(creator.ID, result.Error, "Error deactivating coupon in DeactivateCoupon")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deactivating promo code"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.LogErrorWithUser(creator.ID, errors.New("code introuvable"), "Coupon not found in DeactivateCoupon")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promo code not found"})
+		return
+	}
+
+	utils.LogSuccessWithUser(creator.ID, "Coupon deactivated successfully in DeactivateCoupon")
+	c.JSON(http.StatusOK, gin.H{"message": "Promo code deactivated"})
+}
@@ -0,0 +1,286 @@
+package plans
+
+import (
+	"errors"
+	"net/http"
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// currentCreator charge l'utilisateur connecté et vérifie qu'il est créateur de contenu
+func currentCreator(c *gin.Context, handlerName string) (models.User, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(errors.New("user_id manquant"), "User not authenticated in "+handlerName)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return models.User{}, false
+	}
+
+	var user models.User
+	if err := db.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "User not found in "+handlerName)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return models.User{}, false
+	}
+
+	if user.Role != models.ContentCreator {
+		utils.LogErrorWithUser(userID, errors.New("pas créateur"), "Only content creators can manage plans in "+handlerName)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only content creators can manage subscription plans"})
+		return models.User{}, false
+	}
+
+	return user, true
+}
+
+// validBundles vérifie qu'une durée n'est proposée qu'une fois par plan
+func validBundles(bundles []models.PlanBundle) bool {
+	seen := make(map[int]bool, len(bundles))
+	for _, bundle := range bundles {
+		if seen[bundle.Months] {
+			return false
+		}
+		seen[bundle.Months] = true
+	}
+	return true
+}
+
+// @Summary Get the subscription plans of a content creator
+// @Description Returns the active plans of a content creator, cheapest first
+// @Tags plans
+// @Produce json
+// @Param id path string true "Content creator ID"
+// @Security BearerAuth
+// @Success 200 {array} models.SubscriptionPlan
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /content-creators/{id}/plans [get]
+func GetCreatorPlans(c *gin.Context) {
+	creatorID := c.Param("id")
+
+	var plans []models.SubscriptionPlan
+	if err := db.DB.Where("content_creator_id = ? AND active = ?", creatorID, true).
+		Order("price ASC").
+		Find(&plans).Error; err != nil {
+		utils.LogError(err, "Error fetching plans in GetCreatorPlans")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching subscription plans"})
+		return
+	}
+
+	utils.LogSuccess("Plans fetched successfully in GetCreatorPlans")
+	c.JSON(http.StatusOK, plans)
+}
+
+// @Summary Get my subscription plans
+// @Description Returns all the plans of the connected content creator, including inactive ones
+// @Tags plans
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.SubscriptionPlan
+// @Failure 401 {object} map[string]string "error: User not authenticated"
+// @Failure 403 {object} map[string]string "error: Only content creators can manage subscription plans"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /content-creators/me/plans [get]
+func GetMyPlans(c *gin.Context) {
+	creator, ok := currentCreator(c, "GetMyPlans")
+	if !ok {
+		return
+	}
+
+	var plans []models.SubscriptionPlan
+	if err := db.DB.Where("content_creator_id = ?", creator.ID).
+		Order("price ASC").
+		Find(&plans).Error; err != nil {
+		utils.LogErrorWithUser(creator.ID, err, "Error fetching plans in GetMyPlans")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching subscription plans"})
+		return
+	}
+
+	utils.LogSuccessWithUser(creator.ID, "Plans fetched successfully in GetMyPlans")
+	c.JSON(http.StatusOK, plans)
+}
+
+// @Summary Create a subscription plan
+// @Description Create a monthly subscription plan for the connected content creator (one plan per tier), with an optional free trial for first-time subscribers and discounted 3, 6 or 12 month bundles
+// @Tags plans
+// @Accept json
+// @Produce json
+// @Param plan body models.SubscriptionPlanCreate true "Plan information (price in cents)"
+// @Security BearerAuth
+// @Success 201 {object} models.SubscriptionPlan
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 403 {object} map[string]string "error: Only content creators can manage subscription plans"
+// @Failure 409 {object} map[string]string "error: A plan already exists for this tier"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /content-creators/me/plans [post]
+func CreatePlan(c *gin.Context) {
+	var input models.SubscriptionPlanCreate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.LogError(err, "Error when binding JSON in CreatePlan")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	if !input.Tier.IsValid() {
+		utils.LogError(errors.New("niveau invalide"), "Invalid tier in CreatePlan")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The tier must be BASIC, PREMIUM or VIP"})
+		return
+	}
+
+	if !validBundles(input.Bundles) {
+		utils.LogError(errors.New("formule en double"), "Duplicate bundle duration in CreatePlan")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Each bundle duration can only be offered once"})
+		return
+	}
+
+	creator, ok := currentCreator(c, "CreatePlan")
+	if !ok {
+		return
+	}
+
+	var existing models.SubscriptionPlan
+	if err := db.DB.Where("content_creator_id = ? AND tier = ?", creator.ID, input.Tier).First(&existing).Error; err == nil {
+		utils.LogErrorWithUser(creator.ID, errors.New("plan déjà existant"), "Plan already exists for this tier in CreatePlan")
+		c.JSON(http.StatusConflict, gin.H{"error": "A plan already exists for this tier"})
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.LogErrorWithUser(creator.ID, err, "Error checking existing plan in CreatePlan")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating subscription plan"})
+		return
+	}
+
+	plan := models.SubscriptionPlan{
+		ContentCreatorID: creator.ID,
+		Name:             input.Name,
+		Tier:             input.Tier,
+		Description:      input.Description,
+		Perks:            input.Perks,
+		Price:            input.Price,
+		Currency:         "eur",
+		TrialDays:        input.TrialDays,
+		Bundles:          input.Bundles,
+		Active:           true,
+	}
+
+	if err := db.DB.Create(&plan).Error; err != nil {
+		utils.LogErrorWithUser(creator.ID, err, "Error creating plan in CreatePlan")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating subscription plan"})
+		return
+	}
+
+	utils.LogSuccessWithUser(creator.ID, "Plan created successfully in CreatePlan")
+	c.JSON(http.StatusCreated, plan)
+}
+
+// @Summary Update a subscription plan
+// @Description Update a plan of the connected content creator. A new price only applies to new subscribers.
+// @Tags plans
+// @Accept json
+// @Produce json
+// @Param planId path string true "Plan ID"
+// @Param plan body models.SubscriptionPlanUpdate true "Fields to update (price in cents)"
+// @Security BearerAuth
+// @Success 200 {object} models.SubscriptionPlan
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 403 {object} map[string]string "error: Only content creators can manage subscription plans"
+// @Failure 404 {object} map[string]string "error: Plan not found"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /content-creators/me/plans/{planId} [put]
+func UpdatePlan(c *gin.Context) {
+	planID := c.Param("planId")
+
+	var input models.SubscriptionPlanUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.LogError(err, "Error when binding JSON in UpdatePlan")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	if input.Bundles != nil && !validBundles(*input.Bundles) {
+		utils.LogError(errors.New("formule en double"), "Duplicate bundle duration in UpdatePlan")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Each bundle duration can only be offered once"})
+		return
+	}
+
+	creator, ok := currentCreator(c, "UpdatePlan")
+	if !ok {
+		return
+	}
+
+	var plan models.SubscriptionPlan
+	if err := db.DB.Where("id = ? AND content_creator_id = ?", planID, creator.ID).First(&plan).Error; err != nil {
+		utils.LogErrorWithUser(creator.ID, err, "Plan not found in UpdatePlan")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plan not found"})
+		return
+	}
+
+	if input.Name != nil {
+		plan.Name = *input.Name
+	}
+	if input.Description != nil {
+		plan.Description = *input.Description
+	}
+	if input.Perks != nil {
+		plan.Perks = *input.Perks
+	}
+	if input.Price != nil {
+		plan.Price = *input.Price
+	}
+	if input.TrialDays != nil {
+		plan.TrialDays = *input.TrialDays
+	}
+	if input.Bundles != nil {
+		plan.Bundles = *input.Bundles
+	}
+	if input.Active != nil {
+		plan.Active = *input.Active
+	}
+
+	if err := db.DB.Save(&plan).Error; err != nil {
+		utils.LogErrorWithUser(creator.ID, err, "Error updating plan in UpdatePlan")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating subscription plan"})
+		return
+	}
+
+	utils.LogSuccessWithUser(creator.ID, "Plan updated successfully in UpdatePlan")
+	c.JSON(http.StatusOK, plan)
+}
+
+// @Summary Deactivate a subscription plan
+// @Description Deactivate a plan: it is no longer offered, existing subscriptions are kept
+// @Tags plans
+// @Produce json
+// @Param planId path string true "Plan ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "message: Plan deactivated"
+// @Failure 403 {object} map[string]string "error: Only content creators can manage subscription plans"
+// @Failure 404 {object} map[string]string "error: Plan not found"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /content-creators/me/plans/{planId} [delete]
+func DeletePlan(c *gin.Context) {
+	planID := c.Param("planId")
+
+	creator, ok := currentCreator(c, "DeletePlan")
+	if !ok {
+		return
+	}
+
+	result := db.DB.Model(&models.SubscriptionPlan{}).
+		Where("id = ? AND content_creator_id = ?", planID, creator.ID).
+		Update("active", false)
+	if result.Error != nil {
+		utils.LogErrorWithUser(creator.ID, result.Error, "Error deactivating plan in DeletePlan")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deactivating subscription plan"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.LogErrorWithUser(creator.ID, errors.New("plan introuvable"), "Plan not found in DeletePlan")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plan not found"})
+		return
+	}
+
+	utils.LogSuccessWithUser(creator.ID, "Plan deactivated successfully in DeletePlan")
+	c.JSON(http.StatusOK, gin.H{"message": "Plan deactivated"})
+}
@@ -0,0 +1,210 @@
+package plans
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"pec2-backend/testutils"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	testutils.InitTestMain()
+
+	log.SetOutput(io.Discard)
+
+	exitCode := m.Run()
+
+	log.SetOutput(os.Stdout)
+
+	os.Exit(exitCode)
+}
+
+func expectUser(mock sqlmock.Sqlmock, userID string, role string) {
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs(userID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(userID, "creator", role))
+}
+
+// Test la création d'un plan par un créateur de contenu
+func TestCreatePlan_Success(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	creatorID := "creator-uuid"
+	expectUser(mock, creatorID, "CONTENT_CREATOR")
+	mock.ExpectQuery(`SELECT \* FROM "subscription_plans" WHERE content_creator_id = \$1 AND tier = \$2`).
+		WithArgs(creatorID, "VIP", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "subscription_plans"`).
+		WithArgs(creatorID, "VIP", "VIP", "", `["Lives privés"]`, 1499, "eur", 0, sqlmock.AnyArg(), true, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("plan-uuid"))
+	mock.ExpectCommit()
+
+	r := testutils.SetupTestRouter()
+	r.POST("/content-creators/me/plans", func(c *gin.Context) {
+		c.Set("user_id", creatorID)
+		CreatePlan(c)
+	})
+
+	jsonData, _ := json.Marshal(map[string]interface{}{
+		"name":  "VIP",
+		"tier":  "VIP",
+		"perks": []string{"Lives privés"},
+		"price": 1499,
+	})
+	req, _ := http.NewRequest(http.MethodPost, "/content-creators/me/plans", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+
+	var respBody map[string]interface{}
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "plan-uuid", respBody["id"])
+	assert.Equal(t, float64(1499), respBody["price"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un utilisateur qui n'est pas créateur ne peut pas créer de plan
+func TestCreatePlan_NotContentCreator(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	expectUser(mock, "user-uuid", "USER")
+
+	r := testutils.SetupTestRouter()
+	r.POST("/content-creators/me/plans", func(c *gin.Context) {
+		c.Set("user_id", "user-uuid")
+		CreatePlan(c)
+	})
+
+	jsonData, _ := json.Marshal(map[string]interface{}{"name": "Basic", "tier": "BASIC", "price": 499})
+	req, _ := http.NewRequest(http.MethodPost, "/content-creators/me/plans", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test le refus d'un niveau inconnu
+func TestCreatePlan_InvalidTier(t *testing.T) {
+	r := testutils.SetupTestRouter()
+	r.POST("/content-creators/me/plans", func(c *gin.Context) {
+		c.Set("user_id", "creator-uuid")
+		CreatePlan(c)
+	})
+
+	jsonData, _ := json.Marshal(map[string]interface{}{"name": "Gold", "tier": "GOLD", "price": 499})
+	req, _ := http.NewRequest(http.MethodPost, "/content-creators/me/plans", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+// Test la récupération des plans actifs d'un créateur
+func TestGetCreatorPlans_Success(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT \* FROM "subscription_plans" WHERE content_creator_id = \$1 AND active = \$2 ORDER BY price ASC`).
+		WithArgs("creator-uuid", true).
+		WillReturnRows(mock.NewRows([]string{"id", "content_creator_id", "name", "tier", "perks", "price", "currency", "active"}).
+			AddRow("plan-1", "creator-uuid", "Basic", "BASIC", `["Posts"]`, 499, "eur", true).
+			AddRow("plan-2", "creator-uuid", "VIP", "VIP", `["Posts","Lives"]`, 1499, "eur", true))
+
+	r := testutils.SetupTestRouter()
+	r.GET("/content-creators/:id/plans", GetCreatorPlans)
+
+	req, _ := http.NewRequest(http.MethodGet, "/content-creators/creator-uuid/plans", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var respBody []map[string]interface{}
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Len(t, respBody, 2)
+	assert.Equal(t, "BASIC", respBody[0]["tier"])
+	assert.Equal(t, []interface{}{"Posts", "Lives"}, respBody[1]["perks"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test la création d'un code promo, enregistré en majuscules
+func TestCreateCoupon_Success(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	creatorID := "creator-uuid"
+	expectUser(mock, creatorID, "CONTENT_CREATOR")
+	mock.ExpectQuery(`SELECT \* FROM "coupons" WHERE content_creator_id = \$1 AND code = \$2`).
+		WithArgs(creatorID, "WELCOME20", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "coupons"`).
+		WithArgs(creatorID, "WELCOME20", "PERCENT", 20, 0, nil, 50, true, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("coupon-uuid"))
+	mock.ExpectCommit()
+
+	r := testutils.SetupTestRouter()
+	r.POST("/content-creators/me/coupons", func(c *gin.Context) {
+		c.Set("user_id", creatorID)
+		CreateCoupon(c)
+	})
+
+	jsonData, _ := json.Marshal(map[string]interface{}{
+		"code":           "welcome20",
+		"type":           "PERCENT",
+		"percentOff":     20,
+		"maxRedemptions": 50,
+	})
+	req, _ := http.NewRequest(http.MethodPost, "/content-creators/me/coupons", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+
+	var respBody map[string]interface{}
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "WELCOME20", respBody["code"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un code en pourcentage sans pourcentage est refusé
+func TestCreateCoupon_InvalidDiscount(t *testing.T) {
+	r := testutils.SetupTestRouter()
+	r.POST("/content-creators/me/coupons", func(c *gin.Context) {
+		c.Set("user_id", "creator-uuid")
+		CreateCoupon(c)
+	})
+
+	jsonData, _ := json.Marshal(map[string]interface{}{"code": "OFFERT", "type": "PERCENT", "amountOff": 200})
+	req, _ := http.NewRequest(http.MethodPost, "/content-creators/me/coupons", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
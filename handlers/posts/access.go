@@ -0,0 +1,121 @@
+package posts
+
+import (
+	"time"
+
+	"pec2-backend/db"
+	"pec2-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// postViewer représente l'utilisateur qui consulte les posts (UserID vide si anonyme)
+type postViewer struct {
+	UserID string
+	Role   string
+	// Créateurs auxquels l'utilisateur a un abonnement ACTIVE et non échu
+	subscribedCreators map[string]bool
+	// Posts payants achetés à l'unité
+	purchasedPosts map[string]bool
+}
+
+// getPostViewer construit le viewer à partir du contexte renseigné par OptionalJWTAuth
+// et charge ses abonnements actifs et ses achats s'il est connecté
+func getPostViewer(c *gin.Context) (postViewer, error) {
+	viewer := postViewer{
+		subscribedCreators: make(map[string]bool),
+		purchasedPosts:     make(map[string]bool),
+	}
+
+	if userID, exists := c.Get("user_id"); exists {
+		if id, ok := userID.(string); ok {
+			viewer.UserID = id
+		}
+	}
+	if role, exists := c.Get("role"); exists {
+		if r, ok := role.(string); ok {
+			viewer.Role = r
+		}
+	}
+
+	if viewer.UserID == "" {
+		return viewer, nil
+	}
+
+	var creatorIDs []string
+	if err := db.DB.Model(&models.Subscription{}).
+		Where("user_id = ? AND status = ?", viewer.UserID, models.SubscriptionActive).
+		Where("end_date IS NULL OR end_date > ?", time.Now()).
+		Pluck("content_creator_id", &creatorIDs).Error; err != nil {
+		return viewer, err
+	}
+	for _, creatorID := range creatorIDs {
+		viewer.subscribedCreators[creatorID] = true
+	}
+
+	var postIDs []string
+	if err := db.DB.Model(&models.PostPurchase{}).
+		Where("buyer_id = ? AND status = ?", viewer.UserID, models.PostPurchaseSucceeded).
+		Pluck("post_id", &postIDs).Error; err != nil {
+		return viewer, err
+	}
+	for _, postID := range postIDs {
+		viewer.purchasedPosts[postID] = true
+	}
+
+	return viewer, nil
+}
+
+// canAccess indique si le viewer peut voir le contenu complet du post :
+// post gratuit, auteur du post, admin, acheteur du post ou abonné actif à l'auteur.
+// Un post vendu à l'unité (prix > 0) n'est pas inclus dans l'abonnement
+func (v postViewer) canAccess(post models.Post) bool {
+	if post.IsFree {
+		return true
+	}
+	if v.UserID == "" {
+		return false
+	}
+	if post.UserID == v.UserID || v.Role == string(models.AdminRole) {
+		return true
+	}
+	if v.purchasedPosts[post.ID] {
+		return true
+	}
+	if post.Price > 0 {
+		return false
+	}
+	return v.subscribedCreators[post.UserID]
+}
+
+// toPostResponse construit la réponse d'un post, en teaser verrouillé (sans média)
+// si le viewer n'y a pas accès
+func toPostResponse(post models.Post, viewer postViewer, likesCount, commentsCount, reportsCount int) models.PostResponse {
+	response := models.PostResponse{
+		ID:         post.ID,
+		Name:       post.Name,
+		PictureURL: post.PictureURL,
+		IsFree:     post.IsFree,
+		Price:      post.Price,
+		Enable:     post.Enable,
+		Categories: post.Categories,
+		CreatedAt:  post.CreatedAt,
+		UpdatedAt:  post.UpdatedAt,
+		User: models.UserInfo{
+			ID:             post.User.ID,
+			UserName:       post.User.UserName,
+			ProfilePicture: post.User.ProfilePicture,
+		},
+		LikesCount:    likesCount,
+		CommentsCount: commentsCount,
+		ReportsCount:  reportsCount,
+		IsPurchased:   viewer.purchasedPosts[post.ID],
+	}
+
+	if !viewer.canAccess(post) {
+		response.PictureURL = ""
+		response.IsLocked = true
+	}
+
+	return response
+}
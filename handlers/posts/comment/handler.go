@@ -2,24 +2,17 @@ package comment
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"pec2-backend/db"
 	"pec2-backend/models"
+	"pec2-backend/realtime"
 	"pec2-backend/utils"
-	"sync"
-	"time"
 
 	"github.com/gin-gonic/gin"
-)
-
-var (
-	// Clients connectés au SSE, mappé par postID
-	clients = make(map[string]map[chan string]bool)
-
-	// Mutex pour protéger l'accès à la map clients
-	clientsMutex sync.RWMutex
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // Message SSE
@@ -30,43 +23,85 @@ type SSEMessage struct {
 
 // Commentaire à envoyer via SSE
 type SSEComment struct {
-	ID            string `json:"id"`
-	PostID        string `json:"postId"`
-	UserID        string `json:"userId"`
-	Content       string `json:"content"`
-	UserName      string `json:"userName"`
-	CreatedAt     string `json:"createdAt"`
-	CommentsCount int    `json:"commentsCount"`
+	ID       string  `json:"id"`
+	PostID   string  `json:"postId"`
+	UserID   string  `json:"userId"`
+	ParentID *string `json:"parentId"`
+	Content  string  `json:"content"`
+	UserName string  `json:"userName"`
+	// Nombre de réponses directes au commentaire
+	RepliesCount int    `json:"repliesCount"`
+	Edited       bool   `json:"edited"`
+	Deleted      bool   `json:"deleted"`
+	CreatedAt    string `json:"createdAt"`
+	// Nombre total de commentaires du post, renseigné à la création
+	CommentsCount int `json:"commentsCount"`
+}
+
+// Un commentaire supprimé reste listé tant qu'il a des réponses, pour ne pas casser le fil
+const visibleComments = "deleted_at IS NULL OR comments_count > 0"
+
+// toSSEComment ajoute le nom de l'auteur au commentaire, le contenu d'un commentaire supprimé n'est pas renvoyé
+func toSSEComment(comment models.Comment) SSEComment {
+	var user models.User
+	db.DB.Select("user_name").Where("id = ?", comment.UserID).First(&user)
+
+	sseComment := SSEComment{
+		ID:           comment.ID,
+		PostID:       comment.PostID,
+		UserID:       comment.UserID,
+		ParentID:     comment.ParentID,
+		Content:      comment.Content,
+		UserName:     user.UserName,
+		RepliesCount: comment.CommentsCount,
+		Edited:       comment.Edited,
+		CreatedAt:    comment.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if comment.DeletedAt != nil {
+		sseComment.Content = ""
+		sseComment.Deleted = true
+	}
+	return sseComment
 }
 
+// @Summary Get the comments of a post
+// @Description Returns the top-level comments of a post with their number of replies, or with parent_id the replies to one comment, oldest first
+// @Tags comments
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param parent_id query string false "Comment ID to get the replies of"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "comments: list of comments"
+// @Failure 400 {object} map[string]string "error: Invalid parent comment ID"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 500 {object} map[string]string "error: Failed to retrieve comments"
+// @Router /posts/{id}/comments [get]
 func GetCommentsByPostID(c *gin.Context) {
 	postId := c.Param("id")
 	var comments []models.Comment
 
-	if err := db.DB.Where("post_id = ?", postId).Find(&comments).Error; err != nil {
+	query := db.DB.Where("post_id = ?", postId)
+	if parentID := c.Query("parent_id"); parentID != "" {
+		// parent_id est comparé à une colonne uuid : une valeur invalide ferait échouer la requête
+		if _, err := uuid.Parse(parentID); err != nil {
+			utils.LogError(err, "Invalid parent_id in GetCommentsByPostID")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent comment ID"})
+			return
+		}
+		query = query.Where("parent_id = ?", parentID)
+	} else {
+		query = query.Where("parent_id IS NULL")
+	}
+
+	if err := query.Where(visibleComments).Order("created_at ASC").Find(&comments).Error; err != nil {
 		utils.LogError(err, "Failed to retrieve comments in GetCommentsByPostID")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comments"})
 		return
 	}
 
-	var commentsResponse []SSEComment
-
-	// If no comments, commentsResponse remains an empty slice
-	if len(comments) > 0 {
-		for _, comment := range comments {
-			var user models.User
-			db.DB.Select("user_name").Where("id = ?", comment.UserID).First(&user)
-
-			sseComment := SSEComment{
-				ID:        comment.ID,
-				PostID:    comment.PostID,
-				UserID:    comment.UserID,
-				Content:   comment.Content,
-				UserName:  user.UserName,
-				CreatedAt: comment.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-			}
-			commentsResponse = append(commentsResponse, sseComment)
-		}
+	commentsResponse := []SSEComment{}
+	for _, comment := range comments {
+		commentsResponse = append(commentsResponse, toSSEComment(comment))
 	}
 
 	fmt.Println("Comments retrieved:", commentsResponse)
@@ -80,40 +115,26 @@ func GetCommentsByPostID(c *gin.Context) {
 }
 
 // @Summary Handle SSE connection for comments
-// @Description Connect to SSE to receive comments in real-time for a specific post
+// @Description Connect to SSE to receive comments in real-time for a specific post: "comment" events for new comments, "reply" events for replies (nested under their parentId), "comment_updated" and "comment_deleted" events when a comment is edited or removed, "like" events with the new likesCount and "presence" events with the number of viewers currently connected to the post. Every event except presence has an id: when the stream is opened with the lastEventId parameter (or the Last-Event-ID header) only the missed events are replayed, otherwise all the comments are sent again. A heartbeat comment is sent every 15 seconds, and a client that does not read fast enough is disconnected. The stream ticket is single-use, so the automatic reconnection of EventSource is refused: after any disconnection, request a new ticket and open a new stream with lastEventId set to the id of the last event received
 // @Tags comments
 // @Param id path string true "Post ID"
-// @Param token query string false "JWT Token for web clients (optional)"
-// @Security BearerAuth
+// @Param ticket query string false "Stream ticket from POST /stream-tickets, can also be sent in the stream_ticket cookie"
+// @Param lastEventId query string false "Id of the last event received, to resume the stream with a new ticket"
+// @Param Last-Event-ID header string false "Id of the last event received, to resume the stream"
 // @Success 200 {object} map[string]string "Connected to SSE"
 // @Failure 400 {object} map[string]string "error: Invalid post ID"
 // @Failure 401 {object} map[string]string "error: Unauthorized"
 // @Failure 500 {object} map[string]string "error: Error setting up SSE"
+// @Failure 503 {object} map[string]string "error: Too many clients connected to this stream"
 // @Router /posts/{id}/comments/sse [get]
 func HandleSSE(c *gin.Context) {
 	postID := c.Param("id")
 
-	// Pour l'instant j'ai pas trouver comment passer de header
-	// Donc je vais la vérif dans l'URL
-	tokenFromQuery := c.Query("token")
-	_, exists := c.Get("user_id")
-
-	// Si l'ID utilisateur n'a pas été défini par le middleware (car param dans URL) mais qu'un token est présent dans l'URL
-	if !exists && tokenFromQuery != "" {
-		_, err := utils.DecodeJWT(tokenFromQuery)
-		if err != nil {
-			utils.LogError(err, "Invalid token in URL in HandleSSE")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token in URL"})
-			return
-		}
-		exists = true
-	}
-
-	// C'est une vérif en plus pour le cas où le token est pas valide
-	// dans l'idée c'est toujours à false au début car je passe pas par le middleware
+	// user_id est renseigné par le middleware StreamTicketAuth à partir du ticket
+	userID, exists := c.Get("user_id")
 	if !exists {
-		utils.LogError(nil, "User not found in token in HandleSSE")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		utils.LogError(nil, "User not found in ticket in HandleSSE")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Stream ticket missing"})
 		return
 	}
 
@@ -125,150 +146,63 @@ func HandleSSE(c *gin.Context) {
 		return
 	}
 
-	// ça c'est pour les en-têtes pour le SSE
-	c.Writer.Header().Set("Content-Type", "text/event-stream")
-	c.Writer.Header().Set("Cache-Control", "no-cache")
-	c.Writer.Header().Set("Connection", "keep-alive")
-	c.Writer.Header().Set("Transfer-Encoding", "chunked")
-	c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-
-	// là je crée un channel pour chaque client qui se connecte
-	messageChan := make(chan string)
-
-	// Là je mets le channel dans la map clients
-	// clients est mappé par postID et chaque postID a un channel
-	// Et je bloque l'accès à la map clients pendant les modifs
-	clientsMutex.Lock()
-	if clients[postID] == nil {
-		clients[postID] = make(map[chan string]bool)
-	}
-	clients[postID][messageChan] = true
-	clientsMutex.Unlock()
-
-	flusher, ok := c.Writer.(http.Flusher)
-	if !ok {
-		utils.LogError(nil, "Streaming not supported in HandleSSE")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Streaming not supported"})
-		return
-	}
-
-	c.Writer.Write([]byte("event: connected\ndata: {\"status\":\"connected\"}\n\n"))
-
-	flusher.Flush()
+	utils.LogSuccessWithUser(userID, "SSE connection established in HandleSSE")
 
-	var comments []models.Comment
-	if err := db.DB.Where("post_id = ?", postID).Find(&comments).Error; err != nil {
-		utils.LogError(err, "Error retrieving comments in HandleSSE")
-		log.Printf("Error retrieving comments: %v", err)
-	} else {
+	err := realtime.Default.ServeSSE(c, realtime.PostTopic(postID), func(emit func(name string, data []byte)) {
+		// Les réponses sont envoyées avec leur parentId pour être imbriquées côté client.
+		// Un commentaire publié pendant le chargement peut arriver en double : le client dédoublonne par id
+		var comments []models.Comment
+		if err := db.DB.Where("post_id = ?", postID).Where(visibleComments).Order("created_at ASC").Find(&comments).Error; err != nil {
+			utils.LogError(err, "Error retrieving comments in HandleSSE")
+			return
+		}
 		for _, comment := range comments {
-			var user models.User
-			db.DB.Select("user_name").Where("id = ?", comment.UserID).First(&user)
-
-			sseComment := SSEComment{
-				ID:        comment.ID,
-				PostID:    comment.PostID,
-				UserID:    comment.UserID,
-				Content:   comment.Content,
-				UserName:  user.UserName,
-				CreatedAt: comment.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-			}
-
-			msg := SSEMessage{
+			jsonData, err := json.Marshal(SSEMessage{
 				Type:    "existing_comment",
-				Payload: sseComment,
-			}
-
-			jsonData, err := json.Marshal(msg)
+				Payload: toSSEComment(comment),
+			})
 			if err != nil {
 				utils.LogError(err, "Error marshaling SSE message in HandleSSE")
-				log.Printf("Error marshaling SSE message: %v", err)
 				continue
 			}
-
-			c.Writer.Write(fmt.Appendf(nil, "event: comment\ndata: %s\n\n", jsonData))
-			flusher.Flush()
-		}
-	}
-
-	userID, exists := c.Get("user_id")
-	if !exists {
-		userID = "0"
-	}
-	utils.LogSuccessWithUser(userID, "SSE connection established in HandleSSE")
-
-	ctx := c.Request.Context()
-
-	defer func() {
-		clientsMutex.Lock()
-		delete(clients[postID], messageChan)
-		if len(clients[postID]) == 0 {
-			delete(clients, postID)
-		}
-		clientsMutex.Unlock()
-		close(messageChan)
-	}()
-
-	for {
-		select {
-		case message, ok := <-messageChan:
-			if !ok {
-				return
-			}
-			c.Writer.Write([]byte(message))
-			flusher.Flush()
-		case <-ctx.Done():
-			return
-		case <-time.After(30 * time.Second):
-			c.Writer.Write([]byte("event: ping\ndata: {}\n\n"))
-			flusher.Flush()
+			emit("comment", jsonData)
 		}
+	})
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "SSE connection closed in HandleSSE")
+		return
 	}
+	utils.LogSuccessWithUser(userID, "SSE connection closed in HandleSSE")
 }
 
 // @Summary Create a new comment for a post
-// @Description Create a new comment and broadcast it via SSE
+// @Description Create a new comment, or a reply to a comment of the same post with parentId, and broadcast it via SSE
 // @Tags comments
 // @Accept json
 // @Produce json
 // @Param id path string true "Post ID"
-// @Param token query string false "JWT Token for web clients (optional)"
-// @Param comment body map[string]string true "Comment content"
+// @Param comment body map[string]string true "Comment content and optional parentId"
 // @Security BearerAuth
 // @Success 201 {object} map[string]string "Comment created"
 // @Failure 400 {object} map[string]string "error: Invalid request"
 // @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 404 {object} map[string]string "error: Parent comment not found"
 // @Failure 500 {object} map[string]string "error: Server error"
 // @Router /posts/{id}/comments [post]
 func CreateComment(c *gin.Context) {
 	postID := c.Param("id")
 
-	// Vérifier si le token est passé en paramètre d'URL pour les clients web
-	tokenFromQuery := c.Query("token")
 	userID, exists := c.Get("user_id")
-
-	// Si l'ID utilisateur n'a pas été défini par le middleware (car URL) mais qu'un token est présent dans l'URL
-	if !exists && tokenFromQuery != "" {
-		// Décoder le token de l'URL
-		claims, err := utils.DecodeJWT(tokenFromQuery)
-		if err != nil {
-			utils.LogError(err, "Invalid token in URL in CreateComment")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token in URL"})
-			return
-		}
-		userID = claims["user_id"]
-		exists = true
-	}
-
 	if !exists {
 		utils.LogError(nil, "User not found in token in CreateComment")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
 		return
 	}
 
-	// Récupérer le contenu du commentaire
+	// Récupérer le contenu du commentaire, et le commentaire auquel il répond le cas échéant
 	var commentData struct {
-		Content string `json:"content" binding:"required"`
+		Content  string  `json:"content" binding:"required"`
+		ParentID *string `json:"parentId"`
 	}
 
 	if err := c.BindJSON(&commentData); err != nil {
@@ -284,8 +218,35 @@ func CreateComment(c *gin.Context) {
 		Content: commentData.Content,
 	}
 
-	// Enregistrer dans la base de données
-	if err := db.DB.Create(&comment).Error; err != nil {
+	// Une réponse doit porter sur un commentaire du même post
+	if commentData.ParentID != nil {
+		var parent models.Comment
+		if err := db.DB.Where("id = ? AND post_id = ? AND deleted_at IS NULL", *commentData.ParentID, postID).First(&parent).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.LogError(err, "Parent comment not found in CreateComment")
+				c.JSON(http.StatusNotFound, gin.H{"error": "Parent comment not found"})
+				return
+			}
+			utils.LogError(err, "Failed to retrieve parent comment in CreateComment")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save comment"})
+			return
+		}
+		comment.ParentID = &parent.ID
+	}
+
+	// Enregistrer dans la base de données, avec le nombre de réponses du parent
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		if comment.ParentID == nil {
+			return nil
+		}
+		return tx.Model(&models.Comment{}).
+			Where("id = ?", *comment.ParentID).
+			Update("comments_count", gorm.Expr("comments_count + ?", 1)).Error
+	})
+	if err != nil {
 		utils.LogError(err, "Failed to save comment in CreateComment")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save comment"})
 		return
@@ -293,27 +254,21 @@ func CreateComment(c *gin.Context) {
 
 	// 	// Récupérer le nombre de commentaires pour le post
 	var count int64
-	if err := db.DB.Model(&models.Comment{}).Where("post_id = ?", postID).Count(&count).Error; err != nil {
+	if err := db.DB.Model(&models.Comment{}).Where("post_id = ? AND deleted_at IS NULL", postID).Count(&count).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count comments"})
 		return
 	}
-	comment.CommentsCount = int(count)
 
-	// Récupérer le nom d'utilisateur
-	var user models.User
-	db.DB.Select("user_name").Where("id = ?", userID).First(&user)
 	// Créer la réponse SSE
-	sseComment := SSEComment{
-		ID:            comment.ID,
-		PostID:        comment.PostID,
-		UserID:        comment.UserID,
-		Content:       comment.Content,
-		UserName:      user.UserName,
-		CreatedAt:     comment.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		CommentsCount: comment.CommentsCount,
-	}
+	sseComment := toSSEComment(comment)
+	sseComment.CommentsCount = int(count)
+
 	// Diffuser à tous les clients connectés pour ce post
-	broadcastComment(postID, sseComment)
+	if comment.ParentID != nil {
+		broadcastReply(postID, sseComment)
+	} else {
+		broadcastComment(postID, sseComment)
+	}
 
 	userID, exists = c.Get("user_id")
 	if !exists {
@@ -325,38 +280,23 @@ func CreateComment(c *gin.Context) {
 
 // Diffuser un commentaire à tous les clients connectés pour un post spécifique
 func broadcastComment(postID string, comment SSEComment) {
-	msg := SSEMessage{
+	broadcast(postID, "comment", SSEMessage{
 		Type:    "new_comment",
 		Payload: comment,
-	}
-
-	// Sérialiser le message en JSON
-	jsonData, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("Error marshaling SSE message: %v", err)
-		return
-	}
-
-	// Construire le message SSE
-	sseData := fmt.Sprintf("event: comment\ndata: %s\n\n", jsonData)
-
-	clientsMutex.RLock()
-	defer clientsMutex.RUnlock()
+	})
+}
 
-	// Si aucun client n'est connecté pour ce post, on sort
-	if _, exists := clients[postID]; !exists {
-		return
-	}
+// Diffuser une réponse sous son propre type d'événement : le client l'imbrique sous parentId
+func broadcastReply(postID string, reply SSEComment) {
+	broadcast(postID, "reply", SSEMessage{
+		Type:    "new_reply",
+		Payload: reply,
+	})
+}
 
-	// Envoyer le message à tous les clients connectés pour ce post
-	for clientChan := range clients[postID] {
-		select {
-		case clientChan <- sseData:
-		default:
-			log.Printf("Error broadcasting comment: channel full or closed")
-			clientsMutex.Lock()
-			delete(clients[postID], clientChan)
-			clientsMutex.Unlock()
-		}
+// broadcast publie l'événement sur le flux du post, pour les clients de toutes les instances
+func broadcast(postID string, event string, msg SSEMessage) {
+	if err := realtime.PublishJSON(realtime.PostTopic(postID), event, msg); err != nil {
+		utils.LogError(err, "Error publishing "+event+" event in broadcast")
 	}
 }
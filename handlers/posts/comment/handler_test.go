@@ -0,0 +1,313 @@
+package comment
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"pec2-backend/models"
+	"pec2-backend/realtime"
+	"pec2-backend/testutils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	testutils.InitTestMain()
+
+	log.SetOutput(io.Discard)
+
+	exitCode := m.Run()
+
+	log.SetOutput(os.Stdout)
+
+	os.Exit(exitCode)
+}
+
+// Test qu'une réponse incrémente le nombre de réponses du parent et est diffusée comme une réponse
+func TestCreateComment_Reply(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	postID := "post-uuid"
+	parentID := "parent-uuid"
+	userID := "user-uuid"
+
+	mock.ExpectQuery(`SELECT \* FROM "comments" WHERE id = \$1 AND post_id = \$2 AND deleted_at IS NULL ORDER BY "comments"."id" LIMIT \$3`).
+		WithArgs(parentID, postID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "post_id", "user_id", "content", "comments_count"}).AddRow(parentID, postID, "author-uuid", "Premier !", 2))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "comments" \("post_id","user_id","parent_id","content","comments_count","edited","edited_at","created_at","deleted_at"\) VALUES`).
+		WithArgs(postID, userID, parentID, "Bien vu", 0, false, nil, sqlmock.AnyArg(), nil).
+		WillReturnRows(mock.NewRows([]string{"id", "comments_count", "edited"}).AddRow("reply-uuid", 0, false))
+	mock.ExpectExec(`UPDATE "comments" SET "comments_count"=comments_count \+ \$1 WHERE id = \$2`).
+		WithArgs(1, parentID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "comments" WHERE post_id = \$1 AND deleted_at IS NULL`).
+		WithArgs(postID).
+		WillReturnRows(mock.NewRows([]string{"count"}).AddRow(4))
+	mock.ExpectQuery(`SELECT "user_name" FROM "users" WHERE id = \$1`).
+		WithArgs(userID, 1).
+		WillReturnRows(mock.NewRows([]string{"user_name"}).AddRow("fan"))
+
+	// Un client connecté au SSE du post reçoit l'événement reply
+	messages := connectClient(t, postID)
+
+	r := testutils.SetupTestRouter()
+	r.POST("/posts/:id/comments", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		CreateComment(c)
+	})
+
+	body := `{"content":"Bien vu","parentId":"` + parentID + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/posts/"+postID+"/comments", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var response struct {
+		Comment SSEComment `json:"comment"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, parentID, *response.Comment.ParentID)
+	assert.Equal(t, 4, response.Comment.CommentsCount)
+
+	select {
+	case event := <-messages:
+		assert.Equal(t, "reply", event.Name)
+		assert.Contains(t, string(event.Data), `"type":"new_reply"`)
+	case <-time.After(time.Second):
+		t.Fatal("reply was not broadcast")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'on ne peut pas répondre à un commentaire d'un autre post
+func TestCreateComment_ParentNotFound(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT \* FROM "comments" WHERE id = \$1 AND post_id = \$2`).
+		WithArgs("other-post-comment", "post-uuid", 1).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+
+	r := testutils.SetupTestRouter()
+	r.POST("/posts/:id/comments", func(c *gin.Context) {
+		c.Set("user_id", "user-uuid")
+		CreateComment(c)
+	})
+
+	body := `{"content":"Bien vu","parentId":"other-post-comment"}`
+	req := httptest.NewRequest(http.MethodPost, "/posts/post-uuid/comments", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test que la liste par défaut ne contient que les commentaires de premier niveau avec leur nombre de réponses
+func TestGetCommentsByPostID_TopLevel(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT \* FROM "comments" WHERE post_id = \$1 AND parent_id IS NULL AND \(deleted_at IS NULL OR comments_count > 0\) ORDER BY created_at ASC`).
+		WithArgs("post-uuid").
+		WillReturnRows(mock.NewRows([]string{"id", "post_id", "user_id", "content", "comments_count", "created_at"}).
+			AddRow("comment-uuid", "post-uuid", "author-uuid", "Premier !", 3, time.Now()))
+	mock.ExpectQuery(`SELECT "user_name" FROM "users" WHERE id = \$1`).
+		WithArgs("author-uuid", 1).
+		WillReturnRows(mock.NewRows([]string{"user_name"}).AddRow("author"))
+
+	r := testutils.SetupTestRouter()
+	r.GET("/posts/:id/comments", func(c *gin.Context) {
+		c.Set("user_id", "user-uuid")
+		GetCommentsByPostID(c)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/post-uuid/comments", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Comments []SSEComment `json:"comments"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Comments, 1)
+	assert.Nil(t, response.Comments[0].ParentID)
+	assert.Equal(t, 3, response.Comments[0].RepliesCount)
+	assert.Equal(t, "author", response.Comments[0].UserName)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un parent_id qui n'est pas un UUID est refusé sans interroger la base
+func TestGetCommentsByPostID_InvalidParentID(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	r := testutils.SetupTestRouter()
+	r.GET("/posts/:id/comments", GetCommentsByPostID)
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/post-uuid/comments?parent_id=not-a-uuid", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid parent comment ID")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// connectClient simule un client connecté au SSE du post, sans les mises à jour de présence
+func connectClient(t *testing.T, postID string) <-chan realtime.Event {
+	client, err := realtime.Default.Subscribe(realtime.PostTopic(postID), 0)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		realtime.Default.Unsubscribe(client)
+	})
+
+	events := make(chan realtime.Event, 8)
+	go func() {
+		for {
+			select {
+			case event := <-client.Events():
+				if event.Name != realtime.PresenceEvent {
+					events <- event
+				}
+			case <-client.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
+
+func expectComment(mock sqlmock.Sqlmock, commentID string, postID string, authorID string, parentID any) {
+	mock.ExpectQuery(`SELECT \* FROM "comments" WHERE id = \$1 AND post_id = \$2 AND deleted_at IS NULL`).
+		WithArgs(commentID, postID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "post_id", "user_id", "parent_id", "content", "comments_count", "created_at"}).
+			AddRow(commentID, postID, authorID, parentID, "Premier !", 0, time.Now()))
+}
+
+// Test que l'auteur modifie son commentaire : l'ancienne version est historisée et la modification diffusée
+func TestUpdateComment_Author(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	expectComment(mock, "comment-uuid", "post-uuid", "author-uuid", nil)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "comment_edits" \("comment_id","content","created_at"\) VALUES`).
+		WithArgs("comment-uuid", "Premier !", sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("edit-uuid"))
+	mock.ExpectExec(`UPDATE "comments" SET "content"=\$1,"edited"=\$2,"edited_at"=\$3 WHERE "id" = \$4`).
+		WithArgs("Deuxième !", true, sqlmock.AnyArg(), "comment-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT "user_name" FROM "users" WHERE id = \$1`).
+		WillReturnRows(mock.NewRows([]string{"user_name"}).AddRow("author"))
+
+	messages := connectClient(t, "post-uuid")
+
+	r := testutils.SetupTestRouter()
+	r.PUT("/posts/:id/comments/:commentId", func(c *gin.Context) {
+		c.Set("user_id", "author-uuid")
+		UpdateComment(c)
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/posts/post-uuid/comments/comment-uuid", strings.NewReader(`{"content":"Deuxième !"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Comment SSEComment `json:"comment"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Deuxième !", response.Comment.Content)
+	assert.True(t, response.Comment.Edited)
+
+	event := <-messages
+	assert.Equal(t, "comment_updated", event.Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test que le propriétaire du post peut retirer la réponse d'un autre utilisateur
+func TestDeleteComment_PostOwner(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	expectComment(mock, "reply-uuid", "post-uuid", "author-uuid", "parent-uuid")
+	mock.ExpectQuery(`SELECT "user_id" FROM "posts" WHERE id = \$1`).
+		WithArgs("post-uuid", 1).
+		WillReturnRows(mock.NewRows([]string{"user_id"}).AddRow("owner-uuid"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "comments" SET "deleted_at"=\$1 WHERE "id" = \$2`).
+		WithArgs(sqlmock.AnyArg(), "reply-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "comments" SET "comments_count"=GREATEST\(comments_count - \$1, 0\) WHERE id = \$2`).
+		WithArgs(1, "parent-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT "user_name" FROM "users" WHERE id = \$1`).
+		WillReturnRows(mock.NewRows([]string{"user_name"}).AddRow("author"))
+
+	messages := connectClient(t, "post-uuid")
+
+	r := testutils.SetupTestRouter()
+	r.DELETE("/posts/:id/comments/:commentId", func(c *gin.Context) {
+		c.Set("user_id", "owner-uuid")
+		c.Set("role", string(models.ContentCreator))
+		DeleteComment(c)
+	})
+
+	req := httptest.NewRequest(http.MethodDelete, "/posts/post-uuid/comments/reply-uuid", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	event := <-messages
+	assert.Equal(t, "comment_deleted", event.Name)
+	assert.Contains(t, string(event.Data), `"deleted":true`)
+	assert.Contains(t, string(event.Data), `"content":""`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un autre utilisateur ne peut pas supprimer le commentaire
+func TestDeleteComment_Forbidden(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	expectComment(mock, "comment-uuid", "post-uuid", "author-uuid", nil)
+	mock.ExpectQuery(`SELECT "user_id" FROM "posts" WHERE id = \$1`).
+		WithArgs("post-uuid", 1).
+		WillReturnRows(mock.NewRows([]string{"user_id"}).AddRow("owner-uuid"))
+
+	r := testutils.SetupTestRouter()
+	r.DELETE("/posts/:id/comments/:commentId", func(c *gin.Context) {
+		c.Set("user_id", "stranger-uuid")
+		c.Set("role", string(models.UserRole))
+		DeleteComment(c)
+	})
+
+	req := httptest.NewRequest(http.MethodDelete, "/posts/post-uuid/comments/comment-uuid", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
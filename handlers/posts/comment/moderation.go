@@ -0,0 +1,205 @@
+package comment
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// findComment récupère un commentaire non supprimé du post, et répond 404 s'il n'existe pas
+func findComment(c *gin.Context, handlerName string) (models.Comment, bool) {
+	var comment models.Comment
+	err := db.DB.Where("id = ? AND post_id = ? AND deleted_at IS NULL", c.Param("commentId"), c.Param("id")).First(&comment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.LogError(err, "Comment not found in "+handlerName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return models.Comment{}, false
+		}
+		utils.LogError(err, "Failed to retrieve comment in "+handlerName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comment"})
+		return models.Comment{}, false
+	}
+	return comment, true
+}
+
+// @Summary Edit a comment
+// @Description Edit a comment (author only). The previous content is kept in the edit history and the comment is flagged as edited. Broadcast as a "comment_updated" SSE event
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param commentId path string true "Comment ID"
+// @Param comment body models.CommentUpdate true "New content"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "comment: edited comment"
+// @Failure 400 {object} map[string]string "error: Invalid comment data"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 403 {object} map[string]string "error: Only the author can edit this comment"
+// @Failure 404 {object} map[string]string "error: Comment not found"
+// @Failure 500 {object} map[string]string "error: Server error"
+// @Router /posts/{id}/comments/{commentId} [put]
+func UpdateComment(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(nil, "User not found in token in UpdateComment")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	var input models.CommentUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.LogErrorWithUser(userID, err, "Invalid comment data in UpdateComment")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment data"})
+		return
+	}
+
+	comment, ok := findComment(c, "UpdateComment")
+	if !ok {
+		return
+	}
+
+	if comment.UserID != userID {
+		utils.LogErrorWithUser(userID, errors.New("pas l'auteur"), "Not the author of the comment in UpdateComment")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can edit this comment"})
+		return
+	}
+
+	// Sans changement, rien n'est ajouté à l'historique
+	if input.Content != comment.Content {
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&models.CommentEdit{CommentID: comment.ID, Content: comment.Content}).Error; err != nil {
+				return err
+			}
+			return tx.Model(&comment).Updates(map[string]interface{}{
+				"content":   input.Content,
+				"edited":    true,
+				"edited_at": time.Now(),
+			}).Error
+		})
+		if err != nil {
+			utils.LogErrorWithUser(userID, err, "Failed to edit comment in UpdateComment")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit comment"})
+			return
+		}
+	}
+
+	sseComment := toSSEComment(comment)
+	broadcast(comment.PostID, "comment_updated", SSEMessage{
+		Type:    "comment_updated",
+		Payload: sseComment,
+	})
+
+	utils.LogSuccessWithUser(userID, "Comment edited successfully in UpdateComment")
+	c.JSON(http.StatusOK, gin.H{"comment": sseComment})
+}
+
+// @Summary Delete a comment
+// @Description Delete a comment. Authors can delete their comments, post owners and admins can remove any comment on the post. A deleted comment with replies stays in the thread without its content. Broadcast as a "comment_deleted" SSE event
+// @Tags comments
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param commentId path string true "Comment ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "message: Comment deleted"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 403 {object} map[string]string "error: Not authorized to delete this comment"
+// @Failure 404 {object} map[string]string "error: Comment not found"
+// @Failure 500 {object} map[string]string "error: Server error"
+// @Router /posts/{id}/comments/{commentId} [delete]
+func DeleteComment(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(nil, "User not found in token in DeleteComment")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	comment, ok := findComment(c, "DeleteComment")
+	if !ok {
+		return
+	}
+
+	// Modération : le propriétaire du post et les admins peuvent retirer n'importe quel commentaire
+	role, _ := c.Get("role")
+	moderated := comment.UserID != userID
+	if moderated && role != string(models.AdminRole) {
+		var post models.Post
+		if err := db.DB.Select("user_id").Where("id = ?", comment.PostID).First(&post).Error; err != nil {
+			utils.LogErrorWithUser(userID, err, "Post not found in DeleteComment")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		if post.UserID != userID {
+			utils.LogErrorWithUser(userID, errors.New("ni auteur ni propriétaire"), "Not authorized to delete this comment in DeleteComment")
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to delete this comment"})
+			return
+		}
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&comment).Update("deleted_at", time.Now()).Error; err != nil {
+			return err
+		}
+		if comment.ParentID == nil {
+			return nil
+		}
+		return tx.Model(&models.Comment{}).
+			Where("id = ?", *comment.ParentID).
+			Update("comments_count", gorm.Expr("GREATEST(comments_count - ?, 0)", 1)).Error
+	})
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Failed to delete comment in DeleteComment")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+
+	broadcast(comment.PostID, "comment_deleted", SSEMessage{
+		Type:    "comment_deleted",
+		Payload: toSSEComment(comment),
+	})
+
+	if moderated {
+		utils.LogSuccessWithUser(userID, "Comment "+comment.ID+" removed by moderator in DeleteComment")
+	} else {
+		utils.LogSuccessWithUser(userID, "Comment deleted successfully in DeleteComment")
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
+}
+
+// @Summary Get the edit history of a comment
+// @Description Returns the previous versions of an edited comment, newest first
+// @Tags comments
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param commentId path string true "Comment ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "edits: previous versions of the comment"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 404 {object} map[string]string "error: Comment not found"
+// @Failure 500 {object} map[string]string "error: Server error"
+// @Router /posts/{id}/comments/{commentId}/history [get]
+func GetCommentHistory(c *gin.Context) {
+	comment, ok := findComment(c, "GetCommentHistory")
+	if !ok {
+		return
+	}
+
+	edits := []models.CommentEdit{}
+	if err := db.DB.Where("comment_id = ?", comment.ID).Order("created_at DESC").Find(&edits).Error; err != nil {
+		utils.LogError(err, "Failed to retrieve comment history in GetCommentHistory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comment history"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	utils.LogSuccessWithUser(userID, "Comment history retrieved successfully in GetCommentHistory")
+	c.JSON(http.StatusOK, gin.H{"edits": edits})
+}
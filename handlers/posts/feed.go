@@ -0,0 +1,97 @@
+package posts
+
+import (
+	"net/http"
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get the personalized feed
+// @Description Retrieve, newest first, the posts of the creators the user has an active subscription to, plus the free posts of the creators the user follows and the posts the user bought
+// @Tags posts
+// @Produce json
+// @Param limit query int false "Number of posts per page (default 20, max 100)"
+// @Param cursor query string false "Opaque cursor returned as nextCursor by the previous page"
+// @Security BearerAuth
+// @Success 200 {object} models.PostPage
+// @Failure 400 {object} map[string]string "error: Invalid query parameter"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /feed [get]
+func GetFeed(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(nil, "User not found in token in GetFeed")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	limit, err := parsePostsLimit(c)
+	if err != nil {
+		utils.LogError(err, "Invalid limit in GetFeed")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscribedCreators := db.DB.Model(&models.Subscription{}).
+		Select("content_creator_id").
+		Where("user_id = ? AND status = ?", userID, models.SubscriptionActive).
+		Where("end_date IS NULL OR end_date > ?", time.Now())
+	followedCreators := db.DB.Model(&models.Follow{}).
+		Select("creator_id").
+		Where("follower_id = ?", userID)
+	purchasedPosts := db.DB.Model(&models.PostPurchase{}).
+		Select("post_id").
+		Where("buyer_id = ? AND status = ?", userID, models.PostPurchaseSucceeded)
+
+	query := db.DB.Preload("Categories").Preload("User").
+		Where("posts.enable = ?", true).
+		Where(db.DB.Where("posts.user_id IN (?)", subscribedCreators).
+			Or("posts.is_free = ? AND posts.user_id IN (?)", true, followedCreators).
+			Or("posts.id IN (?)", purchasedPosts)).
+		Order("posts.created_at DESC, posts.id DESC").
+		Limit(limit + 1)
+
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cursor, err := decodePostCursor(cursorStr)
+		if err != nil {
+			utils.LogError(err, "Invalid cursor in GetFeed")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		query = query.Where("(posts.created_at, posts.id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+
+	var posts []models.Post
+	if err := query.Find(&posts).Error; err != nil {
+		utils.LogError(err, "Error retrieving feed in GetFeed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving feed: " + err.Error()})
+		return
+	}
+
+	posts, nextCursor := paginatePosts(posts, limit)
+
+	viewer, err := getPostViewer(c)
+	if err != nil {
+		utils.LogError(err, "Error retrieving subscriptions in GetFeed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving subscriptions: " + err.Error()})
+		return
+	}
+
+	response, err := buildPostResponses(posts, viewer)
+	if err != nil {
+		utils.LogError(err, "Error counting interactions in GetFeed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error counting interactions: " + err.Error()})
+		return
+	}
+
+	utils.LogSuccessWithUser(userID, "Feed retrieved successfully in GetFeed")
+	c.JSON(http.StatusOK, models.PostPage{
+		Posts:      response,
+		NextCursor: nextCursor,
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"pec2-backend/db"
 	"pec2-backend/models"
 	"pec2-backend/utils"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -19,6 +20,7 @@ import (
 // @Produce json
 // @Param name formData string true "Post name"
 // @Param isFree formData boolean false "Is the post free"
+// @Param price formData int false "Pay-per-view price in cents (0 = included in the subscription)"
 // @Param enable formData boolean false "Is the post enabled"
 // @Param categories formData []string false "Category IDs"
 // @Param file formData file false "Post picture"
@@ -55,6 +57,18 @@ func CreatePost(c *gin.Context) {
 
 	}
 
+	price, err := parsePostPrice(c.Request.FormValue("price"))
+	if err != nil {
+		utils.LogError(err, "Invalid price in CreatePost")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if price > 0 && isFree {
+		utils.LogError(nil, "A free post cannot have a price in CreatePost")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A free post cannot have a price"})
+		return
+	}
+
 	categoryIDs := c.PostFormArray("categories")
 	if len(categoryIDs) == 0 {
 		categoriesStr := c.Request.FormValue("categories")
@@ -71,6 +85,7 @@ func CreatePost(c *gin.Context) {
 		UserID: userID.(string),
 		Name:   name,
 		IsFree: isFree,
+		Price:  price,
 		Enable: true,
 	}
 
@@ -125,21 +140,35 @@ func CreatePost(c *gin.Context) {
 }
 
 // @Summary Get all posts
-// @Description Retrieve all posts with optional filtering
+// @Description Retrieve posts newest first with keyset pagination and optional filtering. Paid posts are returned as locked teasers (without pictureUrl) unless the caller is the author, an admin or has an active subscription to the author
 // @Tags posts
 // @Produce json
+// @Security BearerAuth
 // @Param isFree query boolean false "Filter by free posts"
 // @Param category query string false "Filter by category ID"
-// @Success 200 {array} models.PostResponse
+// @Param author query string false "Filter by author user ID"
+// @Param from query string false "Only posts created on or after this date (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "Only posts created before this date: a YYYY-MM-DD date includes the whole day, an RFC3339 timestamp is exclusive"
+// @Param limit query int false "Number of posts per page (default 20, max 100)"
+// @Param cursor query string false "Opaque cursor returned as nextCursor by the previous page"
+// @Success 200 {object} models.PostPage
+// @Failure 400 {object} map[string]string "error: Invalid query parameter"
 // @Failure 500 {object} map[string]string "error: Error message"
 // @Router /posts [get]
 func GetAllPosts(c *gin.Context) {
+	limit, err := parsePostsLimit(c)
+	if err != nil {
+		utils.LogError(err, "Invalid limit in GetAllPosts")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var posts []models.Post
-	query := db.DB.Preload("Categories").Order("created_at DESC")
+	query := db.DB.Preload("Categories").Order("posts.created_at DESC, posts.id DESC").Limit(limit + 1)
 
 	// Filtre pour les posts gratuits/payants
 	if isFree := c.Query("isFree"); isFree != "" {
-		query = query.Where("is_free = ?", isFree == "true")
+		query = query.Where("posts.is_free = ?", isFree == "true")
 	}
 
 	// Afficher le user qui a créé le post
@@ -151,46 +180,64 @@ func GetAllPosts(c *gin.Context) {
 			Where("post_categories.category_id = ?", categoryID)
 	}
 
+	// Filtre par auteur
+	if authorID := c.Query("author"); authorID != "" {
+		query = query.Where("posts.user_id = ?", authorID)
+	}
+
+	// Filtre par période
+	if fromStr := c.Query("from"); fromStr != "" {
+		from, err := parseDateParam(fromStr, false)
+		if err != nil {
+			utils.LogError(err, "Invalid from date in GetAllPosts")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date (YYYY-MM-DD or RFC3339)"})
+			return
+		}
+		query = query.Where("posts.created_at >= ?", from)
+	}
+	if toStr := c.Query("to"); toStr != "" {
+		to, err := parseDateParam(toStr, true)
+		if err != nil {
+			utils.LogError(err, "Invalid to date in GetAllPosts")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date (YYYY-MM-DD or RFC3339)"})
+			return
+		}
+		// Borne exclusive : une date simple a déjà été décalée au jour suivant
+		query = query.Where("posts.created_at < ?", to)
+	}
+
+	// Reprendre après le dernier post de la page précédente
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cursor, err := decodePostCursor(cursorStr)
+		if err != nil {
+			utils.LogError(err, "Invalid cursor in GetAllPosts")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		query = query.Where("(posts.created_at, posts.id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+
 	if err := query.Find(&posts).Error; err != nil {
 		utils.LogError(err, "Error retrieving posts in GetAllPosts")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving posts: " + err.Error()})
 		return
 	}
-	var response []models.PostResponse = make([]models.PostResponse, 0, len(posts))
-	for _, post := range posts {
-		// Compter le nombre de likes
-		var likesCount int64
-		db.DB.Model(&models.Like{}).Where("post_id = ?", post.ID).Count(&likesCount)
-		fmt.Println("Likes count for post ID", post.ID, ":", likesCount)
-		// Compter le nombre de commentaires
-		var commentsCount int64
-		db.DB.Model(&models.Comment{}).Where("post_id = ?", post.ID).Count(&commentsCount)
-
-		// Compter le nombre de reports
-		var reportsCount int64
-		db.DB.Model(&models.Report{}).Where("post_id = ?", post.ID).Count(&reportsCount)
-
-		// Créer la réponse pour ce post
-		postResponse := models.PostResponse{
-			ID:         post.ID,
-			Name:       post.Name,
-			PictureURL: post.PictureURL,
-			IsFree:     post.IsFree,
-			Enable:     post.Enable,
-			Categories: post.Categories,
-			CreatedAt:  post.CreatedAt,
-			UpdatedAt:  post.UpdatedAt,
-			User: models.UserInfo{
-				ID:             post.User.ID,
-				UserName:       post.User.UserName,
-				ProfilePicture: post.User.ProfilePicture,
-			},
-			LikesCount:    int(likesCount),
-			CommentsCount: int(commentsCount),
-			ReportsCount:  int(reportsCount),
-		}
 
-		response = append(response, postResponse)
+	posts, nextCursor := paginatePosts(posts, limit)
+
+	viewer, err := getPostViewer(c)
+	if err != nil {
+		utils.LogError(err, "Error retrieving subscriptions in GetAllPosts")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving subscriptions: " + err.Error()})
+		return
+	}
+
+	// Les réponses sont verrouillées si le viewer n'a pas accès au post
+	response, err := buildPostResponses(posts, viewer)
+	if err != nil {
+		utils.LogError(err, "Error counting interactions in GetAllPosts")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error counting interactions: " + err.Error()})
+		return
 	}
 
 	userID, exists := c.Get("user_id")
@@ -198,14 +245,17 @@ func GetAllPosts(c *gin.Context) {
 		userID = "0"
 	}
 	utils.LogSuccessWithUser(userID, "Posts retrieved successfully in GetAllPosts")
-	utils.LogSuccess("Posts retrieved successfully in GetAllPosts")
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, models.PostPage{
+		Posts:      response,
+		NextCursor: nextCursor,
+	})
 }
 
 // @Summary Get a post by ID
-// @Description Retrieve a post by its ID
+// @Description Retrieve a post by its ID. A paid post is returned as a locked teaser (without pictureUrl) unless the caller is the author, an admin or has an active subscription to the author
 // @Tags posts
 // @Produce json
+// @Security BearerAuth
 // @Param id path string true "Post ID"
 // @Success 200 {object} models.PostResponse
 // @Failure 404 {object} map[string]string "error: Post not found"
@@ -221,40 +271,23 @@ func GetPostByID(c *gin.Context) {
 		return
 	}
 
-	// Compter le nombre de likes
-	var likesCount int64
-	db.DB.Model(&models.Like{}).Where("post_id = ?", post.ID).Count(&likesCount)
-
-	// Compter le nombre de commentaires
-	var commentsCount int64
-	db.DB.Model(&models.Comment{}).Where("post_id = ?", post.ID).Count(&commentsCount)
-
-	// Compter le nombre de reports
-	var reportsCount int64
-	db.DB.Model(&models.Report{}).Where("post_id = ?", post.ID).Count(&reportsCount)
-
-	// Créer la réponse pour ce post
-	postResponse := models.PostResponse{
-		ID:         post.ID,
-		Name:       post.Name,
-		PictureURL: post.PictureURL,
-		IsFree:     post.IsFree,
-		Enable:     post.Enable,
-		Categories: post.Categories,
-		CreatedAt:  post.CreatedAt,
-		UpdatedAt:  post.UpdatedAt,
-		User: models.UserInfo{
-			ID:             post.User.ID,
-			UserName:       post.User.UserName,
-			ProfilePicture: post.User.ProfilePicture,
-		},
-		LikesCount:    int(likesCount),
-		CommentsCount: int(commentsCount),
-		ReportsCount:  int(reportsCount),
+	viewer, err := getPostViewer(c)
+	if err != nil {
+		utils.LogError(err, "Error retrieving subscriptions in GetPostByID")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving subscriptions: " + err.Error()})
+		return
+	}
+
+	// Créer la réponse pour ce post (verrouillée si le viewer n'y a pas accès)
+	response, err := buildPostResponses([]models.Post{post}, viewer)
+	if err != nil {
+		utils.LogError(err, "Error counting interactions in GetPostByID")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error counting interactions: " + err.Error()})
+		return
 	}
 
 	utils.LogSuccess("Post retrieved successfully in GetPostByID")
-	c.JSON(http.StatusOK, postResponse)
+	c.JSON(http.StatusOK, response[0])
 }
 
 // @Summary Update a post
@@ -265,6 +298,7 @@ func GetPostByID(c *gin.Context) {
 // @Param id path string true "Post ID"
 // @Param name formData string false "Post name"
 // @Param isFree formData boolean false "Is the post free"
+// @Param price formData int false "Pay-per-view price in cents (0 = included in the subscription)"
 // @Param enable formData boolean false "Is the post enabled"
 // @Param categories formData []string false "Category IDs"
 // @Param file formData file false "Post picture"
@@ -317,6 +351,21 @@ func UpdatePost(c *gin.Context) {
 		post.Enable = enableStr == "true"
 	}
 
+	if priceStr := c.Request.FormValue("price"); priceStr != "" {
+		price, err := parsePostPrice(priceStr)
+		if err != nil {
+			utils.LogError(err, "Invalid price in UpdatePost")
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		post.Price = price
+	}
+	if post.IsFree && post.Price > 0 {
+		utils.LogError(nil, "A free post cannot have a price in UpdatePost")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A free post cannot have a price"})
+		return
+	}
+
 	file, err := c.FormFile("file")
 	if err == nil && file != nil {
 		if post.PictureURL != "" {
@@ -421,3 +470,18 @@ func DeletePost(c *gin.Context) {
 	utils.LogSuccess("Post deleted successfully in DeletePost")
 	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
 }
+
+// parsePostPrice lit le prix à l'unité d'un post en centimes : 0 (inclus dans l'abonnement) ou entre 1 et 500 euros
+func parsePostPrice(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	price, err := strconv.Atoi(value)
+	if err != nil || price < 0 {
+		return 0, fmt.Errorf("price must be a positive number of cents")
+	}
+	if price > 0 && (price < 100 || price > 50000) {
+		return 0, fmt.Errorf("price must be between 100 and 50000 cents")
+	}
+	return price, nil
+}
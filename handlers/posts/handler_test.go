@@ -1 +1,314 @@
-package posts
\ No newline at end of file
+package posts
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"pec2-backend/models"
+	"pec2-backend/testutils"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	testutils.InitTestMain()
+
+	log.SetOutput(io.Discard)
+
+	exitCode := m.Run()
+
+	log.SetOutput(os.Stdout)
+
+	os.Exit(exitCode)
+}
+
+// expectPaidPost prépare les requêtes de GetPostByID pour un post payant sans catégorie
+func expectPaidPost(mock sqlmock.Sqlmock, postID string, authorID string) {
+	now := time.Now()
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE id = \$1 ORDER BY "posts"."id" LIMIT \$2`).
+		WithArgs(postID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_id", "name", "picture_url", "is_free", "enable", "created_at", "updated_at"}).
+			AddRow(postID, authorID, "Paid Post", "http://example.com/image.jpg", false, true, now, now))
+	mock.ExpectQuery(`SELECT \* FROM "post_categories" WHERE "post_categories"."post_id" = \$1`).
+		WithArgs(postID).
+		WillReturnRows(mock.NewRows([]string{"post_id", "category_id"}))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1`).
+		WithArgs(authorID).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name"}).AddRow(authorID, "creator"))
+	mock.ExpectQuery(`SELECT post_id,(.+)FROM likes WHERE post_id IN \(\$1\)(.+)GROUP BY post_id`).
+		WithArgs(postID, postID, postID).
+		WillReturnRows(mock.NewRows([]string{"post_id", "likes_count", "comments_count", "reports_count"}).
+			AddRow(postID, 2, 1, 0))
+}
+
+func expectPurchasedPosts(mock sqlmock.Sqlmock, userID string, postIDs ...string) {
+	rows := mock.NewRows([]string{"post_id"})
+	for _, postID := range postIDs {
+		rows.AddRow(postID)
+	}
+	mock.ExpectQuery(`SELECT "post_id" FROM "post_purchases" WHERE buyer_id = \$1 AND status = \$2`).
+		WithArgs(userID, models.PostPurchaseSucceeded).
+		WillReturnRows(rows)
+}
+
+func TestGetPostByID_PaidPostLockedForAnonymous(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	mock.MatchExpectationsInOrder(false)
+
+	expectPaidPost(mock, "post-uuid", "author-uuid")
+
+	r := testutils.SetupTestRouter()
+	r.GET("/posts/:id", GetPostByID)
+
+	req, _ := http.NewRequest(http.MethodGet, "/posts/post-uuid", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response models.PostResponse
+	json.Unmarshal(resp.Body.Bytes(), &response)
+	assert.True(t, response.IsLocked)
+	assert.Empty(t, response.PictureURL)
+	assert.Equal(t, "Paid Post", response.Name)
+	assert.Equal(t, 2, response.LikesCount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetPostByID_PaidPostUnlockedForSubscriber(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	mock.MatchExpectationsInOrder(false)
+
+	expectPaidPost(mock, "post-uuid", "author-uuid")
+	mock.ExpectQuery(`SELECT "content_creator_id" FROM "subscriptions" WHERE \(user_id = \$1 AND status = \$2\) AND \(end_date IS NULL OR end_date > \$3\)`).
+		WithArgs("subscriber-uuid", models.SubscriptionActive, sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"content_creator_id"}).AddRow("author-uuid"))
+	expectPurchasedPosts(mock, "subscriber-uuid")
+	mock.ExpectQuery(`SELECT "post_id" FROM "likes" WHERE user_id = \$1 AND post_id IN \(\$2\)`).
+		WithArgs("subscriber-uuid", "post-uuid").
+		WillReturnRows(mock.NewRows([]string{"post_id"}).AddRow("post-uuid"))
+
+	r := testutils.SetupTestRouter()
+	r.GET("/posts/:id", func(c *gin.Context) {
+		c.Set("user_id", "subscriber-uuid")
+		c.Set("role", "USER")
+		GetPostByID(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/posts/post-uuid", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response models.PostResponse
+	json.Unmarshal(resp.Body.Bytes(), &response)
+	assert.False(t, response.IsLocked)
+	assert.Equal(t, "http://example.com/image.jpg", response.PictureURL)
+	assert.True(t, response.IsLiked)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostViewerCanAccess(t *testing.T) {
+	paidPost := models.Post{UserID: "author-uuid", IsFree: false}
+	freePost := models.Post{UserID: "author-uuid", IsFree: true}
+
+	anonymous := postViewer{subscribedCreators: map[string]bool{}}
+	assert.True(t, anonymous.canAccess(freePost))
+	assert.False(t, anonymous.canAccess(paidPost))
+
+	author := postViewer{UserID: "author-uuid", Role: "CONTENT_CREATOR", subscribedCreators: map[string]bool{}}
+	assert.True(t, author.canAccess(paidPost))
+
+	admin := postViewer{UserID: "admin-uuid", Role: "ADMIN", subscribedCreators: map[string]bool{}}
+	assert.True(t, admin.canAccess(paidPost))
+
+	notSubscribed := postViewer{UserID: "user-uuid", Role: "USER", subscribedCreators: map[string]bool{"other-uuid": true}}
+	assert.False(t, notSubscribed.canAccess(paidPost))
+
+	subscribed := postViewer{UserID: "user-uuid", Role: "USER", subscribedCreators: map[string]bool{"author-uuid": true}}
+	assert.True(t, subscribed.canAccess(paidPost))
+
+	// Un post vendu à l'unité n'est pas inclus dans l'abonnement
+	ppvPost := models.Post{ID: "ppv-uuid", UserID: "author-uuid", Price: 499}
+	assert.False(t, subscribed.canAccess(ppvPost))
+	assert.True(t, author.canAccess(ppvPost))
+
+	buyer := postViewer{UserID: "user-uuid", Role: "USER", subscribedCreators: map[string]bool{}, purchasedPosts: map[string]bool{"ppv-uuid": true}}
+	assert.True(t, buyer.canAccess(ppvPost))
+	assert.False(t, buyer.canAccess(paidPost))
+}
+
+func TestPostCursor_RoundTrip(t *testing.T) {
+	createdAt := time.Date(2025, 6, 1, 12, 30, 0, 123456000, time.UTC)
+	cursor := encodePostCursor(models.Post{ID: "post-uuid", CreatedAt: createdAt})
+
+	decoded, err := decodePostCursor(cursor)
+	assert.NoError(t, err)
+	assert.Equal(t, "post-uuid", decoded.ID)
+	assert.True(t, createdAt.Equal(decoded.CreatedAt))
+}
+
+func TestPostCursor_Invalid(t *testing.T) {
+	for _, cursor := range []string{"not base64 !", "bm8tc2VwYXJhdG9y", "bm90LWEtZGF0ZXxwb3N0"} {
+		_, err := decodePostCursor(cursor)
+		assert.ErrorIs(t, err, errInvalidCursor, cursor)
+	}
+}
+
+// Test que la date de fin inclut tout le jour d'une date simple mais pas l'instant d'un timestamp RFC3339
+func TestParseDateParam_EndBound(t *testing.T) {
+	to, err := parseDateParam("2025-06-01", true)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC), to)
+
+	to, err = parseDateParam("2025-06-01T12:30:00Z", true)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC), to)
+
+	_, err = parseDateParam("01/06/2025", true)
+	assert.Error(t, err)
+}
+
+func TestGetAllPosts_ReturnsNextCursor(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	mock.MatchExpectationsInOrder(false)
+
+	newer := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	older := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE posts.user_id = \$1 ORDER BY posts.created_at DESC, posts.id DESC LIMIT \$2`).
+		WithArgs("author-uuid", 2).
+		WillReturnRows(mock.NewRows([]string{"id", "user_id", "name", "picture_url", "is_free", "enable", "created_at", "updated_at"}).
+			AddRow("post-2", "author-uuid", "Newer", "http://example.com/2.jpg", true, true, newer, newer).
+			AddRow("post-1", "author-uuid", "Older", "http://example.com/1.jpg", true, true, older, older))
+	mock.ExpectQuery(`SELECT \* FROM "post_categories" WHERE "post_categories"."post_id" IN \(\$1,\$2\)`).
+		WithArgs("post-2", "post-1").
+		WillReturnRows(mock.NewRows([]string{"post_id", "category_id"}))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1`).
+		WithArgs("author-uuid").
+		WillReturnRows(mock.NewRows([]string{"id", "user_name"}).AddRow("author-uuid", "creator"))
+	mock.ExpectQuery(`SELECT post_id,(.+)GROUP BY post_id`).
+		WithArgs("post-2", "post-2", "post-2").
+		WillReturnRows(mock.NewRows([]string{"post_id", "likes_count", "comments_count", "reports_count"}).
+			AddRow("post-2", 3, 0, 0))
+
+	r := testutils.SetupTestRouter()
+	r.GET("/posts", GetAllPosts)
+
+	req, _ := http.NewRequest(http.MethodGet, "/posts?limit=1&author=author-uuid", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var page models.PostPage
+	json.Unmarshal(resp.Body.Bytes(), &page)
+	assert.Len(t, page.Posts, 1)
+	assert.Equal(t, "post-2", page.Posts[0].ID)
+	assert.Equal(t, 3, page.Posts[0].LikesCount)
+	if assert.NotNil(t, page.NextCursor) {
+		cursor, err := decodePostCursor(*page.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, "post-2", cursor.ID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAllPosts_InvalidCursor(t *testing.T) {
+	_, _, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	r := testutils.SetupTestRouter()
+	r.GET("/posts", GetAllPosts)
+
+	req, _ := http.NewRequest(http.MethodGet, "/posts?cursor=invalid!", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestGetFeed_Unauthorized(t *testing.T) {
+	r := testutils.SetupTestRouter()
+	r.GET("/feed", GetFeed)
+
+	req, _ := http.NewRequest(http.MethodGet, "/feed", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestGetFeed_Success(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+	mock.MatchExpectationsInOrder(false)
+
+	userID := "subscriber-uuid"
+	createdAt := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE posts.enable = \$1 AND \(posts.user_id IN \(SELECT "content_creator_id" FROM "subscriptions" WHERE \(user_id = \$2 AND status = \$3\) AND \(end_date IS NULL OR end_date > \$4\)\) OR \(posts.is_free = \$5 AND posts.user_id IN \(SELECT "creator_id" FROM "follows" WHERE follower_id = \$6\)\) OR posts.id IN \(SELECT "post_id" FROM "post_purchases" WHERE buyer_id = \$7 AND status = \$8\)\) ORDER BY posts.created_at DESC, posts.id DESC LIMIT \$9`).
+		WithArgs(true, userID, models.SubscriptionActive, sqlmock.AnyArg(), true, userID, userID, models.PostPurchaseSucceeded, defaultPostsLimit+1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_id", "name", "picture_url", "is_free", "enable", "created_at", "updated_at"}).
+			AddRow("paid-post", "subscribed-creator", "Paid", "http://example.com/paid.jpg", false, true, createdAt, createdAt).
+			AddRow("free-post", "followed-creator", "Free", "http://example.com/free.jpg", true, true, createdAt.Add(-time.Hour), createdAt.Add(-time.Hour)))
+	mock.ExpectQuery(`SELECT \* FROM "post_categories" WHERE "post_categories"."post_id" IN \(\$1,\$2\)`).
+		WillReturnRows(mock.NewRows([]string{"post_id", "category_id"}))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" IN \(\$1,\$2\)`).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name"}).
+			AddRow("subscribed-creator", "subscribed").
+			AddRow("followed-creator", "followed"))
+	mock.ExpectQuery(`SELECT "content_creator_id" FROM "subscriptions" WHERE \(user_id = \$1 AND status = \$2\) AND \(end_date IS NULL OR end_date > \$3\)`).
+		WithArgs(userID, models.SubscriptionActive, sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"content_creator_id"}).AddRow("subscribed-creator"))
+	expectPurchasedPosts(mock, userID)
+	mock.ExpectQuery(`SELECT post_id,(.+)GROUP BY post_id`).
+		WillReturnRows(mock.NewRows([]string{"post_id", "likes_count", "comments_count", "reports_count"}).
+			AddRow("paid-post", 4, 2, 0))
+	mock.ExpectQuery(`SELECT "post_id" FROM "likes" WHERE user_id = \$1 AND post_id IN \(\$2,\$3\)`).
+		WithArgs(userID, "paid-post", "free-post").
+		WillReturnRows(mock.NewRows([]string{"post_id"}).AddRow("free-post"))
+
+	r := testutils.SetupTestRouter()
+	r.GET("/feed", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Set("role", "USER")
+		GetFeed(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/feed", nil)
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var page models.PostPage
+	json.Unmarshal(resp.Body.Bytes(), &page)
+	if assert.Len(t, page.Posts, 2) {
+		assert.Equal(t, "paid-post", page.Posts[0].ID)
+		assert.False(t, page.Posts[0].IsLocked)
+		assert.False(t, page.Posts[0].IsLiked)
+		assert.Equal(t, 4, page.Posts[0].LikesCount)
+		assert.Equal(t, "free-post", page.Posts[1].ID)
+		assert.True(t, page.Posts[1].IsLiked)
+	}
+	assert.Nil(t, page.NextCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -4,13 +4,14 @@ import (
 	"net/http"
 	"pec2-backend/db"
 	"pec2-backend/models"
+	"pec2-backend/realtime"
 	"pec2-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 // @Summary Toggle like on a post
-// @Description Add or remove a like on a post
+// @Description Add or remove a like on a post, the new likes count is broadcast to the viewers of the post as a "like" event
 // @Tags posts
 // @Produce json
 // @Param id path string true "Post ID"
@@ -52,6 +53,7 @@ func ToggleLike(c *gin.Context) {
 		// Récupérer le nombre actuel de likes pour ce post
 		var likesCount int64
 		db.DB.Model(&models.Like{}).Where("post_id = ?", postID).Count(&likesCount)
+		publishLikesCount(postID, likesCount)
 
 		utils.LogSuccessWithUser(userID, "Like removed successfully in ToggleLike")
 		c.JSON(http.StatusOK, gin.H{
@@ -77,6 +79,7 @@ func ToggleLike(c *gin.Context) {
 	// Récupérer le nombre actuel de likes pour ce post
 	var likesCount int64
 	db.DB.Model(&models.Like{}).Where("post_id = ?", postID).Count(&likesCount)
+	publishLikesCount(postID, likesCount)
 
 	utils.LogSuccessWithUser(userID, "Like added successfully in ToggleLike")
 	c.JSON(http.StatusOK, gin.H{
@@ -85,3 +88,14 @@ func ToggleLike(c *gin.Context) {
 		"likesCount": likesCount,
 	})
 }
+
+// publishLikesCount diffuse le nouveau nombre de likes sur le flux temps réel du post
+func publishLikesCount(postID string, likesCount int64) {
+	err := realtime.PublishJSON(realtime.PostTopic(postID), "like", gin.H{
+		"type":    "likes_count",
+		"payload": gin.H{"postId": postID, "likesCount": likesCount},
+	})
+	if err != nil {
+		utils.LogError(err, "Error publishing like event in ToggleLike")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"pec2-backend/realtime"
 	"pec2-backend/testutils"
 	"testing"
 	"time"
@@ -74,6 +75,11 @@ func TestToggleLike_Add(t *testing.T) {
 		ToggleLike(c)
 	})
 
+	// Un spectateur du post reçoit le nouveau nombre de likes en direct
+	client, err := realtime.Default.Subscribe(realtime.PostTopic(postID), 0)
+	assert.NoError(t, err)
+	defer realtime.Default.Unsubscribe(client)
+
 	req, _ := http.NewRequest(http.MethodPost, "/posts/"+postID+"/like", nil)
 	resp := httptest.NewRecorder()
 
@@ -85,6 +91,19 @@ func TestToggleLike_Add(t *testing.T) {
 	assert.Equal(t, "Like added successfully", response["message"])
 	assert.Equal(t, "added", response["action"])
 	assert.Equal(t, float64(1), response["likesCount"])
+
+	for {
+		select {
+		case event := <-client.Events():
+			if event.Name != "like" {
+				continue
+			}
+			assert.JSONEq(t, `{"type":"likes_count","payload":{"postId":"post-uuid","likesCount":1}}`, string(event.Data))
+		case <-time.After(time.Second):
+			t.Fatal("like was not broadcast")
+		}
+		break
+	}
 }
 
 // Test la suppression d'un like existant
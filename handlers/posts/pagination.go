@@ -0,0 +1,183 @@
+package posts
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"pec2-backend/db"
+	"pec2-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPostsLimit = 20
+	maxPostsLimit     = 100
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// postCursor représente la position dans le fil, triée par (created_at, id) décroissants
+type postCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// encodePostCursor rend le curseur opaque pour le client
+func encodePostCursor(post models.Post) string {
+	raw := post.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + post.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodePostCursor(cursor string) (postCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return postCursor{}, errInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return postCursor{}, errInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return postCursor{}, errInvalidCursor
+	}
+
+	return postCursor{CreatedAt: createdAt, ID: parts[1]}, nil
+}
+
+// parsePostsLimit lit le paramètre limit (défaut 20, max 100)
+func parsePostsLimit(c *gin.Context) (int, error) {
+	limitStr := c.Query("limit")
+	if limitStr == "" {
+		return defaultPostsLimit, nil
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+	if limit > maxPostsLimit {
+		limit = maxPostsLimit
+	}
+	return limit, nil
+}
+
+// parseDateParam accepte une date YYYY-MM-DD ou RFC3339 ; endOfDay décale une date simple au jour suivant
+func parseDateParam(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24 * time.Hour)
+	}
+	return t, nil
+}
+
+// paginatePosts coupe la page à limit et calcule le curseur suivant (nil s'il n'y a plus de posts)
+func paginatePosts(posts []models.Post, limit int) ([]models.Post, *string) {
+	if len(posts) <= limit {
+		return posts, nil
+	}
+
+	posts = posts[:limit]
+	nextCursor := encodePostCursor(posts[len(posts)-1])
+	return posts, &nextCursor
+}
+
+type postCounts struct {
+	PostID        string
+	LikesCount    int
+	CommentsCount int
+	ReportsCount  int
+}
+
+// loadPostCounts récupère en une seule requête agrégée les compteurs de likes, commentaires et reports
+func loadPostCounts(postIDs []string) (map[string]postCounts, error) {
+	counts := make(map[string]postCounts, len(postIDs))
+	if len(postIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []postCounts
+	err := db.DB.Raw(`
+		SELECT post_id,
+			COUNT(*) FILTER (WHERE kind = 'like') AS likes_count,
+			COUNT(*) FILTER (WHERE kind = 'comment') AS comments_count,
+			COUNT(*) FILTER (WHERE kind = 'report') AS reports_count
+		FROM (
+			SELECT post_id, 'like' AS kind FROM likes WHERE post_id IN ?
+			UNION ALL
+			SELECT post_id, 'comment' AS kind FROM comments WHERE post_id IN ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT post_id, 'report' AS kind FROM reports WHERE post_id IN ?
+		) AS interactions
+		GROUP BY post_id`, postIDs, postIDs, postIDs).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.PostID] = row
+	}
+	return counts, nil
+}
+
+// loadLikedPostIDs indique, parmi les posts donnés, ceux que l'utilisateur a likés
+func loadLikedPostIDs(userID string, postIDs []string) (map[string]bool, error) {
+	liked := make(map[string]bool)
+	if userID == "" || len(postIDs) == 0 {
+		return liked, nil
+	}
+
+	var likedIDs []string
+	if err := db.DB.Model(&models.Like{}).
+		Where("user_id = ? AND post_id IN ?", userID, postIDs).
+		Pluck("post_id", &likedIDs).Error; err != nil {
+		return nil, err
+	}
+
+	for _, postID := range likedIDs {
+		liked[postID] = true
+	}
+	return liked, nil
+}
+
+// buildPostResponses construit les réponses d'une page de posts avec leurs compteurs
+// et, si le viewer est connecté, l'indicateur isLiked
+func buildPostResponses(posts []models.Post, viewer postViewer) ([]models.PostResponse, error) {
+	postIDs := make([]string, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+
+	counts, err := loadPostCounts(postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	liked, err := loadLikedPostIDs(viewer.UserID, postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]models.PostResponse, 0, len(posts))
+	for _, post := range posts {
+		count := counts[post.ID]
+		postResponse := toPostResponse(post, viewer, count.LikesCount, count.CommentsCount, count.ReportsCount)
+		postResponse.IsLiked = liked[post.ID]
+		response = append(response, postResponse)
+	}
+	return response, nil
+}
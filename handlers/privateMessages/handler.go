@@ -4,14 +4,16 @@ import (
 	"net/http"
 	"pec2-backend/db"
 	"pec2-backend/models"
+	"pec2-backend/realtime"
 	"pec2-backend/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // @Summary Create a private message
-// @Description Send a private message from the authenticated user to another user
+// @Description Send a private message from the authenticated user to another user. If the receiver is connected to the WebSocket the message is pushed to them and delivered is true
 // @Tags private-messages
 // @Accept json
 // @Produce json
@@ -69,6 +71,16 @@ func CreatePrivateMessage(c *gin.Context) {
 		return
 	}
 
+	// Le destinataire connecté reçoit le message sans attendre de recharger sa boîte.
+	// Hors ligne, il le retrouvera avec GET /private-messages/received à sa prochaine connexion.
+	// Le message est toujours publié : la présence d'une connexion toute récente peut ne pas être
+	// encore connue de cette instance, elle sert seulement à renseigner delivered
+	privateMessage.Delivered = realtime.Default.Viewers(realtime.UserTopic(receiver.ID)) > 0
+	if err := realtime.PublishJSON(realtime.UserTopic(receiver.ID), "message", privateMessage); err != nil {
+		utils.LogErrorWithUser(senderID, err, "Error publishing message event in CreatePrivateMessage")
+		privateMessage.Delivered = false
+	}
+
 	utils.LogSuccessWithUser(senderID, "Private message created successfully in CreatePrivateMessage")
 	c.JSON(http.StatusCreated, privateMessage)
 }
@@ -242,7 +254,7 @@ func GetSentMessages(c *gin.Context) {
 }
 
 // @Summary Mark message as read
-// @Description Mark a specific private message as read
+// @Description Mark a specific private message as read, the sender receives a "read" frame on the WebSocket
 // @Tags private-messages
 // @Accept json
 // @Produce json
@@ -300,6 +312,12 @@ func MarkMessageAsRead(c *gin.Context) {
 		return
 	}
 
+	// Accusé de lecture pour l'expéditeur s'il est connecté
+	receipt := ReadReceipt{MessageID: message.ID, ReadAt: time.Now()}
+	if err := realtime.PublishJSON(realtime.UserTopic(message.SenderID), "read", receipt); err != nil {
+		utils.LogErrorWithUser(userID, err, "Error publishing read receipt in MarkMessageAsRead")
+	}
+
 	utils.LogSuccessWithUser(userID, "Message marked as read successfully in MarkMessageAsRead")
 	c.JSON(http.StatusOK, gin.H{"message": "Message marked as read"})
 }
@@ -0,0 +1,261 @@
+package privateMessages
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/realtime"
+	"pec2-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Délai maximum pour écrire une trame au client
+	writeWait = 10 * time.Second
+	// Sans pong pendant ce délai, la connexion est considérée comme perdue
+	pongWait = 60 * time.Second
+	// Les pings partent avant l'expiration de pongWait
+	pingPeriod = pongWait * 9 / 10
+	// Les clients n'envoient que de petites trames de contrôle (indicateur de saisie)
+	maxFrameSize = 4096
+	// Un indicateur de saisie au plus par intervalle et par connexion, les suivants sont ignorés :
+	// chacun coûte une requête et une publication à toutes les instances
+	typingInterval = 2 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     checkOrigin,
+}
+
+// checkOrigin n'accepte que la même origine et les fronts listés dans FRONTEND_ORIGINS (séparés par des virgules).
+// Le ticket peut venir du cookie, que le navigateur joint lui-même : SameSite ne suffit pas à écarter
+// une page d'un sous-domaine ou d'un autre front du même site
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		// Client hors navigateur, qui passe lui-même le ticket
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	for _, allowed := range strings.Split(os.Getenv("FRONTEND_ORIGINS"), ",") {
+		allowed = strings.TrimSuffix(strings.TrimSpace(allowed), "/")
+		if allowed != "" && strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// WSFrame trame échangée sur le WebSocket des messages privés
+type WSFrame struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// TypingPayload indicateur de saisie : receiverId dans les trames du client, senderId dans celles du serveur
+type TypingPayload struct {
+	ReceiverID string `json:"receiverId,omitempty"`
+	SenderID   string `json:"senderId,omitempty"`
+}
+
+// ReadReceipt accusé de lecture envoyé à l'expéditeur du message
+type ReadReceipt struct {
+	MessageID string    `json:"messageId"`
+	ReadAt    time.Time `json:"readAt"`
+}
+
+// @Summary Private messages WebSocket
+// @Description Open a WebSocket that pushes the private messages of the connected user as JSON frames {"type","payload"}: "unread" with the number of unread messages on connection (to fetch with GET /private-messages/received what arrived while offline), "message" for a new message, "read" when a sent message is read and "typing" when a user is writing to you. Send {"type":"typing","payload":{"receiverId":"..."}} to show you are writing. Authenticated with a ticket from POST /stream-tickets with stream "messages"
+// @Tags private-messages
+// @Param ticket query string false "Stream ticket, can also be sent in the stream_ticket cookie"
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 401 {object} map[string]string "error: Invalid or expired stream ticket"
+// @Router /private-messages/ws [get]
+func HandleWebSocket(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		utils.LogError(errors.New("user_id manquant"), "User not authenticated in HandleWebSocket")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// En cas d'échec, Upgrade a déjà répondu au client
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Error upgrading connection in HandleWebSocket")
+		return
+	}
+	defer conn.Close()
+
+	client, err := realtime.Default.Subscribe(realtime.UserTopic(userID), 0)
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Error subscribing in HandleWebSocket")
+		closeWebSocket(conn, websocket.CloseTryAgainLater, "Too many connections")
+		return
+	}
+	defer realtime.Default.Unsubscribe(client)
+
+	utils.LogSuccessWithUser(userID, "WebSocket connection established in HandleWebSocket")
+
+	// Seule cette goroutine écrit sur la connexion : les réponses de la lecture passent par replies
+	replies := make(chan WSFrame, 8)
+	readerDone := make(chan struct{})
+	go readFrames(conn, userID, replies, readerDone)
+
+	// Les messages reçus hors ligne sont récupérés par le client avec GET /private-messages/received
+	var unread int64
+	if err := db.DB.Model(&models.PrivateMessage{}).
+		Where("receiver_id = ? AND status = ?", userID, models.MessageStatusUnread).
+		Count(&unread).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "Error counting unread messages in HandleWebSocket")
+	}
+	if err := writeFrame(conn, "unread", gin.H{"count": unread}); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(pingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case event := <-client.Events():
+			// Le nombre de connexions de l'utilisateur n'intéresse pas le client
+			if event.Name == realtime.PresenceEvent {
+				continue
+			}
+			if err := writeFrame(conn, event.Name, json.RawMessage(event.Data)); err != nil {
+				return
+			}
+		case reply := <-replies:
+			if err := writeFrame(conn, reply.Type, reply.Payload); err != nil {
+				return
+			}
+		case <-client.Done():
+			utils.LogErrorWithUser(userID, client.Err(), "WebSocket disconnected by the hub in HandleWebSocket")
+			closeWebSocket(conn, websocket.CloseTryAgainLater, "Reconnect")
+			return
+		case <-readerDone:
+			utils.LogSuccessWithUser(userID, "WebSocket connection closed in HandleWebSocket")
+			return
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+			// Sans annonce renouvelée, les autres instances considèrent l'utilisateur hors ligne
+			realtime.Default.RefreshPresence(realtime.UserTopic(userID))
+		}
+	}
+}
+
+// readFrames lit les trames du client jusqu'à la fermeture de la connexion
+func readFrames(conn *websocket.Conn, userID string, replies chan<- WSFrame, done chan<- struct{}) {
+	defer close(done)
+
+	conn.SetReadLimit(maxFrameSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	typingLimit := throttle{interval: typingInterval}
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var frame WSFrame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			replyError(replies, "Invalid frame")
+			continue
+		}
+
+		switch frame.Type {
+		case "typing":
+			var typing TypingPayload
+			if err := json.Unmarshal(frame.Payload, &typing); err != nil || typing.ReceiverID == "" {
+				replyError(replies, "receiverId is required")
+				continue
+			}
+			if !typingLimit.allow(time.Now()) {
+				continue
+			}
+			publishTyping(userID, typing.ReceiverID)
+		default:
+			replyError(replies, "Unknown frame type")
+		}
+	}
+}
+
+// throttle laisse passer une action au plus une fois par intervalle
+type throttle struct {
+	interval time.Duration
+	last     time.Time
+}
+
+func (t *throttle) allow(now time.Time) bool {
+	if !t.last.IsZero() && now.Sub(t.last) < t.interval {
+		return false
+	}
+	t.last = now
+	return true
+}
+
+// publishTyping prévient le destinataire que l'utilisateur lui écrit. L'indicateur n'a de sens
+// qu'en direct : il n'est pas envoyé à un utilisateur hors ligne ou qui refuse les messages
+func publishTyping(senderID string, receiverID string) {
+	if realtime.Default.Viewers(realtime.UserTopic(receiverID)) == 0 {
+		return
+	}
+
+	var receiver models.User
+	if err := db.DB.Select("id", "message_enable").Where("id = ?", receiverID).First(&receiver).Error; err != nil {
+		utils.LogErrorWithUser(senderID, err, "Receiver not found in publishTyping")
+		return
+	}
+	if !receiver.MessageEnable {
+		return
+	}
+
+	if err := realtime.PublishJSON(realtime.UserTopic(receiverID), "typing", TypingPayload{SenderID: senderID}); err != nil {
+		utils.LogErrorWithUser(senderID, err, "Error publishing typing event in publishTyping")
+	}
+}
+
+func replyError(replies chan<- WSFrame, message string) {
+	payload, _ := json.Marshal(gin.H{"error": message})
+	select {
+	case replies <- WSFrame{Type: "error", Payload: payload}:
+	default:
+		// Un client qui enchaîne les trames invalides ne reçoit pas toutes les erreurs
+	}
+}
+
+func writeFrame(conn *websocket.Conn, frameType string, payload any) error {
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return conn.WriteJSON(gin.H{"type": frameType, "payload": payload})
+}
+
+func closeWebSocket(conn *websocket.Conn, code int, reason string) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeWait))
+}
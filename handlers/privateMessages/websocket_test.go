@@ -0,0 +1,236 @@
+package privateMessages
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"pec2-backend/realtime"
+	"pec2-backend/testutils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	testutils.InitTestMain()
+
+	log.SetOutput(io.Discard)
+
+	exitCode := m.Run()
+
+	log.SetOutput(os.Stdout)
+
+	os.Exit(exitCode)
+}
+
+// setupServer démarre l'API en mémoire, l'utilisateur est passé dans l'en-tête X-User-ID à la place du ticket
+func setupServer() *httptest.Server {
+	r := testutils.SetupTestRouter()
+	authenticated := func(handler gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set("user_id", c.GetHeader("X-User-ID"))
+			handler(c)
+		}
+	}
+	r.GET("/private-messages/ws", authenticated(HandleWebSocket))
+	r.POST("/private-messages", authenticated(CreatePrivateMessage))
+	r.PATCH("/private-messages/:id/read", authenticated(MarkMessageAsRead))
+	return httptest.NewServer(r)
+}
+
+func expectUnreadCount(mock sqlmock.Sqlmock, userID string, count int) {
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "private_messages" WHERE receiver_id = \$1 AND status = \$2`).
+		WithArgs(userID, "UNREAD").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+}
+
+// connect ouvre le WebSocket d'un utilisateur et lit la trame du nombre de messages non lus
+func connect(t *testing.T, server *httptest.Server, userID string) (*websocket.Conn, WSFrame) {
+	header := http.Header{}
+	header.Set("X-User-ID", userID)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/private-messages/ws", header)
+	if err != nil {
+		t.Fatalf("websocket dial failed: %v", err)
+	}
+	return conn, readFrame(t, conn)
+}
+
+func readFrame(t *testing.T, conn *websocket.Conn) WSFrame {
+	var frame WSFrame
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatalf("no frame received: %v", err)
+	}
+	return frame
+}
+
+func sendMessage(t *testing.T, server *httptest.Server, senderID string, receiverName string) map[string]any {
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/private-messages",
+		strings.NewReader(`{"receiverUserName":"`+receiverName+`","content":"Salut !"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", senderID)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var body map[string]any
+	json.NewDecoder(resp.Body).Decode(&body)
+	return body
+}
+
+func expectMessageCreated(mock sqlmock.Sqlmock, receiverID string, receiverName string, messageID string) {
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE user_name = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs(receiverName, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_name", "message_enable"}).AddRow(receiverID, receiverName, true))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "private_messages" (.+) RETURNING "id"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(messageID))
+	mock.ExpectCommit()
+}
+
+// Test de la messagerie en direct entre deux clients : indicateur de saisie, nouveau message,
+// accusé de lecture, puis envoi à un destinataire hors ligne
+func TestWebSocket_TwoClients(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	server := setupServer()
+	defer server.Close()
+
+	alice := "alice-uuid"
+	bob := "bob-uuid"
+
+	expectUnreadCount(mock, alice, 0)
+	aliceConn, unread := connect(t, server, alice)
+	defer aliceConn.Close()
+	assert.Equal(t, "unread", unread.Type)
+	assert.JSONEq(t, `{"count":0}`, string(unread.Payload))
+
+	// Les messages reçus hors ligne sont signalés à la connexion
+	expectUnreadCount(mock, bob, 2)
+	bobConn, unread := connect(t, server, bob)
+	defer bobConn.Close()
+	assert.JSONEq(t, `{"count":2}`, string(unread.Payload))
+
+	// Alice écrit à Bob
+	mock.ExpectQuery(`SELECT "id","message_enable" FROM "users" WHERE id = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs(bob, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "message_enable"}).AddRow(bob, true))
+	assert.NoError(t, aliceConn.WriteJSON(map[string]any{"type": "typing", "payload": map[string]string{"receiverId": bob}}))
+	typing := readFrame(t, bobConn)
+	assert.Equal(t, "typing", typing.Type)
+	assert.JSONEq(t, `{"senderId":"alice-uuid"}`, string(typing.Payload))
+
+	// Le message d'Alice est poussé à Bob
+	expectMessageCreated(mock, bob, "bob", "message-uuid")
+	response := sendMessage(t, server, alice, "bob")
+	assert.Equal(t, true, response["delivered"])
+
+	message := readFrame(t, bobConn)
+	assert.Equal(t, "message", message.Type)
+	assert.Contains(t, string(message.Payload), `"content":"Salut !"`)
+	assert.Contains(t, string(message.Payload), `"senderId":"alice-uuid"`)
+
+	// Bob lit le message, Alice reçoit l'accusé de lecture
+	mock.ExpectQuery(`SELECT \* FROM "private_messages" WHERE id = \$1 ORDER BY "private_messages"."id" LIMIT \$2`).
+		WithArgs("message-uuid", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sender_id", "receiver_id", "content", "status"}).
+			AddRow("message-uuid", alice, bob, "Salut !", "UNREAD"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "private_messages" SET "status"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+		WithArgs("READ", sqlmock.AnyArg(), "message-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	req, _ := http.NewRequest(http.MethodPatch, server.URL+"/private-messages/message-uuid/read", nil)
+	req.Header.Set("X-User-ID", bob)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	receipt := readFrame(t, aliceConn)
+	assert.Equal(t, "read", receipt.Type)
+	assert.Contains(t, string(receipt.Payload), `"messageId":"message-uuid"`)
+
+	// Une trame inconnue est signalée sans couper la connexion
+	assert.NoError(t, bobConn.WriteJSON(map[string]string{"type": "shout"}))
+	assert.Equal(t, "error", readFrame(t, bobConn).Type)
+
+	// Alice se déconnecte : le message de Bob est enregistré sans être poussé
+	aliceConn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for realtime.Default.Viewers(realtime.UserTopic(alice)) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	expectMessageCreated(mock, alice, "alice", "reply-uuid")
+	response = sendMessage(t, server, bob, "alice")
+	assert.Equal(t, false, response["delivered"])
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test que les indicateurs de saisie trop rapprochés sont ignorés
+func TestThrottle(t *testing.T) {
+	limit := throttle{interval: typingInterval}
+	now := time.Now()
+
+	assert.True(t, limit.allow(now))
+	assert.False(t, limit.allow(now.Add(typingInterval/2)))
+	assert.True(t, limit.allow(now.Add(typingInterval)))
+	assert.False(t, limit.allow(now.Add(typingInterval+time.Millisecond)))
+}
+
+// Test que seules la même origine et les origines du front configurées peuvent ouvrir le WebSocket
+func TestCheckOrigin(t *testing.T) {
+	t.Setenv("FRONTEND_ORIGINS", "https://app.example.com, http://localhost:3000/")
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"", true},
+		{"https://api.example.com", true},
+		{"https://app.example.com", true},
+		{"http://localhost:3000", true},
+		{"https://evil.example.com", false},
+		{"http://app.example.com", false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "https://api.example.com/private-messages/ws", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		assert.Equal(t, tt.allowed, checkOrigin(req), tt.origin)
+	}
+}
+
+// Test qu'une page d'une autre origine ne peut pas ouvrir le WebSocket avec le cookie de l'utilisateur
+func TestWebSocket_ForeignOriginRejected(t *testing.T) {
+	t.Setenv("FRONTEND_ORIGINS", "https://app.example.com")
+
+	server := setupServer()
+	defer server.Close()
+
+	header := http.Header{}
+	header.Set("X-User-ID", "alice-uuid")
+	header.Set("Origin", "https://evil.example.com")
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/private-messages/ws", header)
+
+	assert.Error(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	}
+}
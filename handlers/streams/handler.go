@@ -0,0 +1,106 @@
+package streams
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Summary Get a stream ticket
+// @Description Exchange the JWT for a single-use ticket valid for one minute, to open the comments SSE stream of a post (stream "comments", the default) or the private messages WebSocket (stream "messages"): browsers cannot send the Authorization header on these connections. The ticket is returned in the body, to pass as the ticket query parameter, and in an HttpOnly cookie restricted to the stream URL
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param request body models.StreamTicketRequest true "Stream to open"
+// @Security BearerAuth
+// @Success 201 {object} models.StreamTicketResponse
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 401 {object} map[string]string "error: User not authenticated"
+// @Failure 404 {object} map[string]string "error: Post not found"
+// @Failure 500 {object} map[string]string "error: Error message"
+// @Router /stream-tickets [post]
+func CreateStreamTicket(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(errors.New("user_id manquant"), "User not authenticated in CreateStreamTicket")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var input models.StreamTicketRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.LogErrorWithUser(userID, err, "Error when binding JSON in CreateStreamTicket")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	ticket := models.StreamTicket{
+		UserID: userID.(string),
+		Stream: input.Stream,
+	}
+	// Le cookie n'est envoyé qu'à l'URL du flux demandé
+	cookiePath := "/private-messages/ws"
+
+	if ticket.Stream == "" || ticket.Stream == models.StreamComments {
+		if input.PostID == "" {
+			utils.LogErrorWithUser(userID, errors.New("postId manquant"), "Missing post in CreateStreamTicket")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "postId is required for the comments stream"})
+			return
+		}
+
+		var post models.Post
+		if err := db.DB.Select("id").Where("id = ?", input.PostID).First(&post).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.LogErrorWithUser(userID, err, "Post not found in CreateStreamTicket")
+				c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+				return
+			}
+			utils.LogErrorWithUser(userID, err, "Error fetching post in CreateStreamTicket")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating stream ticket"})
+			return
+		}
+
+		ticket.Stream = models.StreamComments
+		ticket.PostID = &post.ID
+		cookiePath = "/posts/" + post.ID + "/comments/sse"
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Error generating ticket in CreateStreamTicket")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating stream ticket"})
+		return
+	}
+
+	ticket.TokenHash = utils.HashToken(token)
+	ticket.ExpiresAt = time.Now().Add(utils.StreamTicketTTL)
+	if err := db.DB.Create(&ticket).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "Error saving ticket in CreateStreamTicket")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating stream ticket"})
+		return
+	}
+
+	// Chaque connexion consomme un ticket : les tickets expirés, utilisés ou non, ne servent plus
+	if err := db.DB.Where("expires_at < ?", time.Now()).Delete(&models.StreamTicket{}).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "Error purging expired tickets in CreateStreamTicket")
+	}
+
+	// Le cookie reste illisible en JavaScript
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(utils.StreamTicketCookie, token, int(utils.StreamTicketTTL.Seconds()),
+		cookiePath, "", secure, true)
+
+	utils.LogSuccessWithUser(userID, "Stream ticket created successfully in CreateStreamTicket")
+	c.JSON(http.StatusCreated, models.StreamTicketResponse{
+		Ticket:    token,
+		ExpiresAt: ticket.ExpiresAt,
+	})
+}
@@ -0,0 +1,278 @@
+package streams
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"pec2-backend/middleware"
+	"pec2-backend/models"
+	"pec2-backend/realtime"
+	"pec2-backend/testutils"
+	"pec2-backend/utils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	testutils.InitTestMain()
+
+	log.SetOutput(io.Discard)
+
+	exitCode := m.Run()
+
+	log.SetOutput(os.Stdout)
+
+	os.Exit(exitCode)
+}
+
+// expectExpiredTicketsPurged attend la suppression des tickets expirés qui suit chaque émission
+func expectExpiredTicketsPurged(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "stream_tickets" WHERE expires_at < \$1`).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+}
+
+func setupTicketRouter(userID string) *gin.Engine {
+	r := testutils.SetupTestRouter()
+	r.POST("/stream-tickets", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		CreateStreamTicket(c)
+	})
+	return r
+}
+
+// Route de flux factice protégée par le middleware, qui renvoie l'utilisateur authentifié
+func setupStreamRouter() *gin.Engine {
+	r := testutils.SetupTestRouter()
+	r.GET("/posts/:id/comments/sse", middleware.StreamTicketAuth(models.StreamComments), func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+		c.JSON(http.StatusOK, gin.H{"userId": userID})
+	})
+	return r
+}
+
+var ticketColumns = []string{"id", "user_id", "stream", "post_id", "token_hash", "expires_at", "used_at", "created_at"}
+
+// Test qu'un ticket est émis pour un post existant, dans le corps et dans un cookie HttpOnly limité au flux
+func TestCreateStreamTicket_Success(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	userID := "user-uuid"
+	postID := "post-uuid"
+
+	mock.ExpectQuery(`SELECT "id" FROM "posts" WHERE id = \$1 ORDER BY "posts"."id" LIMIT \$2`).
+		WithArgs(postID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(postID))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "stream_tickets" (.+) RETURNING "id"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("ticket-uuid"))
+	mock.ExpectCommit()
+	expectExpiredTicketsPurged(mock)
+
+	r := setupTicketRouter(userID)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/stream-tickets", strings.NewReader(`{"postId":"`+postID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response models.StreamTicketResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.NotEmpty(t, response.Ticket)
+	assert.WithinDuration(t, time.Now().Add(utils.StreamTicketTTL), response.ExpiresAt, 5*time.Second)
+
+	cookie := w.Result().Cookies()[0]
+	assert.Equal(t, utils.StreamTicketCookie, cookie.Name)
+	assert.Equal(t, response.Ticket, cookie.Value)
+	assert.Equal(t, "/posts/"+postID+"/comments/sse", cookie.Path)
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'aucun ticket n'est émis pour un post inexistant
+func TestCreateStreamTicket_PostNotFound(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT "id" FROM "posts" WHERE id = \$1 ORDER BY "posts"."id" LIMIT \$2`).
+		WithArgs("missing-post", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	r := setupTicketRouter("user-uuid")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/stream-tickets", strings.NewReader(`{"postId":"missing-post"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Result().Cookies())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un ticket pour le WebSocket des messages ne demande pas de post et que son cookie est limité au WebSocket
+func TestCreateStreamTicket_Messages(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "stream_tickets" (.+) RETURNING "id"`).
+		WithArgs("user-uuid", models.StreamMessages, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("ticket-uuid"))
+	mock.ExpectCommit()
+	expectExpiredTicketsPurged(mock)
+
+	r := setupTicketRouter("user-uuid")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/stream-tickets", strings.NewReader(`{"stream":"messages"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/private-messages/ws", w.Result().Cookies()[0].Path)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un ticket passé en cookie ouvre le flux et est consommé
+func TestStreamTicketAuth_CookieConsumed(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	token := "stream-ticket"
+	postID := "post-uuid"
+
+	mock.ExpectQuery(`SELECT \* FROM "stream_tickets" WHERE token_hash = \$1 ORDER BY "stream_tickets"."id" LIMIT \$2`).
+		WithArgs(utils.HashToken(token), 1).
+		WillReturnRows(sqlmock.NewRows(ticketColumns).
+			AddRow("ticket-uuid", "user-uuid", "comments", postID, utils.HashToken(token), time.Now().Add(time.Minute), nil, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "stream_tickets" SET "used_at"=\$1 WHERE id = \$2 AND used_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), "ticket-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	r := setupStreamRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts/"+postID+"/comments/sse", nil)
+	req.AddCookie(&http.Cookie{Name: utils.StreamTicketCookie, Value: token})
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"userId":"user-uuid"`)
+	assert.Equal(t, -1, w.Result().Cookies()[0].MaxAge)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un ticket déjà utilisé est refusé
+func TestStreamTicketAuth_AlreadyUsed(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	token := "stream-ticket"
+	postID := "post-uuid"
+
+	mock.ExpectQuery(`SELECT \* FROM "stream_tickets" WHERE token_hash = \$1`).
+		WithArgs(utils.HashToken(token), 1).
+		WillReturnRows(sqlmock.NewRows(ticketColumns).
+			AddRow("ticket-uuid", "user-uuid", "comments", postID, utils.HashToken(token), time.Now().Add(time.Minute), nil, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "stream_tickets" SET "used_at"=\$1 WHERE id = \$2 AND used_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), "ticket-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	r := setupStreamRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts/"+postID+"/comments/sse?ticket="+token, nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un ticket émis pour un autre post, ou pour un autre flux, est refusé sans être consommé
+func TestStreamTicketAuth_OtherPost(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	token := "stream-ticket"
+
+	mock.ExpectQuery(`SELECT \* FROM "stream_tickets" WHERE token_hash = \$1`).
+		WithArgs(utils.HashToken(token), 1).
+		WillReturnRows(sqlmock.NewRows(ticketColumns).
+			AddRow("ticket-uuid", "user-uuid", "comments", "other-post", utils.HashToken(token), time.Now().Add(time.Minute), nil, time.Now()))
+
+	mock.ExpectQuery(`SELECT \* FROM "stream_tickets" WHERE token_hash = \$1`).
+		WithArgs(utils.HashToken(token), 1).
+		WillReturnRows(sqlmock.NewRows(ticketColumns).
+			AddRow("ticket-uuid", "user-uuid", "messages", nil, utils.HashToken(token), time.Now().Add(time.Minute), nil, time.Now()))
+
+	r := setupStreamRouter()
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/posts/post-uuid/comments/sse?ticket="+token, nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un flux rouvert avec un nouveau ticket et lastEventId reprend là où le précédent s'est arrêté
+func TestStreamTicketAuth_ResumeWithLastEventID(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	token := "fresh-ticket"
+	postID := "post-uuid"
+
+	mock.ExpectQuery(`SELECT \* FROM "stream_tickets" WHERE token_hash = \$1`).
+		WithArgs(utils.HashToken(token), 1).
+		WillReturnRows(sqlmock.NewRows(ticketColumns).
+			AddRow("ticket-uuid", "user-uuid", "comments", postID, utils.HashToken(token), time.Now().Add(time.Minute), nil, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "stream_tickets" SET "used_at"=\$1 WHERE id = \$2 AND used_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), "ticket-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// Le premier flux s'est arrêté après avoir reçu Head, un commentaire a été publié depuis
+	hub := realtime.NewHub(realtime.NewMemoryBroker(), realtime.Options{})
+	previous, err := hub.Subscribe(realtime.PostTopic(postID), 0)
+	assert.NoError(t, err)
+	hub.Unsubscribe(previous)
+	missed, err := hub.Publish(realtime.PostTopic(postID), "comment", []byte(`{"n":1}`))
+	assert.NoError(t, err)
+
+	r := testutils.SetupTestRouter()
+	r.GET("/posts/:id/comments/sse", middleware.StreamTicketAuth(models.StreamComments), func(c *gin.Context) {
+		hub.ServeSSE(c, realtime.PostTopic(c.Param("id")), func(emit func(name string, data []byte)) {
+			t.Error("snapshot sent to a resumed client")
+		})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	url := "/posts/" + postID + "/comments/sse?ticket=" + token + "&lastEventId=" + strconv.FormatInt(previous.Head, 10)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "id: "+strconv.FormatInt(missed.ID, 10)+"\nevent: comment\ndata: {\"n\":1}")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
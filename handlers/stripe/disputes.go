@@ -0,0 +1,489 @@
+package stripe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"pec2-backend/db"
+	"pec2-backend/ledger"
+	"pec2-backend/models"
+	"pec2-backend/payments"
+	"pec2-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	stripe "github.com/stripe/stripe-go/v82"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RefundSubscriptionPayment refunds all or part of a subscription payment (admin only)
+// @Summary Refund a subscription payment
+// @Description Issue a full or partial refund of a subscription payment through the payment provider (admin only). The refund is recorded when the provider confirms it by webhook: a full refund ends the subscription immediately
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param paymentId path string true "ID of the subscription payment"
+// @Param refund body models.PaymentRefundRequest false "Amount to refund in cents (the whole remaining amount if empty)"
+// @Security BearerAuth
+// @Success 202 {object} map[string]interface{} "message: Refund requested, refundId, amount"
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 403 {object} map[string]string "error: Access denied"
+// @Failure 404 {object} map[string]string "error: Payment not found"
+// @Failure 409 {object} map[string]string "error: Only succeeded payments can be refunded"
+// @Failure 500 {object} map[string]string "error: Error when refunding the payment"
+// @Router /subscriptions/payments/{paymentId}/refund [post]
+func RefundSubscriptionPayment(c *gin.Context) {
+	adminID := c.GetString("user_id")
+	paymentID := c.Param("paymentId")
+
+	if _, err := uuid.Parse(paymentID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	var input models.PaymentRefundRequest
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		utils.LogErrorWithUser(adminID, err, "Invalid input dans RefundSubscriptionPayment")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	var payment models.SubscriptionPayment
+	if err := db.DB.First(&payment, "id = ?", paymentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.LogErrorWithUser(adminID, err, "Payment not found dans RefundSubscriptionPayment")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+			return
+		}
+		utils.LogErrorWithUser(adminID, err, "Error fetching payment dans RefundSubscriptionPayment")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching payment"})
+		return
+	}
+
+	// Un paiement contesté est entre les mains de la banque, il ne peut plus être remboursé
+	if payment.Status != models.SubscriptionPaymentSucceeded || payment.StripePaymentIntentId == "" {
+		utils.LogErrorWithUser(adminID, errors.New("statut "+string(payment.Status)), "Payment not refundable dans RefundSubscriptionPayment")
+		c.JSON(http.StatusConflict, gin.H{"error": "Only succeeded payments can be refunded"})
+		return
+	}
+
+	remaining := payment.Amount - payment.RefundedAmount
+	amount := input.Amount
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount <= 0 || amount > remaining {
+		utils.LogErrorWithUser(adminID, fmt.Errorf("montant %d, reste %d", amount, remaining), "Invalid refund amount dans RefundSubscriptionPayment")
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The refund amount must be between 1 and %d", remaining)})
+		return
+	}
+
+	refundID, err := payments.Default.Refund(payment.StripePaymentIntentId, int64(amount))
+	if err != nil {
+		utils.LogErrorWithUser(adminID, err, "Error refunding payment "+payment.ID+" dans RefundSubscriptionPayment")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error when refunding the payment"})
+		return
+	}
+
+	utils.LogSuccessWithUser(adminID, fmt.Sprintf("Refund %s of %d requested on payment %s dans RefundSubscriptionPayment", refundID, amount, payment.ID))
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "Refund requested",
+		"refundId": refundID,
+		"amount":   amount,
+	})
+}
+
+// GetPaymentDisputes lists the disputes opened on subscription payments, tips and post purchases (admin only)
+// @Summary List the payment disputes
+// @Description Returns the chargebacks opened by fans on subscription payments, tips and post purchases, newest first (admin only)
+// @Tags subscriptions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.PaymentDispute
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 403 {object} map[string]string "error: Access denied"
+// @Failure 500 {object} map[string]string "error: Server error"
+// @Router /subscriptions/disputes [get]
+func GetPaymentDisputes(c *gin.Context) {
+	var disputes []models.PaymentDispute
+	if err := db.DB.Order("created_at DESC").Find(&disputes).Error; err != nil {
+		utils.LogError(err, "Error fetching disputes in GetPaymentDisputes")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching disputes"})
+		return
+	}
+
+	utils.LogSuccess("Disputes fetched successfully in GetPaymentDisputes")
+	c.JSON(http.StatusOK, disputes)
+}
+
+// disputeWithdrawsFunds indique si la contestation s'accompagne d'une reprise des fonds.
+// Les demandes de renseignements (warning_*) ne débitent rien
+func disputeWithdrawsFunds(status stripe.DisputeStatus) bool {
+	return !strings.HasPrefix(string(status), "warning_")
+}
+
+// disputedPayment paiement visé par une contestation : paiement d'abonnement, pourboire ou achat de post
+type disputedPayment struct {
+	// record est le *models.SubscriptionPayment, *models.Tip ou *models.PostPurchase mis à jour
+	record         interface{}
+	ledgerSource   models.LedgerSourceType
+	id             string
+	amount         int
+	refundedAmount int
+	// subscriptionID est l'abonnement coupé si la contestation est perdue, vide pour un paiement ponctuel
+	subscriptionID string
+	// dispute est la contestation à enregistrer, déjà rattachée au paiement
+	dispute models.PaymentDispute
+}
+
+// findDisputedPayment retrouve le paiement réglé par le PaymentIntent contesté, nil si aucun ne correspond
+func findDisputedPayment(paymentIntentID string) (*disputedPayment, error) {
+	var payment models.SubscriptionPayment
+	err := db.DB.First(&payment, "stripe_payment_intent_id = ?", paymentIntentID).Error
+	if err == nil {
+		return &disputedPayment{
+			record:         &payment,
+			ledgerSource:   models.LedgerSourceSubscriptionPayment,
+			id:             payment.ID,
+			amount:         payment.Amount,
+			refundedAmount: payment.RefundedAmount,
+			subscriptionID: payment.SubscriptionID,
+			dispute:        models.PaymentDispute{SubscriptionPaymentID: &payment.ID},
+		}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// La contestation peut concerner un paiement ponctuel
+	tip, err := findOneOffPayment[models.Tip]("", paymentIntentID)
+	if err == nil {
+		return &disputedPayment{
+			record:         tip,
+			ledgerSource:   models.LedgerSourceTip,
+			id:             tip.ID,
+			amount:         tip.Amount,
+			refundedAmount: tip.RefundedAmount,
+			dispute:        models.PaymentDispute{TipID: &tip.ID},
+		}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	purchase, err := findOneOffPayment[models.PostPurchase]("", paymentIntentID)
+	if err == nil {
+		return &disputedPayment{
+			record:         purchase,
+			ledgerSource:   models.LedgerSourcePostPurchase,
+			id:             purchase.ID,
+			amount:         purchase.Amount,
+			refundedAmount: purchase.RefundedAmount,
+			dispute:        models.PaymentDispute{PostPurchaseID: &purchase.ID},
+		}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// parseDispute lit la contestation et retrouve le paiement concerné
+func parseDispute(event stripe.Event, handlerName string) (*stripe.Dispute, *disputedPayment, error) {
+	var dispute stripe.Dispute
+	if err := json.Unmarshal(event.Data.Raw, &dispute); err != nil {
+		utils.LogError(err, "Error parsing Dispute dans "+handlerName)
+		return nil, nil, permanent(errors.New("error parsing Dispute"))
+	}
+	if dispute.PaymentIntent == nil || dispute.PaymentIntent.ID == "" {
+		return &dispute, nil, nil
+	}
+
+	payment, err := findDisputedPayment(dispute.PaymentIntent.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching payment: %w", err)
+	}
+	return &dispute, payment, nil
+}
+
+func handleChargeDisputeCreated(event stripe.Event) (string, error) {
+	dispute, payment, err := parseDispute(event, "handleChargeDisputeCreated")
+	if err != nil {
+		return "", err
+	}
+	if payment == nil {
+		utils.LogInfo("Dispute " + dispute.ID + " not linked to a known payment dans handleChargeDisputeCreated")
+		return "Dispute not linked to a known payment - event ignored", nil
+	}
+
+	// La banque ne peut pas reprendre plus que ce qui n'a pas déjà été remboursé
+	amount := int(dispute.Amount)
+	if remaining := payment.amount - payment.refundedAmount; amount > remaining {
+		amount = remaining
+	}
+	withdrawn := disputeWithdrawsFunds(dispute.Status)
+
+	recorded := false
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		record := payment.dispute
+		record.StripeDisputeId = dispute.ID
+		record.Amount = amount
+		record.Reason = string(dispute.Reason)
+		record.Status = string(dispute.Status)
+		record.FundsWithdrawn = withdrawn
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 || !withdrawn {
+			return nil
+		}
+		recorded = true
+		return debitDispute(tx, &record, payment)
+	})
+	if err != nil {
+		utils.LogError(err, "Error recording dispute dans handleChargeDisputeCreated")
+		return "", fmt.Errorf("error recording dispute: %w", err)
+	}
+
+	if !recorded {
+		utils.LogInfo("Dispute " + dispute.ID + " recorded without funds withdrawal dans handleChargeDisputeCreated")
+		return "Dispute recorded", nil
+	}
+
+	utils.LogSuccess("Dispute " + dispute.ID + " opened on payment " + payment.id + " dans handleChargeDisputeCreated")
+	return "Dispute recorded - payment disputed", nil
+}
+
+// findPaymentDispute retrouve la contestation enregistrée par charge.dispute.created.
+// Une erreur non permanente fait rejouer l'événement tant qu'elle n'a pas été traitée
+func findPaymentDispute(disputeID string, handlerName string) (*models.PaymentDispute, error) {
+	var record models.PaymentDispute
+	if err := db.DB.First(&record, "stripe_dispute_id = ?", disputeID).Error; err != nil {
+		utils.LogError(err, "Dispute not recorded yet, will retry dans "+handlerName)
+		return nil, fmt.Errorf("dispute not recorded yet: %w", err)
+	}
+	return &record, nil
+}
+
+// debitDispute le paiement passe en DISPUTED et le créateur est débité du montant contesté
+func debitDispute(tx *gorm.DB, record *models.PaymentDispute, payment *disputedPayment) error {
+	if err := tx.Model(payment.record).Update("status", paymentDisputed).Error; err != nil {
+		return err
+	}
+	return ledger.RecordReversal(tx, models.LedgerSourcePaymentDispute, record.ID,
+		payment.ledgerSource, payment.id, record.Amount)
+}
+
+// withdrawDisputeFunds enregistre la reprise des fonds d'une contestation qui n'en avait pas encore :
+// demande de renseignements devenue litige, charge.dispute.funds_withdrawn ou contestation perdue.
+// Renvoie false si les fonds étaient déjà repris
+func withdrawDisputeFunds(tx *gorm.DB, record *models.PaymentDispute, payment *disputedPayment) (bool, error) {
+	result := tx.Model(record).Where("funds_withdrawn = ?", false).Update("funds_withdrawn", true)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	return true, debitDispute(tx, record, payment)
+}
+
+// reinstateDisputeFunds enregistre le retour des fonds repris : le paiement est rétabli et le créateur recrédité.
+// Renvoie false si aucun fonds n'était repris
+func reinstateDisputeFunds(tx *gorm.DB, record *models.PaymentDispute, payment *disputedPayment) (bool, error) {
+	result := tx.Model(record).Where("funds_withdrawn = ?", true).Update("funds_withdrawn", false)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+
+	status := paymentSucceeded
+	if payment.refundedAmount >= payment.amount {
+		status = paymentRefunded
+	}
+	if err := tx.Model(payment.record).Update("status", status).Error; err != nil {
+		return false, err
+	}
+	return true, ledger.RecordReinstatement(tx, models.LedgerSourcePaymentDispute, record.ID)
+}
+
+func handleChargeDisputeUpdated(event stripe.Event) (string, error) {
+	dispute, payment, err := parseDispute(event, "handleChargeDisputeUpdated")
+	if err != nil {
+		return "", err
+	}
+	if payment == nil {
+		return "Dispute not linked to a known payment - event ignored", nil
+	}
+
+	record, err := findPaymentDispute(dispute.ID, "handleChargeDisputeUpdated")
+	if err != nil {
+		return "", err
+	}
+	if record.ClosedAt != nil {
+		return "Dispute already closed", nil
+	}
+
+	// Une demande de renseignements (warning_*) qui devient un litige s'accompagne de la reprise des fonds
+	withdrawn := false
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(record).Updates(map[string]interface{}{
+			"status": string(dispute.Status),
+			"reason": string(dispute.Reason),
+		}).Error; err != nil {
+			return err
+		}
+		if !disputeWithdrawsFunds(dispute.Status) {
+			return nil
+		}
+		withdrawn, err = withdrawDisputeFunds(tx, record, payment)
+		return err
+	})
+	if err != nil {
+		utils.LogError(err, "Error updating dispute dans handleChargeDisputeUpdated")
+		return "", fmt.Errorf("error updating dispute: %w", err)
+	}
+
+	if withdrawn {
+		utils.LogSuccess("Dispute " + dispute.ID + " escalated on payment " + payment.id + " dans handleChargeDisputeUpdated")
+		return "Dispute updated - payment disputed", nil
+	}
+	utils.LogSuccess("Dispute " + dispute.ID + " updated dans handleChargeDisputeUpdated")
+	return "Dispute updated", nil
+}
+
+func handleChargeDisputeFundsWithdrawn(event stripe.Event) (string, error) {
+	dispute, payment, err := parseDispute(event, "handleChargeDisputeFundsWithdrawn")
+	if err != nil {
+		return "", err
+	}
+	if payment == nil {
+		return "Dispute not linked to a known payment - event ignored", nil
+	}
+
+	record, err := findPaymentDispute(dispute.ID, "handleChargeDisputeFundsWithdrawn")
+	if err != nil {
+		return "", err
+	}
+
+	withdrawn := false
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		withdrawn, err = withdrawDisputeFunds(tx, record, payment)
+		return err
+	})
+	if err != nil {
+		utils.LogError(err, "Error withdrawing dispute funds dans handleChargeDisputeFundsWithdrawn")
+		return "", fmt.Errorf("error withdrawing dispute funds: %w", err)
+	}
+	if !withdrawn {
+		return "Dispute funds already withdrawn", nil
+	}
+
+	utils.LogSuccess("Funds of dispute " + dispute.ID + " withdrawn dans handleChargeDisputeFundsWithdrawn")
+	return "Dispute funds withdrawn - payment disputed", nil
+}
+
+func handleChargeDisputeFundsReinstated(event stripe.Event) (string, error) {
+	dispute, payment, err := parseDispute(event, "handleChargeDisputeFundsReinstated")
+	if err != nil {
+		return "", err
+	}
+	if payment == nil {
+		return "Dispute not linked to a known payment - event ignored", nil
+	}
+
+	record, err := findPaymentDispute(dispute.ID, "handleChargeDisputeFundsReinstated")
+	if err != nil {
+		return "", err
+	}
+
+	reinstated := false
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		reinstated, err = reinstateDisputeFunds(tx, record, payment)
+		return err
+	})
+	if err != nil {
+		utils.LogError(err, "Error reinstating dispute funds dans handleChargeDisputeFundsReinstated")
+		return "", fmt.Errorf("error reinstating dispute funds: %w", err)
+	}
+	if !reinstated {
+		return "Dispute funds already reinstated", nil
+	}
+
+	utils.LogSuccess("Funds of dispute " + dispute.ID + " reinstated dans handleChargeDisputeFundsReinstated")
+	return "Dispute funds reinstated", nil
+}
+
+func handleChargeDisputeClosed(event stripe.Event) (string, error) {
+	dispute, payment, err := parseDispute(event, "handleChargeDisputeClosed")
+	if err != nil {
+		return "", err
+	}
+	if payment == nil {
+		return "Dispute not linked to a known payment - event ignored", nil
+	}
+
+	record, err := findPaymentDispute(dispute.ID, "handleChargeDisputeClosed")
+	if err != nil {
+		return "", err
+	}
+	if record.ClosedAt != nil {
+		return "Dispute already closed", nil
+	}
+
+	lost := dispute.Status == stripe.DisputeStatusLost
+	// Seul un paiement d'abonnement donne un accès à couper
+	endsSubscription := lost && payment.subscriptionID != ""
+	var sub models.Subscription
+	if endsSubscription {
+		if err := db.DB.First(&sub, "id = ?", payment.subscriptionID).Error; err != nil {
+			utils.LogError(err, "Subscription not found dans handleChargeDisputeClosed")
+			return "", fmt.Errorf("error fetching subscription: %w", err)
+		}
+	}
+	wasEnded := isSubscriptionEnded(sub.Status)
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(record).Updates(map[string]interface{}{
+			"status":    string(dispute.Status),
+			"closed_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		// Contestation perdue : l'argent est définitivement repris, même si aucun événement ne l'a encore signalé,
+		// et l'accès est coupé comme pour un remboursement
+		if lost {
+			if _, err := withdrawDisputeFunds(tx, record, payment); err != nil {
+				return err
+			}
+			if !endsSubscription {
+				return nil
+			}
+			return endSubscription(tx, &sub)
+		}
+
+		// Contestation gagnée ou close sans suite : les fonds éventuellement repris sont rendus
+		_, err := reinstateDisputeFunds(tx, record, payment)
+		return err
+	})
+	if err != nil {
+		utils.LogError(err, "Error closing dispute dans handleChargeDisputeClosed")
+		return "", fmt.Errorf("error closing dispute: %w", err)
+	}
+
+	if endsSubscription {
+		if !wasEnded {
+			cancelStripeSubscription(&sub, "handleChargeDisputeClosed")
+		}
+		utils.LogSuccess("Dispute " + dispute.ID + " lost, subscription " + sub.ID + " canceled dans handleChargeDisputeClosed")
+		return "Dispute lost - subscription canceled", nil
+	}
+
+	utils.LogSuccess("Dispute " + dispute.ID + " closed with status " + string(dispute.Status) + " dans handleChargeDisputeClosed")
+	return "Dispute closed", nil
+}
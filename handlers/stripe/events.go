@@ -0,0 +1,262 @@
+package stripe
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	stripe "github.com/stripe/stripe-go/v82"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Politique de relance des événements en échec : délai doublé à chaque tentative
+const (
+	maxEventAttempts     = 8
+	baseEventRetryDelay  = time.Minute
+	maxEventRetryDelay   = 6 * time.Hour
+	staleProcessingDelay = 10 * time.Minute
+	retryBatchSize       = 50
+)
+
+var (
+	errEventIgnored       = errors.New("event type not handled")
+	errEventNotReplayable = errors.New("event is not in a replayable state")
+)
+
+// permanentError marque une erreur qu'une nouvelle tentative ne corrigera pas (payload invalide, etc.)
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// recordEvent enregistre l'événement dans le journal. Renvoie false si l'événement
+// a déjà été reçu : la livraison en double ne doit alors rien faire.
+func recordEvent(event stripe.Event, payload []byte) (bool, error) {
+	record := models.StripeEvent{
+		ID:      event.ID,
+		Type:    string(event.Type),
+		Payload: string(payload),
+		Status:  models.StripeEventProcessing,
+	}
+
+	result := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// runEvent traite l'événement et enregistre le résultat dans le journal
+func runEvent(eventID string, attempts int, event stripe.Event) (string, error) {
+	message, err := dispatchEvent(event)
+
+	now := time.Now()
+	attempts++
+	updates := map[string]interface{}{"attempts": attempts}
+
+	switch {
+	case errors.Is(err, errEventIgnored):
+		message, err = "Event ignored", nil
+		updates["status"] = models.StripeEventIgnored
+		updates["processed_at"] = now
+		updates["next_attempt_at"] = nil
+		updates["last_error"] = ""
+	case err == nil:
+		updates["status"] = models.StripeEventProcessed
+		updates["processed_at"] = now
+		updates["next_attempt_at"] = nil
+		updates["last_error"] = ""
+	default:
+		updates["status"] = models.StripeEventFailed
+		updates["next_attempt_at"] = nextEventAttempt(attempts, err)
+		updates["last_error"] = err.Error()
+	}
+
+	if updateErr := db.DB.Model(&models.StripeEvent{}).Where("id = ?", eventID).Updates(updates).Error; updateErr != nil {
+		utils.LogError(updateErr, "Error updating stripe event "+eventID+" dans runEvent")
+	}
+
+	return message, err
+}
+
+// nextEventAttempt renvoie la date de la prochaine tentative, ou nil si l'événement ne doit plus être relancé
+func nextEventAttempt(attempts int, err error) *time.Time {
+	var perm permanentError
+	if errors.As(err, &perm) || attempts >= maxEventAttempts {
+		return nil
+	}
+
+	delay := baseEventRetryDelay << (attempts - 1)
+	if delay > maxEventRetryDelay || delay <= 0 {
+		delay = maxEventRetryDelay
+	}
+	next := time.Now().Add(delay)
+	return &next
+}
+
+// replayEvent réserve un événement en échec (ou bloqué en cours de traitement) et le traite à nouveau
+func replayEvent(eventID string) (string, error) {
+	claim := db.DB.Model(&models.StripeEvent{}).
+		Where("id = ? AND (status = ? OR (status = ? AND updated_at < ?))",
+			eventID, models.StripeEventFailed, models.StripeEventProcessing, time.Now().Add(-staleProcessingDelay)).
+		Update("status", models.StripeEventProcessing)
+	if claim.Error != nil {
+		return "", claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return "", errEventNotReplayable
+	}
+
+	var record models.StripeEvent
+	if err := db.DB.First(&record, "id = ?", eventID).Error; err != nil {
+		return "", err
+	}
+
+	var event stripe.Event
+	if err := json.Unmarshal([]byte(record.Payload), &event); err != nil {
+		return runEventWithError(record, permanent(err))
+	}
+
+	return runEvent(record.ID, record.Attempts, event)
+}
+
+// runEventWithError enregistre un échec sans passer par le traitement (payload illisible)
+func runEventWithError(record models.StripeEvent, err error) (string, error) {
+	db.DB.Model(&models.StripeEvent{}).Where("id = ?", record.ID).Updates(map[string]interface{}{
+		"status":          models.StripeEventFailed,
+		"attempts":        record.Attempts + 1,
+		"last_error":      err.Error(),
+		"next_attempt_at": nil,
+	})
+	return "", err
+}
+
+// RetryFailedEvents relance les événements dont la prochaine tentative est échue
+func RetryFailedEvents() {
+	now := time.Now()
+
+	var ids []string
+	err := db.DB.Model(&models.StripeEvent{}).
+		Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND updated_at < ?)",
+			models.StripeEventFailed, now, models.StripeEventProcessing, now.Add(-staleProcessingDelay)).
+		Order("created_at ASC").
+		Limit(retryBatchSize).
+		Pluck("id", &ids).Error
+	if err != nil {
+		utils.LogError(err, "Error fetching stripe events to retry dans RetryFailedEvents")
+		return
+	}
+
+	for _, id := range ids {
+		if _, err := replayEvent(id); err != nil && !errors.Is(err, errEventNotReplayable) {
+			utils.LogError(err, "Stripe event "+id+" failed again dans RetryFailedEvents")
+		}
+	}
+}
+
+// StartEventRetryWorker relance périodiquement les événements en échec (à lancer dans une goroutine)
+func StartEventRetryWorker(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		RetryFailedEvents()
+	}
+}
+
+// GetStripeEvents lists the Stripe events of the log (admin only)
+// @Summary List Stripe webhook events
+// @Description List the received Stripe events, failed ones by default (admin only)
+// @Tags stripe
+// @Produce json
+// @Param status query string false "Event status (PROCESSING, PROCESSED, FAILED, IGNORED)" default(FAILED)
+// @Param limit query int false "Maximum number of events" default(50)
+// @Security BearerAuth
+// @Success 200 {array} models.StripeEvent
+// @Failure 400 {object} map[string]string "error: Invalid limit"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 403 {object} map[string]string "error: Access denied: admin role required"
+// @Failure 500 {object} map[string]string "error: Error fetching events"
+// @Router /stripe/events [get]
+func GetStripeEvents(c *gin.Context) {
+	status := c.DefaultQuery("status", string(models.StripeEventFailed))
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		utils.LogError(err, "Invalid limit in GetStripeEvents")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	var events []models.StripeEvent
+	if err := db.DB.Where("status = ?", status).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&events).Error; err != nil {
+		utils.LogError(err, "Error fetching stripe events in GetStripeEvents")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching events"})
+		return
+	}
+
+	utils.LogSuccess("Stripe events fetched successfully in GetStripeEvents")
+	c.JSON(http.StatusOK, events)
+}
+
+// ReplayStripeEvent replays a failed Stripe event (admin only)
+// @Summary Replay a failed Stripe event
+// @Description Process a failed Stripe event again, even if its automatic retries are exhausted (admin only)
+// @Tags stripe
+// @Produce json
+// @Param id path string true "Stripe event ID"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "message: Result of the processing"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 403 {object} map[string]string "error: Access denied: admin role required"
+// @Failure 404 {object} map[string]string "error: Event not found"
+// @Failure 409 {object} map[string]string "error: Event is not in a replayable state"
+// @Failure 500 {object} map[string]string "error: Replay failed"
+// @Router /stripe/events/{id}/replay [post]
+func ReplayStripeEvent(c *gin.Context) {
+	eventID := c.Param("id")
+
+	var record models.StripeEvent
+	if err := db.DB.First(&record, "id = ?", eventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.LogError(err, "Event not found in ReplayStripeEvent")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+		utils.LogError(err, "Error fetching event in ReplayStripeEvent")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching event"})
+		return
+	}
+
+	message, err := replayEvent(eventID)
+	if errors.Is(err, errEventNotReplayable) {
+		utils.LogError(err, "Event not replayable in ReplayStripeEvent")
+		c.JSON(http.StatusConflict, gin.H{"error": "Event is not in a replayable state", "status": record.Status})
+		return
+	}
+	if err != nil {
+		utils.LogError(err, "Replay failed in ReplayStripeEvent")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Replay failed: " + err.Error()})
+		return
+	}
+
+	utils.LogSuccess("Stripe event " + eventID + " replayed in ReplayStripeEvent")
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
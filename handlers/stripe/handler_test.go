@@ -0,0 +1,1740 @@
+package stripe
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"pec2-backend/invoices"
+	"pec2-backend/ledger"
+	"pec2-backend/models"
+	"pec2-backend/payments"
+	"pec2-backend/testutils"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	stripe "github.com/stripe/stripe-go/v82"
+	"github.com/stripe/stripe-go/v82/webhook"
+)
+
+const testWebhookSecret = "whsec_test_secret"
+
+func TestMain(m *testing.M) {
+	testutils.InitTestMain()
+
+	log.SetOutput(io.Discard)
+	os.Setenv("STRIPE_WEBHOOK_SECRET", testWebhookSecret)
+
+	exitCode := m.Run()
+
+	log.SetOutput(os.Stdout)
+
+	os.Exit(exitCode)
+}
+
+// signedWebhookRequest construit une requête webhook signée comme le ferait Stripe
+func signedWebhookRequest(t *testing.T, eventID string, eventType string, object map[string]interface{}) *http.Request {
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":          eventID,
+		"object":      "event",
+		"type":        eventType,
+		"api_version": stripe.APIVersion,
+		"data":        map[string]interface{}{"object": object},
+	})
+	assert.NoError(t, err)
+
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload: payload,
+		Secret:  testWebhookSecret,
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "/stripe/webhook", bytes.NewReader(signed.Payload))
+	req.Header.Set("Stripe-Signature", signed.Header)
+	return req
+}
+
+// Test qu'un événement non géré est journalisé comme ignoré
+func TestStripeWebhook_IgnoredEvent(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "stripe_events" (.+) ON CONFLICT DO NOTHING`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "stripe_events" SET (.+) WHERE id = \$\d+`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/webhook", StripeWebhookHandler)
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, signedWebhookRequest(t, "evt_ignored", "customer.created", map[string]interface{}{"id": "cus_1"}))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var respBody map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "Event ignored", respBody["message"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'une livraison en double ne déclenche aucun traitement
+func TestStripeWebhook_DuplicateDelivery(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "stripe_events" (.+) ON CONFLICT DO NOTHING`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/webhook", StripeWebhookHandler)
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, signedWebhookRequest(t, "evt_duplicate", "payment_intent.succeeded", map[string]interface{}{"id": "pi_1"}))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var respBody map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "Event already received", respBody["message"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test le refus d'une signature invalide
+func TestStripeWebhook_InvalidSignature(t *testing.T) {
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/webhook", StripeWebhookHandler)
+
+	req := signedWebhookRequest(t, "evt_1", "customer.created", map[string]interface{}{})
+	req.Header.Set("Stripe-Signature", "t=1,v1=invalid")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestNextEventAttempt(t *testing.T) {
+	next := nextEventAttempt(1, errSubscriptionNotReady)
+	assert.NotNil(t, next)
+	assert.WithinDuration(t, time.Now().Add(baseEventRetryDelay), *next, time.Second)
+
+	next = nextEventAttempt(3, errSubscriptionNotReady)
+	assert.NotNil(t, next)
+	assert.WithinDuration(t, time.Now().Add(4*baseEventRetryDelay), *next, time.Second)
+
+	assert.Nil(t, nextEventAttempt(maxEventAttempts, errSubscriptionNotReady))
+	assert.Nil(t, nextEventAttempt(1, permanent(errors.New("invalid payload"))))
+}
+
+// Test qu'un événement déjà traité ne peut pas être rejoué
+func TestReplayStripeEvent_NotReplayable(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT \* FROM "stripe_events" WHERE id = \$1`).
+		WithArgs("evt_done", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "type", "status"}).AddRow("evt_done", "invoice.payment_succeeded", "PROCESSED"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "stripe_events" SET "status"=\$1,"updated_at"=\$2 WHERE id = \$3 AND \(status = \$4 OR \(status = \$5 AND updated_at < \$6\)\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/events/:id/replay", ReplayStripeEvent)
+
+	req, _ := http.NewRequest(http.MethodPost, "/stripe/events/evt_done/replay", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test le rejeu d'un événement en échec
+func TestReplayStripeEvent_Success(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"id":   "evt_failed",
+		"type": "payment_intent.created",
+		"data": map[string]interface{}{"object": map[string]interface{}{"id": "pi_1"}},
+	})
+
+	mock.ExpectQuery(`SELECT \* FROM "stripe_events" WHERE id = \$1`).
+		WithArgs("evt_failed", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "type", "status"}).AddRow("evt_failed", "payment_intent.created", "FAILED"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "stripe_events" SET "status"=\$1`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT \* FROM "stripe_events" WHERE id = \$1`).
+		WithArgs("evt_failed", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "type", "payload", "status", "attempts"}).
+			AddRow("evt_failed", "payment_intent.created", string(payload), "PROCESSING", 3))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "stripe_events" SET "attempts"=\$1,(.+)"status"=\$\d+`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/events/:id/replay", ReplayStripeEvent)
+
+	req, _ := http.NewRequest(http.MethodPost, "/stripe/events/evt_failed/replay", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var respBody map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "PaymentIntent created - logged", respBody["message"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectEventRecorded attend la journalisation puis la mise à jour du statut de l'événement
+func expectEventRecorded(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "stripe_events" (.+) ON CONFLICT DO NOTHING`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func expectEventStatusUpdate(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "stripe_events" SET (.+) WHERE id = \$\d+`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+// expectLedgerPayment attend la comptabilisation d'un paiement au grand livre
+func expectLedgerPayment(mock sqlmock.Sqlmock, sourceType models.LedgerSourceType, sourceID string, amount int) {
+	creatorShare, commission := ledger.Split(amount, ledger.CommissionPercent())
+	mock.ExpectQuery(`INSERT INTO "ledger_transactions" (.+) ON CONFLICT DO NOTHING RETURNING "id"`).
+		WithArgs(models.LedgerTransactionPayment, sourceType, sourceID, sqlmock.AnyArg(), amount, commission, creatorShare, "eur", nil, sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("ledger-payment"))
+	mock.ExpectQuery(`INSERT INTO "ledger_entries"`).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("entry-1").AddRow("entry-2").AddRow("entry-3"))
+}
+
+// expectInvoiceIssued attend l'émission de la facture d'un paiement réussi, le vendeur n'ayant de TVA que s'il a un numéro
+func expectInvoiceIssued(mock sqlmock.Sqlmock, creatorID string, paymentID string, amount int, vatNumber string) {
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1, hashtext\(\$2\)\)`).
+		WithArgs(sqlmock.AnyArg(), creatorID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "invoices" WHERE subscription_payment_id = \$1`).
+		WithArgs(paymentID).
+		WillReturnRows(mock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "first_name", "last_name", "email"}).AddRow("subscriber-uuid", "fan", "Jean", "Dupont", "jean@example.com"))
+	infoRows := mock.NewRows([]string{"id", "company_name", "siret_number", "vat_number", "status"})
+	if vatNumber != "" {
+		infoRows.AddRow("info-uuid", "Studio SAS", "12345678900011", vatNumber, models.ContentCreatorStatusApproved)
+	}
+	mock.ExpectQuery(`SELECT \* FROM "content_creator_info" WHERE user_id = \$1 AND status = \$2`).
+		WillReturnRows(infoRows)
+	mock.ExpectQuery(`SELECT COALESCE\(MAX\(sequence\),0\) FROM "invoices" WHERE content_creator_id = \$1`).
+		WithArgs(creatorID).
+		WillReturnRows(mock.NewRows([]string{"max"}).AddRow(41))
+
+	vatRate := 0
+	if vatNumber != "" {
+		vatRate = invoices.StandardVatRate
+	}
+	amountExcludingTax, vat := invoices.Split(amount, vatRate)
+	mock.ExpectQuery(`INSERT INTO "invoices"`).
+		WithArgs(int64(42), sqlmock.AnyArg(), paymentID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), vatNumber, sqlmock.AnyArg(), "Jean Dupont", "jean@example.com", sqlmock.AnyArg(),
+			amountExcludingTax, vatRate, vat, amount, "eur", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("invoice-uuid"))
+}
+
+func TestLocalSubscriptionStatus(t *testing.T) {
+	tests := []struct {
+		subscription stripe.Subscription
+		expected     models.SubscriptionStatus
+	}{
+		{stripe.Subscription{Status: stripe.SubscriptionStatusActive}, models.SubscriptionActive},
+		{stripe.Subscription{Status: stripe.SubscriptionStatusTrialing}, models.SubscriptionActive},
+		{stripe.Subscription{Status: stripe.SubscriptionStatusPastDue}, models.SubscriptionPastDue},
+		{stripe.Subscription{Status: stripe.SubscriptionStatusIncomplete}, models.SubscriptionPending},
+		{stripe.Subscription{Status: stripe.SubscriptionStatusIncompleteExpired}, models.SubscriptionExpired},
+		{stripe.Subscription{Status: stripe.SubscriptionStatusCanceled}, models.SubscriptionCanceled},
+		{stripe.Subscription{
+			Status:              stripe.SubscriptionStatusCanceled,
+			CancellationDetails: &stripe.SubscriptionCancellationDetails{Reason: stripe.SubscriptionCancellationDetailsReasonPaymentFailed},
+		}, models.SubscriptionExpired},
+	}
+
+	for _, tt := range tests {
+		status, ok := localSubscriptionStatus(&tt.subscription)
+		assert.True(t, ok)
+		assert.Equal(t, tt.expected, status, string(tt.subscription.Status))
+	}
+}
+
+// Test qu'une résiliation programmée côté Stripe est répercutée avec la fin de période
+func TestStripeWebhook_SubscriptionUpdated(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	periodEnd := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE stripe_subscription_id = \$1`).
+		WithArgs("sub_123", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "status", "stripe_subscription_id"}).AddRow("local-sub", "ACTIVE", "sub_123"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "subscriptions" SET "cancel_at_period_end"=\$1,"end_date"=\$2,"status"=\$3,"updated_at"=\$4 WHERE "id" = \$5`).
+		WithArgs(true, periodEnd.Local(), models.SubscriptionActive, sqlmock.AnyArg(), "local-sub").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/webhook", StripeWebhookHandler)
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, signedWebhookRequest(t, "evt_sub_updated", "customer.subscription.updated", map[string]interface{}{
+		"id":                   "sub_123",
+		"object":               "subscription",
+		"status":               "active",
+		"cancel_at_period_end": true,
+		"items": map[string]interface{}{
+			"object": "list",
+			"data":   []map[string]interface{}{{"id": "si_1", "current_period_end": periodEnd.Unix()}},
+		},
+	}))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var respBody map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "Subscription updated", respBody["message"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectLedgerReversal attend la reprise d'une partie d'un paiement comptabilisé, alreadyReversed étant
+// le cumul des reprises précédentes lu dans le grand livre
+func expectLedgerReversal(mock sqlmock.Sqlmock, sourceType models.LedgerSourceType, sourceID string, paymentSourceType models.LedgerSourceType, paymentID string, paymentAmount int, alreadyReversed int, amount int) {
+	_, paymentCommission := ledger.Split(paymentAmount, ledger.CommissionPercent())
+	commission := paymentCommission*(alreadyReversed+amount)/paymentAmount - paymentCommission*alreadyReversed/paymentAmount
+	mock.ExpectQuery(`SELECT \* FROM "ledger_transactions" WHERE kind = \$1 AND source_type = \$2 AND source_id = \$3`).
+		WithArgs(models.LedgerTransactionPayment, paymentSourceType, paymentID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "kind", "source_type", "source_id", "content_creator_id", "amount", "commission", "net", "currency"}).
+			AddRow("ledger-payment", models.LedgerTransactionPayment, paymentSourceType, paymentID, "creator-uuid", paymentAmount, paymentCommission, paymentAmount-paymentCommission, "eur"))
+	mock.ExpectQuery(`SELECT COALESCE\(-SUM\(amount\),0\) FROM "ledger_transactions" WHERE reversed_transaction_id = \$1`).
+		WithArgs("ledger-payment").
+		WillReturnRows(mock.NewRows([]string{"coalesce"}).AddRow(alreadyReversed))
+	mock.ExpectQuery(`INSERT INTO "ledger_transactions" (.+) ON CONFLICT DO NOTHING RETURNING "id"`).
+		WithArgs(models.LedgerTransactionRefund, sourceType, sourceID, "creator-uuid", -amount, -commission, -(amount - commission), "eur", "ledger-payment", sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("ledger-reversal"))
+	mock.ExpectQuery(`INSERT INTO "ledger_entries"`).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("entry-4").AddRow("entry-5").AddRow("entry-6"))
+}
+
+// Test qu'un PaymentIntent d'abonnement pas encore rattaché par sa facture n'est attribué à aucun
+// abonnement : le client peut en avoir plusieurs ouverts, chez des créateurs différents
+func TestStripeWebhook_PaymentIntentSucceeded_LeftToInvoice(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs("pi_subscription", 1).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+	expectEventStatusUpdate(mock)
+
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/webhook", StripeWebhookHandler)
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, signedWebhookRequest(t, "evt_pi_succeeded", "payment_intent.succeeded", map[string]interface{}{
+		"id":              "pi_subscription",
+		"object":          "payment_intent",
+		"customer":        "cus_fan",
+		"amount":          999,
+		"amount_received": 999,
+		"status":          "succeeded",
+	}))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var respBody map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "Subscription payment handled by invoice events", respBody["message"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test que l'échec de paiement envoyé par Stripe clôt le pourboire et l'achat en attente
+func TestStripeWebhook_PaymentIntentPaymentFailed(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/webhook", StripeWebhookHandler)
+
+	failedPaymentIntent := func(id string, metadata map[string]string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":       id,
+			"object":   "payment_intent",
+			"customer": "cus_fan",
+			"amount":   500,
+			"status":   "requires_payment_method",
+			"metadata": metadata,
+		}
+	}
+
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "tips" WHERE id = \$1`).
+		WithArgs("tip-uuid", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "sender_id", "content_creator_id", "amount", "status"}).
+			AddRow("tip-uuid", "sender-uuid", "creator-uuid", 500, models.TipPending))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "tips" SET "status"=\$1,"stripe_payment_intent_id"=\$2,"updated_at"=\$3 WHERE "id" = \$4`).
+		WithArgs(models.TipFailed, "pi_tip", sqlmock.AnyArg(), "tip-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, signedWebhookRequest(t, "evt_tip_failed", "payment_intent.payment_failed",
+		failedPaymentIntent("pi_tip", map[string]string{paymentTypeMetadata: tipPaymentType, "tip_id": "tip-uuid"})))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "Tip updated to "+string(models.TipFailed))
+
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "post_purchases" WHERE id = \$1`).
+		WithArgs("purchase-uuid", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "post_id", "buyer_id", "content_creator_id", "amount", "status"}).
+			AddRow("purchase-uuid", "post-uuid", "buyer-uuid", "creator-uuid", 500, models.PostPurchasePending))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "post_purchases" SET "status"=\$1,"stripe_payment_intent_id"=\$2,"updated_at"=\$3 WHERE "id" = \$4`).
+		WithArgs(models.PostPurchaseFailed, "pi_purchase", sqlmock.AnyArg(), "purchase-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, signedWebhookRequest(t, "evt_purchase_failed", "payment_intent.payment_failed",
+		failedPaymentIntent("pi_purchase", map[string]string{paymentTypeMetadata: postPurchasePaymentType, "purchase_id": "purchase-uuid"})))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "Purchase updated to "+string(models.PostPurchaseFailed))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un remboursement total clôt l'abonnement
+func TestStripeWebhook_ChargeRefunded(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs("pi_refunded", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "subscription_id", "amount", "refunded_amount", "status"}).AddRow("payment-1", "local-sub", 999, 0, "SUCCEEDED"))
+	mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE id = \$1`).
+		WithArgs("local-sub", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "status", "stripe_subscription_id"}).AddRow("local-sub", "ACTIVE", ""))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "payment_refunds"`).
+		WithArgs("payment-1", nil, nil, 999, sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("refund-uuid"))
+	mock.ExpectExec(`UPDATE "subscription_payments" SET "refunded_amount"=\$1,"status"=\$2,"updated_at"=\$3 WHERE "id" = \$4`).
+		WithArgs(999, models.SubscriptionPaymentRefunded, sqlmock.AnyArg(), "payment-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerReversal(mock, models.LedgerSourcePaymentRefund, "refund-uuid", models.LedgerSourceSubscriptionPayment, "payment-1", 999, 0, 999)
+	mock.ExpectExec(`UPDATE "subscriptions" SET "end_date"=\$1,"status"=\$2,"updated_at"=\$3 WHERE "id" = \$4`).
+		WithArgs(sqlmock.AnyArg(), models.SubscriptionCanceled, sqlmock.AnyArg(), "local-sub").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/webhook", StripeWebhookHandler)
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, signedWebhookRequest(t, "evt_refund", "charge.refunded", map[string]interface{}{
+		"id":              "ch_1",
+		"object":          "charge",
+		"amount":          999,
+		"amount_refunded": 999,
+		"refunded":        true,
+		"payment_intent":  "pi_refunded",
+	}))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var respBody map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "Payment refunded - subscription canceled", respBody["message"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un remboursement partiel demandé par un admin est enregistré sans couper l'accès
+func TestRefundSubscriptionPayment_Partial_WithFakeProvider(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	fake := payments.NewFakeProvider(testWebhookSecret)
+	previous := payments.Default
+	payments.Default = fake
+	defer func() { payments.Default = previous }()
+
+	customerID, err := fake.EnsureCustomer("", "subscriber")
+	assert.NoError(t, err)
+	session, err := fake.CreateCheckoutSession(payments.CheckoutParams{
+		Mode:       payments.CheckoutModePayment,
+		CustomerID: customerID,
+		Currency:   "eur",
+		Amount:     999,
+	})
+	assert.NoError(t, err)
+	_, paymentIntentID, err := fake.CompleteCheckout(session.ID)
+	assert.NoError(t, err)
+	fake.Webhooks()
+
+	paymentID := "4d3c2b1a-0f9e-4d8c-b7a6-f5e4d3c2b1a0"
+	paymentRow := func(refunded int) *sqlmock.Rows {
+		return mock.NewRows([]string{"id", "subscription_id", "amount", "refunded_amount", "stripe_payment_intent_id", "status"}).
+			AddRow(paymentID, "local-sub", 999, refunded, paymentIntentID, models.SubscriptionPaymentSucceeded)
+	}
+
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/webhook", StripeWebhookHandler)
+	r.POST("/subscriptions/payments/:paymentId/refund", func(c *gin.Context) {
+		c.Set("user_id", "admin-uuid")
+		RefundSubscriptionPayment(c)
+	})
+
+	// Un montant supérieur au paiement est refusé
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE id = \$1`).
+		WithArgs(paymentID, 1).
+		WillReturnRows(paymentRow(0))
+	body, _ := json.Marshal(models.PaymentRefundRequest{Amount: 1500})
+	req, _ := http.NewRequest(http.MethodPost, "/subscriptions/payments/"+paymentID+"/refund", bytes.NewReader(body))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE id = \$1`).
+		WithArgs(paymentID, 1).
+		WillReturnRows(paymentRow(0))
+	body, _ = json.Marshal(models.PaymentRefundRequest{Amount: 300})
+	req, _ = http.NewRequest(http.MethodPost, "/subscriptions/payments/"+paymentID+"/refund", bytes.NewReader(body))
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+
+	// charge.refunded : le remboursement partiel est enregistré, l'abonnement reste actif
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs(paymentIntentID, 1).
+		WillReturnRows(paymentRow(0))
+	mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE id = \$1`).
+		WithArgs("local-sub", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "status"}).AddRow("local-sub", models.SubscriptionActive))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "payment_refunds"`).
+		WithArgs(paymentID, nil, nil, 300, sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("refund-uuid"))
+	mock.ExpectExec(`UPDATE "subscription_payments" SET "refunded_amount"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+		WithArgs(300, sqlmock.AnyArg(), paymentID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerReversal(mock, models.LedgerSourcePaymentRefund, "refund-uuid", models.LedgerSourceSubscriptionPayment, paymentID, 999, 0, 300)
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+	assert.NoError(t, fake.Deliver(r, "/stripe/webhook"))
+
+	// Une livraison en double du même remboursement est sans effet
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs(paymentIntentID, 1).
+		WillReturnRows(paymentRow(300))
+	message, err := handleChargeRefunded(stripe.Event{Data: &stripe.EventData{Raw: json.RawMessage(`{"id":"ch_1","object":"charge","amount":999,"amount_refunded":300,"refunded":false,"payment_intent":"` + paymentIntentID + `"}`)}})
+	assert.NoError(t, err)
+	assert.Equal(t, "Refund already recorded", message)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'une contestation débite le créateur puis le recrédite quand elle est gagnée
+func TestStripeWebhook_DisputeWon(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	paymentRow := func(status models.SubscriptionPaymentStatus) *sqlmock.Rows {
+		return mock.NewRows([]string{"id", "subscription_id", "amount", "refunded_amount", "stripe_payment_intent_id", "status"}).
+			AddRow("payment-1", "local-sub", 999, 0, "pi_disputed", status)
+	}
+	dispute := func(status string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":             "dp_1",
+			"object":         "dispute",
+			"amount":         999,
+			"payment_intent": "pi_disputed",
+			"reason":         "fraudulent",
+			"status":         status,
+		}
+	}
+
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/webhook", StripeWebhookHandler)
+
+	// charge.dispute.created : paiement contesté et fonds repris au créateur
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs("pi_disputed", 1).
+		WillReturnRows(paymentRow(models.SubscriptionPaymentSucceeded))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "payment_disputes" (.+) ON CONFLICT DO NOTHING RETURNING "id"`).
+		WithArgs("payment-1", nil, nil, "dp_1", 999, "fraudulent", "needs_response", true, nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("dispute-uuid"))
+	mock.ExpectExec(`UPDATE "subscription_payments" SET "status"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+		WithArgs(models.SubscriptionPaymentDisputed, sqlmock.AnyArg(), "payment-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerReversal(mock, models.LedgerSourcePaymentDispute, "dispute-uuid", models.LedgerSourceSubscriptionPayment, "payment-1", 999, 0, 999)
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, signedWebhookRequest(t, "evt_dispute_created", "charge.dispute.created", dispute("needs_response")))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var respBody map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "Dispute recorded - payment disputed", respBody["message"])
+
+	// charge.dispute.closed gagnée : paiement rétabli et reprise annulée au grand livre
+	_, commission := ledger.Split(999, ledger.CommissionPercent())
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs("pi_disputed", 1).
+		WillReturnRows(paymentRow(models.SubscriptionPaymentDisputed))
+	mock.ExpectQuery(`SELECT \* FROM "payment_disputes" WHERE stripe_dispute_id = \$1`).
+		WithArgs("dp_1", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "subscription_payment_id", "stripe_dispute_id", "amount", "status", "funds_withdrawn"}).
+			AddRow("dispute-uuid", "payment-1", "dp_1", 999, "needs_response", true))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "payment_disputes" SET "closed_at"=\$1,"status"=\$2,"updated_at"=\$3 WHERE "id" = \$4`).
+		WithArgs(sqlmock.AnyArg(), "won", sqlmock.AnyArg(), "dispute-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "payment_disputes" SET "funds_withdrawn"=\$1,"updated_at"=\$2 WHERE funds_withdrawn = \$3 AND "id" = \$4`).
+		WithArgs(false, sqlmock.AnyArg(), true, "dispute-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "subscription_payments" SET "status"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+		WithArgs(models.SubscriptionPaymentSucceeded, sqlmock.AnyArg(), "payment-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT \* FROM "ledger_transactions" WHERE kind = \$1 AND source_type = \$2 AND source_id = \$3`).
+		WithArgs(models.LedgerTransactionRefund, models.LedgerSourcePaymentDispute, "dispute-uuid", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "kind", "source_type", "source_id", "content_creator_id", "amount", "commission", "net", "currency", "reversed_transaction_id"}).
+			AddRow("ledger-reversal", models.LedgerTransactionRefund, models.LedgerSourcePaymentDispute, "dispute-uuid", "creator-uuid", -999, -commission, -(999 - commission), "eur", "ledger-payment"))
+	mock.ExpectQuery(`INSERT INTO "ledger_transactions" (.+) ON CONFLICT DO NOTHING RETURNING "id"`).
+		WithArgs(models.LedgerTransactionPayment, models.LedgerSourcePaymentDispute, "dispute-uuid", "creator-uuid", 999, commission, 999-commission, "eur", "ledger-payment", sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("ledger-reinstatement"))
+	mock.ExpectQuery(`INSERT INTO "ledger_entries"`).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("entry-7").AddRow("entry-8").AddRow("entry-9"))
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, signedWebhookRequest(t, "evt_dispute_closed", "charge.dispute.closed", dispute("won")))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "Dispute closed", respBody["message"])
+
+	// charge.dispute.funds_reinstated, envoyé avec la clôture : les fonds sont déjà rendus
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs("pi_disputed", 1).
+		WillReturnRows(paymentRow(models.SubscriptionPaymentSucceeded))
+	mock.ExpectQuery(`SELECT \* FROM "payment_disputes" WHERE stripe_dispute_id = \$1`).
+		WithArgs("dp_1", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "subscription_payment_id", "stripe_dispute_id", "amount", "status", "funds_withdrawn", "closed_at"}).
+			AddRow("dispute-uuid", "payment-1", "dp_1", 999, "won", false, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "payment_disputes" SET "funds_withdrawn"=\$1,"updated_at"=\$2 WHERE funds_withdrawn = \$3 AND "id" = \$4`).
+		WithArgs(false, sqlmock.AnyArg(), true, "dispute-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, signedWebhookRequest(t, "evt_dispute_reinstated", "charge.dispute.funds_reinstated", dispute("won")))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "Dispute funds already reinstated", respBody["message"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'une demande de renseignements ne débite rien, mais que le créateur est débité
+// dès qu'elle devient un litige, et une seule fois
+func TestStripeWebhook_DisputeInquiryEscalated(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	paymentRow := func(status models.SubscriptionPaymentStatus) *sqlmock.Rows {
+		return mock.NewRows([]string{"id", "subscription_id", "amount", "refunded_amount", "stripe_payment_intent_id", "status"}).
+			AddRow("payment-2", "local-sub", 999, 0, "pi_inquiry", status)
+	}
+	disputeRow := func(status string, withdrawn bool) *sqlmock.Rows {
+		return mock.NewRows([]string{"id", "subscription_payment_id", "stripe_dispute_id", "amount", "status", "funds_withdrawn"}).
+			AddRow("inquiry-uuid", "payment-2", "dp_2", 999, status, withdrawn)
+	}
+	dispute := func(status string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":             "dp_2",
+			"object":         "dispute",
+			"amount":         999,
+			"payment_intent": "pi_inquiry",
+			"reason":         "product_not_received",
+			"status":         status,
+		}
+	}
+
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/webhook", StripeWebhookHandler)
+
+	// charge.dispute.created en demande de renseignements : rien n'est repris
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs("pi_inquiry", 1).
+		WillReturnRows(paymentRow(models.SubscriptionPaymentSucceeded))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "payment_disputes" (.+) ON CONFLICT DO NOTHING RETURNING "id"`).
+		WithArgs("payment-2", nil, nil, "dp_2", 999, "product_not_received", "warning_needs_response", false, nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("inquiry-uuid"))
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, signedWebhookRequest(t, "evt_inquiry_created", "charge.dispute.created", dispute("warning_needs_response")))
+	var respBody map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "Dispute recorded", respBody["message"])
+
+	// charge.dispute.updated en litige : le paiement est contesté et le créateur débité
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs("pi_inquiry", 1).
+		WillReturnRows(paymentRow(models.SubscriptionPaymentSucceeded))
+	mock.ExpectQuery(`SELECT \* FROM "payment_disputes" WHERE stripe_dispute_id = \$1`).
+		WithArgs("dp_2", 1).
+		WillReturnRows(disputeRow("warning_needs_response", false))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "payment_disputes" SET "reason"=\$1,"status"=\$2,"updated_at"=\$3 WHERE "id" = \$4`).
+		WithArgs("product_not_received", "needs_response", sqlmock.AnyArg(), "inquiry-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "payment_disputes" SET "funds_withdrawn"=\$1,"updated_at"=\$2 WHERE funds_withdrawn = \$3 AND "id" = \$4`).
+		WithArgs(true, sqlmock.AnyArg(), false, "inquiry-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "subscription_payments" SET "status"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+		WithArgs(models.SubscriptionPaymentDisputed, sqlmock.AnyArg(), "payment-2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerReversal(mock, models.LedgerSourcePaymentDispute, "inquiry-uuid", models.LedgerSourceSubscriptionPayment, "payment-2", 999, 0, 999)
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, signedWebhookRequest(t, "evt_inquiry_updated", "charge.dispute.updated", dispute("needs_response")))
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "Dispute updated - payment disputed", respBody["message"])
+
+	// charge.dispute.funds_withdrawn qui suit : les fonds sont déjà repris
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs("pi_inquiry", 1).
+		WillReturnRows(paymentRow(models.SubscriptionPaymentDisputed))
+	mock.ExpectQuery(`SELECT \* FROM "payment_disputes" WHERE stripe_dispute_id = \$1`).
+		WithArgs("dp_2", 1).
+		WillReturnRows(disputeRow("needs_response", true))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "payment_disputes" SET "funds_withdrawn"=\$1,"updated_at"=\$2 WHERE funds_withdrawn = \$3 AND "id" = \$4`).
+		WithArgs(true, sqlmock.AnyArg(), false, "inquiry-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, signedWebhookRequest(t, "evt_inquiry_withdrawn", "charge.dispute.funds_withdrawn", dispute("needs_response")))
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "Dispute funds already withdrawn", respBody["message"])
+
+	// charge.dispute.closed perdue : l'abonnement est coupé sans nouveau débit
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs("pi_inquiry", 1).
+		WillReturnRows(paymentRow(models.SubscriptionPaymentDisputed))
+	mock.ExpectQuery(`SELECT \* FROM "payment_disputes" WHERE stripe_dispute_id = \$1`).
+		WithArgs("dp_2", 1).
+		WillReturnRows(disputeRow("needs_response", true))
+	mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE id = \$1`).
+		WithArgs("local-sub", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "status"}).AddRow("local-sub", models.SubscriptionActive))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "payment_disputes" SET "closed_at"=\$1,"status"=\$2,"updated_at"=\$3 WHERE "id" = \$4`).
+		WithArgs(sqlmock.AnyArg(), "lost", sqlmock.AnyArg(), "inquiry-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "payment_disputes" SET "funds_withdrawn"=\$1,"updated_at"=\$2 WHERE funds_withdrawn = \$3 AND "id" = \$4`).
+		WithArgs(true, sqlmock.AnyArg(), false, "inquiry-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE "subscriptions" SET "end_date"=\$1,"status"=\$2,"updated_at"=\$3 WHERE "id" = \$4`).
+		WithArgs(sqlmock.AnyArg(), models.SubscriptionCanceled, sqlmock.AnyArg(), "local-sub").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, signedWebhookRequest(t, "evt_inquiry_lost", "charge.dispute.closed", dispute("lost")))
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "Dispute lost - subscription canceled", respBody["message"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'une contestation sur un achat de post débite le créateur et reverrouille le post,
+// sans abonnement à couper quand elle est perdue
+func TestStripeWebhook_PostPurchaseDisputeLost(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	purchaseID := "c2d7e9a1-4b3f-4e68-9a05-d81f6b2c7e43"
+	expectPaymentLookup := func(status models.PostPurchaseStatus) {
+		mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+			WithArgs("pi_purchase", 1).
+			WillReturnRows(mock.NewRows([]string{"id"}))
+		mock.ExpectQuery(`SELECT \* FROM "tips" WHERE stripe_payment_intent_id = \$1`).
+			WithArgs("pi_purchase", 1).
+			WillReturnRows(mock.NewRows([]string{"id"}))
+		mock.ExpectQuery(`SELECT \* FROM "post_purchases" WHERE stripe_payment_intent_id = \$1`).
+			WithArgs("pi_purchase", 1).
+			WillReturnRows(mock.NewRows([]string{"id", "amount", "refunded_amount", "stripe_payment_intent_id", "status"}).
+				AddRow(purchaseID, 499, 0, "pi_purchase", status))
+	}
+	dispute := func(status string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":             "dp_3",
+			"object":         "dispute",
+			"amount":         499,
+			"payment_intent": "pi_purchase",
+			"reason":         "fraudulent",
+			"status":         status,
+		}
+	}
+
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/webhook", StripeWebhookHandler)
+
+	// charge.dispute.created : l'achat est contesté et le post de nouveau verrouillé
+	expectEventRecorded(mock)
+	expectPaymentLookup(models.PostPurchaseSucceeded)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "payment_disputes" (.+) ON CONFLICT DO NOTHING RETURNING "id"`).
+		WithArgs(nil, nil, purchaseID, "dp_3", 499, "fraudulent", "needs_response", true, nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("purchase-dispute-uuid"))
+	mock.ExpectExec(`UPDATE "post_purchases" SET "status"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+		WithArgs(models.PostPurchaseDisputed, sqlmock.AnyArg(), purchaseID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerReversal(mock, models.LedgerSourcePaymentDispute, "purchase-dispute-uuid", models.LedgerSourcePostPurchase, purchaseID, 499, 0, 499)
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, signedWebhookRequest(t, "evt_purchase_dispute_created", "charge.dispute.created", dispute("needs_response")))
+	var respBody map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "Dispute recorded - payment disputed", respBody["message"])
+
+	// charge.dispute.closed perdue : les fonds restent repris, aucun abonnement n'est recherché
+	expectEventRecorded(mock)
+	expectPaymentLookup(models.PostPurchaseDisputed)
+	mock.ExpectQuery(`SELECT \* FROM "payment_disputes" WHERE stripe_dispute_id = \$1`).
+		WithArgs("dp_3", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "post_purchase_id", "stripe_dispute_id", "amount", "status", "funds_withdrawn"}).
+			AddRow("purchase-dispute-uuid", purchaseID, "dp_3", 499, "needs_response", true))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "payment_disputes" SET "closed_at"=\$1,"status"=\$2,"updated_at"=\$3 WHERE "id" = \$4`).
+		WithArgs(sqlmock.AnyArg(), "lost", sqlmock.AnyArg(), "purchase-dispute-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "payment_disputes" SET "funds_withdrawn"=\$1,"updated_at"=\$2 WHERE funds_withdrawn = \$3 AND "id" = \$4`).
+		WithArgs(true, sqlmock.AnyArg(), false, "purchase-dispute-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, signedWebhookRequest(t, "evt_purchase_dispute_lost", "charge.dispute.closed", dispute("lost")))
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, "Dispute closed", respBody["message"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test que le chiffre d'affaires est lu au grand livre, reprises de tous les paiements déduites
+// et jour de fin inclus mais pas le lendemain
+func TestGetTotalRevenue_FromLedger(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT (.+) AS subscriptions, (.+) AS refunds, (.+) AS disputes FROM "ledger_transactions" WHERE kind <> \$9 AND created_at >= \$10 AND created_at < \$11`).
+		WithArgs(models.LedgerTransactionPayment, models.LedgerSourceSubscriptionPayment,
+			models.LedgerTransactionPayment, models.LedgerSourceTip,
+			models.LedgerTransactionPayment, models.LedgerSourcePostPurchase,
+			models.LedgerSourcePaymentRefund, models.LedgerSourcePaymentDispute,
+			models.LedgerTransactionPayout, start, end).
+		WillReturnRows(mock.NewRows([]string{"subscriptions", "tips", "purchases", "refunds", "disputes"}).
+			AddRow(10000, 1500, 499, 700, 999))
+
+	r := testutils.SetupTestRouter()
+	r.GET("/subscriptions/revenue", GetTotalRevenue)
+
+	req, _ := http.NewRequest(http.MethodGet, "/subscriptions/revenue?start_date=2026-03-01&end_date=2026-03-31", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var body map[string]int64
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, int64(10000+1500+499-700-999), body["total"])
+	assert.Equal(t, int64(700), body["refunds"])
+	assert.Equal(t, int64(999), body["disputes"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un abonnement échu sans abonnement Stripe est expiré
+func TestReconcileSubscriptions_ExpiresLapsedSubscription(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE status IN \(\$1,\$2,\$3\) AND end_date < \$4 ORDER BY end_date ASC LIMIT \$5`).
+		WithArgs(models.SubscriptionPending, models.SubscriptionActive, models.SubscriptionPastDue, sqlmock.AnyArg(), reconcileBatchSize).
+		WillReturnRows(mock.NewRows([]string{"id", "status", "stripe_subscription_id", "end_date"}).
+			AddRow("lapsed-sub", "ACTIVE", "", time.Now().Add(-time.Hour)))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "subscriptions" SET "status"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+		WithArgs(models.SubscriptionExpired, sqlmock.AnyArg(), "lapsed-sub").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ReconcileSubscriptions()
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test du parcours complet checkout → paiement → renouvellement → résiliation avec le prestataire en mémoire
+func TestSubscriptionLifecycle_WithFakeProvider(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	fake := payments.NewFakeProvider(testWebhookSecret)
+	previous := payments.Default
+	payments.Default = fake
+	defer func() { payments.Default = previous }()
+
+	subscriberID := "6a1f3c9e-1b6f-4a63-9d0a-3f2b8e1c7d10"
+	creatorID := "0c7d5b2a-8e4f-4f1a-b7c3-2d9e6a5f4b21"
+	subscriptionID := "9e8d7c6b-5a4f-4e3d-8c2b-1a0f9e8d7c6b"
+	planID := "3b2a1f0e-9d8c-4b7a-a6f5-e4d3c2b1a0f9"
+
+	withUser := func(handler gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set("user_id", subscriberID)
+			handler(c)
+		}
+	}
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/webhook", StripeWebhookHandler)
+	r.POST("/subscriptions/checkout/:contentCreatorId", withUser(CreateSubscriptionCheckoutSession))
+	r.DELETE("/subscriptions/:subscriptionId", withUser(CancelSubscription))
+
+	subscriptionRow := func(status models.SubscriptionStatus, stripeSubID string) *sqlmock.Rows {
+		return mock.NewRows([]string{"id", "user_id", "content_creator_id", "status", "stripe_subscription_id"}).
+			AddRow(subscriptionID, subscriberID, creatorID, status, stripeSubID)
+	}
+
+	// Création de la session de paiement
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WithArgs(subscriberID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(subscriberID, "subscriber", models.UserRole))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WithArgs(creatorID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(creatorID, "creator", models.ContentCreator))
+	mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE user_id = \$1 AND content_creator_id = \$2`).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "subscription_plans" WHERE \(content_creator_id = \$1 AND active = \$2\) AND id = \$3`).
+		WillReturnRows(mock.NewRows([]string{"id", "content_creator_id", "tier", "name", "price", "currency", "active"}).
+			AddRow(planID, creatorID, models.TierBasic, "Basic", 499, "eur", true))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "stripe_customer_id"=\$1`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	resp := httptest.NewRecorder()
+	body, _ := json.Marshal(models.SubscriptionCheckoutRequest{PlanID: planID})
+	req, _ := http.NewRequest(http.MethodPost, "/subscriptions/checkout/"+creatorID, bytes.NewReader(body))
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var checkout map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &checkout)
+	stripeSubID, _, err := fake.CompleteCheckout(checkout["sessionId"])
+	assert.NoError(t, err)
+
+	// checkout.session.completed : création de l'abonnement local
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE stripe_customer_id = \$1`).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(subscriberID, "subscriber", models.UserRole))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WithArgs(creatorID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(creatorID, "creator", models.ContentCreator))
+	mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE stripe_subscription_id = \$1`).
+		WithArgs(stripeSubID, 1).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE user_id = \$1 AND content_creator_id = \$2`).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "subscription_plans" WHERE id = \$1 AND content_creator_id = \$2`).
+		WithArgs(planID, creatorID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "content_creator_id", "tier", "price"}).AddRow(planID, creatorID, models.TierBasic, 499))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "subscriptions"`).
+		WillReturnRows(mock.NewRows([]string{"id", "cancel_at_period_end"}).AddRow(subscriptionID, false))
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	// invoice.payment_succeeded : premier paiement enregistré
+	expectInvoicePaid := func() {
+		expectEventRecorded(mock)
+		mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE stripe_subscription_id = \$1`).
+			WithArgs(stripeSubID, 1).
+			WillReturnRows(subscriptionRow(models.SubscriptionActive, stripeSubID))
+		mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+			WillReturnRows(mock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "subscription_payments"`).
+			WithArgs(subscriptionID, 499, 0, sqlmock.AnyArg(), sqlmock.AnyArg(), models.SubscriptionPaymentSucceeded, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(mock.NewRows([]string{"id"}).AddRow("payment-uuid"))
+		expectLedgerPayment(mock, models.LedgerSourceSubscriptionPayment, "payment-uuid", 499)
+		expectInvoiceIssued(mock, creatorID, "payment-uuid", 499, "")
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "subscriptions" SET "end_date"=\$1`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		expectEventStatusUpdate(mock)
+	}
+	expectInvoicePaid()
+	assert.NoError(t, fake.Deliver(r, "/stripe/webhook"))
+
+	// customer.subscription.updated : synchronisation de l'abonnement avec Stripe
+	expectSubscriptionSync := func(status models.SubscriptionStatus, cancelAtPeriodEnd bool) {
+		expectEventRecorded(mock)
+		mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE stripe_subscription_id = \$1`).
+			WithArgs(stripeSubID, 1).
+			WillReturnRows(subscriptionRow(models.SubscriptionActive, stripeSubID))
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "subscriptions" SET "cancel_at_period_end"=\$1,"end_date"=\$2,"status"=\$3`).
+			WithArgs(cancelAtPeriodEnd, sqlmock.AnyArg(), status, sqlmock.AnyArg(), subscriptionID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		expectEventStatusUpdate(mock)
+	}
+
+	// Renouvellement mensuel
+	_, err = fake.AdvancePeriod(stripeSubID)
+	assert.NoError(t, err)
+	expectInvoicePaid()
+	expectSubscriptionSync(models.SubscriptionActive, false)
+	assert.NoError(t, fake.Deliver(r, "/stripe/webhook"))
+
+	// Résiliation en fin de période demandée par l'abonné
+	mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE id = \$1`).
+		WithArgs(subscriptionID, 1).
+		WillReturnRows(subscriptionRow(models.SubscriptionActive, stripeSubID))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "subscriptions" SET "cancel_at_period_end"=\$1`).
+		WithArgs(true, sqlmock.AnyArg(), subscriptionID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	resp = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodDelete, "/subscriptions/"+subscriptionID, nil)
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	expectSubscriptionSync(models.SubscriptionActive, true)
+	assert.NoError(t, fake.Deliver(r, "/stripe/webhook"))
+
+	// Fin de période : Stripe supprime l'abonnement
+	_, err = fake.AdvancePeriod(stripeSubID)
+	assert.NoError(t, err)
+	expectSubscriptionSync(models.SubscriptionCanceled, true)
+	assert.NoError(t, fake.Deliver(r, "/stripe/webhook"))
+
+	s, err := fake.GetSubscription(stripeSubID)
+	assert.NoError(t, err)
+	assert.Equal(t, stripe.SubscriptionStatusCanceled, s.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un code promo dont toutes les utilisations sont prises est refusé
+func TestCreateSubscriptionCheckoutSession_PromoCodeLimitReached(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	fake := payments.NewFakeProvider(testWebhookSecret)
+	previous := payments.Default
+	payments.Default = fake
+	defer func() { payments.Default = previous }()
+
+	subscriberID := "6a1f3c9e-1b6f-4a63-9d0a-3f2b8e1c7d10"
+	creatorID := "0c7d5b2a-8e4f-4f1a-b7c3-2d9e6a5f4b21"
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WithArgs(subscriberID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(subscriberID, "subscriber", models.UserRole))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WithArgs(creatorID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(creatorID, "creator", models.ContentCreator))
+	mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE user_id = \$1 AND content_creator_id = \$2`).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "subscription_plans" WHERE content_creator_id = \$1 AND active = \$2 ORDER BY price ASC`).
+		WillReturnRows(mock.NewRows([]string{"id", "content_creator_id", "tier", "name", "price", "currency", "active"}).
+			AddRow("plan-uuid", creatorID, models.TierBasic, "Basic", 499, "eur", true))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "stripe_customer_id"=\$1`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "coupons" WHERE content_creator_id = \$1 AND code = \$2 AND active = \$3 ORDER BY "coupons"."id" LIMIT \$4 FOR UPDATE`).
+		WithArgs(creatorID, "LAUNCH", true, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "content_creator_id", "code", "type", "amount_off", "max_redemptions", "active"}).
+			AddRow("coupon-uuid", creatorID, "LAUNCH", models.CouponFixed, 200, 2, true))
+	mock.ExpectQuery(`SELECT \* FROM "coupon_redemptions" WHERE coupon_id = \$1 AND user_id = \$2`).
+		WithArgs("coupon-uuid", subscriberID, 1).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "coupon_redemptions" WHERE coupon_id = \$1`).
+		WithArgs("coupon-uuid").
+		WillReturnRows(mock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectRollback()
+
+	r := testutils.SetupTestRouter()
+	r.POST("/subscriptions/checkout/:contentCreatorId", func(c *gin.Context) {
+		c.Set("user_id", subscriberID)
+		CreateSubscriptionCheckoutSession(c)
+	})
+
+	body, _ := json.Marshal(models.SubscriptionCheckoutRequest{PromoCode: "launch"})
+	req, _ := http.NewRequest(http.MethodPost, "/subscriptions/checkout/"+creatorID, bytes.NewReader(body))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	assert.Empty(t, fake.Webhooks())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test un premier abonnement de 3 mois avec essai gratuit et code promo : rien n'est prélevé pendant
+// l'essai, puis la première échéance est remisée et la suivante au prix de la formule
+func TestSubscriptionTrialBundleAndPromoCode_WithFakeProvider(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	fake := payments.NewFakeProvider(testWebhookSecret)
+	previous := payments.Default
+	payments.Default = fake
+	defer func() { payments.Default = previous }()
+
+	subscriberID := "6a1f3c9e-1b6f-4a63-9d0a-3f2b8e1c7d10"
+	creatorID := "0c7d5b2a-8e4f-4f1a-b7c3-2d9e6a5f4b21"
+	subscriptionID := "9e8d7c6b-5a4f-4e3d-8c2b-1a0f9e8d7c6b"
+	planID := "3b2a1f0e-9d8c-4b7a-a6f5-e4d3c2b1a0f9"
+
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/webhook", StripeWebhookHandler)
+	r.POST("/subscriptions/checkout/:contentCreatorId", func(c *gin.Context) {
+		c.Set("user_id", subscriberID)
+		CreateSubscriptionCheckoutSession(c)
+	})
+
+	planRows := func() *sqlmock.Rows {
+		return mock.NewRows([]string{"id", "content_creator_id", "tier", "name", "price", "currency", "trial_days", "bundles", "active"}).
+			AddRow(planID, creatorID, models.TierBasic, "Basic", 1000, "eur", 7, `[{"months":3,"discountPercent":10}]`, true)
+	}
+
+	// Session de paiement : formule 3 mois (3000 - 10 %) avec essai et code à 20 %
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WithArgs(subscriberID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(subscriberID, "subscriber", models.UserRole))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WithArgs(creatorID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(creatorID, "creator", models.ContentCreator))
+	mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE user_id = \$1 AND content_creator_id = \$2`).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "subscription_plans" WHERE \(content_creator_id = \$1 AND active = \$2\) AND id = \$3`).
+		WillReturnRows(planRows())
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "subscriptions" WHERE \(user_id = \$1 AND content_creator_id = \$2\) AND \(trial_ends_at IS NOT NULL OR id IN \(SELECT "subscription_id" FROM "subscription_payments" WHERE status = \$3\)\)`).
+		WithArgs(subscriberID, creatorID, models.SubscriptionPaymentSucceeded).
+		WillReturnRows(mock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "stripe_customer_id"=\$1`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "coupons" WHERE content_creator_id = \$1 AND code = \$2 AND active = \$3 ORDER BY "coupons"."id" LIMIT \$4 FOR UPDATE`).
+		WithArgs(creatorID, "WELCOME20", true, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "content_creator_id", "code", "type", "percent_off", "expires_at", "max_redemptions", "active"}).
+			AddRow("coupon-uuid", creatorID, "WELCOME20", models.CouponPercent, 20, time.Now().Add(24*time.Hour), 10, true))
+	mock.ExpectQuery(`SELECT \* FROM "coupon_redemptions" WHERE coupon_id = \$1 AND user_id = \$2`).
+		WithArgs("coupon-uuid", subscriberID, 1).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "coupon_redemptions" WHERE coupon_id = \$1`).
+		WillReturnRows(mock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(`INSERT INTO "coupon_redemptions"`).
+		WithArgs("coupon-uuid", subscriberID, nil, 540, models.CouponRedemptionPending, "", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("redemption-uuid"))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "coupon_redemptions" SET "stripe_checkout_session_id"=\$1`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	resp := httptest.NewRecorder()
+	body, _ := json.Marshal(models.SubscriptionCheckoutRequest{PlanID: planID, BundleMonths: 3, PromoCode: "welcome20"})
+	req, _ := http.NewRequest(http.MethodPost, "/subscriptions/checkout/"+creatorID, bytes.NewReader(body))
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var checkout map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &checkout)
+	stripeSubID, paymentIntentID, err := fake.CompleteCheckout(checkout["sessionId"])
+	assert.NoError(t, err)
+	assert.Empty(t, paymentIntentID)
+
+	// checkout.session.completed : abonnement actif pendant l'essai et code promo consommé
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE stripe_customer_id = \$1`).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(subscriberID, "subscriber", models.UserRole))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WithArgs(creatorID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(creatorID, "creator", models.ContentCreator))
+	mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE stripe_subscription_id = \$1`).
+		WithArgs(stripeSubID, 1).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE user_id = \$1 AND content_creator_id = \$2`).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "subscription_plans" WHERE id = \$1 AND content_creator_id = \$2`).
+		WithArgs(planID, creatorID, 1).
+		WillReturnRows(planRows())
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "subscriptions"`).
+		WithArgs(subscriberID, creatorID, models.SubscriptionActive, stripeSubID, false, planID, models.TierBasic, 2700, 3,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id", "cancel_at_period_end"}).AddRow(subscriptionID, false))
+	mock.ExpectExec(`UPDATE "coupon_redemptions" SET "status"=\$1,"subscription_id"=\$2,"updated_at"=\$3 WHERE id = \$4`).
+		WithArgs(models.CouponRedemptionRedeemed, subscriptionID, sqlmock.AnyArg(), "redemption-uuid").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	// invoice.payment_succeeded à 0 : l'accès reste limité à la fin de l'essai
+	trialEnd := time.Now().AddDate(0, 0, 7)
+	subscriptionRow := func() *sqlmock.Rows {
+		return mock.NewRows([]string{"id", "user_id", "content_creator_id", "status", "stripe_subscription_id", "billing_months", "trial_ends_at"}).
+			AddRow(subscriptionID, subscriberID, creatorID, models.SubscriptionActive, stripeSubID, 3, trialEnd)
+	}
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE stripe_subscription_id = \$1`).
+		WithArgs(stripeSubID, 1).
+		WillReturnRows(subscriptionRow())
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "subscriptions" SET "end_date"=\$1`).
+		WithArgs(trialEnd, sqlmock.AnyArg(), subscriptionID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+	assert.NoError(t, fake.Deliver(r, "/stripe/webhook"))
+
+	// Fin de l'essai : première échéance de 3 mois remisée de 20 %
+	_, err = fake.AdvancePeriod(stripeSubID)
+	assert.NoError(t, err)
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE stripe_subscription_id = \$1`).
+		WithArgs(stripeSubID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_id", "content_creator_id", "status", "stripe_subscription_id", "billing_months", "trial_ends_at"}).
+			AddRow(subscriptionID, subscriberID, creatorID, models.SubscriptionActive, stripeSubID, 3, time.Now().Add(-time.Minute)))
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "subscription_payments"`).
+		WithArgs(subscriptionID, 2160, 0, sqlmock.AnyArg(), sqlmock.AnyArg(), models.SubscriptionPaymentSucceeded, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("payment-uuid"))
+	expectLedgerPayment(mock, models.LedgerSourceSubscriptionPayment, "payment-uuid", 2160)
+	expectInvoiceIssued(mock, creatorID, "payment-uuid", 2160, "FR12345678901")
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "subscriptions" SET "end_date"=\$1`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscriptions" WHERE stripe_subscription_id = \$1`).
+		WithArgs(stripeSubID, 1).
+		WillReturnRows(subscriptionRow())
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "subscriptions" SET "cancel_at_period_end"=\$1,"end_date"=\$2,"status"=\$3`).
+		WithArgs(false, sqlmock.AnyArg(), models.SubscriptionActive, sqlmock.AnyArg(), subscriptionID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+	assert.NoError(t, fake.Deliver(r, "/stripe/webhook"))
+
+	s, err := fake.GetSubscription(stripeSubID)
+	assert.NoError(t, err)
+	periodEnd := time.Unix(s.Items.Data[0].CurrentPeriodEnd, 0)
+	assert.WithinDuration(t, time.Now().AddDate(0, 3, 7), periodEnd, time.Minute)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un créateur ne peut pas s'envoyer un pourboire
+func TestCreateTipCheckoutSession_CannotTipYourself(t *testing.T) {
+	userID := "0c7d5b2a-8e4f-4f1a-b7c3-2d9e6a5f4b21"
+
+	r := testutils.SetupTestRouter()
+	r.POST("/tips/:contentCreatorId", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		CreateTipCheckoutSession(c)
+	})
+
+	body, _ := json.Marshal(models.TipCreate{Amount: 500})
+	req, _ := http.NewRequest(http.MethodPost, "/tips/"+userID, bytes.NewReader(body))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+// customerFailingProvider simule une panne de Stripe à la création du client
+type customerFailingProvider struct {
+	*payments.FakeProvider
+}
+
+func (customerFailingProvider) EnsureCustomer(customerID string, name string) (string, error) {
+	return "", errors.New("stripe unavailable")
+}
+
+// Test qu'aucun pourboire PENDING n'est enregistré si le client Stripe ne peut pas être créé
+func TestCreateTipCheckoutSession_CustomerError(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	previous := payments.Default
+	payments.Default = customerFailingProvider{payments.NewFakeProvider(testWebhookSecret)}
+	defer func() { payments.Default = previous }()
+
+	senderID := "6a1f3c9e-1b6f-4a63-9d0a-3f2b8e1c7d10"
+	creatorID := "0c7d5b2a-8e4f-4f1a-b7c3-2d9e6a5f4b21"
+
+	r := testutils.SetupTestRouter()
+	r.POST("/tips/:contentCreatorId", func(c *gin.Context) {
+		c.Set("user_id", senderID)
+		CreateTipCheckoutSession(c)
+	})
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WithArgs(senderID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(senderID, "fan", models.UserRole))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WithArgs(creatorID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(creatorID, "creator", models.ContentCreator))
+
+	body, _ := json.Marshal(models.TipCreate{Amount: 500})
+	req, _ := http.NewRequest(http.MethodPost, "/tips/"+creatorID, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Contains(t, resp.Body.String(), "Erreur lors de la création du client Stripe")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test du parcours d'un pourboire : paiement puis remboursement
+func TestTipLifecycle_WithFakeProvider(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	fake := payments.NewFakeProvider(testWebhookSecret)
+	previous := payments.Default
+	payments.Default = fake
+	defer func() { payments.Default = previous }()
+
+	senderID := "6a1f3c9e-1b6f-4a63-9d0a-3f2b8e1c7d10"
+	creatorID := "0c7d5b2a-8e4f-4f1a-b7c3-2d9e6a5f4b21"
+	tipID := "5d4c3b2a-1f0e-4d9c-8b7a-6f5e4d3c2b1a"
+
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/webhook", StripeWebhookHandler)
+	r.POST("/tips/:contentCreatorId", func(c *gin.Context) {
+		c.Set("user_id", senderID)
+		CreateTipCheckoutSession(c)
+	})
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WithArgs(senderID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(senderID, "fan", models.UserRole))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WithArgs(creatorID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(creatorID, "creator", models.ContentCreator))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "stripe_customer_id"=\$1`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "tips"`).
+		WithArgs(senderID, creatorID, nil, 500, "eur", "Merci !", models.TipPending, "", "", nil, 0, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow(tipID))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "tips" SET "stripe_checkout_session_id"=\$1`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	body, _ := json.Marshal(models.TipCreate{Amount: 500, Message: "Merci !"})
+	req, _ := http.NewRequest(http.MethodPost, "/tips/"+creatorID, bytes.NewReader(body))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var checkout map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &checkout)
+	assert.Equal(t, tipID, checkout["tipId"])
+
+	_, paymentIntentID, err := fake.CompleteCheckout(checkout["sessionId"])
+	assert.NoError(t, err)
+
+	tipRow := func(status models.TipStatus) *sqlmock.Rows {
+		return mock.NewRows([]string{"id", "sender_id", "content_creator_id", "amount", "status", "stripe_payment_intent_id"}).
+			AddRow(tipID, senderID, creatorID, 500, status, paymentIntentID)
+	}
+
+	// checkout.session.completed : le pourboire est payé
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "tips" WHERE id = \$1`).
+		WithArgs(tipID, 1).
+		WillReturnRows(tipRow(models.TipPending))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "tips" SET "paid_at"=\$1,"status"=\$2,"stripe_payment_intent_id"=\$3,"updated_at"=\$4 WHERE "id" = \$5`).
+		WithArgs(sqlmock.AnyArg(), models.TipSucceeded, paymentIntentID, sqlmock.AnyArg(), tipID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerPayment(mock, models.LedgerSourceTip, tipID, 500)
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	// payment_intent.succeeded : déjà pris en compte, et surtout pas rattaché à un abonnement
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "tips" WHERE id = \$1`).
+		WithArgs(tipID, 1).
+		WillReturnRows(tipRow(models.TipSucceeded))
+	expectEventStatusUpdate(mock)
+	assert.NoError(t, fake.Deliver(r, "/stripe/webhook"))
+
+	// charge.refunded partiel : le créateur est débité de la part remboursée, le pourboire reste payé
+	_, err = fake.Refund(paymentIntentID, 200)
+	assert.NoError(t, err)
+
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs(paymentIntentID, 1).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "tips" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs(paymentIntentID, 1).
+		WillReturnRows(tipRow(models.TipSucceeded))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "tips" SET "refunded_amount"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+		WithArgs(200, sqlmock.AnyArg(), tipID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "payment_refunds"`).
+		WithArgs(nil, tipID, nil, 200, sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("refund-uuid"))
+	expectLedgerReversal(mock, models.LedgerSourcePaymentRefund, "refund-uuid", models.LedgerSourceTip, tipID, 500, 0, 200)
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+	assert.NoError(t, fake.Deliver(r, "/stripe/webhook"))
+
+	// charge.refunded du reste : le pourboire est remboursé
+	_, err = fake.Refund(paymentIntentID, 0)
+	assert.NoError(t, err)
+
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs(paymentIntentID, 1).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "tips" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs(paymentIntentID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "sender_id", "content_creator_id", "amount", "refunded_amount", "status", "stripe_payment_intent_id"}).
+			AddRow(tipID, senderID, creatorID, 500, 200, models.TipSucceeded, paymentIntentID))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "tips" SET "refunded_amount"=\$1,"status"=\$2,"updated_at"=\$3 WHERE "id" = \$4`).
+		WithArgs(500, models.TipRefunded, sqlmock.AnyArg(), tipID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "payment_refunds"`).
+		WithArgs(nil, tipID, nil, 300, sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("refund-uuid-2"))
+	expectLedgerReversal(mock, models.LedgerSourcePaymentRefund, "refund-uuid-2", models.LedgerSourceTip, tipID, 500, 200, 300)
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+	assert.NoError(t, fake.Deliver(r, "/stripe/webhook"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test que les paiements partagent leurs statuts et que pourboires et achats suivent les mêmes transitions
+func TestCanUpdateOneOff(t *testing.T) {
+	for _, statuses := range [][]string{
+		{paymentSucceeded, string(models.SubscriptionPaymentSucceeded), string(models.TipSucceeded), string(models.PostPurchaseSucceeded)},
+		{paymentRefunded, string(models.SubscriptionPaymentRefunded), string(models.TipRefunded), string(models.PostPurchaseRefunded)},
+		{paymentDisputed, string(models.SubscriptionPaymentDisputed), string(models.TipDisputed), string(models.PostPurchaseDisputed)},
+	} {
+		for _, status := range statuses {
+			assert.Equal(t, statuses[0], status)
+		}
+	}
+
+	assert.True(t, canUpdateOneOff(string(models.TipPending), string(models.TipSucceeded)))
+	assert.True(t, canUpdateOneOff(string(models.TipSucceeded), string(models.TipRefunded)))
+	assert.False(t, canUpdateOneOff(string(models.TipSucceeded), string(models.TipCanceled)))
+	assert.False(t, canUpdateOneOff(string(models.TipRefunded), string(models.TipSucceeded)))
+	assert.False(t, canUpdateOneOff(string(models.TipSucceeded), string(models.TipSucceeded)))
+	assert.False(t, canUpdateOneOff(string(models.TipDisputed), string(models.TipSucceeded)))
+	assert.False(t, canUpdateOneOff(string(models.TipDisputed), string(models.TipRefunded)))
+}
+
+// Test que les pourboires reçus n'exposent que le profil public de leur auteur
+func TestGetReceivedTips_OnlyPublicSenderProfile(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	senderID := "6a1f3c9e-1b6f-4a63-9d0a-3f2b8e1c7d10"
+	creatorID := "0c7d5b2a-8e4f-4f1a-b7c3-2d9e6a5f4b21"
+
+	mock.ExpectQuery(`SELECT \* FROM "tips" WHERE content_creator_id = \$1 AND status = \$2 ORDER BY paid_at DESC`).
+		WithArgs(creatorID, models.TipSucceeded).
+		WillReturnRows(mock.NewRows([]string{"id", "sender_id", "content_creator_id", "amount", "status"}).
+			AddRow("tip-1", senderID, creatorID, 500, models.TipSucceeded).
+			AddRow("tip-2", senderID, creatorID, 1000, models.TipSucceeded))
+	mock.ExpectQuery(`SELECT id, user_name, profile_picture FROM "users" WHERE id IN \(\$1,\$2\)`).
+		WithArgs(senderID, senderID).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "profile_picture"}).
+			AddRow(senderID, "fan", "https://cdn.example.com/fan.png"))
+
+	r := testutils.SetupTestRouter()
+	r.GET("/tips/received", func(c *gin.Context) {
+		c.Set("user_id", creatorID)
+		GetReceivedTips(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/tips/received", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var body struct {
+		Tips  []map[string]interface{} `json:"tips"`
+		Total int                      `json:"total"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, 1500, body.Total)
+	assert.Len(t, body.Tips, 2)
+	for _, tip := range body.Tips {
+		sender, ok := tip["sender"].(map[string]interface{})
+		assert.True(t, ok)
+		assert.Equal(t, "fan", sender["userName"])
+		assert.NotContains(t, sender, "password")
+		assert.NotContains(t, sender, "email")
+	}
+	assert.NotContains(t, resp.Body.String(), `"password"`)
+	assert.NotContains(t, resp.Body.String(), `"email"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreatePostPurchaseCheckoutSession_PostNotForSale(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	buyerID := "3e9b1d57-6c2a-4f08-a4d1-7b5e0c8f2a93"
+	postID := "9b8a7c6d-5e4f-4a3b-9c2d-1e0f9a8b7c6d"
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE id = \$1 AND enable = \$2`).
+		WithArgs(postID, true, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_id", "name", "is_free", "price", "enable"}).
+			AddRow(postID, "b4f27a1c-9d3e-4c65-8e0a-51d6f3b9c7e2", "Post abonnés", false, 0, true))
+
+	r := testutils.SetupTestRouter()
+	r.POST("/posts/:id/purchase", func(c *gin.Context) {
+		c.Set("user_id", buyerID)
+		CreatePostPurchaseCheckoutSession(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "/posts/"+postID+"/purchase", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "This post is not for sale")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test qu'un second achat ne peut pas être ouvert tant que le premier attend son paiement
+func TestCreatePostPurchaseCheckoutSession_PurchasePending(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	buyerID := "3e9b1d57-6c2a-4f08-a4d1-7b5e0c8f2a93"
+	postID := "9b8a7c6d-5e4f-4a3b-9c2d-1e0f9a8b7c6d"
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE id = \$1 AND enable = \$2`).
+		WithArgs(postID, true, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_id", "name", "is_free", "price", "enable"}).
+			AddRow(postID, "b4f27a1c-9d3e-4c65-8e0a-51d6f3b9c7e2", "Post exclusif", false, 499, true))
+	mock.ExpectQuery(`SELECT \* FROM "post_purchases" WHERE buyer_id = \$1 AND post_id = \$2 AND status IN \(\$3,\$4\) ORDER BY status DESC`).
+		WithArgs(buyerID, postID, models.PostPurchaseSucceeded, models.PostPurchasePending, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "post_id", "buyer_id", "status"}).
+			AddRow("e81c4f2d-7a5b-4936-b0e8-2c9d6a3f1b54", postID, buyerID, models.PostPurchasePending))
+
+	r := testutils.SetupTestRouter()
+	r.POST("/posts/:id/purchase", func(c *gin.Context) {
+		c.Set("user_id", buyerID)
+		CreatePostPurchaseCheckoutSession(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "/posts/"+postID+"/purchase", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	assert.Contains(t, resp.Body.String(), "already in progress")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test du parcours propre à un achat : le post est débloqué une fois payé puis reverrouillé par un remboursement total.
+// Les remboursements partiels, communs aux pourboires, sont couverts par TestTipLifecycle_WithFakeProvider
+func TestPostPurchaseLifecycle_WithFakeProvider(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	fake := payments.NewFakeProvider(testWebhookSecret)
+	previous := payments.Default
+	payments.Default = fake
+	defer func() { payments.Default = previous }()
+
+	buyerID := "3e9b1d57-6c2a-4f08-a4d1-7b5e0c8f2a93"
+	creatorID := "b4f27a1c-9d3e-4c65-8e0a-51d6f3b9c7e2"
+	postID := "9b8a7c6d-5e4f-4a3b-9c2d-1e0f9a8b7c6d"
+	purchaseID := "e81c4f2d-7a5b-4936-b0e8-2c9d6a3f1b54"
+
+	r := testutils.SetupTestRouter()
+	r.POST("/stripe/webhook", StripeWebhookHandler)
+	r.POST("/posts/:id/purchase", func(c *gin.Context) {
+		c.Set("user_id", buyerID)
+		CreatePostPurchaseCheckoutSession(c)
+	})
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE id = \$1 AND enable = \$2`).
+		WithArgs(postID, true, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_id", "name", "is_free", "price", "enable"}).
+			AddRow(postID, creatorID, "Post exclusif", false, 499, true))
+	mock.ExpectQuery(`SELECT \* FROM "post_purchases" WHERE buyer_id = \$1 AND post_id = \$2 AND status IN \(\$3,\$4\) ORDER BY status DESC`).
+		WithArgs(buyerID, postID, models.PostPurchaseSucceeded, models.PostPurchasePending, 1).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WithArgs(buyerID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "user_name", "role"}).AddRow(buyerID, "fan", models.UserRole))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "stripe_customer_id"=\$1`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "post_purchases"`).
+		WithArgs(postID, buyerID, creatorID, 499, "eur", models.PostPurchasePending, "", "", nil, 0, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow(purchaseID))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "post_purchases" SET "stripe_checkout_session_id"=\$1`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	req, _ := http.NewRequest(http.MethodPost, "/posts/"+postID+"/purchase", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var checkout map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &checkout)
+	assert.Equal(t, purchaseID, checkout["purchaseId"])
+
+	_, paymentIntentID, err := fake.CompleteCheckout(checkout["sessionId"])
+	assert.NoError(t, err)
+
+	purchaseRow := func(status models.PostPurchaseStatus) *sqlmock.Rows {
+		return mock.NewRows([]string{"id", "post_id", "buyer_id", "content_creator_id", "amount", "status", "stripe_payment_intent_id"}).
+			AddRow(purchaseID, postID, buyerID, creatorID, 499, status, paymentIntentID)
+	}
+
+	// checkout.session.completed : le post est débloqué pour l'acheteur
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "post_purchases" WHERE id = \$1`).
+		WithArgs(purchaseID, 1).
+		WillReturnRows(purchaseRow(models.PostPurchasePending))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "post_purchases" SET "paid_at"=\$1,"status"=\$2,"stripe_payment_intent_id"=\$3,"updated_at"=\$4 WHERE "id" = \$5`).
+		WithArgs(sqlmock.AnyArg(), models.PostPurchaseSucceeded, paymentIntentID, sqlmock.AnyArg(), purchaseID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerPayment(mock, models.LedgerSourcePostPurchase, purchaseID, 499)
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+
+	// payment_intent.succeeded : déjà pris en compte
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "post_purchases" WHERE id = \$1`).
+		WithArgs(purchaseID, 1).
+		WillReturnRows(purchaseRow(models.PostPurchaseSucceeded))
+	expectEventStatusUpdate(mock)
+	assert.NoError(t, fake.Deliver(r, "/stripe/webhook"))
+
+	// charge.refunded : l'achat est remboursé et le post de nouveau verrouillé
+	_, err = fake.Refund(paymentIntentID, 0)
+	assert.NoError(t, err)
+
+	expectEventRecorded(mock)
+	mock.ExpectQuery(`SELECT \* FROM "subscription_payments" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs(paymentIntentID, 1).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "tips" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs(paymentIntentID, 1).
+		WillReturnRows(mock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "post_purchases" WHERE stripe_payment_intent_id = \$1`).
+		WithArgs(paymentIntentID, 1).
+		WillReturnRows(purchaseRow(models.PostPurchaseSucceeded))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "post_purchases" SET "refunded_amount"=\$1,"status"=\$2,"updated_at"=\$3 WHERE "id" = \$4`).
+		WithArgs(499, models.PostPurchaseRefunded, sqlmock.AnyArg(), purchaseID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "payment_refunds"`).
+		WithArgs(nil, nil, purchaseID, 499, sqlmock.AnyArg()).
+		WillReturnRows(mock.NewRows([]string{"id"}).AddRow("refund-uuid"))
+	expectLedgerReversal(mock, models.LedgerSourcePaymentRefund, "refund-uuid", models.LedgerSourcePostPurchase, purchaseID, 499, 0, 499)
+	mock.ExpectCommit()
+	expectEventStatusUpdate(mock)
+	assert.NoError(t, fake.Deliver(r, "/stripe/webhook"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
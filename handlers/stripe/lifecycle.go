@@ -0,0 +1,308 @@
+package stripe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"pec2-backend/db"
+	"pec2-backend/ledger"
+	"pec2-backend/models"
+	"pec2-backend/payments"
+	"pec2-backend/utils"
+
+	stripe "github.com/stripe/stripe-go/v82"
+	"gorm.io/gorm"
+)
+
+// Nombre maximum d'abonnements réconciliés à chaque passage du worker
+const reconcileBatchSize = 100
+
+// isSubscriptionEnded indique si le statut local est définitif
+func isSubscriptionEnded(status models.SubscriptionStatus) bool {
+	return status == models.SubscriptionCanceled || status == models.SubscriptionExpired
+}
+
+// localSubscriptionStatus convertit le statut Stripe en statut local.
+// Un abonnement résilié suite à un impayé est considéré comme expiré, les autres résiliations comme annulées
+func localSubscriptionStatus(s *stripe.Subscription) (models.SubscriptionStatus, bool) {
+	switch s.Status {
+	case stripe.SubscriptionStatusActive, stripe.SubscriptionStatusTrialing:
+		return models.SubscriptionActive, true
+	case stripe.SubscriptionStatusPastDue, stripe.SubscriptionStatusUnpaid, stripe.SubscriptionStatusPaused:
+		return models.SubscriptionPastDue, true
+	case stripe.SubscriptionStatusIncomplete:
+		return models.SubscriptionPending, true
+	case stripe.SubscriptionStatusIncompleteExpired:
+		return models.SubscriptionExpired, true
+	case stripe.SubscriptionStatusCanceled:
+		if s.CancellationDetails != nil && s.CancellationDetails.Reason == stripe.SubscriptionCancellationDetailsReasonPaymentFailed {
+			return models.SubscriptionExpired, true
+		}
+		return models.SubscriptionCanceled, true
+	default:
+		return "", false
+	}
+}
+
+// subscriptionPeriodEnd renvoie la fin de la période en cours (portée par les items depuis l'API 2025)
+func subscriptionPeriodEnd(s *stripe.Subscription) *time.Time {
+	var periodEnd int64
+	if s.Items != nil {
+		for _, item := range s.Items.Data {
+			if item != nil && item.CurrentPeriodEnd > periodEnd {
+				periodEnd = item.CurrentPeriodEnd
+			}
+		}
+	}
+	if periodEnd == 0 {
+		return nil
+	}
+	end := time.Unix(periodEnd, 0)
+	return &end
+}
+
+// applyStripeSubscription aligne l'abonnement local sur l'état de l'abonnement Stripe
+func applyStripeSubscription(sub *models.Subscription, s *stripe.Subscription) error {
+	status, ok := localSubscriptionStatus(s)
+	if !ok {
+		return permanent(fmt.Errorf("unknown stripe subscription status %q", s.Status))
+	}
+
+	updates := map[string]interface{}{
+		"status":               status,
+		"cancel_at_period_end": s.CancelAtPeriodEnd,
+	}
+	if isSubscriptionEnded(status) && s.EndedAt > 0 {
+		updates["end_date"] = time.Unix(s.EndedAt, 0)
+	} else if end := subscriptionPeriodEnd(s); end != nil {
+		updates["end_date"] = *end
+	}
+
+	return db.DB.Model(sub).Updates(updates).Error
+}
+
+func handleCustomerSubscriptionUpdated(event stripe.Event) (string, error) {
+	var s stripe.Subscription
+	if err := json.Unmarshal(event.Data.Raw, &s); err != nil {
+		utils.LogError(err, "Error parsing Subscription dans handleCustomerSubscriptionUpdated")
+		return "", permanent(errors.New("error parsing Subscription"))
+	}
+
+	sub, err := findSubscriptionByStripeID(s.ID)
+	if err != nil {
+		utils.LogError(err, "Subscription not found, will retry dans handleCustomerSubscriptionUpdated")
+		return "", errSubscriptionNotReady
+	}
+
+	// Un abonnement terminé ne peut pas redevenir actif : l'événement est arrivé dans le désordre
+	if isSubscriptionEnded(sub.Status) && s.Status != stripe.SubscriptionStatusCanceled {
+		utils.LogInfo("Out of order update for ended subscription " + sub.ID + " dans handleCustomerSubscriptionUpdated")
+		return "Subscription already ended - update ignored", nil
+	}
+
+	if err := applyStripeSubscription(sub, &s); err != nil {
+		utils.LogError(err, "Error updating subscription dans handleCustomerSubscriptionUpdated")
+		return "", fmt.Errorf("error updating subscription: %w", err)
+	}
+
+	utils.LogSuccess("Subscription " + sub.ID + " synchronized dans handleCustomerSubscriptionUpdated")
+	return "Subscription updated", nil
+}
+
+func handleCustomerSubscriptionDeleted(event stripe.Event) (string, error) {
+	var s stripe.Subscription
+	if err := json.Unmarshal(event.Data.Raw, &s); err != nil {
+		utils.LogError(err, "Error parsing Subscription dans handleCustomerSubscriptionDeleted")
+		return "", permanent(errors.New("error parsing Subscription"))
+	}
+
+	sub, err := findSubscriptionByStripeID(s.ID)
+	if err != nil {
+		utils.LogError(err, "Subscription not found, will retry dans handleCustomerSubscriptionDeleted")
+		return "", errSubscriptionNotReady
+	}
+
+	// L'événement de suppression porte toujours le statut canceled
+	s.Status = stripe.SubscriptionStatusCanceled
+	if err := applyStripeSubscription(sub, &s); err != nil {
+		utils.LogError(err, "Error ending subscription dans handleCustomerSubscriptionDeleted")
+		return "", fmt.Errorf("error ending subscription: %w", err)
+	}
+
+	utils.LogSuccess("Subscription " + sub.ID + " ended dans handleCustomerSubscriptionDeleted")
+	return "Subscription ended", nil
+}
+
+func handleChargeRefunded(event stripe.Event) (string, error) {
+	var charge stripe.Charge
+	if err := json.Unmarshal(event.Data.Raw, &charge); err != nil {
+		utils.LogError(err, "Error parsing Charge dans handleChargeRefunded")
+		return "", permanent(errors.New("error parsing Charge"))
+	}
+
+	if charge.PaymentIntent == nil || charge.PaymentIntent.ID == "" {
+		utils.LogInfo("Refunded charge " + charge.ID + " without PaymentIntent dans handleChargeRefunded")
+		return "Charge without PaymentIntent - event ignored", nil
+	}
+
+	var payment models.SubscriptionPayment
+	if err := db.DB.First(&payment, "stripe_payment_intent_id = ?", charge.PaymentIntent.ID).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", fmt.Errorf("error fetching payment: %w", err)
+		}
+
+		// Le remboursement peut concerner un paiement ponctuel
+		message, found, err := refundTip(charge)
+		if err != nil {
+			utils.LogError(err, "Error refunding tip dans handleChargeRefunded")
+			return "", fmt.Errorf("error refunding tip: %w", err)
+		}
+		if found {
+			utils.LogSuccess("Refund of tip paid by " + charge.PaymentIntent.ID + " processed dans handleChargeRefunded")
+			return message, nil
+		}
+		message, found, err = refundPostPurchase(charge)
+		if err != nil {
+			utils.LogError(err, "Error refunding purchase dans handleChargeRefunded")
+			return "", fmt.Errorf("error refunding purchase: %w", err)
+		}
+		if found {
+			utils.LogSuccess("Refund of purchase paid by " + charge.PaymentIntent.ID + " processed dans handleChargeRefunded")
+			return message, nil
+		}
+
+		utils.LogInfo("Refunded charge " + charge.ID + " not linked to a known payment dans handleChargeRefunded")
+		return "Refund not linked to a known payment - event ignored", nil
+	}
+
+	// Le montant remboursé est cumulé par Stripe : seule la part pas encore enregistrée est traitée
+	refunded := int(charge.AmountRefunded)
+	if refunded > payment.Amount {
+		refunded = payment.Amount
+	}
+	alreadyRefunded := payment.RefundedAmount
+	delta := refunded - alreadyRefunded
+	if delta <= 0 {
+		utils.LogInfo("Refund of payment " + payment.ID + " already recorded dans handleChargeRefunded")
+		return "Refund already recorded", nil
+	}
+	fullyRefunded := charge.Refunded || refunded >= payment.Amount
+
+	var sub models.Subscription
+	if err := db.DB.First(&sub, "id = ?", payment.SubscriptionID).Error; err != nil {
+		utils.LogError(err, "Subscription not found dans handleChargeRefunded")
+		return "", fmt.Errorf("error fetching subscription: %w", err)
+	}
+	wasEnded := isSubscriptionEnded(sub.Status)
+
+	// Un remboursement total met fin à l'accès immédiatement
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		refund := models.PaymentRefund{SubscriptionPaymentID: &payment.ID, Amount: delta}
+		if err := tx.Create(&refund).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"refunded_amount": refunded}
+		if fullyRefunded && payment.Status != models.SubscriptionPaymentDisputed {
+			updates["status"] = models.SubscriptionPaymentRefunded
+		}
+		if err := tx.Model(&payment).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := ledger.RecordReversal(tx, models.LedgerSourcePaymentRefund, refund.ID,
+			models.LedgerSourceSubscriptionPayment, payment.ID, delta); err != nil {
+			return err
+		}
+		if !fullyRefunded {
+			return nil
+		}
+		return endSubscription(tx, &sub)
+	})
+	if err != nil {
+		utils.LogError(err, "Error recording refund dans handleChargeRefunded")
+		return "", fmt.Errorf("error recording refund: %w", err)
+	}
+
+	if !fullyRefunded {
+		utils.LogSuccess(fmt.Sprintf("Partial refund of %d recorded on payment %s dans handleChargeRefunded", delta, payment.ID))
+		return "Partial refund recorded", nil
+	}
+
+	if !wasEnded {
+		cancelStripeSubscription(&sub, "handleChargeRefunded")
+	}
+
+	utils.LogSuccess("Payment " + payment.ID + " refunded, subscription " + sub.ID + " canceled dans handleChargeRefunded")
+	return "Payment refunded - subscription canceled", nil
+}
+
+// endSubscription coupe l'accès immédiatement suite à la perte du paiement (remboursement, contestation perdue)
+func endSubscription(tx *gorm.DB, sub *models.Subscription) error {
+	if isSubscriptionEnded(sub.Status) {
+		return nil
+	}
+	return tx.Model(sub).Updates(map[string]interface{}{
+		"status":   models.SubscriptionCanceled,
+		"end_date": time.Now(),
+	}).Error
+}
+
+// cancelStripeSubscription résilie l'abonnement Stripe d'un abonnement clos localement.
+// Sans cela, la prochaine échéance serait prélevée pour un abonnement clos
+func cancelStripeSubscription(sub *models.Subscription, handlerName string) {
+	if sub.StripeSubscriptionId == "" {
+		return
+	}
+	if err := payments.Default.CancelSubscription(sub.StripeSubscriptionId, false); err != nil && !errors.Is(err, payments.ErrNotFound) {
+		utils.LogError(err, "Error canceling stripe subscription "+sub.StripeSubscriptionId+" dans "+handlerName)
+	}
+}
+
+// reconcileSubscription resynchronise un abonnement dont la date de fin est dépassée.
+// Sans abonnement Stripe joignable, l'abonnement local est expiré
+func reconcileSubscription(sub *models.Subscription) error {
+	if sub.StripeSubscriptionId != "" {
+		s, err := payments.Default.GetSubscription(sub.StripeSubscriptionId)
+		if err == nil {
+			return applyStripeSubscription(sub, s)
+		}
+		if !errors.Is(err, payments.ErrNotFound) {
+			// Stripe indisponible : l'accès est déjà coupé par la date de fin, on réessaiera au prochain passage
+			return err
+		}
+	}
+
+	return db.DB.Model(sub).Update("status", models.SubscriptionExpired).Error
+}
+
+// ReconcileSubscriptions traite les abonnements encore ouverts dont la date de fin est passée
+func ReconcileSubscriptions() {
+	var subs []models.Subscription
+	err := db.DB.Where("status IN ? AND end_date < ?",
+		[]models.SubscriptionStatus{models.SubscriptionPending, models.SubscriptionActive, models.SubscriptionPastDue}, time.Now()).
+		Order("end_date ASC").
+		Limit(reconcileBatchSize).
+		Find(&subs).Error
+	if err != nil {
+		utils.LogError(err, "Error fetching lapsed subscriptions dans ReconcileSubscriptions")
+		return
+	}
+
+	for i := range subs {
+		if err := reconcileSubscription(&subs[i]); err != nil {
+			utils.LogError(err, "Error reconciling subscription "+subs[i].ID+" dans ReconcileSubscriptions")
+		}
+	}
+}
+
+// StartSubscriptionReconciler lance la réconciliation périodique des abonnements expirés
+func StartSubscriptionReconciler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ReconcileSubscriptions()
+	}
+}
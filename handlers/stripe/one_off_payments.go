@@ -0,0 +1,155 @@
+package stripe
+
+import (
+	"errors"
+	"time"
+
+	"pec2-backend/db"
+	"pec2-backend/ledger"
+	"pec2-backend/models"
+
+	stripe "github.com/stripe/stripe-go/v82"
+	"gorm.io/gorm"
+)
+
+// Statuts communs aux paiements d'abonnement, pourboires et achats de posts
+const (
+	paymentSucceeded = "SUCCEEDED"
+	paymentRefunded  = "REFUNDED"
+	paymentDisputed  = "DISPUTED"
+)
+
+// oneOffPayment est un paiement ponctuel, pourboire ou achat de post : il crédite le créateur une fois payé
+// et peut être remboursé en partie ou en totalité
+type oneOffPayment struct {
+	// record est le *models.Tip ou le *models.PostPurchase mis à jour
+	record         interface{}
+	ledgerSource   models.LedgerSourceType
+	id             string
+	creatorID      string
+	amount         int
+	currency       string
+	status         string
+	refundedAmount int
+	// refund est le remboursement à enregistrer, déjà rattaché au paiement
+	refund models.PaymentRefund
+}
+
+func tipPayment(tip *models.Tip) oneOffPayment {
+	return oneOffPayment{
+		record:         tip,
+		ledgerSource:   models.LedgerSourceTip,
+		id:             tip.ID,
+		creatorID:      tip.ContentCreatorID,
+		amount:         tip.Amount,
+		currency:       tip.Currency,
+		status:         string(tip.Status),
+		refundedAmount: tip.RefundedAmount,
+		refund:         models.PaymentRefund{TipID: &tip.ID},
+	}
+}
+
+func postPurchasePayment(purchase *models.PostPurchase) oneOffPayment {
+	return oneOffPayment{
+		record:         purchase,
+		ledgerSource:   models.LedgerSourcePostPurchase,
+		id:             purchase.ID,
+		creatorID:      purchase.ContentCreatorID,
+		amount:         purchase.Amount,
+		currency:       purchase.Currency,
+		status:         string(purchase.Status),
+		refundedAmount: purchase.RefundedAmount,
+		refund:         models.PaymentRefund{PostPurchaseID: &purchase.ID},
+	}
+}
+
+// findOneOffPayment retrouve un paiement ponctuel par son identifiant (metadata) ou, à défaut, par son PaymentIntent
+func findOneOffPayment[T models.Tip | models.PostPurchase](id string, paymentIntentID string) (*T, error) {
+	var record T
+	var err error
+	switch {
+	case id != "":
+		err = db.DB.First(&record, "id = ?", id).Error
+	case paymentIntentID != "":
+		err = db.DB.First(&record, "stripe_payment_intent_id = ?", paymentIntentID).Error
+	default:
+		return nil, permanent(errors.New("payment reference missing"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// canUpdateOneOff un paiement réussi ne peut plus que être remboursé, un remboursement est définitif.
+// Un paiement contesté ne change de statut qu'à l'issue de la contestation
+func canUpdateOneOff(current string, next string) bool {
+	switch current {
+	case next, paymentRefunded, paymentDisputed:
+		return false
+	case paymentSucceeded:
+		return next == paymentRefunded
+	default:
+		return true
+	}
+}
+
+// updateOneOffStatus applique le nouveau statut si la transition est permise
+func updateOneOffStatus(payment oneOffPayment, status string, paymentIntentID string) (bool, error) {
+	if !canUpdateOneOff(payment.status, status) {
+		return false, nil
+	}
+
+	updates := map[string]interface{}{"status": status}
+	if paymentIntentID != "" {
+		updates["stripe_payment_intent_id"] = paymentIntentID
+	}
+	if status == paymentSucceeded {
+		updates["paid_at"] = time.Now()
+	}
+	// Le statut et l'écriture correspondante au grand livre sont enregistrés ensemble
+	return true, db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(payment.record).Updates(updates).Error; err != nil {
+			return err
+		}
+		if status == paymentSucceeded {
+			return ledger.RecordPayment(tx, payment.ledgerSource, payment.id, payment.creatorID, payment.amount, payment.currency)
+		}
+		return nil
+	})
+}
+
+// refundOneOff enregistre le remboursement, total ou partiel, du paiement réglé par la charge :
+// le créateur est débité de la part remboursée, le paiement ne passe en REFUNDED qu'une fois remboursé en totalité.
+// Renvoie recorded à false si le remboursement était déjà enregistré
+func refundOneOff(payment oneOffPayment, charge stripe.Charge) (recorded bool, fullyRefunded bool, err error) {
+	// Le montant remboursé est cumulé par Stripe : seule la part pas encore enregistrée est reprise
+	refunded := min(int(charge.AmountRefunded), payment.amount)
+	delta := refunded - payment.refundedAmount
+	fullyRefunded = (charge.Refunded || refunded >= payment.amount) && canUpdateOneOff(payment.status, paymentRefunded)
+	if delta <= 0 && !fullyRefunded {
+		return false, false, nil
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"refunded_amount": max(refunded, payment.refundedAmount)}
+		if fullyRefunded {
+			updates["status"] = paymentRefunded
+		}
+		if err := tx.Model(payment.record).Updates(updates).Error; err != nil {
+			return err
+		}
+		if delta <= 0 {
+			return nil
+		}
+
+		refund := payment.refund
+		refund.Amount = delta
+		if err := tx.Create(&refund).Error; err != nil {
+			return err
+		}
+		return ledger.RecordReversal(tx, models.LedgerSourcePaymentRefund, refund.ID,
+			payment.ledgerSource, payment.id, delta)
+	})
+	return true, fullyRefunded, err
+}
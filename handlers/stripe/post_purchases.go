@@ -0,0 +1,267 @@
+package stripe
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/payments"
+	"pec2-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	stripe "github.com/stripe/stripe-go/v82"
+	"gorm.io/gorm"
+)
+
+const postPurchasePaymentType = "post_purchase"
+
+// CreatePostPurchaseCheckoutSession starts a one-off Stripe payment to unlock a pay-per-view post
+// @Summary Buy a pay-per-view post
+// @Description Create a one-off Stripe Checkout session to buy a post sold at a fixed price. Once paid, the post is unlocked for the buyer, subscribed or not
+// @Tags posts
+// @Produce json
+// @Param id path string true "ID of the post"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "sessionId: ID of the Stripe Checkout session, url: Stripe Checkout URL, purchaseId: ID of the purchase"
+// @Failure 400 {object} map[string]string "error: This post is not for sale"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 404 {object} map[string]string "error: User or post not found"
+// @Failure 409 {object} map[string]string "error: You already bought this post, or a purchase of this post is already in progress"
+// @Failure 500 {object} map[string]string "error: Stripe error or server error"
+// @Router /posts/{id}/purchase [post]
+func CreatePostPurchaseCheckoutSession(c *gin.Context) {
+	postID := c.Param("id")
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(nil, "User not authenticated dans CreatePostPurchaseCheckoutSession")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var post models.Post
+	if err := db.DB.First(&post, "id = ? AND enable = ?", postID, true).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "Post not found dans CreatePostPurchaseCheckoutSession")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
+	if post.IsFree || post.Price <= 0 {
+		utils.LogErrorWithUser(userID, nil, "Post not for sale dans CreatePostPurchaseCheckoutSession")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This post is not for sale"})
+		return
+	}
+	if post.UserID == userID {
+		utils.LogErrorWithUser(userID, nil, "Cannot buy own post dans CreatePostPurchaseCheckoutSession")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot buy your own post"})
+		return
+	}
+
+	// Un achat payé ou en attente de paiement empêche d'en ouvrir un second :
+	// une session abandonnée expire (checkout.session.expired) et annule alors l'achat en attente
+	var existing models.PostPurchase
+	err := db.DB.Where("buyer_id = ? AND post_id = ? AND status IN ?", userID, post.ID,
+		[]models.PostPurchaseStatus{models.PostPurchaseSucceeded, models.PostPurchasePending}).
+		Order("status DESC").First(&existing).Error
+	switch {
+	case err == nil && existing.Status == models.PostPurchaseSucceeded:
+		utils.LogErrorWithUser(userID, nil, "Post already bought dans CreatePostPurchaseCheckoutSession")
+		c.JSON(http.StatusConflict, gin.H{"error": "You already bought this post"})
+		return
+	case err == nil:
+		utils.LogErrorWithUser(userID, nil, "Purchase already pending dans CreatePostPurchaseCheckoutSession")
+		c.JSON(http.StatusConflict, gin.H{"error": "A purchase of this post is already in progress"})
+		return
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		utils.LogErrorWithUser(userID, err, "Error checking purchases dans CreatePostPurchaseCheckoutSession")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking purchases"})
+		return
+	}
+
+	var buyer models.User
+	if err := db.DB.First(&buyer, "id = ?", userID).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "User not found dans CreatePostPurchaseCheckoutSession")
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Le client Stripe est créé avant l'achat : un échec ne laisse pas d'achat PENDING sans session
+	customerID, err := payments.Default.EnsureCustomer(buyer.StripeCustomerId, buyer.UserName)
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Erreur lors de la création du client Stripe dans CreatePostPurchaseCheckoutSession")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création du client Stripe"})
+		return
+	}
+	if customerID != buyer.StripeCustomerId {
+		db.DB.Model(&buyer).Update("stripe_customer_id", customerID)
+	}
+
+	purchase := models.PostPurchase{
+		PostID:           post.ID,
+		BuyerID:          buyer.ID,
+		ContentCreatorID: post.UserID,
+		Amount:           post.Price,
+		Currency:         "eur",
+		Status:           models.PostPurchasePending,
+	}
+	if err := db.DB.Create(&purchase).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "Error creating purchase dans CreatePostPurchaseCheckoutSession")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating purchase"})
+		return
+	}
+
+	s, err := payments.Default.CreateCheckoutSession(payments.CheckoutParams{
+		Mode:              payments.CheckoutModePayment,
+		CustomerID:        customerID,
+		ClientReferenceID: post.UserID,
+		ProductName:       post.Name,
+		Currency:          purchase.Currency,
+		Amount:            int64(purchase.Amount),
+		Metadata: map[string]string{
+			paymentTypeMetadata: postPurchasePaymentType,
+			"purchase_id":       purchase.ID,
+			"post_id":           post.ID,
+		},
+		SuccessURL: "https://tonsite.com/success",
+		CancelURL:  "https://tonsite.com/cancel",
+	})
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Erreur lors de la création de la session Stripe dans CreatePostPurchaseCheckoutSession")
+		db.DB.Model(&purchase).Update("status", models.PostPurchaseFailed)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	db.DB.Model(&purchase).Update("stripe_checkout_session_id", s.ID)
+
+	utils.LogSuccessWithUser(userID, "Session Stripe d'achat de post créée avec succès dans CreatePostPurchaseCheckoutSession")
+	c.JSON(http.StatusOK, gin.H{"sessionId": s.ID, "url": s.URL, "purchaseId": purchase.ID})
+}
+
+// GetMyPostPurchases returns the posts bought by the connected user
+// @Summary List my post purchases
+// @Description Return all the pay-per-view purchases of the connected user, newest first
+// @Tags posts
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.PostPurchase
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 500 {object} map[string]string "error: Error fetching purchases"
+// @Router /purchases [get]
+func GetMyPostPurchases(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(nil, "User not authenticated dans GetMyPostPurchases")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var purchases []models.PostPurchase
+	if err := db.DB.Where("buyer_id = ?", userID).Order("created_at DESC").Find(&purchases).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "Erreur lors de la récupération des achats dans GetMyPostPurchases")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching purchases"})
+		return
+	}
+
+	utils.LogSuccessWithUser(userID, "Achats récupérés avec succès dans GetMyPostPurchases")
+	c.JSON(http.StatusOK, purchases)
+}
+
+func handlePostPurchaseCheckoutCompleted(session stripe.CheckoutSession) (string, error) {
+	var paymentIntentID string
+	if session.PaymentIntent != nil {
+		paymentIntentID = session.PaymentIntent.ID
+	}
+
+	purchase, err := findOneOffPayment[models.PostPurchase](session.Metadata["purchase_id"], paymentIntentID)
+	if err != nil {
+		utils.LogError(err, "Purchase not found dans handlePostPurchaseCheckoutCompleted")
+		return "", permanent(fmt.Errorf("purchase not found: %w", err))
+	}
+
+	status := models.PostPurchasePending
+	if session.PaymentStatus == stripe.CheckoutSessionPaymentStatusPaid {
+		status = models.PostPurchaseSucceeded
+	}
+
+	updated, err := updateOneOffStatus(postPurchasePayment(purchase), string(status), paymentIntentID)
+	if err != nil {
+		utils.LogError(err, "Error updating purchase dans handlePostPurchaseCheckoutCompleted")
+		return "", fmt.Errorf("error updating purchase: %w", err)
+	}
+	if !updated {
+		return "Purchase already up to date", nil
+	}
+
+	utils.LogSuccess("Purchase " + purchase.ID + " updated to " + string(status) + " dans handlePostPurchaseCheckoutCompleted")
+	if status == models.PostPurchaseSucceeded {
+		return "Post unlocked", nil
+	}
+	return "Purchase waiting for payment", nil
+}
+
+func handlePostPurchaseCheckoutExpired(session stripe.CheckoutSession) (string, error) {
+	purchase, err := findOneOffPayment[models.PostPurchase](session.Metadata["purchase_id"], "")
+	if err != nil {
+		utils.LogError(err, "Purchase not found dans handlePostPurchaseCheckoutExpired")
+		return "", permanent(fmt.Errorf("purchase not found: %w", err))
+	}
+
+	if purchase.Status != models.PostPurchasePending {
+		return "Purchase already up to date", nil
+	}
+	if _, err := updateOneOffStatus(postPurchasePayment(purchase), string(models.PostPurchaseCanceled), ""); err != nil {
+		utils.LogError(err, "Error canceling purchase dans handlePostPurchaseCheckoutExpired")
+		return "", fmt.Errorf("error canceling purchase: %w", err)
+	}
+
+	utils.LogSuccess("Purchase " + purchase.ID + " canceled dans handlePostPurchaseCheckoutExpired")
+	return "Purchase canceled", nil
+}
+
+func handlePostPurchasePaymentIntent(pi stripe.PaymentIntent, status models.PostPurchaseStatus) (string, error) {
+	purchase, err := findOneOffPayment[models.PostPurchase](pi.Metadata["purchase_id"], pi.ID)
+	if err != nil {
+		utils.LogError(err, "Purchase not found dans handlePostPurchasePaymentIntent")
+		return "", permanent(fmt.Errorf("purchase not found: %w", err))
+	}
+
+	updated, err := updateOneOffStatus(postPurchasePayment(purchase), string(status), pi.ID)
+	if err != nil {
+		utils.LogError(err, "Error updating purchase dans handlePostPurchasePaymentIntent")
+		return "", fmt.Errorf("error updating purchase: %w", err)
+	}
+	if !updated {
+		return "Purchase already up to date", nil
+	}
+
+	utils.LogSuccess("Purchase " + purchase.ID + " updated to " + string(status) + " dans handlePostPurchasePaymentIntent")
+	return "Purchase updated to " + string(status), nil
+}
+
+// refundPostPurchase enregistre le remboursement de l'achat payé par la charge, voir refundOneOff :
+// seul un remboursement total reverrouille le post.
+// Renvoie false si aucun achat ne correspond
+func refundPostPurchase(charge stripe.Charge) (string, bool, error) {
+	purchase, err := findOneOffPayment[models.PostPurchase]("", charge.PaymentIntent.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	recorded, fullyRefunded, err := refundOneOff(postPurchasePayment(purchase), charge)
+	switch {
+	case err != nil:
+		return "", true, err
+	case !recorded:
+		return "Refund already recorded", true, nil
+	case !fullyRefunded:
+		return "Purchase partially refunded", true, nil
+	default:
+		return "Purchase refunded - post locked again", true, nil
+	}
+}
@@ -0,0 +1,140 @@
+package stripe
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/payments"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errBundleNotOffered   = errors.New("bundle not offered by this plan")
+	errInvalidPromoCode   = errors.New("invalid or expired promo code")
+	errPromoCodeUsed      = errors.New("promo code already used")
+	errPromoCodeExhausted = errors.New("promo code redemption limit reached")
+)
+
+// checkoutMonths renvoie la durée de la formule choisie, un mois par défaut
+func checkoutMonths(bundleMonths int) int {
+	if bundleMonths < 1 {
+		return 1
+	}
+	return bundleMonths
+}
+
+// billingMonths nombre de mois couverts par une échéance de l'abonnement
+func billingMonths(sub *models.Subscription) int {
+	if sub.BillingMonths < 1 {
+		return 1
+	}
+	return sub.BillingMonths
+}
+
+// trialDaysFor renvoie l'essai gratuit du plan, réservé aux utilisateurs qui n'ont jamais
+// bénéficié d'un essai ni payé d'abonnement chez ce créateur
+func trialDaysFor(userID string, plan models.SubscriptionPlan) (int, error) {
+	if plan.TrialDays <= 0 {
+		return 0, nil
+	}
+
+	var count int64
+	err := db.DB.Model(&models.Subscription{}).
+		Where("user_id = ? AND content_creator_id = ?", userID, plan.ContentCreatorID).
+		Where("trial_ends_at IS NOT NULL OR id IN (?)",
+			db.DB.Model(&models.SubscriptionPayment{}).Select("subscription_id").Where("status = ?", models.SubscriptionPaymentSucceeded)).
+		Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return 0, nil
+	}
+	return plan.TrialDays, nil
+}
+
+// reserveCoupon vérifie un code promo et réserve son utilisation pour l'utilisateur le temps du paiement.
+// La ligne du code est verrouillée pour que deux paiements simultanés ne dépassent pas la limite
+func reserveCoupon(userID string, creatorID string, code string, amount int) (*models.CouponRedemption, *payments.Discount, error) {
+	var redemption models.CouponRedemption
+	var discount payments.Discount
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var coupon models.Coupon
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("content_creator_id = ? AND code = ? AND active = ?", creatorID, strings.ToUpper(code), true).
+			First(&coupon).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errInvalidPromoCode
+		}
+		if err != nil {
+			return err
+		}
+		if coupon.IsExpired(time.Now()) {
+			return errInvalidPromoCode
+		}
+
+		if coupon.Type == models.CouponPercent {
+			discount = payments.Discount{PercentOff: coupon.PercentOff}
+		} else {
+			discount = payments.Discount{AmountOff: int64(coupon.AmountOff)}
+		}
+		discountAmount := amount - int(discount.Apply(int64(amount)))
+
+		err = tx.Where("coupon_id = ? AND user_id = ?", coupon.ID, userID).First(&redemption).Error
+		if err == nil {
+			if redemption.Status == models.CouponRedemptionRedeemed {
+				return errPromoCodeUsed
+			}
+			// Un paiement précédent n'a pas abouti : la réservation est reprise
+			return tx.Model(&redemption).Update("discount_amount", discountAmount).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// Les réservations en cours comptent dans la limite jusqu'à l'expiration de leur session
+		if coupon.MaxRedemptions > 0 {
+			var used int64
+			if err := tx.Model(&models.CouponRedemption{}).Where("coupon_id = ?", coupon.ID).Count(&used).Error; err != nil {
+				return err
+			}
+			if used >= int64(coupon.MaxRedemptions) {
+				return errPromoCodeExhausted
+			}
+		}
+
+		redemption = models.CouponRedemption{
+			CouponID:       coupon.ID,
+			UserID:         userID,
+			DiscountAmount: discountAmount,
+			Status:         models.CouponRedemptionPending,
+		}
+		return tx.Create(&redemption).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &redemption, &discount, nil
+}
+
+// releaseCouponRedemption libère une réservation dont le paiement n'a pas abouti
+func releaseCouponRedemption(redemptionID string) error {
+	return db.DB.Where("id = ? AND status = ?", redemptionID, models.CouponRedemptionPending).
+		Delete(&models.CouponRedemption{}).Error
+}
+
+// completeCouponRedemption rattache l'utilisation du code à l'abonnement créé
+func completeCouponRedemption(tx *gorm.DB, redemptionID string, subscriptionID string) error {
+	return tx.Model(&models.CouponRedemption{}).
+		Where("id = ?", redemptionID).
+		Updates(map[string]interface{}{
+			"status":          models.CouponRedemptionRedeemed,
+			"subscription_id": subscriptionID,
+		}).Error
+}
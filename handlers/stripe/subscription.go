@@ -1,40 +1,47 @@
 package stripe
 
 import (
+	"errors"
+	"io"
 	"net/http"
-	"os"
+	"strconv"
 	"time"
 
 	"pec2-backend/db"
 	"pec2-backend/models"
+	"pec2-backend/payments"
 	"pec2-backend/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	stripe "github.com/stripe/stripe-go/v82"
-	session "github.com/stripe/stripe-go/v82/checkout/session"
-	"github.com/stripe/stripe-go/v82/customer"
-	stripeSubscription "github.com/stripe/stripe-go/v82/subscription"
 )
 
 // CreateSubscriptionCheckoutSession start a stripe payment to subscribe to a content creator (verified role). Returns the Stripe session ID to use on the frontend.
 // @Summary Create a Stripe Checkout session for subscription
-// @Description Start a Stripe payment to subscribe to a content creator (verified role). Returns the Stripe session ID to use on the frontend.
+// @Description Start a Stripe payment to subscribe to a content creator (verified role). A 3, 6 or 12 month bundle offered by the plan can be chosen, a promo code of the creator discounts the first payment and first-time subscribers get the free trial of the plan. Returns the Stripe session ID to use on the frontend.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param contentCreatorId path string true "ID of the content creator"
+// @Param checkout body models.SubscriptionCheckoutRequest false "Chosen plan (defaults to the cheapest active plan), bundle and promo code"
 // @Security BearerAuth
 // @Success 200 {object} map[string]string "sessionId: ID of the Stripe Checkout session, url: Stripe Checkout URL"
+// @Failure 400 {object} map[string]string "error: Invalid input"
 // @Failure 401 {object} map[string]string "error: Unauthorized"
 // @Failure 403 {object} map[string]string "error: Can only subscribe to a content creator"
-// @Failure 404 {object} map[string]string "error: User not found"
+// @Failure 404 {object} map[string]string "error: User, content creator or plan not found"
+// @Failure 409 {object} map[string]string "error: Already subscribed, promo code already used or redemption limit reached"
 // @Failure 500 {object} map[string]string "error: Stripe error or server error"
 // @Router /subscriptions/checkout/{contentCreatorId} [post]
 func CreateSubscriptionCheckoutSession(c *gin.Context) {
 	contentCreatorId := c.Param("contentCreatorId")
 
-	stripe.Key = os.Getenv("STRIPE_SECRET_KEY")
+	var input models.SubscriptionCheckoutRequest
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		utils.LogError(err, "Invalid input dans CreateSubscriptionCheckoutSession")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
 
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -66,76 +73,138 @@ func CreateSubscriptionCheckoutSession(c *gin.Context) {
 
 	var existingSub models.Subscription
 	err = db.DB.Where("user_id = ? AND content_creator_id = ? AND status IN (?)",
-		payer.ID, creator.ID, []models.SubscriptionStatus{models.SubscriptionActive, models.SubscriptionPending}).First(&existingSub).Error
+		payer.ID, creator.ID, []models.SubscriptionStatus{models.SubscriptionActive, models.SubscriptionPending, models.SubscriptionPastDue}).First(&existingSub).Error
 	if err == nil {
 		utils.LogErrorWithUser(userID, nil, "Déjà une subscription active ou pending dans CreateSubscriptionCheckoutSession")
 		c.JSON(http.StatusConflict, gin.H{"error": "You already have an active or pending subscription with this creator."})
 		return
 	}
 
-	if payer.StripeCustomerId != "" {
-		// Vérifie que le customer existe vraiment sur Stripe
-		_, err := customer.Get(payer.StripeCustomerId, nil)
-		if err != nil {
-			// S'il n'existe pas, on le recrée
-			payer.StripeCustomerId = ""
-		}
+	plan, err := findCheckoutPlan(creator.ID, input.PlanID)
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Plan not found dans CreateSubscriptionCheckoutSession")
+		c.JSON(http.StatusNotFound, gin.H{"error": "No active subscription plan found for this creator"})
+		return
 	}
-	if payer.StripeCustomerId == "" {
-		custParams := &stripe.CustomerParams{
-			Name: stripe.String(payer.UserName),
-		}
-		cust, err := customer.New(custParams)
-		if err != nil {
-			utils.LogErrorWithUser(userID, err, "Erreur lors de la création du client Stripe dans CreateSubscriptionCheckoutSession")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création du client Stripe"})
+
+	months := checkoutMonths(input.BundleMonths)
+	amount, ok := plan.BundlePrice(months)
+	if !ok {
+		utils.LogErrorWithUser(userID, errBundleNotOffered, "Bundle not offered dans CreateSubscriptionCheckoutSession")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This bundle is not offered by this plan"})
+		return
+	}
+
+	trialDays, err := trialDaysFor(payer.ID, plan)
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Error checking trial eligibility dans CreateSubscriptionCheckoutSession")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking trial eligibility"})
+		return
+	}
+
+	customerID, err := payments.Default.EnsureCustomer(payer.StripeCustomerId, payer.UserName)
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Erreur lors de la création du client Stripe dans CreateSubscriptionCheckoutSession")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création du client Stripe"})
+		return
+	}
+	if customerID != payer.StripeCustomerId {
+		db.DB.Model(&payer).Update("stripe_customer_id", customerID)
+		payer.StripeCustomerId = customerID
+	}
+
+	metadata := map[string]string{
+		"plan_id":          plan.ID,
+		"tier":             string(plan.Tier),
+		"bundle_months":    strconv.Itoa(months),
+		"recurring_amount": strconv.Itoa(amount),
+		"trial_days":       strconv.Itoa(trialDays),
+	}
+
+	// Le code promo est réservé avant le paiement pour respecter sa limite d'utilisations
+	var redemption *models.CouponRedemption
+	var discount *payments.Discount
+	if input.PromoCode != "" {
+		redemption, discount, err = reserveCoupon(payer.ID, creator.ID, input.PromoCode, amount)
+		switch {
+		case errors.Is(err, errInvalidPromoCode):
+			utils.LogErrorWithUser(userID, err, "Invalid promo code dans CreateSubscriptionCheckoutSession")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired promo code"})
+			return
+		case errors.Is(err, errPromoCodeUsed):
+			utils.LogErrorWithUser(userID, err, "Promo code already used dans CreateSubscriptionCheckoutSession")
+			c.JSON(http.StatusConflict, gin.H{"error": "You have already used this promo code"})
+			return
+		case errors.Is(err, errPromoCodeExhausted):
+			utils.LogErrorWithUser(userID, err, "Promo code limit reached dans CreateSubscriptionCheckoutSession")
+			c.JSON(http.StatusConflict, gin.H{"error": "This promo code has reached its redemption limit"})
+			return
+		case err != nil:
+			utils.LogErrorWithUser(userID, err, "Error reserving promo code dans CreateSubscriptionCheckoutSession")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error applying promo code"})
 			return
 		}
-		db.DB.Model(&payer).Update("stripe_customer_id", cust.ID)
-		payer.StripeCustomerId = cust.ID
-	}
-
-	params := &stripe.CheckoutSessionParams{
-		Customer:           stripe.String(payer.StripeCustomerId),
-		PaymentMethodTypes: stripe.StringSlice([]string{"card"}),
-		Mode:               stripe.String(string(stripe.CheckoutSessionModeSubscription)),
-		LineItems: []*stripe.CheckoutSessionLineItemParams{
-			{
-				Price:    stripe.String("price_1RUBlC4PRo6qYhfZsvXhuq8y"),
-				Quantity: stripe.Int64(1),
-			},
-		},
-		SuccessURL:        stripe.String("https://tonsite.com/success"),
-		CancelURL:         stripe.String("https://tonsite.com/cancel"),
-		ClientReferenceID: stripe.String(contentCreatorId),
-	}
-
-	s, err := session.New(params)
+		metadata["coupon_redemption_id"] = redemption.ID
+	}
+
+	s, err := payments.Default.CreateCheckoutSession(payments.CheckoutParams{
+		Mode:              payments.CheckoutModeSubscription,
+		CustomerID:        payer.StripeCustomerId,
+		ClientReferenceID: contentCreatorId,
+		ProductName:       creator.UserName + " - " + plan.Name,
+		Currency:          plan.Currency,
+		Amount:            int64(amount),
+		IntervalMonths:    months,
+		TrialDays:         trialDays,
+		Discount:          discount,
+		Metadata:          metadata,
+		SuccessURL:        "https://tonsite.com/success",
+		CancelURL:         "https://tonsite.com/cancel",
+	})
 	if err != nil {
 		utils.LogErrorWithUser(userID, err, "Erreur lors de la création de la session Stripe dans CreateSubscriptionCheckoutSession")
+		if redemption != nil {
+			if err := releaseCouponRedemption(redemption.ID); err != nil {
+				utils.LogErrorWithUser(userID, err, "Error releasing promo code dans CreateSubscriptionCheckoutSession")
+			}
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if redemption != nil {
+		db.DB.Model(redemption).Update("stripe_checkout_session_id", s.ID)
+	}
 	utils.LogSuccessWithUser(userID, "Session Stripe de souscription créée avec succès dans CreateSubscriptionCheckoutSession")
 	c.JSON(http.StatusOK, gin.H{"sessionId": s.ID, "url": s.URL})
 }
 
-// CancelSubscription cancels a Stripe subscription and updates its status in the database
+// findCheckoutPlan renvoie le plan actif choisi, ou le moins cher du créateur si aucun n'est précisé
+func findCheckoutPlan(creatorID string, planID string) (models.SubscriptionPlan, error) {
+	var plan models.SubscriptionPlan
+	query := db.DB.Where("content_creator_id = ? AND active = ?", creatorID, true)
+	if planID != "" {
+		query = query.Where("id = ?", planID)
+	}
+	err := query.Order("price ASC").First(&plan).Error
+	return plan, err
+}
+
+// CancelSubscription cancels a Stripe subscription at the end of the current period
 // @Summary Cancel a subscription
-// @Description Cancel a Stripe subscription and update its status in the database
+// @Description Schedule the cancellation of a Stripe subscription at the end of the paid period (pending subscriptions are canceled immediately)
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param subscriptionId path string true "ID of the subscription to cancel"
 // @Security BearerAuth
-// @Success 200 {object} map[string]string "message: Subscription canceled successfully"
+// @Success 200 {object} map[string]interface{} "message: Subscription will be canceled at the end of the current period, endDate"
 // @Failure 401 {object} map[string]string "error: Unauthorized"
 // @Failure 403 {object} map[string]string "error: You are not authorized to cancel this subscription"
 // @Failure 404 {object} map[string]string "error: Subscription not found"
+// @Failure 409 {object} map[string]string "error: Subscription already ended"
 // @Failure 500 {object} map[string]string "error: Error when canceling the Stripe subscription"
 // @Router /subscriptions/{subscriptionId} [delete]
 func CancelSubscription(c *gin.Context) {
-	stripe.Key = os.Getenv("STRIPE_SECRET_KEY")
 	subscriptionId := c.Param("subscriptionId")
 
 	// Validation de l'UUID
@@ -165,24 +234,55 @@ func CancelSubscription(c *gin.Context) {
 		return
 	}
 
-	_, err = stripeSubscription.Cancel(subscription.StripeSubscriptionId, &stripe.SubscriptionCancelParams{
-		Prorate: stripe.Bool(false),
-	})
+	if isSubscriptionEnded(subscription.Status) {
+		utils.LogErrorWithUser(userID, nil, "Subscription already ended dans CancelSubscription")
+		c.JSON(http.StatusConflict, gin.H{"error": "Subscription already ended"})
+		return
+	}
+
+	// Un abonnement jamais payé est annulé immédiatement
+	if subscription.Status == models.SubscriptionPending {
+		if subscription.StripeSubscriptionId != "" {
+			err = payments.Default.CancelSubscription(subscription.StripeSubscriptionId, false)
+			if err != nil && !errors.Is(err, payments.ErrNotFound) {
+				utils.LogErrorWithUser(userID, err, "Erreur lors de l'annulation Stripe dans CancelSubscription")
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error when canceling the Stripe subscription"})
+				return
+			}
+		}
+
+		err = db.DB.Model(&subscription).Update("status", models.SubscriptionCanceled).Error
+		if err != nil {
+			utils.LogErrorWithUser(userID, err, "Erreur lors de la mise à jour du statut dans CancelSubscription")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error when updating the subscription status"})
+			return
+		}
+
+		utils.LogSuccessWithUser(userID, "Abonnement en attente annulé avec succès dans CancelSubscription")
+		c.JSON(http.StatusOK, gin.H{"message": "Subscription canceled successfully"})
+		return
+	}
+
+	// L'abonné conserve l'accès jusqu'à la fin de la période payée, Stripe enverra customer.subscription.deleted
+	err = payments.Default.CancelSubscription(subscription.StripeSubscriptionId, true)
 	if err != nil {
 		utils.LogErrorWithUser(userID, err, "Erreur lors de l'annulation Stripe dans CancelSubscription")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error when canceling the Stripe subscription"})
 		return
 	}
 
-	err = db.DB.Model(&subscription).Update("status", models.SubscriptionCanceled).Error
+	err = db.DB.Model(&subscription).Update("cancel_at_period_end", true).Error
 	if err != nil {
-		utils.LogErrorWithUser(userID, err, "Erreur lors de la mise à jour du statut dans CancelSubscription")
+		utils.LogErrorWithUser(userID, err, "Erreur lors de la mise à jour de l'abonnement dans CancelSubscription")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error when updating the subscription status"})
 		return
 	}
 
-	utils.LogSuccessWithUser(userID, "Abonnement annulé avec succès dans CancelSubscription")
-	c.JSON(http.StatusOK, gin.H{"message": "Subscription canceled successfully"})
+	utils.LogSuccessWithUser(userID, "Résiliation programmée en fin de période dans CancelSubscription")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Subscription will be canceled at the end of the current period",
+		"endDate": subscription.EndDate,
+	})
 }
 
 // GetUserSubscriptions get all the subscriptions (active, canceled, history) of the connected user
@@ -263,14 +363,14 @@ func GetSubscriptionDetail(c *gin.Context) {
 
 // GetTotalRevenue allows an admin to retrieve the total sum of payments over a given period (admin only)
 // @Summary Get the total revenue of the site
-// @Description Returns the total amount of successful subscription payments between two dates (admin only)
+// @Description Returns the net revenue between two dates, both included, from the ledger (admin only): subscription payments, tips and post purchases collected over the period, minus the refunds issued and the disputes withdrawn over the period, whatever the payment they apply to
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param start_date query string true "Start date (YYYY-MM-DD)"
-// @Param end_date query string true "End date (YYYY-MM-DD)"
+// @Param end_date query string true "End date, included (YYYY-MM-DD)"
 // @Security BearerAuth
-// @Success 200 {object} map[string]interface{} "total: total amount in cents"
+// @Success 200 {object} map[string]interface{} "total: net amount in cents, subscriptions, tips and purchases: payments collected in cents, refunds and disputes: amounts taken back in cents"
 // @Failure 400 {object} map[string]string "error: Invalid input"
 // @Failure 401 {object} map[string]string "error: Unauthorized"
 // @Failure 403 {object} map[string]string "error: Access denied"
@@ -304,11 +404,28 @@ func GetTotalRevenue(c *gin.Context) {
 		return
 	}
 
-	var total int64
-	err = db.DB.Model(&models.SubscriptionPayment{}).
-		Where("status = ? AND paid_at >= ? AND paid_at <= ?", models.SubscriptionPaymentSucceeded, startDate, endDate.Add(24*time.Hour)).
-		Select("COALESCE(SUM(amount),0)").
-		Scan(&total).Error
+	// Le grand livre porte tous les paiements encaissés et toutes les reprises (remboursements, contestations),
+	// quel que soit le type de paiement, chacun à sa date. La date de fin est incluse
+	var revenue struct {
+		Subscriptions int64
+		Tips          int64
+		Purchases     int64
+		Refunds       int64
+		Disputes      int64
+	}
+	err = db.DB.Model(&models.LedgerTransaction{}).
+		Select(`COALESCE(SUM(amount) FILTER (WHERE kind = ? AND source_type = ?), 0) AS subscriptions,
+			COALESCE(SUM(amount) FILTER (WHERE kind = ? AND source_type = ?), 0) AS tips,
+			COALESCE(SUM(amount) FILTER (WHERE kind = ? AND source_type = ?), 0) AS purchases,
+			COALESCE(SUM(-amount) FILTER (WHERE source_type = ?), 0) AS refunds,
+			COALESCE(SUM(-amount) FILTER (WHERE source_type = ?), 0) AS disputes`,
+			models.LedgerTransactionPayment, models.LedgerSourceSubscriptionPayment,
+			models.LedgerTransactionPayment, models.LedgerSourceTip,
+			models.LedgerTransactionPayment, models.LedgerSourcePostPurchase,
+			models.LedgerSourcePaymentRefund,
+			models.LedgerSourcePaymentDispute).
+		Where("kind <> ? AND created_at >= ? AND created_at < ?", models.LedgerTransactionPayout, startDate, endDate.AddDate(0, 0, 1)).
+		Scan(&revenue).Error
 	if err != nil {
 		utils.LogError(err, "Error calculating total revenue in GetTotalRevenue")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error calculating total revenue"})
@@ -316,7 +433,14 @@ func GetTotalRevenue(c *gin.Context) {
 	}
 
 	utils.LogSuccess("Total revenue successfully retrieved in GetTotalRevenue")
-	c.JSON(http.StatusOK, gin.H{"total": total})
+	c.JSON(http.StatusOK, gin.H{
+		"total":         revenue.Subscriptions + revenue.Tips + revenue.Purchases - revenue.Refunds - revenue.Disputes,
+		"subscriptions": revenue.Subscriptions,
+		"tips":          revenue.Tips,
+		"purchases":     revenue.Purchases,
+		"refunds":       revenue.Refunds,
+		"disputes":      revenue.Disputes,
+	})
 }
 
 // GetTopContentCreators returns the top 3 content creators with the most active subscriptions (admin only)
@@ -0,0 +1,329 @@
+package stripe
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/payments"
+	"pec2-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	stripe "github.com/stripe/stripe-go/v82"
+	"gorm.io/gorm"
+)
+
+// Les paiements ponctuels portent leur type dans les metadata de la session et du PaymentIntent
+const (
+	paymentTypeMetadata = "payment_type"
+	tipPaymentType      = "tip"
+)
+
+// CreateTipCheckoutSession starts a one-off Stripe payment to tip a content creator
+// @Summary Tip a content creator
+// @Description Create a one-off Stripe Checkout session to send a tip (with an optional message) to a content creator, from their profile or on one of their posts
+// @Tags tips
+// @Accept json
+// @Produce json
+// @Param contentCreatorId path string true "ID of the content creator"
+// @Param tip body models.TipCreate true "Amount in cents, optional message and post"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "sessionId: ID of the Stripe Checkout session, url: Stripe Checkout URL, tipId: ID of the tip"
+// @Failure 400 {object} map[string]string "error: Invalid input"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 403 {object} map[string]string "error: Can only tip a content creator"
+// @Failure 404 {object} map[string]string "error: User, content creator or post not found"
+// @Failure 500 {object} map[string]string "error: Stripe error or server error"
+// @Router /tips/{contentCreatorId} [post]
+func CreateTipCheckoutSession(c *gin.Context) {
+	contentCreatorId := c.Param("contentCreatorId")
+
+	var input models.TipCreate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.LogError(err, "Invalid input dans CreateTipCheckoutSession")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(nil, "User not authenticated dans CreateTipCheckoutSession")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if userID == contentCreatorId {
+		utils.LogErrorWithUser(userID, nil, "Cannot tip yourself dans CreateTipCheckoutSession")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot tip yourself"})
+		return
+	}
+
+	var payer models.User
+	if err := db.DB.First(&payer, "id = ?", userID).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "User not found dans CreateTipCheckoutSession")
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var creator models.User
+	if err := db.DB.First(&creator, "id = ?", contentCreatorId).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "Content creator not found dans CreateTipCheckoutSession")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Content creator not found"})
+		return
+	}
+	if creator.Role != models.ContentCreator {
+		utils.LogErrorWithUser(userID, nil, "Can only tip a content creator dans CreateTipCheckoutSession")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Can only tip a content creator"})
+		return
+	}
+
+	tip := models.Tip{
+		SenderID:         payer.ID,
+		ContentCreatorID: creator.ID,
+		Amount:           input.Amount,
+		Currency:         "eur",
+		Message:          input.Message,
+		Status:           models.TipPending,
+	}
+
+	if input.PostID != "" {
+		var post models.Post
+		if err := db.DB.First(&post, "id = ? AND user_id = ?", input.PostID, creator.ID).Error; err != nil {
+			utils.LogErrorWithUser(userID, err, "Post not found dans CreateTipCheckoutSession")
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found for this content creator"})
+			return
+		}
+		tip.PostID = &post.ID
+	}
+
+	// Le client Stripe est créé avant le pourboire : un échec ne laisse pas de pourboire PENDING sans session
+	customerID, err := payments.Default.EnsureCustomer(payer.StripeCustomerId, payer.UserName)
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Erreur lors de la création du client Stripe dans CreateTipCheckoutSession")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création du client Stripe"})
+		return
+	}
+	if customerID != payer.StripeCustomerId {
+		db.DB.Model(&payer).Update("stripe_customer_id", customerID)
+	}
+
+	if err := db.DB.Create(&tip).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "Error creating tip dans CreateTipCheckoutSession")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating tip"})
+		return
+	}
+
+	s, err := payments.Default.CreateCheckoutSession(payments.CheckoutParams{
+		Mode:              payments.CheckoutModePayment,
+		CustomerID:        customerID,
+		ClientReferenceID: creator.ID,
+		ProductName:       "Tip for " + creator.UserName,
+		Currency:          tip.Currency,
+		Amount:            int64(tip.Amount),
+		Metadata: map[string]string{
+			paymentTypeMetadata: tipPaymentType,
+			"tip_id":            tip.ID,
+		},
+		SuccessURL: "https://tonsite.com/success",
+		CancelURL:  "https://tonsite.com/cancel",
+	})
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Erreur lors de la création de la session Stripe dans CreateTipCheckoutSession")
+		db.DB.Model(&tip).Update("status", models.TipFailed)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	db.DB.Model(&tip).Update("stripe_checkout_session_id", s.ID)
+
+	utils.LogSuccessWithUser(userID, "Session Stripe de pourboire créée avec succès dans CreateTipCheckoutSession")
+	c.JSON(http.StatusOK, gin.H{"sessionId": s.ID, "url": s.URL, "tipId": tip.ID})
+}
+
+// GetSentTips returns the tips sent by the connected user
+// @Summary List the tips I sent
+// @Description Return all the tips sent by the connected user, newest first
+// @Tags tips
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Tip
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 500 {object} map[string]string "error: Error fetching tips"
+// @Router /tips/sent [get]
+func GetSentTips(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(nil, "User not authenticated dans GetSentTips")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var tips []models.Tip
+	if err := db.DB.Where("sender_id = ?", userID).Order("created_at DESC").Find(&tips).Error; err != nil {
+		utils.LogErrorWithUser(userID, err, "Erreur lors de la récupération des pourboires dans GetSentTips")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tips"})
+		return
+	}
+
+	utils.LogSuccessWithUser(userID, "Pourboires envoyés récupérés avec succès dans GetSentTips")
+	c.JSON(http.StatusOK, tips)
+}
+
+// GetReceivedTips returns the paid tips received by the connected creator and their total
+// @Summary List the tips I received
+// @Description Return the paid tips received by the connected content creator, newest first, with the total amount in cents
+// @Tags tips
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "tips: list of tips, total: total amount in cents"
+// @Failure 401 {object} map[string]string "error: Unauthorized"
+// @Failure 500 {object} map[string]string "error: Error fetching tips"
+// @Router /tips/received [get]
+func GetReceivedTips(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.LogError(nil, "User not authenticated dans GetReceivedTips")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var tips []models.Tip
+	err := db.DB.Where("content_creator_id = ? AND status = ?", userID, models.TipSucceeded).
+		Order("paid_at DESC").
+		Find(&tips).Error
+	if err != nil {
+		utils.LogErrorWithUser(userID, err, "Erreur lors de la récupération des pourboires dans GetReceivedTips")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tips"})
+		return
+	}
+
+	// Seul le profil public des auteurs est exposé au créateur
+	senderIDs := make([]string, 0, len(tips))
+	for _, tip := range tips {
+		senderIDs = append(senderIDs, tip.SenderID)
+	}
+	var senders []models.UserInfo
+	if len(senderIDs) > 0 {
+		if err := db.DB.Model(&models.User{}).
+			Select("id, user_name, profile_picture").
+			Where("id IN ?", senderIDs).
+			Find(&senders).Error; err != nil {
+			utils.LogErrorWithUser(userID, err, "Erreur lors de la récupération des auteurs dans GetReceivedTips")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tips"})
+			return
+		}
+	}
+	senderByID := make(map[string]models.UserInfo, len(senders))
+	for _, sender := range senders {
+		senderByID[sender.ID] = sender
+	}
+
+	total := 0
+	for i := range tips {
+		total += tips[i].Amount
+		if sender, ok := senderByID[tips[i].SenderID]; ok {
+			tips[i].Sender = &sender
+		}
+	}
+
+	utils.LogSuccessWithUser(userID, "Pourboires reçus récupérés avec succès dans GetReceivedTips")
+	c.JSON(http.StatusOK, gin.H{"tips": tips, "total": total})
+}
+
+func handleTipCheckoutCompleted(session stripe.CheckoutSession) (string, error) {
+	var paymentIntentID string
+	if session.PaymentIntent != nil {
+		paymentIntentID = session.PaymentIntent.ID
+	}
+
+	tip, err := findOneOffPayment[models.Tip](session.Metadata["tip_id"], paymentIntentID)
+	if err != nil {
+		utils.LogError(err, "Tip not found dans handleTipCheckoutCompleted")
+		return "", permanent(fmt.Errorf("tip not found: %w", err))
+	}
+
+	status := models.TipPending
+	if session.PaymentStatus == stripe.CheckoutSessionPaymentStatusPaid {
+		status = models.TipSucceeded
+	}
+
+	updated, err := updateOneOffStatus(tipPayment(tip), string(status), paymentIntentID)
+	if err != nil {
+		utils.LogError(err, "Error updating tip dans handleTipCheckoutCompleted")
+		return "", fmt.Errorf("error updating tip: %w", err)
+	}
+	if !updated {
+		return "Tip already up to date", nil
+	}
+
+	utils.LogSuccess("Tip " + tip.ID + " updated to " + string(status) + " dans handleTipCheckoutCompleted")
+	if status == models.TipSucceeded {
+		return "Tip paid", nil
+	}
+	return "Tip waiting for payment", nil
+}
+
+func handleTipCheckoutExpired(session stripe.CheckoutSession) (string, error) {
+	tip, err := findOneOffPayment[models.Tip](session.Metadata["tip_id"], "")
+	if err != nil {
+		utils.LogError(err, "Tip not found dans handleTipCheckoutExpired")
+		return "", permanent(fmt.Errorf("tip not found: %w", err))
+	}
+
+	if tip.Status != models.TipPending {
+		return "Tip already up to date", nil
+	}
+	if _, err := updateOneOffStatus(tipPayment(tip), string(models.TipCanceled), ""); err != nil {
+		utils.LogError(err, "Error canceling tip dans handleTipCheckoutExpired")
+		return "", fmt.Errorf("error canceling tip: %w", err)
+	}
+
+	utils.LogSuccess("Tip " + tip.ID + " canceled dans handleTipCheckoutExpired")
+	return "Tip canceled", nil
+}
+
+func handleTipPaymentIntent(pi stripe.PaymentIntent, status models.TipStatus) (string, error) {
+	tip, err := findOneOffPayment[models.Tip](pi.Metadata["tip_id"], pi.ID)
+	if err != nil {
+		utils.LogError(err, "Tip not found dans handleTipPaymentIntent")
+		return "", permanent(fmt.Errorf("tip not found: %w", err))
+	}
+
+	updated, err := updateOneOffStatus(tipPayment(tip), string(status), pi.ID)
+	if err != nil {
+		utils.LogError(err, "Error updating tip dans handleTipPaymentIntent")
+		return "", fmt.Errorf("error updating tip: %w", err)
+	}
+	if !updated {
+		return "Tip already up to date", nil
+	}
+
+	utils.LogSuccess("Tip " + tip.ID + " updated to " + string(status) + " dans handleTipPaymentIntent")
+	return "Tip updated to " + string(status), nil
+}
+
+// refundTip enregistre le remboursement du pourboire payé par la charge, voir refundOneOff.
+// Renvoie false si aucun pourboire ne correspond
+func refundTip(charge stripe.Charge) (string, bool, error) {
+	tip, err := findOneOffPayment[models.Tip]("", charge.PaymentIntent.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	recorded, fullyRefunded, err := refundOneOff(tipPayment(tip), charge)
+	switch {
+	case err != nil:
+		return "", true, err
+	case !recorded:
+		return "Refund already recorded", true, nil
+	case !fullyRefunded:
+		return "Tip partially refunded", true, nil
+	default:
+		return "Tip refunded", true, nil
+	}
+}
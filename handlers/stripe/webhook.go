@@ -2,21 +2,29 @@ package stripe
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"pec2-backend/db"
+	"pec2-backend/invoices"
+	"pec2-backend/ledger"
 	"pec2-backend/models"
 	"pec2-backend/utils"
 
 	"github.com/gin-gonic/gin"
 	stripe "github.com/stripe/stripe-go/v82"
 	"github.com/stripe/stripe-go/v82/webhook"
+	"gorm.io/gorm"
 )
 
+// errSubscriptionNotReady l'événement est arrivé avant la création de l'abonnement local : il sera relancé
+var errSubscriptionNotReady = errors.New("subscription not ready")
+
 func StripeWebhookHandler(c *gin.Context) {
 	const MaxBodyBytes = int64(65536)
 	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxBodyBytes)
@@ -43,68 +51,113 @@ func StripeWebhookHandler(c *gin.Context) {
 		return
 	}
 
+	isNew, err := recordEvent(event, payload)
+	if err != nil {
+		// Sans journal on ne peut pas garantir l'idempotence : Stripe relivrera l'événement
+		utils.LogError(err, "Error recording stripe event dans StripeWebhookHandler")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error recording event"})
+		return
+	}
+	if !isNew {
+		utils.LogInfo("Duplicate stripe event " + event.ID + " ignored dans StripeWebhookHandler")
+		c.JSON(http.StatusOK, gin.H{"message": "Event already received"})
+		return
+	}
+
+	message, err := runEvent(event.ID, 0, event)
+	if err != nil {
+		// L'événement est journalisé en échec, la relance est gérée par le worker
+		utils.LogError(err, "Error processing stripe event "+event.ID+" dans StripeWebhookHandler")
+		c.JSON(http.StatusOK, gin.H{"message": "Event recorded, processing failed and will be retried"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// dispatchEvent exécute le traitement correspondant au type d'événement
+func dispatchEvent(event stripe.Event) (string, error) {
 	switch event.Type {
 	case "checkout.session.completed":
-		handleCheckoutSessionCompleted(c, event)
+		return handleCheckoutSessionCompleted(event)
+	case "checkout.session.expired":
+		return handleCheckoutSessionExpired(event)
 	case "payment_intent.created":
-		handlePaymentIntentCreated(c, event)
+		return handlePaymentIntentCreated(event)
 	case "payment_intent.processing":
-		handlePaymentIntentProcessing(c, event)
+		return handlePaymentIntentProcessing(event)
 	case "payment_intent.succeeded":
-		handlePaymentIntentSucceeded(c, event)
-	case "payment_intent.failed":
-		handlePaymentIntentFailed(c, event)
+		return handlePaymentIntentSucceeded(event)
+	case "payment_intent.payment_failed":
+		return handlePaymentIntentFailed(event)
 	case "payment_intent.canceled":
-		handlePaymentIntentCanceled(c, event)
+		return handlePaymentIntentCanceled(event)
 	case "invoice.payment_succeeded":
-		handleInvoicePaymentSucceeded(c, event)
+		return handleInvoicePaymentSucceeded(event)
 	case "invoice.payment_failed":
-		handleInvoicePaymentFailed(c, event)
+		return handleInvoicePaymentFailed(event)
+	case "customer.subscription.updated":
+		return handleCustomerSubscriptionUpdated(event)
+	case "customer.subscription.deleted":
+		return handleCustomerSubscriptionDeleted(event)
+	case "charge.refunded":
+		return handleChargeRefunded(event)
+	case "charge.dispute.created":
+		return handleChargeDisputeCreated(event)
+	case "charge.dispute.updated":
+		return handleChargeDisputeUpdated(event)
+	case "charge.dispute.funds_withdrawn":
+		return handleChargeDisputeFundsWithdrawn(event)
+	case "charge.dispute.funds_reinstated":
+		return handleChargeDisputeFundsReinstated(event)
+	case "charge.dispute.closed":
+		return handleChargeDisputeClosed(event)
 	default:
-		c.JSON(http.StatusOK, gin.H{"message": "Event ignored"})
+		return "", errEventIgnored
 	}
 }
 
-func handleCheckoutSessionCompleted(c *gin.Context, event stripe.Event) {
+func handleCheckoutSessionCompleted(event stripe.Event) (string, error) {
 	var session stripe.CheckoutSession
 	if err := json.Unmarshal(event.Data.Raw, &session); err != nil {
 		utils.LogError(err, "Error parsing CheckoutSession dans handleCheckoutSessionCompleted")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing CheckoutSession"})
-		return
+		return "", permanent(errors.New("error parsing CheckoutSession"))
+	}
+
+	switch session.Metadata[paymentTypeMetadata] {
+	case tipPaymentType:
+		return handleTipCheckoutCompleted(session)
+	case postPurchasePaymentType:
+		return handlePostPurchaseCheckoutCompleted(session)
 	}
 
 	if session.Customer == nil {
 		utils.LogError(nil, "Customer missing in session dans handleCheckoutSessionCompleted")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Customer missing in session"})
-		return
+		return "", permanent(errors.New("customer missing in session"))
 	}
 
 	customerID := session.Customer.ID
 	creatorID := session.ClientReferenceID
 	if creatorID == "" {
 		utils.LogError(nil, "ClientReferenceID missing dans handleCheckoutSessionCompleted")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ClientReferenceID missing"})
-		return
+		return "", permanent(errors.New("clientReferenceID missing"))
 	}
 
 	var user models.User
 	if err := db.DB.First(&user, "stripe_customer_id = ?", customerID).Error; err != nil {
 		utils.LogError(err, "User not found for this customer dans handleCheckoutSessionCompleted")
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found for this customer"})
-		return
+		return "", errors.New("user not found for this customer")
 	}
 
 	var creator models.User
 	if err := db.DB.First(&creator, "id = ?", creatorID).Error; err != nil {
 		utils.LogError(err, "Creator not found dans handleCheckoutSessionCompleted")
-		c.JSON(http.StatusNotFound, gin.H{"error": "Creator not found"})
-		return
+		return "", permanent(errors.New("creator not found"))
 	}
 
 	if creator.Role != models.ContentCreator {
 		utils.LogError(nil, "The target is not a content creator dans handleCheckoutSessionCompleted")
-		c.JSON(http.StatusForbidden, gin.H{"error": "The target is not a content creator"})
-		return
+		return "", permanent(errors.New("the target is not a content creator"))
 	}
 
 	var stripeSubID string
@@ -113,26 +166,30 @@ func handleCheckoutSessionCompleted(c *gin.Context, event stripe.Event) {
 		var tmp models.Subscription
 		if err := db.DB.First(&tmp, "stripe_subscription_id = ?", stripeSubID).Error; err == nil {
 			utils.LogError(nil, "Stripe subscription already exists dans handleCheckoutSessionCompleted")
-			c.JSON(http.StatusOK, gin.H{"message": "Stripe subscription already exists"})
-			return
+			return "Stripe subscription already exists", nil
 		}
 	}
 
 	var dup models.Subscription
 	if err := db.DB.Where("user_id = ? AND content_creator_id = ? AND status IN ?",
 		user.ID, creator.ID,
-		[]models.SubscriptionStatus{models.SubscriptionPending, models.SubscriptionActive}).
+		[]models.SubscriptionStatus{models.SubscriptionPending, models.SubscriptionActive, models.SubscriptionPastDue}).
 		First(&dup).Error; err == nil {
 		utils.LogError(nil, "Local subscription already exists dans handleCheckoutSessionCompleted")
-		c.JSON(http.StatusOK, gin.H{"message": "Local subscription already exists"})
-		return
+		return "Local subscription already exists", nil
+	}
+
+	months := 1
+	if value, err := strconv.Atoi(session.Metadata["bundle_months"]); err == nil && value > 1 {
+		months = value
 	}
 
 	now := time.Now()
-	end := now.AddDate(0, 1, 0)
+	end := now.AddDate(0, months, 0)
 
+	// Un essai ou une réduction totale ne demande aucun paiement : l'accès est ouvert tout de suite
 	initialStatus := models.SubscriptionPending
-	if session.PaymentStatus == "paid" {
+	if session.PaymentStatus == stripe.CheckoutSessionPaymentStatusPaid || session.PaymentStatus == stripe.CheckoutSessionPaymentStatusNoPaymentRequired {
 		initialStatus = models.SubscriptionActive
 	}
 
@@ -141,24 +198,57 @@ func handleCheckoutSessionCompleted(c *gin.Context, event stripe.Event) {
 		ContentCreatorID:     creator.ID,
 		Status:               initialStatus,
 		StripeSubscriptionId: stripeSubID,
+		BillingMonths:        months,
 		StartDate:            now,
 		EndDate:              &end,
 	}
+	if trialDays, err := strconv.Atoi(session.Metadata["trial_days"]); err == nil && trialDays > 0 {
+		trialEnd := now.AddDate(0, 0, trialDays)
+		sub.TrialEndsAt = &trialEnd
+		sub.EndDate = &trialEnd
+	}
+
+	// Le plan choisi est transmis dans les metadata de la session de paiement
+	if planID := session.Metadata["plan_id"]; planID != "" {
+		var plan models.SubscriptionPlan
+		if err := db.DB.First(&plan, "id = ? AND content_creator_id = ?", planID, creator.ID).Error; err == nil {
+			sub.PlanID = &plan.ID
+			sub.Tier = plan.Tier
+			// Le montant de l'échéance fait foi si le créateur a changé son prix entre-temps
+			sub.Price = plan.Price
+			if recurring, err := strconv.Atoi(session.Metadata["recurring_amount"]); err == nil && recurring > 0 {
+				sub.Price = recurring
+			} else if session.AmountTotal > 0 {
+				sub.Price = int(session.AmountTotal)
+			}
+		} else {
+			utils.LogError(err, "Plan not found dans handleCheckoutSessionCompleted")
+		}
+	}
 
-	if err := db.DB.Create(&sub).Error; err != nil {
+	// L'utilisation du code promo est confirmée avec la création de l'abonnement
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&sub).Error; err != nil {
+			return err
+		}
+		if redemptionID := session.Metadata["coupon_redemption_id"]; redemptionID != "" {
+			return completeCouponRedemption(tx, redemptionID, sub.ID)
+		}
+		return nil
+	})
+	if err != nil {
 		utils.LogError(err, "Error creating subscription dans handleCheckoutSessionCompleted")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating subscription"})
-		return
+		return "", fmt.Errorf("error creating subscription: %w", err)
 	}
 
 	if session.PaymentIntent != nil {
 		utils.LogError(nil, "PaymentIntent présent dans handleCheckoutSessionCompleted")
-		err1 := upsertSubscriptionPayment(sub.ID, int(session.AmountTotal), session.PaymentIntent.ID, models.SubscriptionPaymentPending)
+		err1 := upsertSubscriptionPayment(&sub, int(session.AmountTotal), session.PaymentIntent.ID, models.SubscriptionPaymentPending)
 		if err1 != nil {
 			utils.LogError(err1, "Erreur upsertSubscriptionPayment (pending) dans handleCheckoutSessionCompleted")
 		}
 		if session.PaymentStatus == "paid" {
-			err2 := upsertSubscriptionPayment(sub.ID, int(session.AmountTotal), session.PaymentIntent.ID, models.SubscriptionPaymentSucceeded)
+			err2 := upsertSubscriptionPayment(&sub, int(session.AmountTotal), session.PaymentIntent.ID, models.SubscriptionPaymentSucceeded)
 			if err2 != nil {
 				utils.LogError(err2, "Erreur upsertSubscriptionPayment (paid) dans handleCheckoutSessionCompleted")
 			}
@@ -169,32 +259,54 @@ func handleCheckoutSessionCompleted(c *gin.Context, event stripe.Event) {
 
 	if initialStatus == models.SubscriptionActive {
 		utils.LogSuccess("Subscription created and activated dans handleCheckoutSessionCompleted")
-		c.JSON(http.StatusOK, gin.H{"message": "Subscription created and activated"})
+		return "Subscription created and activated", nil
 	} else {
 		utils.LogSuccess("Subscription created, waiting for payment dans handleCheckoutSessionCompleted")
-		c.JSON(http.StatusOK, gin.H{"message": "Subscription created, waiting for payment"})
+		return "Subscription created, waiting for payment", nil
 	}
 }
 
-func findSubscriptionByCustomer(customerID string, allowMultiple bool) (*models.Subscription, error) {
-	var user models.User
-	if err := db.DB.First(&user, "stripe_customer_id = ?", customerID).Error; err != nil {
-		return nil, fmt.Errorf("user not found")
+func handleCheckoutSessionExpired(event stripe.Event) (string, error) {
+	var session stripe.CheckoutSession
+	if err := json.Unmarshal(event.Data.Raw, &session); err != nil {
+		utils.LogError(err, "Error parsing CheckoutSession dans handleCheckoutSessionExpired")
+		return "", permanent(errors.New("error parsing CheckoutSession"))
 	}
 
-	var sub models.Subscription
-	query := db.DB.Where("user_id = ? AND status IN ?",
-		user.ID,
-		[]models.SubscriptionStatus{models.SubscriptionPending, models.SubscriptionActive})
+	switch session.Metadata[paymentTypeMetadata] {
+	case tipPaymentType:
+		return handleTipCheckoutExpired(session)
+	case postPurchasePaymentType:
+		return handlePostPurchaseCheckoutExpired(session)
+	}
 
-	if !allowMultiple {
-		query = query.Order("created_at desc")
+	// Le code promo réservé redevient disponible
+	if redemptionID := session.Metadata["coupon_redemption_id"]; redemptionID != "" {
+		if err := releaseCouponRedemption(redemptionID); err != nil {
+			utils.LogError(err, "Error releasing promo code dans handleCheckoutSessionExpired")
+			return "", fmt.Errorf("error releasing promo code: %w", err)
+		}
+		utils.LogSuccess("Promo code reservation " + redemptionID + " released dans handleCheckoutSessionExpired")
+		return "Checkout expired - promo code released", nil
 	}
 
-	if err := query.First(&sub).Error; err != nil {
-		return nil, fmt.Errorf("subscription not found")
+	// Les abonnements en attente sont clos par customer.subscription.deleted ou par la réconciliation
+	return "", errEventIgnored
+}
+
+// findSubscriptionByPaymentIntent retrouve l'abonnement d'un paiement déjà rattaché par un événement invoice.*.
+// Un client peut être abonné à plusieurs créateurs : seul l'abonnement Stripe porté par la facture désigne
+// le bon créateur, le PaymentIntent seul ne permet pas de le retrouver
+func findSubscriptionByPaymentIntent(paymentIntentID string) (*models.Subscription, error) {
+	var payment models.SubscriptionPayment
+	if err := db.DB.First(&payment, "stripe_payment_intent_id = ?", paymentIntentID).Error; err != nil {
+		return nil, err
 	}
 
+	var sub models.Subscription
+	if err := db.DB.First(&sub, "id = ?", payment.SubscriptionID).Error; err != nil {
+		return nil, err
+	}
 	return &sub, nil
 }
 
@@ -206,7 +318,7 @@ func findSubscriptionByStripeID(stripeSubID string) (*models.Subscription, error
 	return &sub, nil
 }
 
-func upsertSubscriptionPayment(subscriptionID string, amount int, paymentIntentID string, status models.SubscriptionPaymentStatus) error {
+func upsertSubscriptionPayment(sub *models.Subscription, amount int, paymentIntentID string, status models.SubscriptionPaymentStatus) error {
 	if paymentIntentID == "" {
 		return nil
 	}
@@ -216,37 +328,61 @@ func upsertSubscriptionPayment(subscriptionID string, amount int, paymentIntentI
 
 	if err == nil {
 		// Le paiement existe déjà
-		if payment.Status == models.SubscriptionPaymentSucceeded && status == models.SubscriptionPaymentSucceeded {
-			// Éviter de mettre à jour un paiement déjà réussi
-			return fmt.Errorf("payment already recorded")
+		switch payment.Status {
+		case models.SubscriptionPaymentSucceeded, models.SubscriptionPaymentRefunded, models.SubscriptionPaymentDisputed:
+			// Un paiement réussi est définitif : une livraison tardive ou en double ne le modifie pas
+			return nil
 		}
 
 		// Mettre à jour uniquement si le nouveau statut est différent
-		if payment.Status != status {
-			return db.DB.Model(&payment).Updates(map[string]interface{}{
+		if payment.Status == status {
+			return nil
+		}
+	}
+
+	// Le passage à SUCCEEDED et le crédit du créateur sont enregistrés ensemble
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err == nil {
+			if err := tx.Model(&payment).Updates(map[string]interface{}{
 				"status":  status,
 				"amount":  amount,
 				"paid_at": time.Now(),
-			}).Error
+			}).Error; err != nil {
+				return err
+			}
+		} else {
+			// Créer un nouveau paiement
+			payment = models.SubscriptionPayment{
+				SubscriptionID:        sub.ID,
+				Amount:                amount,
+				PaidAt:                time.Now(),
+				StripePaymentIntentId: paymentIntentID,
+				Status:                status,
+			}
+			if err := tx.Create(&payment).Error; err != nil {
+				return err
+			}
 		}
-		return nil
-	}
 
-	// Créer un nouveau paiement
-	payment = models.SubscriptionPayment{
-		SubscriptionID:        subscriptionID,
-		Amount:                amount,
-		PaidAt:                time.Now(),
-		StripePaymentIntentId: paymentIntentID,
-		Status:                status,
-	}
-	return db.DB.Create(&payment).Error
+		if status != models.SubscriptionPaymentSucceeded {
+			return nil
+		}
+		if err := ledger.RecordPayment(tx, models.LedgerSourceSubscriptionPayment, payment.ID, sub.ContentCreatorID, amount, "eur"); err != nil {
+			return err
+		}
+		return invoices.Issue(tx, payment, sub)
+	})
 }
 
 func updateSubscriptionStatus(sub *models.Subscription) {
-	newEnd := time.Now().AddDate(0, 1, 0)
+	now := time.Now()
+	newEnd := now.AddDate(0, billingMonths(sub), 0)
+	// La facture à 0 de l'essai ne prolonge pas l'accès au-delà de l'essai
+	if sub.TrialEndsAt != nil && sub.TrialEndsAt.After(now) {
+		newEnd = *sub.TrialEndsAt
+	}
 
-	if sub.Status == models.SubscriptionPending {
+	if sub.Status == models.SubscriptionPending || sub.Status == models.SubscriptionPastDue {
 		db.DB.Model(sub).Updates(map[string]interface{}{
 			"status":   models.SubscriptionActive,
 			"end_date": newEnd,
@@ -256,89 +392,99 @@ func updateSubscriptionStatus(sub *models.Subscription) {
 	}
 }
 
-func handlePaymentIntentCreated(c *gin.Context, event stripe.Event) {
+func handlePaymentIntentCreated(event stripe.Event) (string, error) {
 	var pi stripe.PaymentIntent
 	if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
 		utils.LogError(err, "Error parsing PaymentIntent created dans handlePaymentIntentCreated")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing PaymentIntent created"})
-		return
+		return "", permanent(errors.New("error parsing PaymentIntent created"))
 	}
 
 	utils.LogSuccess("PaymentIntent created dans handlePaymentIntentCreated")
-	c.JSON(http.StatusOK, gin.H{"message": "PaymentIntent created - logged"})
+	return "PaymentIntent created - logged", nil
 }
 
-func handlePaymentIntentProcessing(c *gin.Context, event stripe.Event) {
+func handlePaymentIntentProcessing(event stripe.Event) (string, error) {
 	var pi stripe.PaymentIntent
 	if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
 		utils.LogError(err, "Error parsing PaymentIntent processing dans handlePaymentIntentProcessing")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing PaymentIntent processing"})
-		return
+		return "", permanent(errors.New("error parsing PaymentIntent processing"))
 	}
 
 	utils.LogSuccess("PaymentIntent processing dans handlePaymentIntentProcessing")
-	c.JSON(http.StatusOK, gin.H{"message": "PaymentIntent processing - logged"})
+	return "PaymentIntent processing - logged", nil
 }
 
-func handlePaymentIntentSucceeded(c *gin.Context, event stripe.Event) {
+func handlePaymentIntentSucceeded(event stripe.Event) (string, error) {
 	var pi stripe.PaymentIntent
 	if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
 		utils.LogError(err, "Error parsing PaymentIntent succeeded dans handlePaymentIntentSucceeded")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing PaymentIntent succeeded"})
-		return
+		return "", permanent(errors.New("error parsing PaymentIntent succeeded"))
 	}
 
-	if pi.Customer == nil || pi.ID == "" {
-		utils.LogError(nil, "PaymentIntent missing customer or ID dans handlePaymentIntentSucceeded")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "PaymentIntent missing customer or ID"})
-		return
+	switch pi.Metadata[paymentTypeMetadata] {
+	case tipPaymentType:
+		return handleTipPaymentIntent(pi, models.TipSucceeded)
+	case postPurchasePaymentType:
+		return handlePostPurchasePaymentIntent(pi, models.PostPurchaseSucceeded)
+	}
+
+	if pi.ID == "" {
+		utils.LogError(nil, "PaymentIntent missing ID dans handlePaymentIntentSucceeded")
+		return "", permanent(errors.New("payment intent missing ID"))
 	}
 
-	sub, err := findSubscriptionByCustomer(pi.Customer.ID, true)
+	sub, err := findSubscriptionByPaymentIntent(pi.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Le paiement est rattaché à son abonnement par les événements invoice.*
+		utils.LogInfo("Subscription payment " + pi.ID + " left to invoice events dans handlePaymentIntentSucceeded")
+		return "Subscription payment handled by invoice events", nil
+	}
 	if err != nil {
-		utils.LogError(err, "Subscription not found, will retry dans handlePaymentIntentSucceeded")
-		c.JSON(http.StatusOK, gin.H{"message": "Subscription not ready, will retry"})
-		return
+		utils.LogError(err, "Error finding subscription dans handlePaymentIntentSucceeded")
+		return "", fmt.Errorf("error finding subscription: %w", err)
 	}
 
-	if err := upsertSubscriptionPayment(sub.ID, int(pi.AmountReceived), pi.ID, models.SubscriptionPaymentSucceeded); err != nil {
-		if err.Error() == "payment already recorded" {
-			utils.LogError(err, "Payment already recorded dans handlePaymentIntentSucceeded")
-			c.JSON(http.StatusOK, gin.H{"message": "Payment already recorded"})
-			return
-		}
+	if err := upsertSubscriptionPayment(sub, int(pi.AmountReceived), pi.ID, models.SubscriptionPaymentSucceeded); err != nil {
 		utils.LogError(err, "Error creating payment dans handlePaymentIntentSucceeded")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating payment"})
-		return
+		return "", fmt.Errorf("error creating payment: %w", err)
 	}
 
 	utils.LogSuccess("Subscription activated via payment_intent.succeeded dans handlePaymentIntentSucceeded")
 	updateSubscriptionStatus(sub)
-	c.JSON(http.StatusOK, gin.H{"message": "Subscription activated via payment_intent.succeeded"})
+	return "Subscription activated via payment_intent.succeeded", nil
 }
 
-func handlePaymentIntentFailed(c *gin.Context, event stripe.Event) {
+func handlePaymentIntentFailed(event stripe.Event) (string, error) {
 	var pi stripe.PaymentIntent
 	if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
 		utils.LogError(err, "Error parsing PaymentIntent failed dans handlePaymentIntentFailed")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing PaymentIntent failed"})
-		return
+		return "", permanent(errors.New("error parsing PaymentIntent failed"))
 	}
 
-	if pi.Customer == nil || pi.ID == "" {
-		utils.LogError(nil, "PaymentIntent missing customer or ID dans handlePaymentIntentFailed")
-		c.JSON(http.StatusOK, gin.H{"message": "PaymentIntent missing customer or ID"})
-		return
+	switch pi.Metadata[paymentTypeMetadata] {
+	case tipPaymentType:
+		return handleTipPaymentIntent(pi, models.TipFailed)
+	case postPurchasePaymentType:
+		return handlePostPurchasePaymentIntent(pi, models.PostPurchaseFailed)
+	}
+
+	if pi.ID == "" {
+		utils.LogError(nil, "PaymentIntent missing ID dans handlePaymentIntentFailed")
+		return "PaymentIntent missing ID", nil
 	}
 
-	sub, err := findSubscriptionByCustomer(pi.Customer.ID, true)
+	sub, err := findSubscriptionByPaymentIntent(pi.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Le paiement est rattaché à son abonnement par les événements invoice.*
+		utils.LogInfo("Subscription payment " + pi.ID + " left to invoice events dans handlePaymentIntentFailed")
+		return "Subscription payment handled by invoice events", nil
+	}
 	if err != nil {
-		utils.LogError(err, "Subscription not found, will retry dans handlePaymentIntentFailed")
-		c.JSON(http.StatusOK, gin.H{"message": "Subscription not ready, will retry"})
-		return
+		utils.LogError(err, "Error finding subscription dans handlePaymentIntentFailed")
+		return "", fmt.Errorf("error finding subscription: %w", err)
 	}
 
-	_ = upsertSubscriptionPayment(sub.ID, int(pi.Amount), pi.ID, models.SubscriptionPaymentFailed)
+	_ = upsertSubscriptionPayment(sub, int(pi.Amount), pi.ID, models.SubscriptionPaymentFailed)
 
 	utils.LogError(nil, "Payment failed dans handlePaymentIntentFailed pour subscription: "+sub.ID)
 
@@ -347,31 +493,40 @@ func handlePaymentIntentFailed(c *gin.Context, event stripe.Event) {
 	}
 
 	utils.LogSuccess("Payment failed - subscription canceled if pending dans handlePaymentIntentFailed")
-	c.JSON(http.StatusOK, gin.H{"message": "Payment failed - subscription canceled if pending"})
+	return "Payment failed - subscription canceled if pending", nil
 }
 
-func handlePaymentIntentCanceled(c *gin.Context, event stripe.Event) {
+func handlePaymentIntentCanceled(event stripe.Event) (string, error) {
 	var pi stripe.PaymentIntent
 	if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
 		utils.LogError(err, "Error parsing PaymentIntent canceled dans handlePaymentIntentCanceled")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing PaymentIntent canceled"})
-		return
+		return "", permanent(errors.New("error parsing PaymentIntent canceled"))
 	}
 
-	if pi.Customer == nil || pi.ID == "" {
-		utils.LogError(nil, "PaymentIntent missing customer or ID dans handlePaymentIntentCanceled")
-		c.JSON(http.StatusOK, gin.H{"message": "PaymentIntent missing customer or ID"})
-		return
+	switch pi.Metadata[paymentTypeMetadata] {
+	case tipPaymentType:
+		return handleTipPaymentIntent(pi, models.TipCanceled)
+	case postPurchasePaymentType:
+		return handlePostPurchasePaymentIntent(pi, models.PostPurchaseCanceled)
+	}
+
+	if pi.ID == "" {
+		utils.LogError(nil, "PaymentIntent missing ID dans handlePaymentIntentCanceled")
+		return "PaymentIntent missing ID", nil
 	}
 
-	sub, err := findSubscriptionByCustomer(pi.Customer.ID, true)
+	sub, err := findSubscriptionByPaymentIntent(pi.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Le paiement est rattaché à son abonnement par les événements invoice.*
+		utils.LogInfo("Subscription payment " + pi.ID + " left to invoice events dans handlePaymentIntentCanceled")
+		return "Subscription payment handled by invoice events", nil
+	}
 	if err != nil {
-		utils.LogError(err, "Subscription not found, will retry dans handlePaymentIntentCanceled")
-		c.JSON(http.StatusOK, gin.H{"message": "Subscription not ready, will retry"})
-		return
+		utils.LogError(err, "Error finding subscription dans handlePaymentIntentCanceled")
+		return "", fmt.Errorf("error finding subscription: %w", err)
 	}
 
-	_ = upsertSubscriptionPayment(sub.ID, int(pi.Amount), pi.ID, models.SubscriptionPaymentCanceled)
+	_ = upsertSubscriptionPayment(sub, int(pi.Amount), pi.ID, models.SubscriptionPaymentCanceled)
 
 	utils.LogError(nil, "Payment canceled dans handlePaymentIntentCanceled pour subscription: "+sub.ID)
 
@@ -380,15 +535,14 @@ func handlePaymentIntentCanceled(c *gin.Context, event stripe.Event) {
 	}
 
 	utils.LogSuccess("Payment canceled - subscription canceled if pending dans handlePaymentIntentCanceled")
-	c.JSON(http.StatusOK, gin.H{"message": "Payment canceled - subscription canceled if pending"})
+	return "Payment canceled - subscription canceled if pending", nil
 }
 
-func handleInvoicePaymentSucceeded(c *gin.Context, event stripe.Event) {
+func handleInvoicePaymentSucceeded(event stripe.Event) (string, error) {
 	var invoiceData map[string]interface{}
 	if err := json.Unmarshal(event.Data.Raw, &invoiceData); err != nil {
 		utils.LogError(err, "Error parsing Invoice dans handleInvoicePaymentSucceeded")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing Invoice"})
-		return
+		return "", permanent(errors.New("error parsing Invoice"))
 	}
 
 	utils.LogSuccess("Received invoiceData dans handleInvoicePaymentSucceeded")
@@ -404,15 +558,13 @@ func handleInvoicePaymentSucceeded(c *gin.Context, event stripe.Event) {
 
 	if stripeSubID == "" {
 		utils.LogError(nil, "Impossible to retrieve subscription ID dans handleInvoicePaymentSucceeded")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
-		return
+		return "", permanent(errors.New("invalid subscription ID"))
 	}
 
 	sub, err := findSubscriptionByStripeID(stripeSubID)
 	if err != nil {
 		utils.LogError(err, "Subscription not found, will retry dans handleInvoicePaymentSucceeded")
-		c.JSON(http.StatusOK, gin.H{"message": "Subscription not ready, will retry"})
-		return
+		return "", errSubscriptionNotReady
 	}
 
 	var paymentIntentID string
@@ -425,19 +577,12 @@ func handleInvoicePaymentSucceeded(c *gin.Context, event stripe.Event) {
 		amount = int(amountPaid)
 	} else {
 		utils.LogError(nil, "amount_paid missing or invalid dans handleInvoicePaymentSucceeded")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount"})
-		return
+		return "", permanent(errors.New("invalid amount"))
 	}
 
-	if err := upsertSubscriptionPayment(sub.ID, amount, paymentIntentID, models.SubscriptionPaymentSucceeded); err != nil {
-		if err.Error() == "payment already recorded" {
-			utils.LogError(err, "Payment already recorded dans handleInvoicePaymentSucceeded")
-			c.JSON(http.StatusOK, gin.H{"message": "Payment already recorded"})
-			return
-		}
+	if err := upsertSubscriptionPayment(sub, amount, paymentIntentID, models.SubscriptionPaymentSucceeded); err != nil {
 		utils.LogError(err, "Error creating payment dans handleInvoicePaymentSucceeded")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating payment"})
-		return
+		return "", fmt.Errorf("error creating payment: %w", err)
 	}
 
 	utils.LogSuccess("Subscription activated via invoice.payment_succeeded dans handleInvoicePaymentSucceeded")
@@ -450,15 +595,14 @@ func handleInvoicePaymentSucceeded(c *gin.Context, event stripe.Event) {
 		message = "Subscription renewed via invoice.payment_succeeded"
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": message})
+	return message, nil
 }
 
-func handleInvoicePaymentFailed(c *gin.Context, event stripe.Event) {
+func handleInvoicePaymentFailed(event stripe.Event) (string, error) {
 	var invoiceData map[string]interface{}
 	if err := json.Unmarshal(event.Data.Raw, &invoiceData); err != nil {
 		utils.LogError(err, "Error parsing Invoice failed dans handleInvoicePaymentFailed")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error parsing Invoice"})
-		return
+		return "", permanent(errors.New("error parsing Invoice"))
 	}
 
 	var stripeSubID string
@@ -467,11 +611,17 @@ func handleInvoicePaymentFailed(c *gin.Context, event stripe.Event) {
 			stripeSubID = s
 		}
 	}
+	if parent, ok := invoiceData["parent"].(map[string]interface{}); ok && stripeSubID == "" {
+		if subDetails, ok := parent["subscription_details"].(map[string]interface{}); ok {
+			if s, ok := subDetails["subscription"].(string); ok {
+				stripeSubID = s
+			}
+		}
+	}
 
 	if stripeSubID == "" {
 		utils.LogError(nil, "Impossible to retrieve subscription ID for failed payment dans handleInvoicePaymentFailed")
-		c.JSON(http.StatusOK, gin.H{"message": "Invalid subscription ID - event ignored"})
-		return
+		return "Invalid subscription ID - event ignored", nil
 	}
 
 	var paymentIntentID string
@@ -481,10 +631,18 @@ func handleInvoicePaymentFailed(c *gin.Context, event stripe.Event) {
 
 	sub, err := findSubscriptionByStripeID(stripeSubID)
 	if err == nil {
-		_ = upsertSubscriptionPayment(sub.ID, 0, paymentIntentID, models.SubscriptionPaymentFailed)
+		_ = upsertSubscriptionPayment(sub, 0, paymentIntentID, models.SubscriptionPaymentFailed)
+
+		// Stripe relance le prélèvement : l'abonnement reste ouvert mais n'est plus à jour
+		if sub.Status == models.SubscriptionActive {
+			if err := db.DB.Model(sub).Update("status", models.SubscriptionPastDue).Error; err != nil {
+				utils.LogError(err, "Error marking subscription past due dans handleInvoicePaymentFailed")
+				return "", fmt.Errorf("error updating subscription: %w", err)
+			}
+		}
 	}
 
 	utils.LogError(nil, "Failed payment for subscription: "+stripeSubID+", PaymentIntent: "+paymentIntentID)
 
-	c.JSON(http.StatusOK, gin.H{"message": "Invoice payment failed - logged"})
+	return "Invoice payment failed - logged", nil
 }
@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"pec2-backend/db"
 	"pec2-backend/models"
+	"pec2-backend/sessions"
+	"pec2-backend/throttle"
 	"pec2-backend/utils"
 	mailsmodels "pec2-backend/utils/mails-models"
 	"strconv"
@@ -70,7 +72,7 @@ func GetAllUsers(c *gin.Context) {
 }
 
 // @Summary Update user password
-// @Description Update user's password by verifying the old password and setting a new one
+// @Description Update user's password by verifying the old password and setting a new one. Every session of the user is revoked, including the current one
 // @Tags users
 // @Accept json
 // @Produce json
@@ -129,8 +131,16 @@ func UpdatePassword(c *gin.Context) {
 		return
 	}
 
-	if result := db.DB.Model(&user).Update("password", string(hashedPassword)); result.Error != nil {
-		utils.LogError(result.Error, "Error when updating password in UpdatePassword")
+	// Un mot de passe changé doit déconnecter les appareils qui utilisaient l'ancien
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+			return err
+		}
+		_, err := sessions.RevokeAll(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		utils.LogError(err, "Error when updating password in UpdatePassword")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating password"})
 		return
 	}
@@ -587,6 +597,7 @@ func GetUserGenderStats(c *gin.Context) {
 // @Param data body PasswordResetRequest true "Email of the user"
 // @Success 200 {object} map[string]string "message: Code sent"
 // @Failure 404 {object} map[string]string "error: User not found"
+// @Failure 429 {object} map[string]string "error: Too many failed attempts or a code was sent recently, retryAfter: seconds before retry"
 // @Router /users/password/reset/request [post]
 func RequestPasswordReset(c *gin.Context) {
 	var req struct {
@@ -604,6 +615,10 @@ func RequestPasswordReset(c *gin.Context) {
 		return
 	}
 
+	if throttle.AbortIfThrottled(c, req.Email, "RequestPasswordReset") {
+		return
+	}
+
 	var user models.User
 	if err := db.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
 		utils.LogError(err, "User not found in RequestPasswordReset")
@@ -611,11 +626,21 @@ func RequestPasswordReset(c *gin.Context) {
 		return
 	}
 
-	code := utils.GenerateCode()
-	end := time.Now().Add(15 * time.Minute)
+	if throttle.AbortIfCodeRecentlySent(c, user.ResetPasswordCodeEnd.Add(-utils.ResetCodeTTL), "RequestPasswordReset") {
+		return
+	}
 
-	user.ResetPasswordCode = code
+	code, err := utils.GenerateCode()
+	if err != nil {
+		utils.LogError(err, "Error when generating the reset code in RequestPasswordReset")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating the code"})
+		return
+	}
+	end := time.Now().Add(utils.ResetCodeTTL)
+
+	user.ResetPasswordCode = utils.HashCode(code)
 	user.ResetPasswordCodeEnd = end
+	user.ResetCodeAttempts = 0
 
 	if err := db.DB.Save(&user).Error; err != nil {
 		utils.LogError(err, "Error when saving the reset code in RequestPasswordReset")
@@ -633,13 +658,14 @@ func RequestPasswordReset(c *gin.Context) {
 }
 
 // @Summary Reset password with a code
-// @Description Change the password if the code is correct and not expired
+// @Description Change the password if the code is correct and not expired. Every session of the user is revoked
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param data body PasswordResetConfirm true "Email, code, new password"
 // @Success 200 {object} map[string]string "message: Password reset"
 // @Failure 400 {object} map[string]string "error: Invalid data or code incorrect/expired"
+// @Failure 429 {object} map[string]string "error: Too many attempts, request a new code, or too many failed attempts on the account or IP"
 // @Router /users/password/reset/confirm [post]
 func ConfirmPasswordReset(c *gin.Context) {
 	var req struct {
@@ -659,6 +685,10 @@ func ConfirmPasswordReset(c *gin.Context) {
 		return
 	}
 
+	if throttle.AbortIfThrottled(c, req.Email, "ConfirmPasswordReset") {
+		return
+	}
+
 	var user models.User
 	if err := db.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
 		utils.LogError(err, "User not found in ConfirmPasswordReset")
@@ -666,12 +696,40 @@ func ConfirmPasswordReset(c *gin.Context) {
 		return
 	}
 
-	if user.ResetPasswordCode != req.Code || time.Now().After(user.ResetPasswordCodeEnd) {
+	if user.ResetPasswordCode == "" || time.Now().After(user.ResetPasswordCodeEnd) {
 		utils.LogError(errors.New("code invalide ou expiré"), "Invalid or expired reset code in ConfirmPasswordReset")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code or expired"})
 		return
 	}
 
+	// L'essai est décompté avant la comparaison pour que des requêtes concurrentes ne dépassent pas la limite
+	attempt := db.DB.Model(&models.User{}).
+		Where("id = ? AND reset_code_attempts < ?", user.ID, utils.MaxCodeAttempts).
+		UpdateColumn("reset_code_attempts", gorm.Expr("reset_code_attempts + 1"))
+	if attempt.Error != nil {
+		utils.LogError(attempt.Error, "Error when counting the attempt in ConfirmPasswordReset")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving the user"})
+		return
+	}
+	if attempt.RowsAffected == 0 {
+		throttle.RegisterFailure(c, req.Email)
+		utils.LogError(errors.New("trop de tentatives"), "Too many attempts in ConfirmPasswordReset")
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many attempts, request a new code"})
+		return
+	}
+
+	if !utils.CheckCode(req.Code, user.ResetPasswordCode) {
+		// Les échecs comptent aussi dans le verrouillage du compte et de l'IP : demander un nouveau code
+		// remet les essais du code à zéro mais pas ce compteur
+		throttle.RegisterFailure(c, req.Email)
+		utils.LogError(errors.New("code invalide ou expiré"), "Invalid or expired reset code in ConfirmPasswordReset")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "Invalid code or expired",
+			"remainingAttempts": utils.MaxCodeAttempts - user.ResetCodeAttempts - 1,
+		})
+		return
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
 		utils.LogError(err, "Error when hashing the new password in ConfirmPasswordReset")
@@ -682,13 +740,24 @@ func ConfirmPasswordReset(c *gin.Context) {
 	user.Password = string(hashedPassword)
 	user.ResetPasswordCode = ""
 	user.ResetPasswordCodeEnd = time.Time{}
+	user.ResetCodeAttempts = 0
 
-	if err := db.DB.Save(&user).Error; err != nil {
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		_, err := sessions.RevokeAll(tx, user.ID)
+		return err
+	})
+	if err != nil {
 		utils.LogError(err, "Error when saving the new password in ConfirmPasswordReset")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving the user"})
 		return
 	}
 
+	// Le code prouve la possession de l'adresse email : le compte peut de nouveau se connecter
+	throttle.ResetAccount(req.Email)
+
 	userID, exists := c.Get("user_id")
 	if !exists {
 		userID = "0"
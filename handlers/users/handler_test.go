@@ -10,9 +10,11 @@ import (
 	"os"
 	"pec2-backend/models"
 	"pec2-backend/testutils"
+	"pec2-backend/utils"
 	"testing"
 	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
@@ -196,3 +198,202 @@ func TestUpdatePassword_SamePassword(t *testing.T) {
 	json.Unmarshal(resp.Body.Bytes(), &respBody)
 	assert.Equal(t, "The new password must be different from the old password", respBody["error"])
 }
+
+// expectThrottleCheck simule la vérification du verrouillage du compte et de l'IP (aucun verrou actif)
+func expectThrottleCheck(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT \* FROM "auth_throttles" WHERE throttle_key IN \(\$1,\$2\) AND locked_until > \$3`).
+		WillReturnRows(mock.NewRows([]string{"throttle_key", "failures", "locked_until"}))
+}
+
+// expectThrottleFailure simule l'enregistrement d'un échec pour une clé (compte ou IP)
+func expectThrottleFailure(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`INSERT INTO auth_throttles`).
+		WillReturnRows(mock.NewRows([]string{"failures"}).AddRow(1))
+}
+
+// Test qu'un nouveau code de réinitialisation n'est pas envoyé moins d'une minute après le précédent
+func TestRequestPasswordReset_CodeRecentlySent(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	expectThrottleCheck(mock)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs("user@example.com", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "email", "reset_password_code", "reset_password_code_end", "reset_code_attempts"}).
+			AddRow("user-uuid", "user@example.com", utils.HashCode("12345678"), time.Now().Add(utils.ResetCodeTTL-10*time.Second), 0))
+
+	r := testutils.SetupTestRouter()
+	r.POST("/users/password/reset/request", RequestPasswordReset)
+
+	jsonData, _ := json.Marshal(map[string]string{"email": "user@example.com"})
+	req, _ := http.NewRequest(http.MethodPost, "/users/password/reset/request", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfirmPasswordReset_TooManyAttempts(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	expectThrottleCheck(mock)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs("user@example.com", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "email", "reset_password_code", "reset_password_code_end", "reset_code_attempts"}).
+			AddRow("user-uuid", "user@example.com", utils.HashCode("12345"), time.Now().Add(10*time.Minute), utils.MaxCodeAttempts))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "reset_code_attempts"=reset_code_attempts \+ 1 WHERE id = \$1 AND reset_code_attempts < \$2`).
+		WithArgs("user-uuid", utils.MaxCodeAttempts).
+		WillReturnResult(testutils.NewResult(0, 0))
+	mock.ExpectCommit()
+	expectThrottleFailure(mock)
+	expectThrottleFailure(mock)
+
+	r := testutils.SetupTestRouter()
+	r.POST("/users/password/reset/confirm", ConfirmPasswordReset)
+
+	jsonData, _ := json.Marshal(map[string]string{
+		"email":       "user@example.com",
+		"code":        "12345",
+		"newPassword": "NouveauMotdepasse123",
+	})
+	req, _ := http.NewRequest(http.MethodPost, "/users/password/reset/confirm", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfirmPasswordReset_InvalidCode(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	expectThrottleCheck(mock)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs("user@example.com", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "email", "reset_password_code", "reset_password_code_end", "reset_code_attempts"}).
+			AddRow("user-uuid", "user@example.com", utils.HashCode("12345"), time.Now().Add(10*time.Minute), 0))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "reset_code_attempts"=reset_code_attempts \+ 1 WHERE id = \$1 AND reset_code_attempts < \$2`).
+		WillReturnResult(testutils.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectThrottleFailure(mock)
+	expectThrottleFailure(mock)
+
+	r := testutils.SetupTestRouter()
+	r.POST("/users/password/reset/confirm", ConfirmPasswordReset)
+
+	jsonData, _ := json.Marshal(map[string]string{
+		"email":       "user@example.com",
+		"code":        "54321",
+		"newPassword": "NouveauMotdepasse123",
+	})
+	req, _ := http.NewRequest(http.MethodPost, "/users/password/reset/confirm", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	var respBody map[string]interface{}
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+	assert.Equal(t, float64(utils.MaxCodeAttempts-1), respBody["remainingAttempts"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdatePassword_RevokesSessions(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	userID := "user-uuid-1"
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = (.+) ORDER BY "users"."id" LIMIT (.+)`).
+		WithArgs(userID, 1).
+		WillReturnRows(mock.NewRows([]string{"id", "email", "password"}).
+			AddRow(userID, "user@example.com", "$2a$10$8b9qfHvbQVnP1IgEyd/AX.X5PCNGO/ZVE13NZS8xg3wDo6f4rWpiW"))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "password"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+		WillReturnResult(testutils.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "user_sessions" SET "revoked_at"=\$1,"updated_at"=\$2 WHERE user_id = \$3 AND revoked_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), userID).
+		WillReturnResult(testutils.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	r := testutils.SetupTestRouter()
+	r.PUT("/users/password", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		UpdatePassword(c)
+	})
+
+	jsonData, _ := json.Marshal(map[string]string{
+		"oldPassword": "Test123!",
+		"newPassword": "NouveauMotdepasse123",
+	})
+	req, _ := http.NewRequest(http.MethodPut, "/users/password", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfirmPasswordReset_RevokesSessions(t *testing.T) {
+	_, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	expectThrottleCheck(mock)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		WithArgs("user@example.com", 1).
+		WillReturnRows(mock.NewRows([]string{"id", "email", "reset_password_code", "reset_password_code_end", "reset_code_attempts"}).
+			AddRow("user-uuid", "user@example.com", utils.HashCode("12345"), time.Now().Add(10*time.Minute), 0))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "reset_code_attempts"=reset_code_attempts \+ 1 WHERE id = \$1 AND reset_code_attempts < \$2`).
+		WillReturnResult(testutils.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET .+ WHERE "id" = \$\d+`).
+		WillReturnResult(testutils.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "user_sessions" SET "revoked_at"=\$1,"updated_at"=\$2 WHERE user_id = \$3 AND revoked_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "user-uuid").
+		WillReturnResult(testutils.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "auth_throttles" WHERE throttle_key = \$1`).
+		WithArgs("account:user@example.com").
+		WillReturnResult(testutils.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	r := testutils.SetupTestRouter()
+	r.POST("/users/password/reset/confirm", ConfirmPasswordReset)
+
+	jsonData, _ := json.Marshal(map[string]string{
+		"email":       "user@example.com",
+		"code":        "12345",
+		"newPassword": "NouveauMotdepasse123",
+	})
+	req, _ := http.NewRequest(http.MethodPost, "/users/password/reset/confirm", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
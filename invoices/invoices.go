@@ -0,0 +1,131 @@
+package invoices
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"pec2-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// Verrou applicatif (pg_advisory_xact_lock), pris par créateur : deux factures du même créateur
+// émises en même temps ne doivent pas prendre le même numéro
+const invoiceLockKey = 4242002
+
+// StandardVatRate taux normal de TVA en France, en points de base
+const StandardVatRate = 2000
+
+// Split décompose un montant TTC en montant HT et TVA, arrondis au centime le plus proche
+func Split(amountIncludingTax int, vatRate int) (amountExcludingTax int, vat int) {
+	amountExcludingTax = (amountIncludingTax*10000 + (10000+vatRate)/2) / (10000 + vatRate)
+	return amountExcludingTax, amountIncludingTax - amountExcludingTax
+}
+
+// Number formate le numéro de facture à partir de la séquence
+func Number(issuedAt time.Time, sequence int64) string {
+	return fmt.Sprintf("F%d-%06d", issuedAt.Year(), sequence)
+}
+
+// Issue émet la facture d'un paiement d'abonnement réussi. Un paiement déjà facturé est ignoré,
+// ce qui rend les webhooks rejoués sans effet. Doit être appelée dans la transaction du paiement
+func Issue(tx *gorm.DB, payment models.SubscriptionPayment, sub *models.Subscription) error {
+	if payment.Amount <= 0 {
+		return nil
+	}
+
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?, hashtext(?))", invoiceLockKey, sub.ContentCreatorID).Error; err != nil {
+		return err
+	}
+
+	var existing int64
+	if err := tx.Model(&models.Invoice{}).Where("subscription_payment_id = ?", payment.ID).Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return nil
+	}
+
+	var buyer models.User
+	if err := tx.First(&buyer, "id = ?", sub.UserID).Error; err != nil {
+		return err
+	}
+
+	// Le vendeur est le créateur : sa société validée figure sur la facture
+	var info models.ContentCreatorInfo
+	err := tx.Where("user_id = ? AND status = ?", sub.ContentCreatorID, models.ContentCreatorStatusApproved).First(&info).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	// Sans numéro de TVA, le créateur est en franchise de TVA (art. 293 B du CGI)
+	vatRate := 0
+	if info.VatNumber != "" {
+		vatRate = StandardVatRate
+	}
+	amountExcludingTax, vat := Split(payment.Amount, vatRate)
+
+	// La série est celle du vendeur : la numérotation de chaque créateur reste continue
+	var last int64
+	if err := tx.Model(&models.Invoice{}).
+		Where("content_creator_id = ?", sub.ContentCreatorID).
+		Select("COALESCE(MAX(sequence),0)").
+		Scan(&last).Error; err != nil {
+		return err
+	}
+
+	issuedAt := time.Now()
+	invoice := models.Invoice{
+		Sequence:              last + 1,
+		Number:                Number(issuedAt, last+1),
+		SubscriptionPaymentID: payment.ID,
+		SubscriptionID:        sub.ID,
+		UserID:                sub.UserID,
+		ContentCreatorID:      sub.ContentCreatorID,
+		SellerName:            info.CompanyName,
+		SellerSiret:           info.SiretNumber,
+		SellerVatNumber:       info.VatNumber,
+		SellerAddress:         sellerAddress(info),
+		BuyerName:             buyerName(buyer),
+		BuyerEmail:            buyer.Email,
+		Description:           description(sub),
+		AmountExcludingTax:    amountExcludingTax,
+		VatRate:               vatRate,
+		VatAmount:             vat,
+		AmountIncludingTax:    payment.Amount,
+		Currency:              "eur",
+		IssuedAt:              issuedAt,
+	}
+	return tx.Create(&invoice).Error
+}
+
+func sellerAddress(info models.ContentCreatorInfo) string {
+	parts := []string{}
+	for _, part := range []string{info.StreetAddress, strings.TrimSpace(info.PostalCode + " " + info.City), info.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func buyerName(user models.User) string {
+	if name := strings.TrimSpace(user.FirstName + " " + user.LastName); name != "" {
+		return name
+	}
+	return user.UserName
+}
+
+func description(sub *models.Subscription) string {
+	months := sub.BillingMonths
+	if months < 1 {
+		months = 1
+	}
+	label := "Abonnement"
+	if sub.Tier != "" {
+		label += " " + string(sub.Tier)
+	}
+	return fmt.Sprintf("%s - %d mois", label, months)
+}
@@ -0,0 +1,60 @@
+package invoices
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"pec2-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	excl, vat := Split(1200, StandardVatRate)
+	assert.Equal(t, 1000, excl)
+	assert.Equal(t, 200, vat)
+
+	// 999 / 1,2 = 832,5 : arrondi au centime le plus proche, la somme reste exacte
+	excl, vat = Split(999, StandardVatRate)
+	assert.Equal(t, 833, excl)
+	assert.Equal(t, 166, vat)
+
+	excl, vat = Split(999, 0)
+	assert.Equal(t, 999, excl)
+	assert.Equal(t, 0, vat)
+}
+
+func TestNumberAndFormatAmount(t *testing.T) {
+	issuedAt := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "F2026-000042", Number(issuedAt, 42))
+
+	assert.Equal(t, "9,99 €", FormatAmount(999, "eur"))
+	assert.Equal(t, "1 234 567,05 €", FormatAmount(123456705, "eur"))
+	assert.Equal(t, "20 %", formatRate(2000))
+	assert.Equal(t, "5,50 %", formatRate(550))
+}
+
+func TestRenderPDF(t *testing.T) {
+	invoice := models.Invoice{
+		Number:             "F2026-000001",
+		SellerName:         "Créations Éloïse SARL",
+		SellerSiret:        "12345678900011",
+		SellerVatNumber:    "FR12345678901",
+		SellerAddress:      "1 rue de la Paix, 75002 Paris, France",
+		BuyerName:          "Jean Dupont",
+		BuyerEmail:         "jean@example.com",
+		Description:        "Abonnement - 3 mois",
+		AmountExcludingTax: 2498,
+		VatRate:            StandardVatRate,
+		VatAmount:          499,
+		AmountIncludingTax: 2997,
+		Currency:           "eur",
+		IssuedAt:           time.Now(),
+	}
+
+	file, err := RenderPDF(invoice)
+
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(file, []byte("%PDF-")))
+}
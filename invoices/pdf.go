@@ -0,0 +1,133 @@
+package invoices
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"pec2-backend/models"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// RenderPDF génère le document PDF d'une facture au format A4
+func RenderPDF(invoice models.Invoice) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	// Les polices standard sont en cp1252 : les accents et le symbole euro doivent être convertis
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle("Facture "+invoice.Number, true)
+	pdf.SetCreator("OnlyFlick", true)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 10, tr("Facture "+invoice.Number), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 6, tr("Date d'émission : "+invoice.IssuedAt.Format("02/01/2006")), "", 1, "L", false, 0, "")
+	pdf.Ln(6)
+
+	seller := []string{sellerDisplayName(invoice)}
+	if invoice.SellerAddress != "" {
+		seller = append(seller, invoice.SellerAddress)
+	}
+	if invoice.SellerSiret != "" {
+		seller = append(seller, "SIRET : "+invoice.SellerSiret)
+	}
+	if invoice.SellerVatNumber != "" {
+		seller = append(seller, "N° TVA : "+invoice.SellerVatNumber)
+	}
+	buyer := []string{invoice.BuyerName, invoice.BuyerEmail}
+
+	top := pdf.GetY()
+	party(pdf, tr, 10, top, "Vendeur", seller)
+	party(pdf, tr, 110, top, "Client", buyer)
+	pdf.SetXY(10, top+8+6*float64(max(len(seller), len(buyer)))+8)
+
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(235, 235, 235)
+	pdf.CellFormat(100, 8, tr("Désignation"), "1", 0, "L", true, 0, "")
+	pdf.CellFormat(30, 8, tr("Montant HT"), "1", 0, "R", true, 0, "")
+	pdf.CellFormat(25, 8, tr("TVA"), "1", 0, "R", true, 0, "")
+	pdf.CellFormat(35, 8, tr("Montant TTC"), "1", 1, "R", true, 0, "")
+
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(100, 8, tr(invoice.Description), "1", 0, "L", false, 0, "")
+	pdf.CellFormat(30, 8, tr(FormatAmount(invoice.AmountExcludingTax, invoice.Currency)), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(25, 8, tr(formatRate(invoice.VatRate)), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(35, 8, tr(FormatAmount(invoice.AmountIncludingTax, invoice.Currency)), "1", 1, "R", false, 0, "")
+	pdf.Ln(4)
+
+	totals := [][2]string{
+		{"Total HT", FormatAmount(invoice.AmountExcludingTax, invoice.Currency)},
+		{"TVA " + formatRate(invoice.VatRate), FormatAmount(invoice.VatAmount, invoice.Currency)},
+		{"Total TTC", FormatAmount(invoice.AmountIncludingTax, invoice.Currency)},
+	}
+	for i, total := range totals {
+		if i == len(totals)-1 {
+			pdf.SetFont("Helvetica", "B", 10)
+		}
+		pdf.CellFormat(155, 7, tr(total[0]), "", 0, "R", false, 0, "")
+		pdf.CellFormat(35, 7, tr(total[1]), "", 1, "R", false, 0, "")
+	}
+	pdf.Ln(6)
+
+	pdf.SetFont("Helvetica", "I", 9)
+	if invoice.VatRate == 0 {
+		pdf.MultiCell(0, 5, tr("TVA non applicable, art. 293 B du CGI."), "", "L", false)
+	}
+	pdf.MultiCell(0, 5, tr("Facture émise par OnlyFlick au nom et pour le compte du vendeur. Montant réglé par carte bancaire."), "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func party(pdf *fpdf.Fpdf, tr func(string) string, x float64, y float64, title string, lines []string) {
+	pdf.SetXY(x, y)
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(90, 8, tr(title), "", 2, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range lines {
+		pdf.CellFormat(90, 6, tr(line), "", 2, "L", false, 0, "")
+	}
+}
+
+// sellerDisplayName un créateur sans société validée apparaît sous la mention générique
+func sellerDisplayName(invoice models.Invoice) string {
+	if invoice.SellerName != "" {
+		return invoice.SellerName
+	}
+	return "Créateur OnlyFlick"
+}
+
+// FormatAmount formate un montant en centimes à la française : 1 234,56 €
+func FormatAmount(amount int, currency string) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	units := fmt.Sprintf("%d", amount/100)
+	var grouped strings.Builder
+	for i, digit := range units {
+		if i > 0 && (len(units)-i)%3 == 0 {
+			grouped.WriteByte(' ')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	symbol := strings.ToUpper(currency)
+	if symbol == "EUR" || symbol == "" {
+		symbol = "€"
+	}
+	return fmt.Sprintf("%s%s,%02d %s", sign, grouped.String(), amount%100, symbol)
+}
+
+// formatRate formate un taux en points de base : 2000 → 20 %
+func formatRate(rate int) string {
+	if rate%100 == 0 {
+		return fmt.Sprintf("%d %%", rate/100)
+	}
+	return strings.Replace(fmt.Sprintf("%.2f %%", float64(rate)/100), ".", ",", 1)
+}
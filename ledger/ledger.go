@@ -0,0 +1,224 @@
+package ledger
+
+import (
+	"errors"
+	"os"
+	"strconv"
+
+	"pec2-backend/models"
+	"pec2-backend/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Commission prélevée par défaut par la plateforme, en pourcentage
+const defaultCommissionPercent = 20
+
+// CommissionPercent lit la commission de la plateforme dans PLATFORM_COMMISSION_PERCENT (0 à 100)
+func CommissionPercent() int {
+	value := os.Getenv("PLATFORM_COMMISSION_PERCENT")
+	if value == "" {
+		return defaultCommissionPercent
+	}
+
+	percent, err := strconv.Atoi(value)
+	if err != nil || percent < 0 || percent > 100 {
+		utils.LogError(err, "Invalid PLATFORM_COMMISSION_PERCENT "+value+", using the default commission")
+		return defaultCommissionPercent
+	}
+	return percent
+}
+
+// Split répartit un montant entre le créateur et la plateforme. La commission est arrondie au centime inférieur
+func Split(amount int, percent int) (creatorShare int, commission int) {
+	commission = amount * percent / 100
+	return amount - commission, commission
+}
+
+// record enregistre une transaction et ses écritures. Une transaction déjà comptabilisée pour
+// la même source est ignorée, ce qui rend les webhooks rejoués sans effet
+func record(tx *gorm.DB, transaction models.LedgerTransaction, entries []models.LedgerEntry) error {
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&transaction)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	for i := range entries {
+		entries[i].TransactionID = transaction.ID
+	}
+	return tx.Create(&entries).Error
+}
+
+// RecordPayment crédite le créateur d'un paiement réussi, commission de la plateforme déduite
+func RecordPayment(tx *gorm.DB, sourceType models.LedgerSourceType, sourceID string, creatorID string, amount int, currency string) error {
+	if amount <= 0 {
+		return nil
+	}
+	if currency == "" {
+		currency = "eur"
+	}
+
+	creatorShare, commission := Split(amount, CommissionPercent())
+	return record(tx, models.LedgerTransaction{
+		Kind:             models.LedgerTransactionPayment,
+		SourceType:       sourceType,
+		SourceID:         sourceID,
+		ContentCreatorID: creatorID,
+		Amount:           amount,
+		Commission:       commission,
+		Net:              creatorShare,
+		Currency:         currency,
+	}, []models.LedgerEntry{
+		{Account: models.LedgerAccountPlatformCash, Debit: amount},
+		{Account: models.LedgerAccountCreatorBalance, ContentCreatorID: &creatorID, Credit: creatorShare},
+		{Account: models.LedgerAccountPlatformRevenue, Credit: commission},
+	})
+}
+
+// RecordReversal reprend une partie d'un paiement comptabilisé (remboursement, contestation).
+// Le cumul déjà repris est lu dans le grand livre, remboursements et contestations confondus : les reprises
+// d'un même paiement ne dépassent jamais son montant. La commission est reprise au prorata de ce cumul,
+// pour que les reprises successives soldent exactement la transaction d'origine
+func RecordReversal(tx *gorm.DB, sourceType models.LedgerSourceType, sourceID string, paymentSourceType models.LedgerSourceType, paymentSourceID string, amount int) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	// Le verrou sérialise les reprises concurrentes d'un même paiement (remboursement et contestation)
+	var payment models.LedgerTransaction
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("kind = ? AND source_type = ? AND source_id = ?", models.LedgerTransactionPayment, paymentSourceType, paymentSourceID).
+		First(&payment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.LogInfo("No ledger payment to reverse for " + string(paymentSourceType) + " " + paymentSourceID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if payment.Amount <= 0 {
+		return nil
+	}
+
+	alreadyReversed, err := reversedAmount(tx, payment.ID)
+	if err != nil {
+		return err
+	}
+	if alreadyReversed+amount > payment.Amount {
+		amount = payment.Amount - alreadyReversed
+		if amount <= 0 {
+			return nil
+		}
+	}
+
+	commission := payment.Commission*(alreadyReversed+amount)/payment.Amount - payment.Commission*alreadyReversed/payment.Amount
+	creatorShare := amount - commission
+	creatorID := payment.ContentCreatorID
+	return record(tx, models.LedgerTransaction{
+		Kind:                  models.LedgerTransactionRefund,
+		SourceType:            sourceType,
+		SourceID:              sourceID,
+		ContentCreatorID:      creatorID,
+		Amount:                -amount,
+		Commission:            -commission,
+		Net:                   -creatorShare,
+		Currency:              payment.Currency,
+		ReversedTransactionID: &payment.ID,
+	}, []models.LedgerEntry{
+		{Account: models.LedgerAccountPlatformCash, Credit: amount},
+		{Account: models.LedgerAccountCreatorBalance, ContentCreatorID: &creatorID, Debit: creatorShare},
+		{Account: models.LedgerAccountPlatformRevenue, Debit: commission},
+	})
+}
+
+// reversedAmount renvoie le montant net déjà repris sur une transaction de paiement : les reprises sont
+// négatives et les contestations gagnées les compensent
+func reversedAmount(tx *gorm.DB, paymentTransactionID string) (int, error) {
+	var reversed int
+	err := tx.Model(&models.LedgerTransaction{}).
+		Where("reversed_transaction_id = ?", paymentTransactionID).
+		Select("COALESCE(-SUM(amount),0)").
+		Scan(&reversed).Error
+	return reversed, err
+}
+
+// RecordReinstatement annule une reprise enregistrée pour cette source (contestation gagnée)
+func RecordReinstatement(tx *gorm.DB, sourceType models.LedgerSourceType, sourceID string) error {
+	var reversal models.LedgerTransaction
+	err := tx.Where("kind = ? AND source_type = ? AND source_id = ?", models.LedgerTransactionRefund, sourceType, sourceID).
+		First(&reversal).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.LogInfo("No ledger reversal to reinstate for " + string(sourceType) + " " + sourceID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	creatorID := reversal.ContentCreatorID
+	return record(tx, models.LedgerTransaction{
+		Kind:                  models.LedgerTransactionPayment,
+		SourceType:            sourceType,
+		SourceID:              sourceID,
+		ContentCreatorID:      creatorID,
+		Amount:                -reversal.Amount,
+		Commission:            -reversal.Commission,
+		Net:                   -reversal.Net,
+		Currency:              reversal.Currency,
+		ReversedTransactionID: reversal.ReversedTransactionID,
+	}, []models.LedgerEntry{
+		{Account: models.LedgerAccountPlatformCash, Debit: -reversal.Amount},
+		{Account: models.LedgerAccountCreatorBalance, ContentCreatorID: &creatorID, Credit: -reversal.Net},
+		{Account: models.LedgerAccountPlatformRevenue, Credit: -reversal.Commission},
+	})
+}
+
+// RecordPayout débite le solde du créateur du montant qui lui est versé
+func RecordPayout(tx *gorm.DB, payout models.Payout) error {
+	creatorID := payout.ContentCreatorID
+	return record(tx, models.LedgerTransaction{
+		Kind:             models.LedgerTransactionPayout,
+		SourceType:       models.LedgerSourcePayout,
+		SourceID:         payout.ID,
+		ContentCreatorID: creatorID,
+		Amount:           -payout.Amount,
+		Net:              -payout.Amount,
+		Currency:         payout.Currency,
+	}, []models.LedgerEntry{
+		{Account: models.LedgerAccountCreatorBalance, ContentCreatorID: &creatorID, Debit: payout.Amount},
+		{Account: models.LedgerAccountPlatformCash, Credit: payout.Amount},
+	})
+}
+
+// CreatorBalance renvoie le montant dû au créateur, en centimes
+func CreatorBalance(tx *gorm.DB, creatorID string) (int64, error) {
+	var balance int64
+	err := tx.Model(&models.LedgerEntry{}).
+		Where("account = ? AND content_creator_id = ?", models.LedgerAccountCreatorBalance, creatorID).
+		Select("COALESCE(SUM(credit - debit),0)").
+		Scan(&balance).Error
+	return balance, err
+}
+
+// CreatorBalanceRow solde positif d'un créateur
+type CreatorBalanceRow struct {
+	ContentCreatorID string
+	Balance          int64
+}
+
+// PositiveBalances renvoie les créateurs à qui la plateforme doit de l'argent
+func PositiveBalances(tx *gorm.DB) ([]CreatorBalanceRow, error) {
+	var rows []CreatorBalanceRow
+	err := tx.Model(&models.LedgerEntry{}).
+		Select("content_creator_id, SUM(credit - debit) AS balance").
+		Where("account = ?", models.LedgerAccountCreatorBalance).
+		Group("content_creator_id").
+		Having("SUM(credit - debit) > 0").
+		Order("content_creator_id").
+		Scan(&rows).Error
+	return rows, err
+}
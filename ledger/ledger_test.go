@@ -0,0 +1,115 @@
+package ledger
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"pec2-backend/models"
+	"pec2-backend/testutils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestSplit(t *testing.T) {
+	creatorShare, commission := Split(999, 20)
+	assert.Equal(t, 800, creatorShare)
+	assert.Equal(t, 199, commission)
+	assert.Equal(t, 999, creatorShare+commission)
+
+	creatorShare, commission = Split(500, 0)
+	assert.Equal(t, 500, creatorShare)
+	assert.Equal(t, 0, commission)
+}
+
+func TestCommissionPercent(t *testing.T) {
+	t.Setenv("PLATFORM_COMMISSION_PERCENT", "")
+	assert.Equal(t, defaultCommissionPercent, CommissionPercent())
+
+	t.Setenv("PLATFORM_COMMISSION_PERCENT", "15")
+	assert.Equal(t, 15, CommissionPercent())
+
+	for _, invalid := range []string{"abc", "-1", "101"} {
+		t.Setenv("PLATFORM_COMMISSION_PERCENT", invalid)
+		assert.Equal(t, defaultCommissionPercent, CommissionPercent(), invalid)
+	}
+}
+
+func TestBuildSepaCreditTransfer(t *testing.T) {
+	batch := models.PayoutBatch{
+		Reference: "PAYOUT-20250601120000",
+		CreatedAt: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+		Payouts: []models.Payout{
+			{ID: "payout-1", EndToEndID: "PAYOUT-20250601120000-0001", Amount: 12345, Currency: "eur", AccountHolder: "Studio Créatif & Co", Iban: "FR7630006000011234567890189", Bic: "BNPAFRPP"},
+			{ID: "payout-2", EndToEndID: "PAYOUT-20250601120000-0002", Amount: 5, Currency: "eur", AccountHolder: "Autre", Iban: "DE89370400440532013000", Bic: "COBADEFFXXX"},
+		},
+	}
+	debtor := SepaDebtor{Name: "OnlyFlick", Iban: "FR7610107001011234567890129", Bic: "BREDFRPP"}
+
+	file, err := BuildSepaCreditTransfer(batch, debtor)
+	assert.NoError(t, err)
+
+	content := string(file)
+	assert.True(t, strings.HasPrefix(content, xml.Header))
+	assert.Contains(t, content, `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.03">`)
+	assert.Contains(t, content, "<MsgId>PAYOUT-20250601120000</MsgId>")
+	assert.Contains(t, content, "<CreDtTm>2025-06-01T12:00:00</CreDtTm>")
+	assert.Contains(t, content, "<NbOfTxs>2</NbOfTxs>")
+	assert.Contains(t, content, "<CtrlSum>123.50</CtrlSum>")
+	assert.Contains(t, content, `<InstdAmt Ccy="EUR">123.45</InstdAmt>`)
+	assert.Contains(t, content, `<InstdAmt Ccy="EUR">0.05</InstdAmt>`)
+	assert.Contains(t, content, "<Nm>Studio Creatif + Co</Nm>")
+	assert.Contains(t, content, "<IBAN>FR7610107001011234567890129</IBAN>")
+	assert.Contains(t, content, "<EndToEndId>PAYOUT-20250601120000-0002</EndToEndId>")
+
+	// Le fichier doit rester du XML valide
+	var parsed struct {
+		XMLName xml.Name
+	}
+	assert.NoError(t, xml.Unmarshal(file, &parsed))
+}
+
+func TestBuildSepaCreditTransfer_MissingBankDetails(t *testing.T) {
+	batch := models.PayoutBatch{
+		Reference: "PAYOUT-20250601120000",
+		Payouts:   []models.Payout{{ID: "payout-1", Amount: 100, Currency: "eur"}},
+	}
+
+	_, err := BuildSepaCreditTransfer(batch, SepaDebtor{Name: "OnlyFlick", Iban: "FR76", Bic: "BREDFRPP"})
+	assert.Error(t, err)
+
+	_, err = BuildSepaCreditTransfer(models.PayoutBatch{}, SepaDebtor{})
+	assert.Error(t, err)
+}
+
+// Test qu'un remboursement après une contestation perdue ne reprend que ce qui reste du paiement
+func TestRecordReversal_CountsEveryReversal(t *testing.T) {
+	gormDB, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	_, commission := Split(999, CommissionPercent())
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "ledger_transactions" WHERE kind = \$1 AND source_type = \$2 AND source_id = \$3 ORDER BY "ledger_transactions"."id" LIMIT \$4 FOR UPDATE`).
+		WithArgs(models.LedgerTransactionPayment, models.LedgerSourceTip, "tip-uuid", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind", "source_type", "source_id", "content_creator_id", "amount", "commission", "net", "currency"}).
+			AddRow("ledger-payment", models.LedgerTransactionPayment, models.LedgerSourceTip, "tip-uuid", "creator-uuid", 999, commission, 999-commission, "eur"))
+	// La contestation a déjà repris 900 centimes, le remboursement de 500 est ramené à 99
+	mock.ExpectQuery(`SELECT COALESCE\(-SUM\(amount\),0\) FROM "ledger_transactions" WHERE reversed_transaction_id = \$1`).
+		WithArgs("ledger-payment").
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(900))
+	mock.ExpectQuery(`INSERT INTO "ledger_transactions"`).
+		WithArgs(models.LedgerTransactionRefund, models.LedgerSourcePaymentRefund, "refund-uuid", "creator-uuid", -99, sqlmock.AnyArg(), sqlmock.AnyArg(), "eur", "ledger-payment", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("ledger-reversal"))
+	mock.ExpectQuery(`INSERT INTO "ledger_entries"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("entry-1").AddRow("entry-2").AddRow("entry-3"))
+	mock.ExpectCommit()
+
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		return RecordReversal(tx, models.LedgerSourcePaymentRefund, "refund-uuid", models.LedgerSourceTip, "tip-uuid", 500)
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,190 @@
+package ledger
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"pec2-backend/models"
+)
+
+const sepaNamespace = "urn:iso:std:iso:20022:tech:xsd:pain.001.001.03"
+
+// SepaDebtor compte de la plateforme débité par les virements aux créateurs
+type SepaDebtor struct {
+	Name string
+	Iban string
+	Bic  string
+}
+
+// DebtorFromEnv lit le compte de la plateforme dans SEPA_DEBTOR_NAME, SEPA_DEBTOR_IBAN et SEPA_DEBTOR_BIC
+func DebtorFromEnv() (SepaDebtor, error) {
+	debtor := SepaDebtor{
+		Name: os.Getenv("SEPA_DEBTOR_NAME"),
+		Iban: NormalizeBankID(os.Getenv("SEPA_DEBTOR_IBAN")),
+		Bic:  NormalizeBankID(os.Getenv("SEPA_DEBTOR_BIC")),
+	}
+	if debtor.Name == "" || debtor.Iban == "" || debtor.Bic == "" {
+		return SepaDebtor{}, errors.New("SEPA_DEBTOR_NAME, SEPA_DEBTOR_IBAN and SEPA_DEBTOR_BIC must be defined")
+	}
+	return debtor, nil
+}
+
+// NormalizeBankID supprime les espaces et passe en majuscules (IBAN ou BIC)
+func NormalizeBankID(value string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(value), ""))
+}
+
+// Caractères acceptés par les banques dans les zones de texte SEPA
+const sepaAllowed = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789/-?:().,'+ "
+
+var sepaAccents = strings.NewReplacer(
+	"à", "a", "â", "a", "ä", "a", "ç", "c", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i", "ô", "o", "ö", "o", "ù", "u", "û", "u", "ü", "u", "ÿ", "y",
+	"À", "A", "Â", "A", "Ä", "A", "Ç", "C", "É", "E", "È", "E", "Ê", "E", "Ë", "E",
+	"Î", "I", "Ï", "I", "Ô", "O", "Ö", "O", "Ù", "U", "Û", "U", "Ü", "U", "&", "+",
+)
+
+// sepaText translittère le texte dans le jeu de caractères SEPA et le tronque à max caractères
+func sepaText(value string, max int) string {
+	value = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(sepaAllowed, r) {
+			return r
+		}
+		return ' '
+	}, sepaAccents.Replace(value))
+	value = strings.Join(strings.Fields(value), " ")
+	if len(value) > max {
+		value = strings.TrimSpace(value[:max])
+	}
+	return value
+}
+
+// formatAmount convertit des centimes en montant décimal
+func formatAmount(cents int) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
+
+type sepaDocument struct {
+	XMLName xml.Name         `xml:"Document"`
+	Xmlns   string           `xml:"xmlns,attr"`
+	Initn   sepaCreditTrfIni `xml:"CstmrCdtTrfInitn"`
+}
+
+type sepaCreditTrfIni struct {
+	GrpHdr sepaGroupHeader `xml:"GrpHdr"`
+	PmtInf sepaPaymentInfo `xml:"PmtInf"`
+}
+
+type sepaGroupHeader struct {
+	MsgID    string    `xml:"MsgId"`
+	CreDtTm  string    `xml:"CreDtTm"`
+	NbOfTxs  int       `xml:"NbOfTxs"`
+	CtrlSum  string    `xml:"CtrlSum"`
+	InitgPty sepaParty `xml:"InitgPty"`
+}
+
+type sepaParty struct {
+	Nm string `xml:"Nm"`
+}
+
+type sepaAccount struct {
+	IBAN string `xml:"Id>IBAN"`
+}
+
+type sepaAgent struct {
+	BIC string `xml:"FinInstnId>BIC"`
+}
+
+type sepaPaymentInfo struct {
+	PmtInfID    string            `xml:"PmtInfId"`
+	PmtMtd      string            `xml:"PmtMtd"`
+	BtchBookg   bool              `xml:"BtchBookg"`
+	NbOfTxs     int               `xml:"NbOfTxs"`
+	CtrlSum     string            `xml:"CtrlSum"`
+	SvcLvl      string            `xml:"PmtTpInf>SvcLvl>Cd"`
+	ReqdExctnDt string            `xml:"ReqdExctnDt"`
+	Dbtr        sepaParty         `xml:"Dbtr"`
+	DbtrAcct    sepaAccount       `xml:"DbtrAcct"`
+	DbtrAgt     sepaAgent         `xml:"DbtrAgt"`
+	ChrgBr      string            `xml:"ChrgBr"`
+	Transfers   []sepaTransaction `xml:"CdtTrfTxInf"`
+}
+
+type sepaTransaction struct {
+	EndToEndID string         `xml:"PmtId>EndToEndId"`
+	Amount     sepaAmount     `xml:"Amt>InstdAmt"`
+	CdtrAgt    sepaAgent      `xml:"CdtrAgt"`
+	Cdtr       sepaParty      `xml:"Cdtr"`
+	CdtrAcct   sepaAccount    `xml:"CdtrAcct"`
+	RmtInf     sepaRemittance `xml:"RmtInf"`
+}
+
+type sepaAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type sepaRemittance struct {
+	Ustrd string `xml:"Ustrd"`
+}
+
+// BuildSepaCreditTransfer génère le fichier de virements SEPA (pain.001.001.03) d'un lot de versements
+func BuildSepaCreditTransfer(batch models.PayoutBatch, debtor SepaDebtor) ([]byte, error) {
+	if len(batch.Payouts) == 0 {
+		return nil, errors.New("payout batch is empty")
+	}
+
+	total := 0
+	transfers := make([]sepaTransaction, 0, len(batch.Payouts))
+	for _, payout := range batch.Payouts {
+		if payout.Iban == "" || payout.Bic == "" {
+			return nil, fmt.Errorf("payout %s has no bank details", payout.ID)
+		}
+		total += payout.Amount
+		transfers = append(transfers, sepaTransaction{
+			EndToEndID: payout.EndToEndID,
+			Amount:     sepaAmount{Currency: strings.ToUpper(payout.Currency), Value: formatAmount(payout.Amount)},
+			CdtrAgt:    sepaAgent{BIC: payout.Bic},
+			Cdtr:       sepaParty{Nm: sepaText(payout.AccountHolder, 70)},
+			CdtrAcct:   sepaAccount{IBAN: payout.Iban},
+			RmtInf:     sepaRemittance{Ustrd: sepaText("Versement "+debtor.Name+" "+batch.Reference, 140)},
+		})
+	}
+
+	document := sepaDocument{
+		Xmlns: sepaNamespace,
+		Initn: sepaCreditTrfIni{
+			GrpHdr: sepaGroupHeader{
+				MsgID:    batch.Reference,
+				CreDtTm:  batch.CreatedAt.UTC().Format("2006-01-02T15:04:05"),
+				NbOfTxs:  len(transfers),
+				CtrlSum:  formatAmount(total),
+				InitgPty: sepaParty{Nm: sepaText(debtor.Name, 70)},
+			},
+			PmtInf: sepaPaymentInfo{
+				PmtInfID:    batch.Reference,
+				PmtMtd:      "TRF",
+				BtchBookg:   true,
+				NbOfTxs:     len(transfers),
+				CtrlSum:     formatAmount(total),
+				SvcLvl:      "SEPA",
+				ReqdExctnDt: time.Now().Format("2006-01-02"),
+				Dbtr:        sepaParty{Nm: sepaText(debtor.Name, 70)},
+				DbtrAcct:    sepaAccount{IBAN: debtor.Iban},
+				DbtrAgt:     sepaAgent{BIC: debtor.Bic},
+				ChrgBr:      "SLEV",
+				Transfers:   transfers,
+			},
+		},
+	}
+
+	output, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), output...), nil
+}
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
 	"os"
+	"time"
 
 	"pec2-backend/db"
 	"pec2-backend/docs"
+	stripeHandlers "pec2-backend/handlers/stripe"
+	"pec2-backend/payments"
+	"pec2-backend/realtime"
 	"pec2-backend/routes"
 	"pec2-backend/utils"
 
@@ -38,6 +43,11 @@ func main() {
 		utils.LogError(err, "Error when initializing Cloudinary")
 	}
 
+	// Initialiser le prestataire de paiement
+	if err := payments.InitStripe(); err != nil {
+		utils.LogError(err, "Error when initializing the payment provider")
+	}
+
 	// Récupérer les variables d'environnement
 	baseURL := os.Getenv("BASE_URL")
 	if baseURL == "" {
@@ -50,6 +60,19 @@ func main() {
 
 	docs.SwaggerInfo.Host = "localhost:" + port
 
+	// Avec plusieurs instances derrière un load balancer, les événements temps réel
+	// (commentaires, likes, messages) passent par Postgres pour atteindre tous les clients
+	if os.Getenv("REALTIME_BROKER") == "postgres" {
+		broker := realtime.NewPostgresBroker(db.DB, os.Getenv("DB_URL"))
+		go broker.Listen(context.Background())
+		realtime.Default = realtime.NewHub(broker, realtime.Options{})
+	}
+
+	// Relance en arrière-plan des événements Stripe en échec
+	go stripeHandlers.StartEventRetryWorker(time.Minute)
+	// Expiration des abonnements dont la période est terminée
+	go stripeHandlers.StartSubscriptionReconciler(15 * time.Minute)
+
 	utils.LogSuccess("Le serveur fonctionne sur le port " + port + " baseUrl:" + baseURL)
 	r := routes.SetupRouter()
 
@@ -1,11 +1,15 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"pec2-backend/db"
+	"pec2-backend/models"
 	"pec2-backend/utils"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
 )
 
 func JWTAuth() gin.HandlerFunc {
@@ -17,22 +21,14 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 
-		authHeader = strings.Trim(authHeader, "\"' ")
-		if !strings.HasPrefix(strings.ToLower(authHeader), "bearer ") {
-			authHeader = "Bearer " + authHeader
-		}
-
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		tokenString, ok := extractBearerToken(authHeader)
+		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format, expected: Bearer <token>"})
 			c.Abort()
 			return
 		}
 
-		tokenString := parts[1]
-		tokenString = strings.Trim(tokenString, "\"' ")
-
-		claims, err := utils.DecodeJWT(tokenString)
+		claims, err := authenticateAccessToken(tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token: " + err.Error()})
 			c.Abort()
@@ -41,10 +37,85 @@ func JWTAuth() gin.HandlerFunc {
 
 		c.Set("user_id", claims["user_id"])
 		c.Set("role", claims["role"])
+		c.Set("session_id", claims["sid"])
 		c.Next()
 	}
 }
 
+// OptionalJWTAuth renseigne user_id et role si un token valide est fourni,
+// sinon la requête continue en anonyme (utile pour les routes publiques
+// dont la réponse dépend de l'utilisateur, comme le paywall des posts)
+func OptionalJWTAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.Next()
+			return
+		}
+
+		tokenString, ok := extractBearerToken(authHeader)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		claims, err := authenticateAccessToken(tokenString)
+		if err != nil {
+			utils.LogError(err, "Invalid token ignored in OptionalJWTAuth")
+			c.Next()
+			return
+		}
+
+		c.Set("user_id", claims["user_id"])
+		c.Set("role", claims["role"])
+		c.Set("session_id", claims["sid"])
+		c.Next()
+	}
+}
+
+// authenticateAccessToken décode le JWT et vérifie que la session associée n'a pas été révoquée
+func authenticateAccessToken(tokenString string) (jwt.MapClaims, error) {
+	claims, err := utils.DecodeJWT(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenType, _ := claims["type"].(string); tokenType != utils.AccessTokenType {
+		return nil, errors.New("not an access token")
+	}
+
+	sessionID, _ := claims["sid"].(string)
+	if sessionID == "" {
+		return nil, errors.New("token without session")
+	}
+
+	var count int64
+	if err := db.DB.Model(&models.UserSession{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errors.New("session revoked")
+	}
+
+	return claims, nil
+}
+
+func extractBearerToken(authHeader string) (string, bool) {
+	authHeader = strings.Trim(authHeader, "\"' ")
+	if !strings.HasPrefix(strings.ToLower(authHeader), "bearer ") {
+		authHeader = "Bearer " + authHeader
+	}
+
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return "", false
+	}
+
+	return strings.Trim(parts[1], "\"' "), true
+}
+
 func AdminAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("role")
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// StreamTicketAuth authentifie un flux SSE ou WebSocket par un ticket obtenu via POST /stream-tickets,
+// passé dans le paramètre ticket ou dans le cookie HttpOnly. Le ticket est consommé à
+// l'ouverture du flux et n'est valable que pour ce type de flux (et le post de l'URL pour les commentaires).
+func StreamTicketAuth(stream models.StreamType) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Query("ticket")
+		fromCookie := false
+		if token == "" {
+			token, _ = c.Cookie(utils.StreamTicketCookie)
+			fromCookie = true
+		}
+		if token == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Stream ticket missing"})
+			c.Abort()
+			return
+		}
+
+		ticket, err := consumeStreamTicket(token, stream, c.Param("id"))
+		if err != nil {
+			utils.LogError(err, "Stream ticket rejected in StreamTicketAuth")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired stream ticket"})
+			c.Abort()
+			return
+		}
+
+		// Le cookie ne sert plus, le navigateur peut l'oublier
+		if fromCookie {
+			secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+			c.SetCookie(utils.StreamTicketCookie, "", -1, c.Request.URL.Path, "", secure, true)
+		}
+
+		c.Set("user_id", ticket.UserID)
+		c.Next()
+	}
+}
+
+// consumeStreamTicket marque le ticket comme utilisé. La condition sur used_at rend la consommation
+// atomique : deux connexions simultanées avec le même ticket ne peuvent pas passer toutes les deux
+func consumeStreamTicket(token string, stream models.StreamType, postID string) (*models.StreamTicket, error) {
+	var ticket models.StreamTicket
+	if err := db.DB.Where("token_hash = ?", utils.HashToken(token)).First(&ticket).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("unknown ticket")
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if ticket.Stream != stream {
+		return nil, errors.New("ticket issued for another stream")
+	}
+	if stream == models.StreamComments && (ticket.PostID == nil || *ticket.PostID != postID) {
+		return nil, errors.New("ticket issued for another post")
+	}
+	if !ticket.ExpiresAt.After(now) {
+		return nil, errors.New("ticket expired")
+	}
+
+	result := db.DB.Model(&models.StreamTicket{}).
+		Where("id = ? AND used_at IS NULL", ticket.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("ticket already used")
+	}
+
+	return &ticket, nil
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"time"
+)
+
+// CreatorAnalyticsOverview indicateurs actuels des abonnements d'un créateur
+// @Description MRR en centimes : revenu mensuel récurrent des abonnements payants en cours, les formules de plusieurs mois ramenées au mois
+type CreatorAnalyticsOverview struct {
+	Mrr                 int64  `json:"mrr" example:"24950"`
+	Currency            string `json:"currency" example:"eur"`
+	ActiveSubscribers   int64  `json:"activeSubscribers" example:"50"`
+	TrialingSubscribers int64  `json:"trialingSubscribers" example:"4"`
+	PastDueSubscribers  int64  `json:"pastDueSubscribers" example:"2"`
+}
+
+// SubscriberPoint nouveaux abonnés et abonnés perdus sur une période
+type SubscriberPoint struct {
+	Period  time.Time `json:"period"`
+	New     int64     `json:"new" example:"12"`
+	Churned int64     `json:"churned" example:"3"`
+	Net     int64     `json:"net" example:"9"`
+}
+
+// SubscriberSeries évolution des abonnés par jour, semaine ou mois
+type SubscriberSeries struct {
+	Period string            `json:"period" example:"week"`
+	Points []SubscriberPoint `json:"points"`
+}
+
+// RevenuePoint revenus des abonnements sur une période, en centimes
+type RevenuePoint struct {
+	Period   time.Time `json:"period"`
+	Payments int64     `json:"payments" example:"25"`
+	Gross    int64     `json:"gross" example:"12475"`
+	Refunds  int64     `json:"refunds" example:"499"`
+	Disputes int64     `json:"disputes" example:"0"`
+	Net      int64     `json:"net" example:"11976"`
+}
+
+// RevenueSeries revenus des abonnements par jour, semaine ou mois
+type RevenueSeries struct {
+	Period   string         `json:"period" example:"month"`
+	Currency string         `json:"currency" example:"eur"`
+	Points   []RevenuePoint `json:"points"`
+}
+
+// PostEngagement post d'un créateur avec ses likes et commentaires
+type PostEngagement struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	PictureURL    string    `json:"pictureUrl"`
+	LikesCount    int64     `json:"likesCount" example:"120"`
+	CommentsCount int64     `json:"commentsCount" example:"14"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// RetentionCohort abonnés ayant commencé le même mois et part encore abonnée après chaque mois
+// @Description retained[k] : abonnements toujours en cours k mois après leur début, rates[k] : retained[k] / size
+type RetentionCohort struct {
+	Cohort   time.Time `json:"cohort"`
+	Size     int64     `json:"size" example:"40"`
+	Retained []int64   `json:"retained"`
+	Rates    []float64 `json:"rates"`
+}
+
+// PlatformRevenuePoint revenus de la plateforme sur une période d'après le grand livre, en centimes
+// @Description gross : paiements encaissés (abonnements, pourboires, achats), refunds et disputes : sommes reprises, commission : part de la plateforme
+type PlatformRevenuePoint struct {
+	Period        time.Time `json:"period"`
+	Subscriptions int64     `json:"subscriptions" example:"99800"`
+	Tips          int64     `json:"tips" example:"15000"`
+	Purchases     int64     `json:"purchases" example:"4990"`
+	Gross         int64     `json:"gross" example:"119790"`
+	Refunds       int64     `json:"refunds" example:"1998"`
+	Disputes      int64     `json:"disputes" example:"0"`
+	Net           int64     `json:"net" example:"117792"`
+	Commission    int64     `json:"commission" example:"23558"`
+	CreatorShare  int64     `json:"creatorShare" example:"94234"`
+}
+
+// CreatorRevenue revenus générés par un créateur sur la plage demandée, en centimes
+type CreatorRevenue struct {
+	ContentCreatorID string `json:"contentCreatorId"`
+	UserName         string `json:"userName"`
+	Payments         int64  `json:"payments" example:"42"`
+	Gross            int64  `json:"gross" example:"20958"`
+	Refunds          int64  `json:"refunds" example:"499"`
+	Net              int64  `json:"net" example:"20459"`
+	Commission       int64  `json:"commission" example:"4092"`
+	CreatorShare     int64  `json:"creatorShare" example:"16367"`
+}
+
+// PaymentRatesPoint issue des paiements d'abonnement tentés sur une période
+// @Description failureRate : échecs / tentatives, refundRate : paiements remboursés (même partiellement) / paiements réussis
+type PaymentRatesPoint struct {
+	Period      time.Time `json:"period"`
+	Attempts    int64     `json:"attempts" example:"120"`
+	Succeeded   int64     `json:"succeeded" example:"112"`
+	Failed      int64     `json:"failed" example:"8"`
+	Refunded    int64     `json:"refunded" example:"3"`
+	Disputed    int64     `json:"disputed" example:"1"`
+	FailureRate float64   `json:"failureRate" example:"0.067"`
+	RefundRate  float64   `json:"refundRate" example:"0.027"`
+}
+
+// SubscriptionsPoint abonnements de la plateforme sur une période
+// @Description active et mrr : situation en fin de période, churnRate : abonnements perdus / abonnements actifs en début de période
+type SubscriptionsPoint struct {
+	Period    time.Time `json:"period"`
+	Active    int64     `json:"active" example:"830"`
+	Mrr       int64     `json:"mrr" example:"414170"`
+	New       int64     `json:"new" example:"64"`
+	Churned   int64     `json:"churned" example:"21"`
+	ChurnRate float64   `json:"churnRate" example:"0.026"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// AuthThrottle compte les échecs d'authentification par clé (compte ou adresse IP)
+// et porte le verrouillage temporaire qui en découle
+type AuthThrottle struct {
+	ThrottleKey   string     `json:"throttleKey" gorm:"primaryKey"`
+	Failures      int        `json:"failures" gorm:"not null;default:0"`
+	LastFailureAt time.Time  `json:"lastFailureAt"`
+	LockedUntil   *time.Time `json:"lockedUntil"`
+}
+
+func (AuthThrottle) TableName() string {
+	return "auth_throttles"
+}
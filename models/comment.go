@@ -5,14 +5,38 @@ import (
 )
 
 type Comment struct {
-	ID            string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	PostID        string    `json:"postId" gorm:"column:post_id"`
-	UserID        string    `json:"userId" gorm:"column:user_id"`
-	Content       string    `json:"content" binding:"required"`
-	CommentsCount int       `json:"commentsCount" gorm:"column:comments_count;default:0"`
-	CreatedAt     time.Time `json:"createdAt"`
+	ID     string `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	PostID string `json:"postId" gorm:"column:post_id"`
+	UserID string `json:"userId" gorm:"column:user_id"`
+	// Commentaire auquel celui-ci répond, nil pour un commentaire de premier niveau
+	ParentID *string `json:"parentId" gorm:"column:parent_id;type:uuid;index"`
+	Content  string  `json:"content" binding:"required"`
+	// Nombre de réponses directes (non supprimées) à ce commentaire
+	CommentsCount int        `json:"commentsCount" gorm:"column:comments_count;default:0"`
+	Edited        bool       `json:"edited" gorm:"default:false"`
+	EditedAt      *time.Time `json:"editedAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	// Suppression logique : un commentaire supprimé qui a des réponses reste affiché sans contenu
+	DeletedAt *time.Time `json:"deletedAt,omitempty" gorm:"index"`
 }
 
 func (Comment) TableName() string {
 	return "comments"
 }
+
+// CommentEdit version précédente d'un commentaire modifié
+type CommentEdit struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	CommentID string    `json:"commentId" gorm:"type:uuid;not null;index"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (CommentEdit) TableName() string {
+	return "comment_edits"
+}
+
+// CommentUpdate modèle pour modifier un commentaire
+type CommentUpdate struct {
+	Content string `json:"content" binding:"required" example:"Nouveau contenu"`
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+)
+
+type CouponType string
+
+const (
+	CouponPercent CouponType = "PERCENT"
+	CouponFixed   CouponType = "FIXED"
+)
+
+type CouponRedemptionStatus string
+
+const (
+	// Réservée à l'ouverture du paiement, libérée si la session expire
+	CouponRedemptionPending  CouponRedemptionStatus = "PENDING"
+	CouponRedemptionRedeemed CouponRedemptionStatus = "REDEEMED"
+)
+
+// Coupon code promo d'un créateur, appliqué à la première échéance d'un abonnement.
+// Les règles (expiration, nombre d'utilisations) sont vérifiées chez nous, pas chez Stripe
+type Coupon struct {
+	ID               string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	ContentCreatorID string     `json:"contentCreatorId" gorm:"type:uuid;not null;uniqueIndex:idx_coupons_creator_code"`
+	Code             string     `json:"code" gorm:"type:varchar(32);not null;uniqueIndex:idx_coupons_creator_code"`
+	Type             CouponType `json:"type" gorm:"type:varchar(10);not null"`
+	PercentOff       int        `json:"percentOff"`
+	AmountOff        int        `json:"amountOff"`
+	ExpiresAt        *time.Time `json:"expiresAt"`
+	// 0 = nombre d'utilisations illimité
+	MaxRedemptions int       `json:"maxRedemptions" gorm:"default:0"`
+	Active         bool      `json:"active" gorm:"default:true"`
+	TimesRedeemed  int64     `json:"timesRedeemed" gorm:"-"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+func (Coupon) TableName() string {
+	return "coupons"
+}
+
+// IsExpired indique si la date d'expiration du code est dépassée
+func (c Coupon) IsExpired(now time.Time) bool {
+	return c.ExpiresAt != nil && !c.ExpiresAt.After(now)
+}
+
+// CouponRedemption utilisation d'un code par un utilisateur, une seule par code et par utilisateur
+type CouponRedemption struct {
+	ID                      string                 `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	CouponID                string                 `json:"couponId" gorm:"type:uuid;not null;uniqueIndex:idx_coupon_redemptions_user"`
+	UserID                  string                 `json:"userId" gorm:"type:uuid;not null;uniqueIndex:idx_coupon_redemptions_user"`
+	SubscriptionID          *string                `json:"subscriptionId" gorm:"type:uuid"`
+	DiscountAmount          int                    `json:"discountAmount"`
+	Status                  CouponRedemptionStatus `json:"status" gorm:"type:varchar(20);default:'PENDING'"`
+	StripeCheckoutSessionId string                 `json:"-"`
+	CreatedAt               time.Time              `json:"createdAt"`
+	UpdatedAt               time.Time              `json:"updatedAt"`
+}
+
+func (CouponRedemption) TableName() string {
+	return "coupon_redemptions"
+}
+
+// CouponCreate modèle pour créer un code promo
+// @Description code promo en pourcentage (percentOff) ou en montant fixe en centimes (amountOff), maxRedemptions à 0 pour illimité
+type CouponCreate struct {
+	Code           string     `json:"code" binding:"required,min=3,max=32,alphanum" example:"WELCOME20"`
+	Type           CouponType `json:"type" binding:"required,oneof=PERCENT FIXED" example:"PERCENT"`
+	PercentOff     int        `json:"percentOff" binding:"omitempty,min=1,max=100" example:"20"`
+	AmountOff      int        `json:"amountOff" binding:"omitempty,min=1" example:"200"`
+	ExpiresAt      *time.Time `json:"expiresAt" example:"2026-12-31T23:59:59Z"`
+	MaxRedemptions int        `json:"maxRedemptions" binding:"min=0" example:"100"`
+}
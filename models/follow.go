@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// Follow représente un utilisateur qui suit un créateur de contenu (accès à ses posts gratuits dans le fil)
+type Follow struct {
+	ID         string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	FollowerID string    `json:"followerId" gorm:"column:follower_id;type:uuid;not null;uniqueIndex:idx_follows_follower_creator"`
+	CreatorID  string    `json:"creatorId" gorm:"column:creator_id;type:uuid;not null;uniqueIndex:idx_follows_follower_creator"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func (Follow) TableName() string {
+	return "follows"
+}
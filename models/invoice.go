@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// Invoice facture émise pour un paiement d'abonnement réussi, au nom du créateur.
+// Les informations du vendeur et de l'acheteur sont figées à l'émission : une facture ne change plus
+type Invoice struct {
+	ID string `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	// Numérotation continue, sans trou, attribuée dans l'ordre d'émission. Le créateur étant le vendeur,
+	// chaque créateur a sa propre série
+	Sequence              int64  `json:"sequence" gorm:"not null;uniqueIndex:idx_invoices_creator_sequence,priority:2"`
+	Number                string `json:"number" gorm:"type:varchar(30);not null;uniqueIndex:idx_invoices_creator_number,priority:2"`
+	SubscriptionPaymentID string `json:"subscriptionPaymentId" gorm:"type:uuid;uniqueIndex;not null"`
+	SubscriptionID        string `json:"subscriptionId" gorm:"type:uuid;not null"`
+	UserID                string `json:"userId" gorm:"type:uuid;not null;index"`
+	ContentCreatorID      string `json:"contentCreatorId" gorm:"type:uuid;not null;uniqueIndex:idx_invoices_creator_sequence,priority:1;uniqueIndex:idx_invoices_creator_number,priority:1"`
+
+	SellerName      string `json:"sellerName"`
+	SellerSiret     string `json:"sellerSiret"`
+	SellerVatNumber string `json:"sellerVatNumber"`
+	SellerAddress   string `json:"sellerAddress"`
+	BuyerName       string `json:"buyerName"`
+	BuyerEmail      string `json:"buyerEmail"`
+	Description     string `json:"description"`
+
+	// Montants en centimes : le prix payé est TTC
+	AmountExcludingTax int `json:"amountExcludingTax"`
+	// Taux de TVA en points de base (2000 = 20 %)
+	VatRate            int       `json:"vatRate"`
+	VatAmount          int       `json:"vatAmount"`
+	AmountIncludingTax int       `json:"amountIncludingTax"`
+	Currency           string    `json:"currency" gorm:"type:varchar(3);default:'eur'"`
+	IssuedAt           time.Time `json:"issuedAt"`
+	CreatedAt          time.Time `json:"createdAt"`
+}
+
+func (Invoice) TableName() string {
+	return "invoices"
+}
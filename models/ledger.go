@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+)
+
+// LedgerAccount identifie un compte du grand livre
+type LedgerAccount string
+
+const (
+	// LedgerAccountPlatformCash argent encaissé par la plateforme via Stripe
+	LedgerAccountPlatformCash LedgerAccount = "PLATFORM_CASH"
+	// LedgerAccountPlatformRevenue commission conservée par la plateforme
+	LedgerAccountPlatformRevenue LedgerAccount = "PLATFORM_REVENUE"
+	// LedgerAccountCreatorBalance montant dû à un créateur (un compte par créateur)
+	LedgerAccountCreatorBalance LedgerAccount = "CREATOR_BALANCE"
+)
+
+type LedgerTransactionKind string
+
+const (
+	LedgerTransactionPayment LedgerTransactionKind = "PAYMENT"
+	LedgerTransactionRefund  LedgerTransactionKind = "REFUND"
+	LedgerTransactionPayout  LedgerTransactionKind = "PAYOUT"
+)
+
+// LedgerSourceType indique l'objet à l'origine d'une écriture
+type LedgerSourceType string
+
+const (
+	LedgerSourceSubscriptionPayment LedgerSourceType = "SUBSCRIPTION_PAYMENT"
+	LedgerSourceTip                 LedgerSourceType = "TIP"
+	LedgerSourcePostPurchase        LedgerSourceType = "POST_PURCHASE"
+	LedgerSourcePayout              LedgerSourceType = "PAYOUT"
+	LedgerSourcePaymentRefund       LedgerSourceType = "PAYMENT_REFUND"
+	LedgerSourcePaymentDispute      LedgerSourceType = "PAYMENT_DISPUTE"
+)
+
+// LedgerTransaction regroupe des écritures équilibrées (total des débits = total des crédits).
+// Une source ne peut être comptabilisée qu'une fois par type de transaction
+type LedgerTransaction struct {
+	ID               string                `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Kind             LedgerTransactionKind `json:"kind" gorm:"type:varchar(20);not null;uniqueIndex:idx_ledger_transactions_source,priority:1"`
+	SourceType       LedgerSourceType      `json:"sourceType" gorm:"type:varchar(30);not null;uniqueIndex:idx_ledger_transactions_source,priority:2"`
+	SourceID         string                `json:"sourceId" gorm:"type:uuid;not null;uniqueIndex:idx_ledger_transactions_source,priority:3"`
+	ContentCreatorID string                `json:"contentCreatorId" gorm:"type:uuid;not null;index"`
+	// Montant brut payé par le client, en centimes
+	Amount int `json:"amount"`
+	// Part conservée par la plateforme, en centimes
+	Commission int `json:"commission"`
+	// Effet sur le solde du créateur, négatif pour un remboursement ou un versement
+	Net      int    `json:"net"`
+	Currency string `json:"currency" gorm:"type:varchar(3);default:'eur'"`
+	// Paiement repris (remboursement, contestation) ou rétabli (contestation gagnée) par cette transaction,
+	// pour cumuler les reprises d'un même paiement quelle que soit leur origine
+	ReversedTransactionID *string       `json:"reversedTransactionId,omitempty" gorm:"type:uuid;index"`
+	Entries               []LedgerEntry `json:"-" gorm:"foreignKey:TransactionID"`
+	CreatedAt             time.Time     `json:"createdAt"`
+}
+
+func (LedgerTransaction) TableName() string {
+	return "ledger_transactions"
+}
+
+// LedgerEntry est une ligne de débit ou de crédit sur un compte
+type LedgerEntry struct {
+	ID               string        `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	TransactionID    string        `json:"transactionId" gorm:"type:uuid;not null;index"`
+	Account          LedgerAccount `json:"account" gorm:"type:varchar(30);not null;index:idx_ledger_entries_account,priority:1"`
+	ContentCreatorID *string       `json:"contentCreatorId" gorm:"type:uuid;index:idx_ledger_entries_account,priority:2"`
+	Debit            int           `json:"debit" gorm:"not null;default:0"`
+	Credit           int           `json:"credit" gorm:"not null;default:0"`
+	CreatedAt        time.Time     `json:"createdAt"`
+}
+
+func (LedgerEntry) TableName() string {
+	return "ledger_entries"
+}
+
+// CreatorEarnings est la réponse de l'endpoint des gains d'un créateur
+// @Description Solde et historique des gains d'un créateur
+type CreatorEarnings struct {
+	Balance           int64               `json:"balance" example:"12450"`
+	Currency          string              `json:"currency" example:"eur"`
+	CommissionPercent int                 `json:"commissionPercent" example:"20"`
+	Transactions      []LedgerTransaction `json:"transactions"`
+	Total             int64               `json:"total" example:"42"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// PaymentDispute contestation d'un paiement d'abonnement, d'un pourboire ou d'un achat de post par le client
+// auprès de sa banque. Un seul des trois paiements est renseigné.
+// Le statut est celui de Stripe (needs_response, under_review, won, lost...)
+type PaymentDispute struct {
+	ID                    string  `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	SubscriptionPaymentID *string `json:"subscriptionPaymentId" gorm:"type:uuid;index"`
+	TipID                 *string `json:"tipId" gorm:"type:uuid;index"`
+	PostPurchaseID        *string `json:"postPurchaseId" gorm:"type:uuid;index"`
+	StripeDisputeId       string  `json:"stripeDisputeId" gorm:"uniqueIndex;not null"`
+	Amount                int     `json:"amount"`
+	Reason                string  `json:"reason" gorm:"type:varchar(50)"`
+	Status                string  `json:"status" gorm:"type:varchar(30)"`
+	// Les fonds ont été repris par la banque : le créateur a été débité au grand livre
+	FundsWithdrawn bool       `json:"fundsWithdrawn" gorm:"default:false"`
+	ClosedAt       *time.Time `json:"closedAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+func (PaymentDispute) TableName() string {
+	return "payment_disputes"
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// PayoutBatch regroupe les versements aux créateurs exportés dans un même virement SEPA
+type PayoutBatch struct {
+	ID string `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	// Identifiant du message SEPA (MsgId, 35 caractères maximum)
+	Reference    string    `json:"reference" gorm:"type:varchar(35);uniqueIndex;not null"`
+	TotalAmount  int       `json:"totalAmount"`
+	Currency     string    `json:"currency" gorm:"type:varchar(3);default:'eur'"`
+	PayoutsCount int       `json:"payoutsCount"`
+	CreatedByID  string    `json:"createdById" gorm:"type:uuid;not null"`
+	Payouts      []Payout  `json:"payouts,omitempty" gorm:"foreignKey:BatchID"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+func (PayoutBatch) TableName() string {
+	return "payout_batches"
+}
+
+// Payout est le versement du solde d'un créateur. Les coordonnées bancaires sont
+// copiées au moment du versement pour que l'export reste identique si le créateur les modifie
+type Payout struct {
+	ID               string `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	BatchID          string `json:"batchId" gorm:"type:uuid;not null;index"`
+	ContentCreatorID string `json:"contentCreatorId" gorm:"type:uuid;not null;index"`
+	// Identifiant de bout en bout du virement (EndToEndId, 35 caractères maximum)
+	EndToEndID    string    `json:"endToEndId" gorm:"type:varchar(35);not null"`
+	Amount        int       `json:"amount"`
+	Currency      string    `json:"currency" gorm:"type:varchar(3);default:'eur'"`
+	AccountHolder string    `json:"accountHolder"`
+	Iban          string    `json:"iban"`
+	Bic           string    `json:"bic"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+func (Payout) TableName() string {
+	return "payouts"
+}
@@ -5,17 +5,18 @@ import (
 )
 
 type Post struct {
-	ID         string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	ID         string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid();index:idx_posts_created_at_id,priority:2"`
 	UserID     string     `json:"userId" gorm:"column:user_id;type:uuid;references:ID;foreignKey:fk_posts_user"`
 	Name       string     `json:"name" binding:"required"`
 	PictureURL string     `json:"pictureUrl" gorm:"column:picture_url"`
 	IsFree     bool       `json:"isFree" gorm:"default:false"`
+	Price      int        `json:"price" gorm:"default:0"`
 	Enable     bool       `json:"enable" gorm:"default:true"`
 	Categories []Category `json:"categories" gorm:"many2many:post_categories;"`
 	Likes      []Like     `json:"likes,omitempty"`
 	Reports    []Report   `json:"reports,omitempty"`
 	User       User       `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	CreatedAt  time.Time  `json:"createdAt"`
+	CreatedAt  time.Time  `json:"createdAt" gorm:"index:idx_posts_created_at_id,priority:1"`
 	UpdatedAt  time.Time  `json:"updatedAt"`
 	DeletedAt  *time.Time `json:"deletedAt,omitempty" gorm:"index"`
 }
@@ -23,6 +24,7 @@ type Post struct {
 type PostCreate struct {
 	Name       string   `json:"name" binding:"required"`
 	IsFree     bool     `json:"isFree"`
+	Price      int      `json:"price"`
 	PictureURL string   `json:"pictureUrl"`
 	Categories []string `json:"categories"`
 }
@@ -30,6 +32,7 @@ type PostCreate struct {
 type PostUpdate struct {
 	Name       string   `json:"name"`
 	IsFree     bool     `json:"isFree"`
+	Price      int      `json:"price"`
 	Categories []string `json:"categories"`
 	Enable     bool     `json:"enable"`
 }
@@ -39,6 +42,9 @@ type PostResponse struct {
 	Name          string     `json:"name"`
 	PictureURL    string     `json:"pictureUrl"`
 	IsFree        bool       `json:"isFree"`
+	Price         int        `json:"price"`
+	IsLocked      bool       `json:"isLocked"`
+	IsPurchased   bool       `json:"isPurchased"`
 	Enable        bool       `json:"enable"`
 	Categories    []Category `json:"categories"`
 	CreatedAt     time.Time  `json:"createdAt"`
@@ -47,6 +53,13 @@ type PostResponse struct {
 	LikesCount    int        `json:"likesCount"`
 	CommentsCount int        `json:"commentsCount"`
 	ReportsCount  int        `json:"reportsCount"`
+	IsLiked       bool       `json:"isLiked"`
+}
+
+// PostPage est une page du fil de posts ; nextCursor vaut null sur la dernière page
+type PostPage struct {
+	Posts      []PostResponse `json:"posts"`
+	NextCursor *string        `json:"nextCursor"`
 }
 
 type UserInfo struct {
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+type PostPurchaseStatus string
+
+const (
+	PostPurchasePending   PostPurchaseStatus = "PENDING"
+	PostPurchaseSucceeded PostPurchaseStatus = "SUCCEEDED"
+	PostPurchaseFailed    PostPurchaseStatus = "FAILED"
+	PostPurchaseCanceled  PostPurchaseStatus = "CANCELED"
+	PostPurchaseRefunded  PostPurchaseStatus = "REFUNDED"
+	// Contesté par le client auprès de sa banque (chargeback) : le post est de nouveau verrouillé
+	PostPurchaseDisputed PostPurchaseStatus = "DISPUTED"
+)
+
+// PostPurchase est l'achat à l'unité d'un post payant : un achat SUCCEEDED débloque le post pour l'acheteur
+type PostPurchase struct {
+	ID                      string             `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	PostID                  string             `json:"postId" gorm:"type:uuid;not null;index:idx_post_purchases_buyer_post,priority:2"`
+	BuyerID                 string             `json:"buyerId" gorm:"type:uuid;not null;index:idx_post_purchases_buyer_post,priority:1"`
+	ContentCreatorID        string             `json:"contentCreatorId" gorm:"type:uuid;not null;index"`
+	Amount                  int                `json:"amount"`
+	Currency                string             `json:"currency" gorm:"type:varchar(3);default:'eur'"`
+	Status                  PostPurchaseStatus `json:"status" gorm:"type:varchar(20);default:'PENDING'"`
+	StripeCheckoutSessionId string             `json:"-"`
+	StripePaymentIntentId   string             `json:"stripePaymentIntentId" gorm:"index"`
+	PaidAt                  *time.Time         `json:"paidAt"`
+	// Montant déjà remboursé, en centimes (remboursements partiels cumulés)
+	RefundedAmount int       `json:"refundedAmount" gorm:"default:0"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+func (PostPurchase) TableName() string {
+	return "post_purchases"
+}
@@ -24,6 +24,8 @@ type PrivateMessage struct {
 	CreatedAt  time.Time         `json:"createdAt"`
 	UpdatedAt  time.Time         `json:"updatedAt"`
 	DeletedAt  *time.Time        `json:"deletedAt,omitempty" gorm:"index"`
+	// Vrai si le destinataire était connecté au WebSocket et a reçu le message en direct
+	Delivered bool `json:"delivered" gorm:"-"`
 }
 
 // PrivateMessageCreate model for creating a private message
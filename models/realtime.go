@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// RealtimePayload message temps réel trop volumineux pour une notification Postgres (8000 octets) :
+// la notification ne transporte que l'ID de la ligne
+type RealtimePayload struct {
+	ID        int64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	Payload   string    `json:"payload" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"createdAt" gorm:"index"`
+}
+
+func (RealtimePayload) TableName() string {
+	return "realtime_payloads"
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode code de secours à usage unique pour la 2FA, stocké haché
+type RecoveryCode struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID    string     `json:"userId" gorm:"type:uuid;not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
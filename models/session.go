@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// UserSession regroupe une famille de refresh tokens issus d'une même connexion.
+// Révoquer la session invalide tous les access tokens qui la référencent.
+type UserSession struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID    string     `json:"userId" gorm:"type:uuid;not null;index"`
+	UserAgent string     `json:"userAgent"`
+	IPAddress string     `json:"ipAddress"`
+	RevokedAt *time.Time `json:"revokedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
+
+// RefreshToken est stocké haché (sha256), le token en clair n'est renvoyé qu'une fois au client.
+// UsedAt est renseigné lors de la rotation : une seconde utilisation signale un vol du token.
+type RefreshToken struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	SessionID string     `json:"sessionId" gorm:"type:uuid;not null;index"`
+	UserID    string     `json:"userId" gorm:"type:uuid;not null"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// RefreshTokenRequest modèle pour renouveler un access token
+// @Description modèle pour renouveler un access token à partir d'un refresh token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required" example:"4kY1b0..."`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+type StreamType string
+
+const (
+	// Flux SSE des commentaires d'un post
+	StreamComments StreamType = "comments"
+	// WebSocket des messages privés de l'utilisateur
+	StreamMessages StreamType = "messages"
+)
+
+// StreamTicket ticket à usage unique pour ouvrir un flux temps réel (SSE ou WebSocket).
+// Les navigateurs ne permettent pas d'envoyer l'en-tête Authorization sur ces connexions : le client
+// échange son JWT contre ce ticket, qui peut passer dans l'URL sans exposer de token longue durée dans les logs.
+type StreamTicket struct {
+	ID     string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID string     `json:"userId" gorm:"type:uuid;not null;index"`
+	Stream StreamType `json:"stream" gorm:"type:varchar(20);not null;default:'comments'"`
+	// Renseigné pour le flux des commentaires, le ticket n'est valable que pour ce post
+	PostID    *string    `json:"postId" gorm:"type:uuid"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expiresAt" gorm:"index"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+func (StreamTicket) TableName() string {
+	return "stream_tickets"
+}
+
+// StreamTicketRequest modèle pour demander un ticket de flux
+// @Description flux à ouvrir : les commentaires d'un post (postId obligatoire) ou les messages privés
+type StreamTicketRequest struct {
+	Stream StreamType `json:"stream" binding:"omitempty,oneof=comments messages" example:"comments"`
+	PostID string     `json:"postId" example:"3f1c2b4a-8d6e-4c1f-9a7b-2e5d6f8a9b0c"`
+}
+
+// StreamTicketResponse ticket en clair, renvoyé une seule fois
+type StreamTicketResponse struct {
+	Ticket    string    `json:"ticket" example:"4kY1b0..."`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
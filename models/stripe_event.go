@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+type StripeEventStatus string
+
+const (
+	StripeEventProcessing StripeEventStatus = "PROCESSING"
+	StripeEventProcessed  StripeEventStatus = "PROCESSED"
+	StripeEventFailed     StripeEventStatus = "FAILED"
+	StripeEventIgnored    StripeEventStatus = "IGNORED"
+)
+
+// StripeEvent journal des événements Stripe reçus (clé = ID de l'événement Stripe).
+// Sert à ignorer les livraisons en double et à rejouer les événements en échec.
+type StripeEvent struct {
+	ID            string            `json:"id" gorm:"primaryKey"`
+	Type          string            `json:"type" gorm:"index"`
+	Payload       string            `json:"-" gorm:"type:text;not null"`
+	Status        StripeEventStatus `json:"status" gorm:"type:varchar(20);index"`
+	Attempts      int               `json:"attempts" gorm:"not null;default:0"`
+	LastError     string            `json:"lastError"`
+	NextAttemptAt *time.Time        `json:"nextAttemptAt" gorm:"index"`
+	ProcessedAt   *time.Time        `json:"processedAt"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	UpdatedAt     time.Time         `json:"updatedAt"`
+}
+
+func (StripeEvent) TableName() string {
+	return "stripe_events"
+}
@@ -10,6 +10,10 @@ const (
 	SubscriptionActive   SubscriptionStatus = "ACTIVE"
 	SubscriptionCanceled SubscriptionStatus = "CANCELED"
 	SubscriptionPending  SubscriptionStatus = "PENDING"
+	// Paiement du renouvellement en échec, Stripe retente le prélèvement
+	SubscriptionPastDue SubscriptionStatus = "PAST_DUE"
+	// Période terminée sans renouvellement
+	SubscriptionExpired SubscriptionStatus = "EXPIRED"
 )
 
 type Subscription struct {
@@ -18,8 +22,15 @@ type Subscription struct {
 	ContentCreatorID     string             `json:"contentCreatorId" gorm:"type:uuid;not null"`
 	Status               SubscriptionStatus `json:"status" gorm:"type:varchar(20);default:'PENDING'"`
 	StripeSubscriptionId string             `json:"stripeSubscriptionId"`
-	StartDate            time.Time          `json:"startDate"`
-	EndDate              *time.Time         `json:"endDate"`
-	CreatedAt            time.Time          `json:"createdAt"`
-	UpdatedAt            time.Time          `json:"updatedAt"`
+	CancelAtPeriodEnd    bool               `json:"cancelAtPeriodEnd" gorm:"default:false"`
+	PlanID               *string            `json:"planId" gorm:"type:uuid"`
+	Tier                 SubscriptionTier   `json:"tier" gorm:"type:varchar(20)"`
+	Price                int                `json:"price"`
+	// Nombre de mois couverts par chaque échéance (formules de 3, 6 ou 12 mois)
+	BillingMonths int        `json:"billingMonths" gorm:"default:1"`
+	TrialEndsAt   *time.Time `json:"trialEndsAt"`
+	StartDate     time.Time  `json:"startDate"`
+	EndDate       *time.Time `json:"endDate"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
 }
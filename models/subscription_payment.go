@@ -5,9 +5,11 @@ import (
 )
 
 type SubscriptionPayment struct {
-	ID                    string                    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	SubscriptionID        string                    `json:"subscriptionId" gorm:"type:uuid;not null"`
-	Amount                int                       `json:"amount"`
+	ID             string `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	SubscriptionID string `json:"subscriptionId" gorm:"type:uuid;not null"`
+	Amount         int    `json:"amount"`
+	// Montant déjà remboursé, en centimes (remboursements partiels cumulés)
+	RefundedAmount        int                       `json:"refundedAmount" gorm:"default:0"`
 	PaidAt                time.Time                 `json:"paidAt"`
 	StripePaymentIntentId string                    `json:"stripePaymentIntentId"`
 	Status                SubscriptionPaymentStatus `json:"status"`
@@ -22,4 +24,28 @@ const (
 	SubscriptionPaymentSucceeded SubscriptionPaymentStatus = "SUCCEEDED"
 	SubscriptionPaymentFailed    SubscriptionPaymentStatus = "FAILED"
 	SubscriptionPaymentCanceled  SubscriptionPaymentStatus = "CANCELED"
+	SubscriptionPaymentRefunded  SubscriptionPaymentStatus = "REFUNDED"
+	// Contesté par le client auprès de sa banque (chargeback)
+	SubscriptionPaymentDisputed SubscriptionPaymentStatus = "DISPUTED"
 )
+
+// PaymentRefund remboursement, total ou partiel, d'un paiement d'abonnement, d'un pourboire ou d'un achat de post.
+// Un seul des trois paiements est renseigné
+type PaymentRefund struct {
+	ID                    string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	SubscriptionPaymentID *string   `json:"subscriptionPaymentId" gorm:"type:uuid;index"`
+	TipID                 *string   `json:"tipId" gorm:"type:uuid;index"`
+	PostPurchaseID        *string   `json:"postPurchaseId" gorm:"type:uuid;index"`
+	Amount                int       `json:"amount"`
+	CreatedAt             time.Time `json:"createdAt"`
+}
+
+func (PaymentRefund) TableName() string {
+	return "payment_refunds"
+}
+
+// PaymentRefundRequest modèle pour rembourser un paiement d'abonnement
+// @Description montant à rembourser en centimes, la totalité du montant restant si absent
+type PaymentRefundRequest struct {
+	Amount int `json:"amount" binding:"omitempty,min=1" example:"499"`
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+)
+
+// SubscriptionTier niveau d'abonnement proposé par un créateur (permet de restreindre des posts par niveau)
+type SubscriptionTier string
+
+const (
+	TierBasic   SubscriptionTier = "BASIC"
+	TierPremium SubscriptionTier = "PREMIUM"
+	TierVIP     SubscriptionTier = "VIP"
+)
+
+// Rank renvoie l'ordre du niveau (0 si inconnu) : un niveau donne accès à tout ce qui est de rang inférieur ou égal
+func (t SubscriptionTier) Rank() int {
+	switch t {
+	case TierBasic:
+		return 1
+	case TierPremium:
+		return 2
+	case TierVIP:
+		return 3
+	default:
+		return 0
+	}
+}
+
+func (t SubscriptionTier) IsValid() bool {
+	return t.Rank() > 0
+}
+
+// SubscriptionPlan offre d'abonnement mensuel d'un créateur de contenu, un plan par niveau.
+// Le prix est en centimes d'euro, comme les montants Stripe.
+type SubscriptionPlan struct {
+	ID               string           `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	ContentCreatorID string           `json:"contentCreatorId" gorm:"type:uuid;not null;uniqueIndex:idx_plans_creator_tier"`
+	Name             string           `json:"name" gorm:"not null"`
+	Tier             SubscriptionTier `json:"tier" gorm:"type:varchar(20);not null;uniqueIndex:idx_plans_creator_tier"`
+	Description      string           `json:"description"`
+	Perks            []string         `json:"perks" gorm:"serializer:json"`
+	Price            int              `json:"price" gorm:"not null"`
+	Currency         string           `json:"currency" gorm:"type:varchar(3);default:'eur'"`
+	// Jours d'essai gratuit offerts à un premier abonnement chez ce créateur
+	TrialDays int `json:"trialDays" gorm:"default:0"`
+	// Formules de plusieurs mois payées d'avance avec une remise
+	Bundles   []PlanBundle `json:"bundles" gorm:"serializer:json"`
+	Active    bool         `json:"active" gorm:"default:true"`
+	CreatedAt time.Time    `json:"createdAt"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+}
+
+func (SubscriptionPlan) TableName() string {
+	return "subscription_plans"
+}
+
+// PlanBundle formule de plusieurs mois facturés en une fois, renouvelée à la même durée
+type PlanBundle struct {
+	Months          int `json:"months" binding:"oneof=3 6 12" example:"6"`
+	DiscountPercent int `json:"discountPercent" binding:"min=1,max=90" example:"15"`
+}
+
+// BundlePrice renvoie le prix d'une échéance pour la durée choisie (1 mois = prix du plan)
+func (p SubscriptionPlan) BundlePrice(months int) (int, bool) {
+	if months == 1 {
+		return p.Price, true
+	}
+	for _, bundle := range p.Bundles {
+		if bundle.Months == months {
+			total := p.Price * months
+			return total - total*bundle.DiscountPercent/100, true
+		}
+	}
+	return 0, false
+}
+
+// SubscriptionPlanCreate modèle pour créer un plan d'abonnement
+// @Description modèle pour créer un plan d'abonnement (prix mensuel en centimes)
+type SubscriptionPlanCreate struct {
+	Name        string           `json:"name" binding:"required" example:"VIP"`
+	Tier        SubscriptionTier `json:"tier" binding:"required" example:"VIP"`
+	Description string           `json:"description" example:"Accès à tous mes contenus"`
+	Perks       []string         `json:"perks" example:"Lives privés,Messages prioritaires"`
+	Price       int              `json:"price" binding:"required,min=100,max=100000" example:"999"`
+	TrialDays   int              `json:"trialDays" binding:"min=0,max=90" example:"7"`
+	Bundles     []PlanBundle     `json:"bundles" binding:"omitempty,dive"`
+}
+
+// SubscriptionPlanUpdate modèle pour modifier un plan d'abonnement
+// @Description modèle pour modifier un plan d'abonnement, le nouveau prix ne s'applique qu'aux nouveaux abonnés
+type SubscriptionPlanUpdate struct {
+	Name        *string       `json:"name" example:"VIP"`
+	Description *string       `json:"description" example:"Accès à tous mes contenus"`
+	Perks       *[]string     `json:"perks"`
+	Price       *int          `json:"price" binding:"omitempty,min=100,max=100000" example:"1499"`
+	TrialDays   *int          `json:"trialDays" binding:"omitempty,min=0,max=90" example:"7"`
+	Bundles     *[]PlanBundle `json:"bundles" binding:"omitempty,dive"`
+	Active      *bool         `json:"active" example:"true"`
+}
+
+// SubscriptionCheckoutRequest modèle pour démarrer un abonnement
+// @Description plan choisi lors du paiement (le moins cher du créateur si absent), durée de la formule et code promo éventuel
+type SubscriptionCheckoutRequest struct {
+	PlanID       string `json:"planId" example:"0f8fad5b-d9cb-469f-a165-70867728950e"`
+	BundleMonths int    `json:"bundleMonths" binding:"omitempty,oneof=1 3 6 12" example:"3"`
+	PromoCode    string `json:"promoCode" binding:"omitempty,max=32" example:"WELCOME20"`
+}
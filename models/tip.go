@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+type TipStatus string
+
+const (
+	TipPending   TipStatus = "PENDING"
+	TipSucceeded TipStatus = "SUCCEEDED"
+	TipFailed    TipStatus = "FAILED"
+	TipCanceled  TipStatus = "CANCELED"
+	TipRefunded  TipStatus = "REFUNDED"
+	// Contesté par le client auprès de sa banque (chargeback)
+	TipDisputed TipStatus = "DISPUTED"
+)
+
+// Tip est un paiement ponctuel d'un fan à un créateur, depuis son profil ou sur un post
+type Tip struct {
+	ID                      string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	SenderID                string     `json:"senderId" gorm:"type:uuid;not null;index"`
+	ContentCreatorID        string     `json:"contentCreatorId" gorm:"type:uuid;not null;index"`
+	PostID                  *string    `json:"postId" gorm:"type:uuid"`
+	Amount                  int        `json:"amount"`
+	Currency                string     `json:"currency" gorm:"type:varchar(3);default:'eur'"`
+	Message                 string     `json:"message" gorm:"type:text"`
+	Status                  TipStatus  `json:"status" gorm:"type:varchar(20);default:'PENDING'"`
+	StripeCheckoutSessionId string     `json:"-"`
+	StripePaymentIntentId   string     `json:"stripePaymentIntentId" gorm:"index"`
+	PaidAt                  *time.Time `json:"paidAt"`
+	// Montant déjà remboursé, en centimes (remboursements partiels cumulés)
+	RefundedAmount int       `json:"refundedAmount" gorm:"default:0"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	// Profil public de l'auteur, renseigné pour les pourboires reçus par le créateur
+	Sender *UserInfo `json:"sender,omitempty" gorm:"-"`
+}
+
+func (Tip) TableName() string {
+	return "tips"
+}
+
+// TipCreate montant en centimes, entre 1 et 500 euros
+type TipCreate struct {
+	Amount  int    `json:"amount" binding:"required,min=100,max=50000"`
+	Message string `json:"message" binding:"max=500"`
+	PostID  string `json:"postId"`
+}
@@ -41,10 +41,15 @@ type User struct {
 	CreatedAt            time.Time  `json:"createdAt"`
 	UpdatedAt            time.Time  `json:"updatedAt"`
 	DeletedAt            *time.Time `json:"deletedAt,omitempty" gorm:"index"`
-	ConfirmationCode     string     `json:"confirmationCode"`
+	ConfirmationCode     string     `json:"-"`
 	ConfirmationCodeEnd  time.Time  `json:"ConfirmationCodeEnd"`
-	ResetPasswordCode    string     `json:"resetPasswordCode"`
+	ConfirmationAttempts int        `json:"-"`
+	ResetPasswordCode    string     `json:"-"`
 	ResetPasswordCodeEnd time.Time  `json:"resetPasswordCodeEnd"`
+	ResetCodeAttempts    int        `json:"-"`
+	TwoFactorEnabled     bool       `json:"twoFactorEnabled"`
+	TwoFactorSecret      string     `json:"-"`
+	TwoFactorLastStep    int64      `json:"-"`
 }
 
 func (User) TableName() string {
@@ -0,0 +1,436 @@
+package payments
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	stripe "github.com/stripe/stripe-go/v82"
+	"github.com/stripe/stripe-go/v82/webhook"
+)
+
+// Webhook est un événement simulé, signé comme le ferait Stripe
+type Webhook struct {
+	ID        string
+	Type      string
+	Payload   []byte
+	Signature string
+}
+
+// Request construit la requête HTTP envoyée au endpoint de webhook
+func (w Webhook) Request(path string) *http.Request {
+	req, _ := http.NewRequest(http.MethodPost, path, bytes.NewReader(w.Payload))
+	req.Header.Set("Stripe-Signature", w.Signature)
+	return req
+}
+
+type fakeCheckout struct {
+	params    CheckoutParams
+	completed bool
+}
+
+type fakePayment struct {
+	chargeID       string
+	customerID     string
+	subscriptionID string
+	amount         int64
+	refunded       int64
+}
+
+// FakeProvider est un prestataire en mémoire : aucun appel réseau, les changements d'état
+// sont émis sous forme de webhooks signés à livrer au handler
+type FakeProvider struct {
+	mu            sync.Mutex
+	webhookSecret string
+	seq           int
+	customers     map[string]string
+	checkouts     map[string]*fakeCheckout
+	subscriptions map[string]*stripe.Subscription
+	prices        map[string]CheckoutParams
+	// Réductions pas encore consommées (abonnement en essai)
+	discounts map[string]*Discount
+	payments  map[string]*fakePayment
+	pending   []Webhook
+}
+
+// NewFakeProvider crée un prestataire en mémoire qui signe ses webhooks avec webhookSecret
+func NewFakeProvider(webhookSecret string) *FakeProvider {
+	return &FakeProvider{
+		webhookSecret: webhookSecret,
+		customers:     make(map[string]string),
+		checkouts:     make(map[string]*fakeCheckout),
+		subscriptions: make(map[string]*stripe.Subscription),
+		prices:        make(map[string]CheckoutParams),
+		discounts:     make(map[string]*Discount),
+		payments:      make(map[string]*fakePayment),
+	}
+}
+
+func (f *FakeProvider) nextID(prefix string) string {
+	f.seq++
+	return fmt.Sprintf("%s_fake_%d", prefix, f.seq)
+}
+
+func (f *FakeProvider) EnsureCustomer(customerID string, name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.customers[customerID]; ok {
+		return customerID, nil
+	}
+	id := f.nextID("cus")
+	f.customers[id] = name
+	return id, nil
+}
+
+func (f *FakeProvider) CreateCheckoutSession(params CheckoutParams) (*CheckoutSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.customers[params.CustomerID]; !ok {
+		return nil, fmt.Errorf("%w: customer %s", ErrNotFound, params.CustomerID)
+	}
+	id := f.nextID("cs")
+	f.checkouts[id] = &fakeCheckout{params: params}
+	return &CheckoutSession{ID: id, URL: "https://checkout.fake/" + id}, nil
+}
+
+func (f *FakeProvider) GetSubscription(subscriptionID string) (*stripe.Subscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.subscriptions[subscriptionID]
+	if !ok {
+		return nil, fmt.Errorf("%w: subscription %s", ErrNotFound, subscriptionID)
+	}
+	copied := *s
+	return &copied, nil
+}
+
+func (f *FakeProvider) CancelSubscription(subscriptionID string, atPeriodEnd bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.subscriptions[subscriptionID]
+	if !ok || s.Status == stripe.SubscriptionStatusCanceled {
+		return fmt.Errorf("%w: subscription %s", ErrNotFound, subscriptionID)
+	}
+
+	if atPeriodEnd {
+		s.CancelAtPeriodEnd = true
+		f.emit("customer.subscription.updated", f.subscriptionObject(s))
+		return nil
+	}
+
+	f.endSubscription(s, stripe.SubscriptionCancellationDetailsReasonCancellationRequested)
+	return nil
+}
+
+func (f *FakeProvider) Refund(paymentIntentID string, amount int64) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	payment, ok := f.payments[paymentIntentID]
+	if !ok {
+		return "", fmt.Errorf("%w: payment intent %s", ErrNotFound, paymentIntentID)
+	}
+	remaining := payment.amount - payment.refunded
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount <= 0 || amount > remaining {
+		return "", fmt.Errorf("invalid refund amount %d for payment intent %s", amount, paymentIntentID)
+	}
+
+	payment.refunded += amount
+	f.emit("charge.refunded", map[string]interface{}{
+		"id":              payment.chargeID,
+		"object":          "charge",
+		"amount":          payment.amount,
+		"amount_refunded": payment.refunded,
+		"refunded":        payment.refunded == payment.amount,
+		"customer":        payment.customerID,
+		"payment_intent":  paymentIntentID,
+	})
+	return f.nextID("re"), nil
+}
+
+// CompleteCheckout simule le paiement de la session par le client.
+// Renvoie l'abonnement créé (vide pour un paiement ponctuel) et le paiement associé
+func (f *FakeProvider) CompleteCheckout(sessionID string) (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	checkout, ok := f.checkouts[sessionID]
+	if !ok || checkout.completed {
+		return "", "", fmt.Errorf("%w: checkout session %s", ErrNotFound, sessionID)
+	}
+	checkout.completed = true
+	params := checkout.params
+
+	if params.Mode == CheckoutModePayment {
+		paymentIntentID := f.nextID("pi")
+		f.payments[paymentIntentID] = &fakePayment{
+			chargeID:   f.nextID("ch"),
+			customerID: params.CustomerID,
+			amount:     params.Amount,
+		}
+		f.emit("checkout.session.completed", map[string]interface{}{
+			"id":                  sessionID,
+			"object":              "checkout.session",
+			"mode":                "payment",
+			"customer":            params.CustomerID,
+			"client_reference_id": params.ClientReferenceID,
+			"payment_intent":      paymentIntentID,
+			"payment_status":      "paid",
+			"amount_total":        params.Amount,
+			"metadata":            params.Metadata,
+		})
+		f.emit("payment_intent.succeeded", map[string]interface{}{
+			"id":              paymentIntentID,
+			"object":          "payment_intent",
+			"customer":        params.CustomerID,
+			"amount":          params.Amount,
+			"amount_received": params.Amount,
+			"status":          "succeeded",
+			"metadata":        params.Metadata,
+		})
+		return "", paymentIntentID, nil
+	}
+
+	s := &stripe.Subscription{
+		ID:       f.nextID("sub"),
+		Status:   stripe.SubscriptionStatusActive,
+		Customer: &stripe.Customer{ID: params.CustomerID},
+		Metadata: params.Metadata,
+		Items: &stripe.SubscriptionItemList{Data: []*stripe.SubscriptionItem{{
+			ID:               f.nextID("si"),
+			CurrentPeriodEnd: time.Now().AddDate(0, intervalMonths(params), 0).Unix(),
+		}}},
+	}
+	f.subscriptions[s.ID] = s
+	f.prices[s.ID] = params
+
+	// Pendant l'essai rien n'est prélevé, la réduction s'applique à la première vraie échéance
+	amount := params.Discount.Apply(params.Amount)
+	if params.TrialDays > 0 {
+		s.Status = stripe.SubscriptionStatusTrialing
+		s.Items.Data[0].CurrentPeriodEnd = time.Now().AddDate(0, 0, params.TrialDays).Unix()
+		f.discounts[s.ID] = params.Discount
+		amount = 0
+	}
+	paymentStatus := stripe.CheckoutSessionPaymentStatusPaid
+	if amount == 0 {
+		paymentStatus = stripe.CheckoutSessionPaymentStatusNoPaymentRequired
+	}
+
+	f.emit("checkout.session.completed", map[string]interface{}{
+		"id":                  sessionID,
+		"object":              "checkout.session",
+		"mode":                "subscription",
+		"customer":            params.CustomerID,
+		"client_reference_id": params.ClientReferenceID,
+		"subscription":        s.ID,
+		"payment_status":      paymentStatus,
+		"amount_total":        amount,
+		"metadata":            params.Metadata,
+	})
+	paymentIntentID := f.chargeInvoice(s, amount)
+	return s.ID, paymentIntentID, nil
+}
+
+func intervalMonths(params CheckoutParams) int {
+	if params.IntervalMonths < 1 {
+		return 1
+	}
+	return params.IntervalMonths
+}
+
+// ExpireCheckout simule l'abandon de la page de paiement par le client
+func (f *FakeProvider) ExpireCheckout(sessionID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	checkout, ok := f.checkouts[sessionID]
+	if !ok || checkout.completed {
+		return fmt.Errorf("%w: checkout session %s", ErrNotFound, sessionID)
+	}
+	checkout.completed = true
+	f.emit("checkout.session.expired", map[string]interface{}{
+		"id":                  sessionID,
+		"object":              "checkout.session",
+		"mode":                checkout.params.Mode,
+		"customer":            checkout.params.CustomerID,
+		"client_reference_id": checkout.params.ClientReferenceID,
+		"status":              "expired",
+		"payment_status":      "unpaid",
+		"metadata":            checkout.params.Metadata,
+	})
+	return nil
+}
+
+// AdvancePeriod simule l'arrivée à échéance : l'abonnement est renouvelé et prélevé,
+// ou supprimé si sa résiliation était programmée. Renvoie le paiement du renouvellement
+func (f *FakeProvider) AdvancePeriod(subscriptionID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.subscriptions[subscriptionID]
+	if !ok || s.Status == stripe.SubscriptionStatusCanceled {
+		return "", fmt.Errorf("%w: subscription %s", ErrNotFound, subscriptionID)
+	}
+
+	if s.CancelAtPeriodEnd {
+		f.endSubscription(s, stripe.SubscriptionCancellationDetailsReasonCancellationRequested)
+		return "", nil
+	}
+
+	params := f.prices[s.ID]
+	amount := f.discounts[s.ID].Apply(params.Amount)
+	delete(f.discounts, s.ID)
+
+	item := s.Items.Data[0]
+	item.CurrentPeriodEnd = time.Unix(item.CurrentPeriodEnd, 0).AddDate(0, intervalMonths(params), 0).Unix()
+	s.Status = stripe.SubscriptionStatusActive
+	paymentIntentID := f.chargeInvoice(s, amount)
+	f.emit("customer.subscription.updated", f.subscriptionObject(s))
+	return paymentIntentID, nil
+}
+
+// FailRenewal simule l'échec du prélèvement de renouvellement
+func (f *FakeProvider) FailRenewal(subscriptionID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.subscriptions[subscriptionID]
+	if !ok || s.Status == stripe.SubscriptionStatusCanceled {
+		return fmt.Errorf("%w: subscription %s", ErrNotFound, subscriptionID)
+	}
+
+	s.Status = stripe.SubscriptionStatusPastDue
+	f.emit("invoice.payment_failed", map[string]interface{}{
+		"id":             f.nextID("in"),
+		"object":         "invoice",
+		"customer":       s.Customer.ID,
+		"amount_paid":    0,
+		"payment_intent": f.nextID("pi"),
+		"parent": map[string]interface{}{
+			"subscription_details": map[string]interface{}{"subscription": s.ID},
+		},
+	})
+	f.emit("customer.subscription.updated", f.subscriptionObject(s))
+	return nil
+}
+
+// Webhooks renvoie les événements émis depuis le dernier appel
+func (f *FakeProvider) Webhooks() []Webhook {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pending := f.pending
+	f.pending = nil
+	return pending
+}
+
+// Deliver livre les événements en attente au handler, dans l'ordre d'émission
+func (f *FakeProvider) Deliver(handler http.Handler, path string) error {
+	for _, w := range f.Webhooks() {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, w.Request(path))
+		if resp.Code != http.StatusOK {
+			return fmt.Errorf("webhook %s (%s) returned %d: %s", w.ID, w.Type, resp.Code, resp.Body.String())
+		}
+	}
+	return nil
+}
+
+// chargeInvoice enregistre un paiement réussi et émet la facture correspondante.
+// Une facture à 0 (essai, réduction totale) n'a pas de paiement
+func (f *FakeProvider) chargeInvoice(s *stripe.Subscription, amount int64) string {
+	invoice := map[string]interface{}{
+		"id":          f.nextID("in"),
+		"object":      "invoice",
+		"customer":    s.Customer.ID,
+		"amount_paid": amount,
+		"parent": map[string]interface{}{
+			"subscription_details": map[string]interface{}{"subscription": s.ID},
+		},
+	}
+
+	var paymentIntentID string
+	if amount > 0 {
+		paymentIntentID = f.nextID("pi")
+		f.payments[paymentIntentID] = &fakePayment{
+			chargeID:       f.nextID("ch"),
+			customerID:     s.Customer.ID,
+			subscriptionID: s.ID,
+			amount:         amount,
+		}
+		invoice["payment_intent"] = paymentIntentID
+	}
+
+	f.emit("invoice.payment_succeeded", invoice)
+	return paymentIntentID
+}
+
+func (f *FakeProvider) endSubscription(s *stripe.Subscription, reason stripe.SubscriptionCancellationDetailsReason) {
+	s.Status = stripe.SubscriptionStatusCanceled
+	s.EndedAt = time.Now().Unix()
+	s.CancellationDetails = &stripe.SubscriptionCancellationDetails{Reason: reason}
+	f.emit("customer.subscription.deleted", f.subscriptionObject(s))
+}
+
+// subscriptionObject sérialise l'abonnement au format des webhooks Stripe
+func (f *FakeProvider) subscriptionObject(s *stripe.Subscription) map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(s.Items.Data))
+	for _, item := range s.Items.Data {
+		items = append(items, map[string]interface{}{
+			"id":                 item.ID,
+			"object":             "subscription_item",
+			"current_period_end": item.CurrentPeriodEnd,
+		})
+	}
+
+	object := map[string]interface{}{
+		"id":                   s.ID,
+		"object":               "subscription",
+		"customer":             s.Customer.ID,
+		"status":               s.Status,
+		"cancel_at_period_end": s.CancelAtPeriodEnd,
+		"ended_at":             s.EndedAt,
+		"metadata":             s.Metadata,
+		"items":                map[string]interface{}{"object": "list", "data": items},
+	}
+	if s.CancellationDetails != nil {
+		object["cancellation_details"] = map[string]interface{}{"reason": s.CancellationDetails.Reason}
+	}
+	return object
+}
+
+// emit ajoute un événement signé à la file, l'appelant détient le verrou
+func (f *FakeProvider) emit(eventType string, object map[string]interface{}) {
+	id := f.nextID("evt")
+	payload, _ := json.Marshal(map[string]interface{}{
+		"id":          id,
+		"object":      "event",
+		"type":        eventType,
+		"api_version": stripe.APIVersion,
+		"created":     time.Now().Unix(),
+		"data":        map[string]interface{}{"object": object},
+	})
+
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload: payload,
+		Secret:  f.webhookSecret,
+	})
+	f.pending = append(f.pending, Webhook{
+		ID:        id,
+		Type:      eventType,
+		Payload:   signed.Payload,
+		Signature: signed.Header,
+	})
+}
@@ -0,0 +1,124 @@
+package payments
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	stripe "github.com/stripe/stripe-go/v82"
+	"github.com/stripe/stripe-go/v82/webhook"
+)
+
+const testWebhookSecret = "whsec_test_secret"
+
+func TestFakeProvider_CheckoutEmitsSignedEvents(t *testing.T) {
+	fake := NewFakeProvider(testWebhookSecret)
+
+	customerID, err := fake.EnsureCustomer("", "subscriber")
+	assert.NoError(t, err)
+	sameID, err := fake.EnsureCustomer(customerID, "subscriber")
+	assert.NoError(t, err)
+	assert.Equal(t, customerID, sameID)
+
+	session, err := fake.CreateCheckoutSession(CheckoutParams{
+		CustomerID:        customerID,
+		ClientReferenceID: "creator-uuid",
+		Currency:          "eur",
+		Amount:            499,
+		Metadata:          map[string]string{"plan_id": "plan-uuid"},
+	})
+	assert.NoError(t, err)
+
+	subscriptionID, paymentIntentID, err := fake.CompleteCheckout(session.ID)
+	assert.NoError(t, err)
+
+	webhooks := fake.Webhooks()
+	assert.Len(t, webhooks, 2)
+	assert.Empty(t, fake.Webhooks())
+
+	event, err := webhook.ConstructEvent(webhooks[0].Payload, webhooks[0].Signature, testWebhookSecret)
+	assert.NoError(t, err)
+	assert.Equal(t, "checkout.session.completed", string(event.Type))
+
+	var checkout stripe.CheckoutSession
+	assert.NoError(t, json.Unmarshal(event.Data.Raw, &checkout))
+	assert.Equal(t, subscriptionID, checkout.Subscription.ID)
+	assert.Equal(t, "creator-uuid", checkout.ClientReferenceID)
+	assert.Equal(t, "plan-uuid", checkout.Metadata["plan_id"])
+	assert.Equal(t, "invoice.payment_succeeded", webhooks[1].Type)
+
+	_, err = fake.Refund(paymentIntentID, 0)
+	assert.NoError(t, err)
+	refunds := fake.Webhooks()
+	assert.Len(t, refunds, 1)
+
+	event, err = webhook.ConstructEvent(refunds[0].Payload, refunds[0].Signature, testWebhookSecret)
+	assert.NoError(t, err)
+	var charge stripe.Charge
+	assert.NoError(t, json.Unmarshal(event.Data.Raw, &charge))
+	assert.True(t, charge.Refunded)
+	assert.Equal(t, paymentIntentID, charge.PaymentIntent.ID)
+
+	_, err = fake.Refund(paymentIntentID, 0)
+	assert.Error(t, err)
+}
+
+func TestFakeProvider_UnknownSubscription(t *testing.T) {
+	fake := NewFakeProvider(testWebhookSecret)
+
+	_, err := fake.GetSubscription("sub_missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, fake.CancelSubscription("sub_missing", true), ErrNotFound)
+}
+
+func TestDiscountApply(t *testing.T) {
+	var none *Discount
+	assert.Equal(t, int64(999), none.Apply(999))
+	assert.Equal(t, int64(800), (&Discount{PercentOff: 20}).Apply(1000))
+	assert.Equal(t, int64(0), (&Discount{PercentOff: 100}).Apply(1000))
+	assert.Equal(t, int64(700), (&Discount{AmountOff: 300}).Apply(1000))
+	assert.Equal(t, int64(0), (&Discount{AmountOff: 1500}).Apply(1000))
+}
+
+// Test qu'un essai ne prélève rien et que la réduction s'applique à la première vraie échéance
+func TestFakeProvider_TrialDefersDiscount(t *testing.T) {
+	fake := NewFakeProvider(testWebhookSecret)
+
+	customerID, err := fake.EnsureCustomer("", "subscriber")
+	assert.NoError(t, err)
+	session, err := fake.CreateCheckoutSession(CheckoutParams{
+		CustomerID:        customerID,
+		ClientReferenceID: "creator-uuid",
+		Currency:          "eur",
+		Amount:            2700,
+		IntervalMonths:    3,
+		TrialDays:         7,
+		Discount:          &Discount{PercentOff: 20},
+	})
+	assert.NoError(t, err)
+
+	subscriptionID, paymentIntentID, err := fake.CompleteCheckout(session.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, paymentIntentID)
+
+	webhooks := fake.Webhooks()
+	event, err := webhook.ConstructEvent(webhooks[0].Payload, webhooks[0].Signature, testWebhookSecret)
+	assert.NoError(t, err)
+	var checkout stripe.CheckoutSession
+	assert.NoError(t, json.Unmarshal(event.Data.Raw, &checkout))
+	assert.Equal(t, stripe.CheckoutSessionPaymentStatusNoPaymentRequired, checkout.PaymentStatus)
+
+	s, err := fake.GetSubscription(subscriptionID)
+	assert.NoError(t, err)
+	assert.Equal(t, stripe.SubscriptionStatusTrialing, s.Status)
+
+	// Fin de l'essai : première échéance de 3 mois avec la réduction
+	paymentIntentID, err = fake.AdvancePeriod(subscriptionID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2160), fake.payments[paymentIntentID].amount)
+
+	// Renouvellement : plein tarif de la formule
+	paymentIntentID, err = fake.AdvancePeriod(subscriptionID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2700), fake.payments[paymentIntentID].amount)
+}
@@ -0,0 +1,80 @@
+package payments
+
+import (
+	"errors"
+
+	stripe "github.com/stripe/stripe-go/v82"
+)
+
+// ErrNotFound l'objet demandé n'existe pas (ou plus) chez le prestataire
+var ErrNotFound = errors.New("payment resource not found")
+
+// PaymentProvider regroupe les appels faits au prestataire de paiement.
+// Les changements d'état arrivent ensuite par webhook, au format des événements Stripe
+type PaymentProvider interface {
+	// EnsureCustomer renvoie l'identifiant client, en le recréant s'il n'existe plus
+	EnsureCustomer(customerID string, name string) (string, error)
+	CreateCheckoutSession(params CheckoutParams) (*CheckoutSession, error)
+	GetSubscription(subscriptionID string) (*stripe.Subscription, error)
+	// CancelSubscription résilie immédiatement ou à la fin de la période en cours
+	CancelSubscription(subscriptionID string, atPeriodEnd bool) error
+	// Refund rembourse un paiement, en totalité si amount vaut 0. Renvoie l'identifiant du remboursement
+	Refund(paymentIntentID string, amount int64) (string, error)
+}
+
+type CheckoutMode string
+
+const (
+	// CheckoutModeSubscription abonnement mensuel renouvelé automatiquement
+	CheckoutModeSubscription CheckoutMode = "subscription"
+	// CheckoutModePayment paiement ponctuel
+	CheckoutModePayment CheckoutMode = "payment"
+)
+
+// CheckoutParams décrit un paiement à effectuer via une page de paiement hébergée
+type CheckoutParams struct {
+	Mode              CheckoutMode
+	CustomerID        string
+	ClientReferenceID string
+	ProductName       string
+	Currency          string
+	Amount            int64
+	Metadata          map[string]string
+	SuccessURL        string
+	CancelURL         string
+	// Abonnement uniquement : nombre de mois facturés à chaque échéance (1 si vide)
+	IntervalMonths int
+	// Abonnement uniquement : période d'essai gratuite avant la première échéance
+	TrialDays int
+	// Réduction appliquée à la première échéance
+	Discount *Discount
+}
+
+// Discount réduction ponctuelle, en pourcentage ou en montant fixe (centimes)
+type Discount struct {
+	PercentOff int
+	AmountOff  int64
+}
+
+// Apply renvoie le montant après réduction, jamais négatif
+func (d *Discount) Apply(amount int64) int64 {
+	if d == nil {
+		return amount
+	}
+	if d.PercentOff > 0 {
+		amount -= amount * int64(d.PercentOff) / 100
+	}
+	amount -= d.AmountOff
+	if amount < 0 {
+		return 0
+	}
+	return amount
+}
+
+type CheckoutSession struct {
+	ID  string
+	URL string
+}
+
+// Default est le prestataire utilisé par les handlers, remplacé par un FakeProvider dans les tests
+var Default PaymentProvider = NewStripeProvider("")
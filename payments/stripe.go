@@ -0,0 +1,178 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	stripe "github.com/stripe/stripe-go/v82"
+)
+
+// StripeProvider appelle l'API Stripe avec sa propre clé, sans passer par stripe.Key
+type StripeProvider struct {
+	client *stripe.Client
+}
+
+func NewStripeProvider(secretKey string) *StripeProvider {
+	return &StripeProvider{client: stripe.NewClient(secretKey)}
+}
+
+// InitStripe installe le prestataire Stripe configuré par STRIPE_SECRET_KEY
+func InitStripe() error {
+	secretKey := os.Getenv("STRIPE_SECRET_KEY")
+	if secretKey == "" {
+		return fmt.Errorf("the STRIPE_SECRET_KEY environment variable is not defined")
+	}
+	Default = NewStripeProvider(secretKey)
+	return nil
+}
+
+// wrapStripeError traduit les erreurs Stripe « ressource absente » en ErrNotFound
+func wrapStripeError(err error) error {
+	var stripeErr *stripe.Error
+	if errors.As(err, &stripeErr) && stripeErr.Code == stripe.ErrorCodeResourceMissing {
+		return fmt.Errorf("%w: %s", ErrNotFound, stripeErr.Msg)
+	}
+	return err
+}
+
+func (p *StripeProvider) EnsureCustomer(customerID string, name string) (string, error) {
+	if customerID != "" {
+		// Vérifie que le customer existe vraiment sur Stripe, sinon on le recrée
+		if _, err := p.client.V1Customers.Retrieve(context.Background(), customerID, nil); err == nil {
+			return customerID, nil
+		}
+	}
+
+	cust, err := p.client.V1Customers.Create(context.Background(), &stripe.CustomerCreateParams{
+		Name: stripe.String(name),
+	})
+	if err != nil {
+		return "", err
+	}
+	return cust.ID, nil
+}
+
+func (p *StripeProvider) CreateCheckoutSession(params CheckoutParams) (*CheckoutSession, error) {
+	priceData := &stripe.CheckoutSessionCreateLineItemPriceDataParams{
+		Currency:   stripe.String(params.Currency),
+		UnitAmount: stripe.Int64(params.Amount),
+		ProductData: &stripe.CheckoutSessionCreateLineItemPriceDataProductDataParams{
+			Name: stripe.String(params.ProductName),
+		},
+	}
+	sessionParams := &stripe.CheckoutSessionCreateParams{
+		Customer:           stripe.String(params.CustomerID),
+		PaymentMethodTypes: stripe.StringSlice([]string{"card"}),
+		LineItems: []*stripe.CheckoutSessionCreateLineItemParams{
+			{
+				// Prix défini par le créateur, Stripe crée le prix à la volée
+				PriceData: priceData,
+				Quantity:  stripe.Int64(1),
+			},
+		},
+		Metadata:          params.Metadata,
+		SuccessURL:        stripe.String(params.SuccessURL),
+		CancelURL:         stripe.String(params.CancelURL),
+		ClientReferenceID: stripe.String(params.ClientReferenceID),
+	}
+
+	// Les metadata sont recopiées sur l'objet créé pour les retrouver dans les webhooks suivants
+	if params.Mode == CheckoutModePayment {
+		sessionParams.Mode = stripe.String(string(stripe.CheckoutSessionModePayment))
+		sessionParams.PaymentIntentData = &stripe.CheckoutSessionCreatePaymentIntentDataParams{
+			Metadata: params.Metadata,
+		}
+	} else {
+		sessionParams.Mode = stripe.String(string(stripe.CheckoutSessionModeSubscription))
+		intervalMonths := params.IntervalMonths
+		if intervalMonths < 1 {
+			intervalMonths = 1
+		}
+		priceData.Recurring = &stripe.CheckoutSessionCreateLineItemPriceDataRecurringParams{
+			Interval:      stripe.String(string(stripe.PriceRecurringIntervalMonth)),
+			IntervalCount: stripe.Int64(int64(intervalMonths)),
+		}
+		sessionParams.SubscriptionData = &stripe.CheckoutSessionCreateSubscriptionDataParams{
+			Metadata: params.Metadata,
+		}
+		if params.TrialDays > 0 {
+			sessionParams.SubscriptionData.TrialPeriodDays = stripe.Int64(int64(params.TrialDays))
+		}
+	}
+
+	// Les règles des codes promo sont gérées chez nous : Stripe reçoit un coupon à usage unique
+	if params.Discount != nil {
+		couponID, err := p.createCoupon(params.Currency, params.Discount)
+		if err != nil {
+			return nil, err
+		}
+		sessionParams.Discounts = []*stripe.CheckoutSessionCreateDiscountParams{
+			{Coupon: stripe.String(couponID)},
+		}
+	}
+
+	s, err := p.client.V1CheckoutSessions.Create(context.Background(), sessionParams)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckoutSession{ID: s.ID, URL: s.URL}, nil
+}
+
+// createCoupon crée un coupon Stripe valable une seule fois, sur la première échéance
+func (p *StripeProvider) createCoupon(currency string, discount *Discount) (string, error) {
+	params := &stripe.CouponCreateParams{
+		Duration:       stripe.String(string(stripe.CouponDurationOnce)),
+		MaxRedemptions: stripe.Int64(1),
+	}
+	if discount.PercentOff > 0 {
+		params.PercentOff = stripe.Float64(float64(discount.PercentOff))
+	} else {
+		params.AmountOff = stripe.Int64(discount.AmountOff)
+		params.Currency = stripe.String(currency)
+	}
+
+	coupon, err := p.client.V1Coupons.Create(context.Background(), params)
+	if err != nil {
+		return "", err
+	}
+	return coupon.ID, nil
+}
+
+func (p *StripeProvider) GetSubscription(subscriptionID string) (*stripe.Subscription, error) {
+	s, err := p.client.V1Subscriptions.Retrieve(context.Background(), subscriptionID, nil)
+	if err != nil {
+		return nil, wrapStripeError(err)
+	}
+	return s, nil
+}
+
+func (p *StripeProvider) CancelSubscription(subscriptionID string, atPeriodEnd bool) error {
+	var err error
+	if atPeriodEnd {
+		_, err = p.client.V1Subscriptions.Update(context.Background(), subscriptionID, &stripe.SubscriptionUpdateParams{
+			CancelAtPeriodEnd: stripe.Bool(true),
+		})
+	} else {
+		_, err = p.client.V1Subscriptions.Cancel(context.Background(), subscriptionID, &stripe.SubscriptionCancelParams{
+			Prorate: stripe.Bool(false),
+		})
+	}
+	return wrapStripeError(err)
+}
+
+func (p *StripeProvider) Refund(paymentIntentID string, amount int64) (string, error) {
+	params := &stripe.RefundCreateParams{
+		PaymentIntent: stripe.String(paymentIntentID),
+	}
+	if amount > 0 {
+		params.Amount = stripe.Int64(amount)
+	}
+
+	refund, err := p.client.V1Refunds.Create(context.Background(), params)
+	if err != nil {
+		return "", wrapStripeError(err)
+	}
+	return refund.ID, nil
+}
@@ -0,0 +1,55 @@
+package realtime
+
+import (
+	"sync"
+)
+
+// Message événement transporté par un broker entre les instances de l'API.
+// L'ID est attribué par le hub qui publie, pour que toutes les instances partagent les mêmes Last-Event-ID
+type Message struct {
+	ID    int64  `json:"id"`
+	Topic string `json:"topic"`
+	Name  string `json:"name"`
+	Data  []byte `json:"data"`
+}
+
+// Receiver reçoit les messages publiés sur un broker, y compris ceux de sa propre instance
+type Receiver interface {
+	Receive(msg Message)
+	// Lost signale que des messages ont pu être perdus (connexion au broker coupée)
+	Lost()
+}
+
+// Broker diffuse les messages à tous les hubs abonnés, sur une ou plusieurs instances
+type Broker interface {
+	Publish(msg Message) error
+	Subscribe(receiver Receiver)
+}
+
+// MemoryBroker broker en mémoire, pour une seule instance et pour les tests.
+// Les messages sont remis de façon synchrone pendant Publish
+type MemoryBroker struct {
+	mu        sync.RWMutex
+	receivers []Receiver
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+func (b *MemoryBroker) Publish(msg Message) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, receiver := range b.receivers {
+		receiver.Receive(msg)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(receiver Receiver) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.receivers = append(b.receivers, receiver)
+}
@@ -0,0 +1,160 @@
+package realtime
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"pec2-backend/testutils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	testutils.InitTestMain()
+
+	log.SetOutput(io.Discard)
+
+	exitCode := m.Run()
+
+	log.SetOutput(os.Stdout)
+
+	os.Exit(exitCode)
+}
+
+// Test que deux instances branchées sur le même broker reçoivent les événements publiés par l'autre,
+// avec le même ID pour qu'un client puisse reprendre son flux sur n'importe quelle instance
+func TestBroker_CrossInstanceDelivery(t *testing.T) {
+	broker := NewMemoryBroker()
+	first := NewHub(broker, Options{})
+	second := NewHub(broker, Options{})
+
+	onFirst, err := first.Subscribe(PostTopic("post-uuid"), 0)
+	assert.NoError(t, err)
+	onSecond, err := second.Subscribe(PostTopic("post-uuid"), 0)
+	assert.NoError(t, err)
+
+	published := publish(t, first, PostTopic("post-uuid"), "comment", []byte(`{"n":1}`))
+
+	received := nextEvent(t, onSecond)
+	assert.Equal(t, published, received)
+	assert.Equal(t, published, nextEvent(t, onFirst))
+
+	// Le client passé sur la seconde instance reprend après l'événement reçu sur la première
+	second.Unsubscribe(onSecond)
+	missed := publish(t, second, PostTopic("post-uuid"), "comment", []byte(`{"n":2}`))
+	resumed, err := second.Subscribe(PostTopic("post-uuid"), received.ID)
+	assert.NoError(t, err)
+	assert.True(t, resumed.Resumed)
+	assert.Equal(t, []Event{missed}, resumed.Replay)
+}
+
+// Test qu'une perte de messages du broker déconnecte les clients et les oblige à recharger l'état
+func TestHub_Lost(t *testing.T) {
+	hub := NewHub(NewMemoryBroker(), Options{})
+
+	client, err := hub.Subscribe(PostTopic("post-uuid"), 0)
+	assert.NoError(t, err)
+	event := publish(t, hub, PostTopic("post-uuid"), "comment", []byte(`{}`))
+
+	hub.Lost()
+	assert.ErrorIs(t, client.Err(), ErrMessagesLost)
+
+	client, err = hub.Subscribe(PostTopic("post-uuid"), event.ID-1)
+	assert.NoError(t, err)
+	assert.False(t, client.Resumed)
+}
+
+// Test qu'un message trop gros pour NOTIFY est stocké en base et que la notification ne porte que sa référence
+func TestPostgresBroker_LargePayload(t *testing.T) {
+	gormDB, mock, cleanup := testutils.SetupTestDB(t)
+	defer cleanup()
+
+	broker := NewPostgresBroker(gormDB, "")
+	msg := Message{ID: 42, Topic: PostTopic("post-uuid"), Name: "comment", Data: []byte(strings.Repeat("a", maxNotifyPayload))}
+	stored, _ := json.Marshal(notification{Message: msg})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "realtime_payloads" \("payload","created_at"\) VALUES \(\$1,\$2\) RETURNING "id"`).
+		WithArgs(string(stored), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "realtime_payloads" WHERE created_at < \$1`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_notify\(\$1, \$2\)`).
+		WithArgs(notifyChannel, `{"id":0,"topic":"","name":"","data":null,"ref":7}`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, broker.Publish(msg))
+
+	// L'instance qui reçoit la notification relit le message en base
+	mock.ExpectQuery(`SELECT \* FROM "realtime_payloads" WHERE "realtime_payloads"."id" = \$1`).
+		WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "payload"}).AddRow(7, string(stored)))
+
+	decoded, err := broker.decode(`{"ref":7}`)
+	assert.NoError(t, err)
+	assert.Equal(t, msg, decoded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test que le nombre de spectateurs additionne les clients de toutes les instances
+func TestPresence_AcrossInstances(t *testing.T) {
+	broker := NewMemoryBroker()
+	first := NewHub(broker, Options{})
+	second := NewHub(broker, Options{})
+	topic := PostTopic("post-uuid")
+
+	onFirst, err := first.Subscribe(topic, 0)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"viewers","payload":{"viewers":1}}`, string((<-onFirst.Events()).Data))
+
+	onSecond, err := second.Subscribe(topic, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, first.Viewers(topic))
+	assert.Equal(t, 2, second.Viewers(topic))
+
+	presence := <-onFirst.Events()
+	assert.Equal(t, "presence", presence.Name)
+	assert.Zero(t, presence.ID)
+	assert.JSONEq(t, `{"type":"viewers","payload":{"viewers":2}}`, string(presence.Data))
+
+	first.Unsubscribe(onFirst)
+	assert.Equal(t, 1, second.Viewers(topic))
+	second.Unsubscribe(onSecond)
+	assert.Equal(t, 0, first.Viewers(topic))
+}
+
+// Test qu'une instance qui cesse d'annoncer sa présence sort du total sans attendre une nouvelle annonce
+func TestPresence_ExpiredInstance(t *testing.T) {
+	broker := NewMemoryBroker()
+	first := NewHub(broker, Options{})
+	second := NewHub(broker, Options{})
+	topic := PostTopic("post-uuid")
+
+	onFirst, err := first.Subscribe(topic, 0)
+	assert.NoError(t, err)
+	<-onFirst.Events()
+	_, err = second.Subscribe(topic, 0)
+	assert.NoError(t, err)
+	<-onFirst.Events()
+	assert.Equal(t, 2, first.Viewers(topic))
+
+	// L'autre instance s'arrête sans se désabonner
+	first.mu.Lock()
+	presence := first.topics[topic].presence[second.instance]
+	presence.seenAt = time.Now().Add(-presenceTTL - time.Second)
+	first.topics[topic].presence[second.instance] = presence
+	first.mu.Unlock()
+
+	first.RefreshPresence(topic)
+	assert.Equal(t, 1, first.Viewers(topic))
+	assert.JSONEq(t, `{"type":"viewers","payload":{"viewers":1}}`, string((<-onFirst.Events()).Data))
+}
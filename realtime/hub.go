@@ -0,0 +1,333 @@
+package realtime
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTooManyClients le sujet a atteint son nombre maximum de clients connectés
+	ErrTooManyClients = errors.New("too many clients on this topic")
+	// ErrSlowClient le client ne lisait pas assez vite et a été déconnecté
+	ErrSlowClient = errors.New("client too slow, disconnected")
+	// ErrMessagesLost le broker a pu perdre des messages, le client doit recharger l'état complet
+	ErrMessagesLost = errors.New("realtime messages lost, disconnected")
+)
+
+// Event événement diffusé sur un sujet (un post par exemple).
+// Les IDs sont croissants sur tout le hub et servent de Last-Event-ID aux clients SSE
+type Event struct {
+	ID   int64
+	Name string
+	Data []byte
+}
+
+// Options réglages d'un hub, les valeurs nulles prennent les valeurs par défaut
+type Options struct {
+	// Nombre maximum de clients connectés par sujet
+	MaxClients int
+	// Événements en attente par client avant de le considérer comme trop lent
+	ClientBuffer int
+	// Événements conservés par sujet pour la reprise après une reconnexion
+	History int
+	// Durée de conservation de l'historique d'un sujet sans client
+	IdleTTL time.Duration
+	// Intervalle des commentaires de keep-alive envoyés aux clients SSE
+	Heartbeat time.Duration
+}
+
+const (
+	defaultMaxClients   = 500
+	defaultClientBuffer = 64
+	defaultHistory      = 256
+	defaultIdleTTL      = 10 * time.Minute
+	defaultHeartbeat    = 15 * time.Second
+)
+
+// Hub diffuse des événements aux clients abonnés à un sujet et garde un historique borné
+// pour que les clients qui se reconnectent ne reçoivent que ce qu'ils ont manqué.
+// Les événements passent par le broker : chaque instance les reçoit, même ceux qu'elle publie
+type Hub struct {
+	opts   Options
+	broker Broker
+	// Identifie l'instance dans les annonces de présence
+	instance string
+
+	mu     sync.Mutex
+	topics map[string]*topic
+	// Dernier ID remis aux clients de cette instance
+	lastID int64
+	// Dernier ID attribué par cette instance à un événement publié
+	lastAssigned int64
+	// Numéro des annonces de présence de cette instance, pour ignorer celles reçues dans le désordre
+	presenceSeq int64
+}
+
+type topic struct {
+	clients map[*Client]struct{}
+	history []Event
+	// Dernier ID que le sujet ne peut plus rejouer : avant sa création ou sorti de l'historique
+	floor     int64
+	idleSince time.Time
+
+	// Clients connectés par instance, d'après leurs annonces de présence
+	presence    map[string]instancePresence
+	viewers     int
+	announcedAt time.Time
+}
+
+func newTopic(floor int64) *topic {
+	return &topic{
+		clients:  make(map[*Client]struct{}),
+		presence: make(map[string]instancePresence),
+		floor:    floor,
+	}
+}
+
+// Client abonnement d'une connexion à un sujet
+type Client struct {
+	topic  string
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+	err    error
+
+	// Replay contient les événements manqués depuis le Last-Event-ID quand Resumed est vrai.
+	// Sinon le client doit recharger l'état complet, à jour jusqu'à Head
+	Replay  []Event
+	Resumed bool
+	Head    int64
+}
+
+// Events renvoie les nouveaux événements du sujet
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// Done est fermé quand le client est désabonné ou déconnecté par le hub, Err en donne la raison
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err renvoie la raison de la déconnexion par le hub, nil après un désabonnement normal
+func (c *Client) Err() error {
+	<-c.done
+	return c.err
+}
+
+func (c *Client) disconnect(reason error) {
+	c.once.Do(func() {
+		c.err = reason
+		close(c.done)
+	})
+}
+
+func NewHub(broker Broker, opts Options) *Hub {
+	if opts.MaxClients <= 0 {
+		opts.MaxClients = defaultMaxClients
+	}
+	if opts.ClientBuffer <= 0 {
+		opts.ClientBuffer = defaultClientBuffer
+	}
+	if opts.History <= 0 {
+		opts.History = defaultHistory
+	}
+	if opts.IdleTTL <= 0 {
+		opts.IdleTTL = defaultIdleTTL
+	}
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = defaultHeartbeat
+	}
+	hub := &Hub{
+		opts:     opts,
+		broker:   broker,
+		instance: uuid.NewString(),
+		topics:   make(map[string]*topic),
+		// Les IDs partent de l'heure courante : après un redémarrage, un ancien Last-Event-ID
+		// est plus petit que tout ce que le hub connaît et déclenche un rechargement complet
+		lastID: time.Now().UnixMicro(),
+	}
+	broker.Subscribe(hub)
+	return hub
+}
+
+// nextID renvoie un ID basé sur l'horloge en microsecondes, strictement croissant sur cette instance
+func (h *Hub) nextID() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	id := time.Now().UnixMicro()
+	if last := max(h.lastID, h.lastAssigned); id <= last {
+		id = last + 1
+	}
+	h.lastAssigned = id
+	return id
+}
+
+// Publish envoie un événement au broker, qui le remet aux hubs de toutes les instances
+func (h *Hub) Publish(topicName string, name string, data []byte) (Event, error) {
+	event := Event{ID: h.nextID(), Name: name, Data: data}
+	err := h.broker.Publish(Message{ID: event.ID, Topic: topicName, Name: name, Data: data})
+	return event, err
+}
+
+// Receive diffuse un événement du broker aux clients du sujet. Un client dont le tampon est plein
+// est déconnecté : il se reconnectera avec son Last-Event-ID au lieu de perdre des événements
+func (h *Hub) Receive(msg Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if msg.Name == PresenceEvent {
+		h.receivePresence(msg)
+		return
+	}
+
+	// Les instances reçoivent les messages dans le même ordre ; un ID qui ne suit pas
+	// (horloges décalées entre instances) est décalé pour que la reprise reste possible
+	event := Event{ID: msg.ID, Name: msg.Name, Data: msg.Data}
+	if event.ID <= h.lastID {
+		event.ID = h.lastID + 1
+	}
+	h.lastID = event.ID
+
+	t, ok := h.topics[msg.Topic]
+	if !ok {
+		// Personne n'écoute : le sujet sera créé avec un plancher postérieur à cet événement
+		return
+	}
+
+	t.history = append(t.history, event)
+	if len(t.history) > h.opts.History {
+		t.floor = t.history[0].ID
+		t.history = t.history[1:]
+	}
+
+	h.deliver(t, event)
+}
+
+// deliver remet l'événement aux clients du sujet, appelé avec le verrou pris
+func (h *Hub) deliver(t *topic, event Event) {
+	for client := range t.clients {
+		select {
+		case client.events <- event:
+		default:
+			delete(t.clients, client)
+			client.disconnect(ErrSlowClient)
+		}
+	}
+	if len(t.clients) == 0 && t.idleSince.IsZero() {
+		t.idleSince = time.Now()
+	}
+}
+
+// Lost déconnecte tous les clients après une perte de messages du broker :
+// leur Last-Event-ID n'étant plus couvert, ils rechargeront l'état complet
+func (h *Hub) Lost() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, t := range h.topics {
+		t.floor = h.lastID
+		t.history = nil
+		for client := range t.clients {
+			delete(t.clients, client)
+			client.disconnect(ErrMessagesLost)
+		}
+		t.idleSince = time.Now()
+	}
+}
+
+// Subscribe abonne un client au sujet et annonce la nouvelle présence aux autres instances.
+// lastEventID vaut 0 pour une première connexion
+func (h *Hub) Subscribe(topicName string, lastEventID int64) (*Client, error) {
+	client, err := h.subscribe(topicName, lastEventID)
+	if err != nil {
+		return nil, err
+	}
+	h.announcePresence(topicName)
+	return client, nil
+}
+
+func (h *Hub) subscribe(topicName string, lastEventID int64) (*Client, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sweep()
+
+	t := h.topic(topicName)
+	if len(t.clients) >= h.opts.MaxClients {
+		return nil, ErrTooManyClients
+	}
+
+	client := &Client{
+		topic:  topicName,
+		events: make(chan Event, h.opts.ClientBuffer),
+		done:   make(chan struct{}),
+		Head:   h.lastID,
+	}
+
+	// La reprise n'est possible que si rien n'a été perdu entre le Last-Event-ID et l'historique
+	if lastEventID > 0 && lastEventID >= t.floor && lastEventID <= h.lastID {
+		client.Resumed = true
+		for _, event := range t.history {
+			if event.ID > lastEventID {
+				client.Replay = append(client.Replay, event)
+			}
+		}
+	}
+
+	t.clients[client] = struct{}{}
+	t.idleSince = time.Time{}
+	return client, nil
+}
+
+// Unsubscribe retire le client de son sujet, l'historique est gardé pour une reconnexion
+func (h *Hub) Unsubscribe(client *Client) {
+	h.mu.Lock()
+	if t, ok := h.topics[client.topic]; ok {
+		delete(t.clients, client)
+		if len(t.clients) == 0 {
+			t.idleSince = time.Now()
+		}
+	}
+	h.mu.Unlock()
+
+	client.disconnect(nil)
+	h.announcePresence(client.topic)
+}
+
+// ClientCount renvoie le nombre de clients connectés au sujet
+func (h *Hub) ClientCount(topicName string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if t, ok := h.topics[topicName]; ok {
+		return len(t.clients)
+	}
+	return 0
+}
+
+// topic renvoie le sujet, créé au besoin, appelé avec le verrou pris
+func (h *Hub) topic(topicName string) *topic {
+	t, ok := h.topics[topicName]
+	if !ok {
+		t = newTopic(h.lastID)
+		h.topics[topicName] = t
+	}
+	return t
+}
+
+// sweep oublie les sujets sans client depuis plus de IdleTTL, appelé avec le verrou pris.
+// Un sujet suivi sur une autre instance est gardé pour connaître sa présence, tant que celle-ci l'annonce
+func (h *Hub) sweep() {
+	now := time.Now()
+	for name, t := range h.topics {
+		h.recountPresence(t, now)
+		if len(t.clients) == 0 && t.viewers == 0 && !t.idleSince.IsZero() && now.Sub(t.idleSince) > h.opts.IdleTTL {
+			delete(h.topics, name)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package realtime
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func publish(t *testing.T, hub *Hub, topic string, name string, data []byte) Event {
+	event, err := hub.Publish(topic, name, data)
+	assert.NoError(t, err)
+	return event
+}
+
+// nextEvent renvoie le prochain événement du client en passant les mises à jour de présence
+func nextEvent(t *testing.T, client *Client) Event {
+	for {
+		select {
+		case event := <-client.Events():
+			if event.Name != PresenceEvent {
+				return event
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no event received")
+		}
+	}
+}
+
+// Test qu'un client qui se reconnecte ne reçoit que les événements publiés après son Last-Event-ID
+func TestSubscribe_ResumeReplaysMissedEvents(t *testing.T) {
+	hub := NewHub(NewMemoryBroker(), Options{})
+
+	first, err := hub.Subscribe("post-uuid", 0)
+	assert.NoError(t, err)
+	assert.False(t, first.Resumed)
+
+	seen := publish(t, hub, "post-uuid", "comment", []byte(`{"n":1}`))
+	hub.Unsubscribe(first)
+	missed := publish(t, hub, "post-uuid", "comment", []byte(`{"n":2}`))
+	publish(t, hub, "other-post", "comment", []byte(`{"n":3}`))
+
+	client, err := hub.Subscribe("post-uuid", seen.ID)
+	assert.NoError(t, err)
+	assert.True(t, client.Resumed)
+	assert.Equal(t, []Event{missed}, client.Replay)
+	assert.Greater(t, missed.ID, seen.ID)
+}
+
+// Test qu'un Last-Event-ID sorti de l'historique ou inconnu oblige à recharger l'état complet
+func TestSubscribe_ResumeOutsideHistory(t *testing.T) {
+	hub := NewHub(NewMemoryBroker(), Options{History: 2})
+
+	client, err := hub.Subscribe("post-uuid", 0)
+	assert.NoError(t, err)
+	hub.Unsubscribe(client)
+
+	oldest := publish(t, hub, "post-uuid", "comment", []byte(`{}`))
+	publish(t, hub, "post-uuid", "comment", []byte(`{}`))
+	publish(t, hub, "post-uuid", "comment", []byte(`{}`))
+
+	client, err = hub.Subscribe("post-uuid", oldest.ID-1)
+	assert.NoError(t, err)
+	assert.False(t, client.Resumed)
+	assert.Empty(t, client.Replay)
+
+	// Un ID d'avant le redémarrage du serveur est plus petit que tout ce que le hub connaît
+	client, err = NewHub(NewMemoryBroker(), Options{}).Subscribe("post-uuid", oldest.ID)
+	assert.NoError(t, err)
+	assert.False(t, client.Resumed)
+}
+
+// Test qu'un client qui ne lit pas ses événements est déconnecté au lieu de les perdre
+func TestPublish_DisconnectsSlowClient(t *testing.T) {
+	hub := NewHub(NewMemoryBroker(), Options{ClientBuffer: 2})
+
+	// Le nombre de spectateurs reçu à l'abonnement occupe la première place du tampon
+	client, err := hub.Subscribe("post-uuid", 0)
+	assert.NoError(t, err)
+
+	publish(t, hub, "post-uuid", "comment", []byte(`{}`))
+	select {
+	case <-client.Done():
+		t.Fatal("client disconnected while its buffer had room")
+	default:
+	}
+
+	publish(t, hub, "post-uuid", "comment", []byte(`{}`))
+	select {
+	case <-client.Done():
+	default:
+		t.Fatal("slow client was not disconnected")
+	}
+	assert.Equal(t, 0, hub.ClientCount("post-uuid"))
+
+	// Le désabonnement du handler après la déconnexion reste sans effet
+	hub.Unsubscribe(client)
+}
+
+// Test que le nombre de clients par sujet est limité
+func TestSubscribe_MaxClients(t *testing.T) {
+	hub := NewHub(NewMemoryBroker(), Options{MaxClients: 1})
+
+	client, err := hub.Subscribe("post-uuid", 0)
+	assert.NoError(t, err)
+
+	_, err = hub.Subscribe("post-uuid", 0)
+	assert.ErrorIs(t, err, ErrTooManyClients)
+
+	_, err = hub.Subscribe("other-post", 0)
+	assert.NoError(t, err)
+
+	hub.Unsubscribe(client)
+	_, err = hub.Subscribe("post-uuid", 0)
+	assert.NoError(t, err)
+}
+
+// Test du flux SSE : reprise avec Last-Event-ID sans recharger l'état, puis événements en direct avec leur id
+func TestServeSSE_Resume(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hub := NewHub(NewMemoryBroker(), Options{})
+
+	client, err := hub.Subscribe("post-uuid", 0)
+	assert.NoError(t, err)
+	hub.Unsubscribe(client)
+	missed := publish(t, hub, "post-uuid", "comment", []byte(`{"n":1}`))
+
+	r := gin.New()
+	r.GET("/posts/:id/comments/sse", func(c *gin.Context) {
+		hub.ServeSSE(c, c.Param("id"), func(emit func(name string, data []byte)) {
+			t.Error("snapshot sent to a resumed client")
+		})
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/posts/post-uuid/comments/sse", nil)
+	req.Header.Set("Last-Event-ID", strconv.FormatInt(client.Head, 10))
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	readUntil := func(prefix string) string {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("stream closed before %q: %v", prefix, err)
+			}
+			if strings.HasPrefix(line, prefix) {
+				return strings.TrimSpace(line)
+			}
+		}
+	}
+
+	assert.Equal(t, "event: connected", readUntil("event:"))
+	assert.Equal(t, "id: "+strconv.FormatInt(missed.ID, 10), readUntil("id:"))
+	assert.Equal(t, `data: {"n":1}`, readUntil("data:"))
+
+	// Attendre que le flux soit abonné avant de publier un événement en direct
+	for hub.ClientCount("post-uuid") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	live := publish(t, hub, "post-uuid", "reply", []byte(`{"n":2}`))
+	assert.Equal(t, "id: "+strconv.FormatInt(live.ID, 10), readUntil("id:"))
+	assert.Equal(t, "event: reply", readUntil("event: reply"))
+}
@@ -0,0 +1,170 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"pec2-backend/models"
+	"pec2-backend/utils"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	notifyChannel = "realtime_events"
+	// Postgres refuse les notifications de 8000 octets et plus
+	maxNotifyPayload = 7900
+	// Les messages volumineux ne servent qu'au moment de leur diffusion
+	payloadRetention = time.Hour
+	maxListenBackoff = 30 * time.Second
+)
+
+// notification charge d'un NOTIFY : le message, ou la référence de la ligne qui le contient
+type notification struct {
+	Message
+	Ref int64 `json:"ref,omitempty"`
+}
+
+// PostgresBroker diffuse les messages entre les instances de l'API avec LISTEN/NOTIFY.
+// La publication passe par le pool gorm, l'écoute par une connexion dédiée ouverte par Listen
+type PostgresBroker struct {
+	db  *gorm.DB
+	dsn string
+
+	mu        sync.RWMutex
+	receivers []Receiver
+}
+
+func NewPostgresBroker(gormDB *gorm.DB, dsn string) *PostgresBroker {
+	return &PostgresBroker{db: gormDB, dsn: dsn}
+}
+
+func (b *PostgresBroker) Publish(msg Message) error {
+	payload, err := json.Marshal(notification{Message: msg})
+	if err != nil {
+		return err
+	}
+
+	if len(payload) > maxNotifyPayload {
+		row := models.RealtimePayload{Payload: string(payload)}
+		if err := b.db.Create(&row).Error; err != nil {
+			return err
+		}
+		if payload, err = json.Marshal(notification{Ref: row.ID}); err != nil {
+			return err
+		}
+		if err := b.db.Where("created_at < ?", time.Now().Add(-payloadRetention)).
+			Delete(&models.RealtimePayload{}).Error; err != nil {
+			utils.LogError(err, "Error purging realtime payloads in PostgresBroker")
+		}
+	}
+
+	return b.db.Exec("SELECT pg_notify(?, ?)", notifyChannel, string(payload)).Error
+}
+
+func (b *PostgresBroker) Subscribe(receiver Receiver) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.receivers = append(b.receivers, receiver)
+}
+
+// Listen écoute les notifications jusqu'à l'annulation du contexte, en se reconnectant si la
+// connexion tombe. Les messages publiés pendant la coupure sont perdus : les hubs en sont avertis
+func (b *PostgresBroker) Listen(ctx context.Context) {
+	backoff := time.Second
+	connectedBefore := false
+
+	for ctx.Err() == nil {
+		err := b.listen(ctx, func() {
+			backoff = time.Second
+			if connectedBefore {
+				b.lost()
+			}
+			connectedBefore = true
+			utils.LogInfo("Listening to realtime events on Postgres")
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		utils.LogError(err, "Realtime listener disconnected in PostgresBroker")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxListenBackoff)
+	}
+}
+
+func (b *PostgresBroker) listen(ctx context.Context, onListening func()) error {
+	conn, err := pgx.Connect(ctx, b.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+		return err
+	}
+	onListening()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		msg, err := b.decode(n.Payload)
+		if err != nil {
+			utils.LogError(err, "Invalid realtime notification in PostgresBroker")
+			continue
+		}
+		b.dispatch(msg)
+	}
+}
+
+// decode lit une notification, en allant chercher le message en base s'il a été stocké
+func (b *PostgresBroker) decode(payload string) (Message, error) {
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		return Message{}, err
+	}
+	if n.Ref == 0 {
+		return n.Message, nil
+	}
+
+	var row models.RealtimePayload
+	if err := b.db.First(&row, n.Ref).Error; err != nil {
+		return Message{}, err
+	}
+	if err := json.Unmarshal([]byte(row.Payload), &n); err != nil {
+		return Message{}, err
+	}
+	if n.Topic == "" {
+		return Message{}, errors.New("empty realtime payload")
+	}
+	return n.Message, nil
+}
+
+func (b *PostgresBroker) dispatch(msg Message) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, receiver := range b.receivers {
+		receiver.Receive(msg)
+	}
+}
+
+func (b *PostgresBroker) lost() {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, receiver := range b.receivers {
+		receiver.Lost()
+	}
+}
@@ -0,0 +1,130 @@
+package realtime
+
+import (
+	"encoding/json"
+	"time"
+
+	"pec2-backend/utils"
+)
+
+const (
+	// Annonces de présence entre instances, puis nombre total de clients envoyé à ceux du sujet
+	PresenceEvent = "presence"
+	// Chaque instance renouvelle son annonce tant qu'elle a des clients sur le sujet ;
+	// une instance arrêtée sans prévenir cesse d'être comptée après presenceTTL
+	presenceRefresh = 30 * time.Second
+	presenceTTL     = 3 * presenceRefresh
+)
+
+type instancePresence struct {
+	count  int
+	seq    int64
+	seenAt time.Time
+}
+
+type presenceAnnounce struct {
+	Instance string `json:"instance"`
+	Seq      int64  `json:"seq"`
+	Count    int    `json:"count"`
+}
+
+// PresencePayload nombre de clients connectés au sujet sur toutes les instances
+type PresencePayload struct {
+	Viewers int `json:"viewers"`
+}
+
+// Viewers renvoie le nombre de clients connectés au sujet sur toutes les instances
+func (h *Hub) Viewers(topicName string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if t, ok := h.topics[topicName]; ok {
+		h.recountPresence(t, time.Now())
+		return t.viewers
+	}
+	return 0
+}
+
+// announcePresence publie le nombre de clients de cette instance sur le sujet.
+// Appelé sans le verrou : le broker en mémoire remet le message pendant Publish
+func (h *Hub) announcePresence(topicName string) {
+	h.mu.Lock()
+	h.presenceSeq++
+	announce := presenceAnnounce{Instance: h.instance, Seq: h.presenceSeq}
+	if t, ok := h.topics[topicName]; ok {
+		announce.Count = len(t.clients)
+		t.announcedAt = time.Now()
+	}
+	h.mu.Unlock()
+
+	data, err := json.Marshal(announce)
+	if err == nil {
+		err = h.broker.Publish(Message{Topic: topicName, Name: PresenceEvent, Data: data})
+	}
+	if err != nil {
+		utils.LogError(err, "Error announcing presence in Hub")
+	}
+}
+
+// RefreshPresence renouvelle l'annonce de cette instance avant qu'elle n'expire chez les autres
+// et retire du total les instances qui ont cessé d'annoncer. À appeler périodiquement par les connexions
+func (h *Hub) RefreshPresence(topicName string) {
+	h.mu.Lock()
+	t, ok := h.topics[topicName]
+	if ok {
+		h.recountPresence(t, time.Now())
+	}
+	due := ok && time.Since(t.announcedAt) >= presenceRefresh
+	h.mu.Unlock()
+
+	if due {
+		h.announcePresence(topicName)
+	}
+}
+
+// receivePresence met à jour le total du sujet et le diffuse aux clients s'il a changé.
+// Appelé avec le verrou pris
+func (h *Hub) receivePresence(msg Message) {
+	var announce presenceAnnounce
+	if err := json.Unmarshal(msg.Data, &announce); err != nil {
+		utils.LogError(err, "Invalid presence announce in Hub")
+		return
+	}
+
+	t := h.topic(msg.Topic)
+	if previous, ok := t.presence[announce.Instance]; ok && previous.seq > announce.Seq {
+		return
+	}
+
+	now := time.Now()
+	t.presence[announce.Instance] = instancePresence{count: announce.Count, seq: announce.Seq, seenAt: now}
+	if len(t.clients) == 0 && t.idleSince.IsZero() {
+		t.idleSince = now
+	}
+	h.recountPresence(t, now)
+}
+
+// recountPresence retire les instances dont l'annonce a expiré et diffuse le total aux clients s'il a changé.
+// Appelé avec le verrou pris
+func (h *Hub) recountPresence(t *topic, now time.Time) {
+	viewers := 0
+	for instance, presence := range t.presence {
+		// L'annonce de cette instance suit ses propres abonnements, elle n'expire pas
+		if instance != h.instance && now.Sub(presence.seenAt) > presenceTTL {
+			delete(t.presence, instance)
+			continue
+		}
+		viewers += presence.count
+	}
+	if viewers == t.viewers {
+		return
+	}
+	t.viewers = viewers
+
+	// La présence est un état, pas un historique : l'événement n'a pas d'ID et n'est pas rejoué
+	data, _ := json.Marshal(map[string]any{
+		"type":    "viewers",
+		"payload": PresencePayload{Viewers: viewers},
+	})
+	h.deliver(t, Event{Name: PresenceEvent, Data: data})
+}
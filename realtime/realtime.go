@@ -0,0 +1,29 @@
+package realtime
+
+import (
+	"encoding/json"
+)
+
+// Default hub de l'application. Le broker en mémoire suffit pour une seule instance ;
+// main le remplace par un hub branché sur Postgres quand REALTIME_BROKER=postgres
+var Default = NewHub(NewMemoryBroker(), Options{})
+
+// PostTopic sujet des événements d'un post (commentaires, likes)
+func PostTopic(postID string) string {
+	return "post:" + postID
+}
+
+// UserTopic sujet des événements destinés à un utilisateur (messages privés)
+func UserTopic(userID string) string {
+	return "user:" + userID
+}
+
+// PublishJSON sérialise payload et le publie sur le hub par défaut
+func PublishJSON(topic string, name string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = Default.Publish(topic, name, data)
+	return err
+}
@@ -0,0 +1,100 @@
+package realtime
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errStreamNotSupported = errors.New("streaming not supported")
+
+// Délai de reconnexion conseillé au navigateur
+const retryDelay = 3 * time.Second
+
+// SnapshotFunc envoie l'état complet d'un sujet à un client qui ne peut pas reprendre son flux
+type SnapshotFunc func(emit func(name string, data []byte))
+
+// ServeSSE ouvre un flux SSE sur le sujet jusqu'à la déconnexion du client.
+// Avec un en-tête Last-Event-ID (ou un paramètre lastEventId) encore couvert par l'historique, seuls
+// les événements manqués sont rejoués ; sinon snapshot renvoie l'état complet. Renvoie ErrTooManyClients (la réponse 503
+// est déjà écrite), ErrSlowClient ou ErrMessagesLost quand le flux a été coupé par le hub
+func (h *Hub) ServeSSE(c *gin.Context, topicName string, snapshot SnapshotFunc) error {
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Streaming not supported"})
+		return errStreamNotSupported
+	}
+
+	// Le ticket de flux ne sert qu'une fois : la reconnexion automatique d'EventSource est refusée
+	// et le client rouvre le flux avec un nouveau ticket, sans en-tête mais avec lastEventId dans l'URL
+	resumeFrom := c.GetHeader("Last-Event-ID")
+	if resumeFrom == "" {
+		resumeFrom = c.Query("lastEventId")
+	}
+	lastEventID, _ := strconv.ParseInt(resumeFrom, 10, 64)
+
+	client, err := h.Subscribe(topicName, lastEventID)
+	if errors.Is(err, ErrTooManyClients) {
+		c.Header("Retry-After", strconv.Itoa(int(retryDelay.Seconds())))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many clients connected to this stream"})
+		return err
+	}
+	defer h.Unsubscribe(client)
+
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	// Désactive la mise en tampon des réponses par nginx
+	c.Writer.Header().Set("X-Accel-Buffering", "no")
+	c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n", retryDelay.Milliseconds())
+	fmt.Fprint(c.Writer, "event: connected\ndata: {\"status\":\"connected\"}\n\n")
+
+	if client.Resumed {
+		for _, event := range client.Replay {
+			writeEvent(c.Writer, event)
+		}
+	} else {
+		snapshot(func(name string, data []byte) {
+			fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", name, data)
+		})
+		// Un message sans data ne déclenche aucun événement côté navigateur
+		// mais met à jour son Last-Event-ID : l'état envoyé est à jour jusqu'à Head
+		fmt.Fprintf(c.Writer, "id: %d\n\n", client.Head)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.opts.Heartbeat)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case event := <-client.Events():
+			writeEvent(c.Writer, event)
+			flusher.Flush()
+		case <-client.Done():
+			return client.Err()
+		case <-ctx.Done():
+			return nil
+		case <-heartbeat.C:
+			// Commentaire SSE ignoré par le navigateur, il évite que les proxies coupent la connexion
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			flusher.Flush()
+			h.RefreshPresence(topicName)
+		}
+	}
+}
+
+func writeEvent(w gin.ResponseWriter, event Event) {
+	if event.ID > 0 {
+		fmt.Fprintf(w, "id: %d\n", event.ID)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Name, event.Data)
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"pec2-backend/handlers/analytics"
+	"pec2-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AnalyticsRoutes(r *gin.Engine) {
+	// Rapports financiers de la plateforme (admin), exportables en CSV
+	analyticsRoutes := r.Group("/analytics")
+	analyticsRoutes.Use(middleware.JWTAuth(), middleware.AdminAuth())
+	{
+		analyticsRoutes.GET("/revenue", analytics.GetRevenueReport)
+		analyticsRoutes.GET("/creators", analytics.GetCreatorsRevenueReport)
+		analyticsRoutes.GET("/payments", analytics.GetPaymentRatesReport)
+		analyticsRoutes.GET("/subscriptions", analytics.GetSubscriptionsReport)
+	}
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"pec2-backend/handlers/auth"
+	"pec2-backend/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,4 +12,16 @@ func AuthRoutes(r *gin.Engine) {
 	r.POST("/login", auth.Login)
 	r.GET("/valid-email/:code", auth.ValidEmail)
 	r.GET("/resend-valid-email/:email", auth.ResendValidEmail)
+	r.POST("/token/refresh", auth.RefreshToken)
+	r.POST("/logout", middleware.JWTAuth(), auth.Logout)
+	r.POST("/logout-all", middleware.JWTAuth(), auth.LogoutAll)
+
+	r.POST("/2fa/verify", auth.VerifyTwoFactor)
+	twoFactor := r.Group("/2fa")
+	twoFactor.Use(middleware.JWTAuth())
+	{
+		twoFactor.POST("/enroll", auth.EnrollTwoFactor)
+		twoFactor.POST("/confirm", auth.ConfirmTwoFactor)
+		twoFactor.POST("/disable", auth.DisableTwoFactor)
+	}
 }
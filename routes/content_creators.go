@@ -1,7 +1,10 @@
 package routes
 
 import (
+	"pec2-backend/handlers/analytics"
 	"pec2-backend/handlers/content_creators"
+	"pec2-backend/handlers/payouts"
+	"pec2-backend/handlers/plans"
 	"pec2-backend/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -15,6 +18,28 @@ func ContentCreatorsRoutes(r *gin.Engine) {
 		contentCreatorRoutes.POST("", content_creators.Apply)
 		contentCreatorRoutes.PUT("", content_creators.UpdateContentCreatorInfo)
 
+		// Plans d'abonnement
+		contentCreatorRoutes.GET("/:id/plans", plans.GetCreatorPlans)
+		contentCreatorRoutes.GET("/me/plans", plans.GetMyPlans)
+		contentCreatorRoutes.POST("/me/plans", plans.CreatePlan)
+		contentCreatorRoutes.PUT("/me/plans/:planId", plans.UpdatePlan)
+		contentCreatorRoutes.DELETE("/me/plans/:planId", plans.DeletePlan)
+
+		// Codes promo
+		contentCreatorRoutes.GET("/me/coupons", plans.GetMyCoupons)
+		contentCreatorRoutes.POST("/me/coupons", plans.CreateCoupon)
+		contentCreatorRoutes.DELETE("/me/coupons/:couponId", plans.DeactivateCoupon)
+
+		// Gains et historique du grand livre
+		contentCreatorRoutes.GET("/me/earnings", payouts.GetMyEarnings)
+
+		// Statistiques d'abonnés, de revenus et d'engagement
+		contentCreatorRoutes.GET("/me/analytics", analytics.GetOverview)
+		contentCreatorRoutes.GET("/me/analytics/subscribers", analytics.GetSubscribers)
+		contentCreatorRoutes.GET("/me/analytics/revenue", analytics.GetRevenue)
+		contentCreatorRoutes.GET("/me/analytics/top-posts", analytics.GetTopPosts)
+		contentCreatorRoutes.GET("/me/analytics/retention", analytics.GetRetention)
+
 		// Routes admin
 		contentCreatorRoutes.GET("/all", middleware.AdminAuth(), content_creators.GetAllContentCreators)
 		contentCreatorRoutes.PUT("/:id/status", middleware.AdminAuth(), content_creators.UpdateContentCreatorStatus)
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"pec2-backend/handlers/invoices"
+	"pec2-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InvoicesRoutes(r *gin.Engine) {
+	// Factures des paiements d'abonnement
+	invoiceRoutes := r.Group("/invoices")
+	invoiceRoutes.Use(middleware.JWTAuth())
+	{
+		invoiceRoutes.GET("", invoices.GetMyInvoices)
+		invoiceRoutes.GET("/received", invoices.GetReceivedInvoices)
+		invoiceRoutes.GET("/:id/pdf", invoices.DownloadInvoicePdf)
+	}
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"pec2-backend/handlers/payouts"
+	"pec2-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PayoutsRoutes(r *gin.Engine) {
+	// Versements aux créateurs (admin)
+	payoutRoutes := r.Group("/payouts")
+	payoutRoutes.Use(middleware.JWTAuth(), middleware.AdminAuth())
+	{
+		payoutRoutes.GET("/batches", payouts.GetPayoutBatches)
+		payoutRoutes.POST("/batches", payouts.CreatePayoutBatch)
+		payoutRoutes.GET("/batches/:id/sepa", payouts.ExportPayoutBatchSepa)
+	}
+}
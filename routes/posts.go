@@ -6,17 +6,26 @@ import (
 	"pec2-backend/handlers/posts/likes"
 	"pec2-backend/handlers/posts/report"
 	"pec2-backend/middleware"
+	"pec2-backend/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-func PostsRoutes(r *gin.Engine) { // Routes publiques
-	r.GET("/posts", posts.GetAllPosts)
-	r.GET("/posts/:id", posts.GetPostByID)
-	// J'ai pas trouvé la solution pour faire la vérification avec le middleware
-	// J'ai l'impression qu'en SSE on peut pas envoyer de token dans le header
-	// Du coup middleware = useless
-	r.GET("/posts/:id/comments/sse", comment.HandleSSE)
+func PostsRoutes(r *gin.Engine) {
+	// Routes publiques, avec authentification optionnelle pour le paywall
+	postsPublicRoutes := r.Group("/posts")
+	postsPublicRoutes.Use(middleware.OptionalJWTAuth())
+	{
+		postsPublicRoutes.GET("", posts.GetAllPosts)
+		postsPublicRoutes.GET("/:id", posts.GetPostByID)
+	}
+
+	// Fil personnalisé (abonnements actifs + posts gratuits des créateurs suivis)
+	r.GET("/feed", middleware.JWTAuth(), posts.GetFeed)
+
+	// EventSource ne peut pas envoyer l'en-tête Authorization : le flux s'ouvre avec
+	// un ticket à usage unique obtenu via POST /stream-tickets (paramètre ticket ou cookie)
+	r.GET("/posts/:id/comments/sse", middleware.StreamTicketAuth(models.StreamComments), comment.HandleSSE)
 	
 	// Routes protégées
 	postsRoutes := r.Group("/posts")
@@ -24,6 +33,9 @@ func PostsRoutes(r *gin.Engine) { // Routes publiques
 	{
 		postsRoutes.POST("/:id/comments", comment.CreateComment)
 		postsRoutes.GET("/:id/comments", comment.GetCommentsByPostID)
+		postsRoutes.PUT("/:id/comments/:commentId", comment.UpdateComment)
+		postsRoutes.DELETE("/:id/comments/:commentId", comment.DeleteComment)
+		postsRoutes.GET("/:id/comments/:commentId/history", comment.GetCommentHistory)
 		postsRoutes.POST("", posts.CreatePost)
 		postsRoutes.PUT("/:id", posts.UpdatePost)
 		postsRoutes.DELETE("/:id", posts.DeletePost)
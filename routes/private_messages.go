@@ -3,11 +3,16 @@ package routes
 import (
 	"pec2-backend/handlers/privateMessages"
 	"pec2-backend/middleware"
+	"pec2-backend/models"
 
 	"github.com/gin-gonic/gin"
 )
 
 func PrivateMessagesRoutes(r *gin.Engine) {
+	// Les navigateurs n'envoient pas l'en-tête Authorization à l'ouverture d'un WebSocket :
+	// la connexion s'authentifie avec un ticket à usage unique obtenu via POST /stream-tickets
+	r.GET("/private-messages/ws", middleware.StreamTicketAuth(models.StreamMessages), privateMessages.HandleWebSocket)
+
 	privateMessagesGroup := r.Group("/private-messages")
 	privateMessagesGroup.Use(middleware.JWTAuth())
 	{
@@ -28,10 +28,14 @@ func SetupRouter() *gin.Engine {
 	UsersRoutes(r)
 	CategoriesRoutes(r)
 	PostsRoutes(r)
+	StreamsRoutes(r)
 	ContentCreatorsRoutes(r)
 	InseeRoutes(r)
 	PrivateMessagesRoutes(r)
 	StripeRoutes(r)
+	PayoutsRoutes(r)
+	InvoicesRoutes(r)
+	AnalyticsRoutes(r)
 
 	return r
 }
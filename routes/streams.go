@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"pec2-backend/handlers/streams"
+	"pec2-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func StreamsRoutes(r *gin.Engine) {
+	// Tickets à usage unique pour les flux SSE, qui ne peuvent pas porter l'en-tête Authorization
+	r.POST("/stream-tickets", middleware.JWTAuth(), streams.CreateStreamTicket)
+}
@@ -17,6 +17,26 @@ func StripeRoutes(r *gin.Engine) {
 		subscriptionRoutes.GET("/:subscriptionId", stripe.GetSubscriptionDetail)
 		subscriptionRoutes.GET("/revenue", middleware.AdminAuth(), stripe.GetTotalRevenue)
 		subscriptionRoutes.GET("/top-creators", middleware.AdminAuth(), stripe.GetTopContentCreators)
+		subscriptionRoutes.GET("/disputes", middleware.AdminAuth(), stripe.GetPaymentDisputes)
+		subscriptionRoutes.POST("/payments/:paymentId/refund", middleware.AdminAuth(), stripe.RefundSubscriptionPayment)
 	}
+	tipRoutes := r.Group("/tips")
+	tipRoutes.Use(middleware.JWTAuth())
+	{
+		tipRoutes.GET("/sent", stripe.GetSentTips)
+		tipRoutes.GET("/received", stripe.GetReceivedTips)
+		tipRoutes.POST("/:contentCreatorId", stripe.CreateTipCheckoutSession)
+	}
+	// Achat à l'unité des posts payants
+	r.POST("/posts/:id/purchase", middleware.JWTAuth(), stripe.CreatePostPurchaseCheckoutSession)
+	r.GET("/purchases", middleware.JWTAuth(), stripe.GetMyPostPurchases)
 	r.POST("/stripe/webhook", stripe.StripeWebhookHandler)
+
+	// Journal des événements Stripe (admin)
+	stripeEventsRoutes := r.Group("/stripe/events")
+	stripeEventsRoutes.Use(middleware.JWTAuth(), middleware.AdminAuth())
+	{
+		stripeEventsRoutes.GET("", stripe.GetStripeEvents)
+		stripeEventsRoutes.POST("/:id/replay", stripe.ReplayStripeEvent)
+	}
 }
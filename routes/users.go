@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"pec2-backend/handlers/follows"
 	"pec2-backend/handlers/users"
 	"pec2-backend/middleware"
 
@@ -28,5 +29,7 @@ func UsersRoutes(r *gin.Engine) {
 		userRoutes.PUT("/password", users.UpdatePassword)
 		userRoutes.PUT("/profile", users.UpdateUserProfile)
 		userRoutes.GET("/profile", users.GetUserProfile)
+		userRoutes.GET("/following", follows.GetFollowing)
+		userRoutes.POST("/:id/follow", follows.ToggleFollow)
 	}
 }
@@ -0,0 +1,26 @@
+// Package sessions révoque les sessions de connexion : une session révoquée invalide
+// les access tokens et toute la famille de refresh tokens qui la référencent
+package sessions
+
+import (
+	"pec2-backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Revoke invalide la session, et donc toute la famille de refresh tokens
+func Revoke(tx *gorm.DB, sessionID string) error {
+	return tx.Model(&models.UserSession{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAll révoque toutes les sessions actives de l'utilisateur et renvoie leur nombre,
+// par exemple après un changement de mot de passe
+func RevokeAll(tx *gorm.DB, userID string) (int64, error) {
+	result := tx.Model(&models.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
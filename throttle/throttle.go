@@ -0,0 +1,160 @@
+// Package throttle verrouille temporairement un compte ou une adresse IP après des échecs
+// d'authentification répétés (mot de passe, code 2FA, code reçu par email)
+package throttle
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"pec2-backend/db"
+	"pec2-backend/models"
+	"pec2-backend/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Paramètres du verrouillage après échecs répétés : au-delà du seuil, chaque nouvel
+// échec double la durée de blocage, dans la limite de maxLockDuration
+const (
+	accountFailureThreshold = 5
+	ipFailureThreshold      = 20
+	baseLockDuration        = time.Minute
+	maxLockDuration         = time.Hour
+	failureWindow           = time.Hour
+)
+
+// CodeCooldown délai minimum entre deux envois de code au même compte
+const CodeCooldown = time.Minute
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// lockDuration renvoie la durée de blocage pour un nombre d'échecs donné (0 sous le seuil)
+func lockDuration(failures int, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+	d := time.Duration(float64(baseLockDuration) * math.Pow(2, float64(failures-threshold)))
+	if d > maxLockDuration || d <= 0 {
+		return maxLockDuration
+	}
+	return d
+}
+
+// checkThrottle renvoie le temps restant avant déblocage si l'une des clés est verrouillée
+func checkThrottle(keys ...string) (time.Duration, error) {
+	now := time.Now()
+
+	var throttles []models.AuthThrottle
+	if err := db.DB.Where("throttle_key IN ? AND locked_until > ?", keys, now).Find(&throttles).Error; err != nil {
+		return 0, err
+	}
+
+	var retryAfter time.Duration
+	for _, throttle := range throttles {
+		if remaining := throttle.LockedUntil.Sub(now); remaining > retryAfter {
+			retryAfter = remaining
+		}
+	}
+	return retryAfter, nil
+}
+
+// registerFailure incrémente le compteur d'échecs de la clé et pose un verrou si le seuil est atteint.
+// Les échecs plus anciens que failureWindow ne sont pas cumulés.
+func registerFailure(key string, threshold int) error {
+	now := time.Now()
+
+	var failures int
+	err := db.DB.Raw(`INSERT INTO auth_throttles (throttle_key, failures, last_failure_at)
+		VALUES (?, 1, ?)
+		ON CONFLICT (throttle_key) DO UPDATE SET
+			failures = CASE WHEN auth_throttles.last_failure_at < ? THEN 1 ELSE auth_throttles.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures`, key, now, now.Add(-failureWindow)).Scan(&failures).Error
+	if err != nil {
+		return err
+	}
+
+	if d := lockDuration(failures, threshold); d > 0 {
+		return db.DB.Model(&models.AuthThrottle{}).
+			Where("throttle_key = ?", key).
+			Update("locked_until", now.Add(d)).Error
+	}
+	return nil
+}
+
+// throttleKeys renvoie les clés surveillées pour la requête : l'IP, et le compte s'il est connu
+func throttleKeys(c *gin.Context, email string) []string {
+	keys := []string{ipThrottleKey(c.ClientIP())}
+	if strings.TrimSpace(email) != "" {
+		keys = append([]string{accountThrottleKey(email)}, keys...)
+	}
+	return keys
+}
+
+// RegisterFailure enregistre un échec pour le compte (si l'email est connu) et pour l'adresse IP
+func RegisterFailure(c *gin.Context, email string) {
+	if strings.TrimSpace(email) != "" {
+		if err := registerFailure(accountThrottleKey(email), accountFailureThreshold); err != nil {
+			utils.LogError(err, "Error when registering account failure")
+		}
+	}
+	if err := registerFailure(ipThrottleKey(c.ClientIP()), ipFailureThreshold); err != nil {
+		utils.LogError(err, "Error when registering IP failure")
+	}
+}
+
+// ResetAccount efface les échecs du compte après une authentification réussie.
+// Le compteur IP n'est pas remis à zéro pour qu'un compte valide ne serve pas à débloquer une IP.
+func ResetAccount(email string) {
+	if err := db.DB.Where("throttle_key = ?", accountThrottleKey(email)).Delete(&models.AuthThrottle{}).Error; err != nil {
+		utils.LogError(err, "Error when resetting account throttle")
+	}
+}
+
+// AbortIfThrottled répond 429 avec Retry-After si le compte ou l'IP est verrouillé
+func AbortIfThrottled(c *gin.Context, email string, handlerName string) bool {
+	retryAfter, err := checkThrottle(throttleKeys(c, email)...)
+	if err != nil {
+		// On ne bloque pas la connexion si la table de throttling est indisponible
+		utils.LogError(err, "Error when checking throttle in "+handlerName)
+		return false
+	}
+	if retryAfter <= 0 {
+		return false
+	}
+
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	utils.LogError(fmt.Errorf("verrouillé pour %ds", seconds), "Too many failed attempts in "+handlerName)
+	abortTooManyRequests(c, seconds, "Too many failed attempts, try again later")
+	return true
+}
+
+// AbortIfCodeRecentlySent répond 429 si le code en cours du compte a été émis il y a moins de CodeCooldown,
+// pour qu'un nouveau code (et donc de nouveaux essais) ne puisse pas être demandé en boucle
+func AbortIfCodeRecentlySent(c *gin.Context, issuedAt time.Time, handlerName string) bool {
+	remaining := time.Until(issuedAt.Add(CodeCooldown))
+	if remaining <= 0 {
+		return false
+	}
+
+	seconds := int(math.Ceil(remaining.Seconds()))
+	utils.LogError(fmt.Errorf("code envoyé il y a moins de %s", CodeCooldown), "Code requested too often in "+handlerName)
+	abortTooManyRequests(c, seconds, "A code was sent recently, try again later")
+	return true
+}
+
+func abortTooManyRequests(c *gin.Context, seconds int, message string) {
+	c.Header("Retry-After", fmt.Sprint(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":      message,
+		"retryAfter": seconds,
+	})
+}
@@ -0,0 +1,16 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockDuration(t *testing.T) {
+	assert.Equal(t, time.Duration(0), lockDuration(4, 5))
+	assert.Equal(t, time.Minute, lockDuration(5, 5))
+	assert.Equal(t, 4*time.Minute, lockDuration(7, 5))
+	assert.Equal(t, time.Hour, lockDuration(50, 5))
+	assert.Equal(t, time.Hour, lockDuration(500, 5))
+}
@@ -1,11 +1,26 @@
 package utils
 
 import (
+	"crypto/rand"
 	"fmt"
-	"math/rand"
+	"math/big"
+	"time"
 )
 
-func GenerateCode() string {
-	code := rand.Intn(100000)
-	return fmt.Sprintf("%05d", code)
+// MaxCodeAttempts nombre d'essais autorisés pour un même code (email, reset du mot de passe)
+const MaxCodeAttempts = 5
+
+// Durée de validité des codes envoyés par email
+const (
+	ConfirmationCodeTTL = time.Hour
+	ResetCodeTTL        = 15 * time.Minute
+)
+
+// GenerateCode génère un code à 8 chiffres avec une source aléatoire cryptographique
+func GenerateCode() (string, error) {
+	code, err := rand.Int(rand.Reader, big.NewInt(100000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%08d", code.Int64()), nil
 }
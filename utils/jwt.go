@@ -9,13 +9,41 @@ import (
 	"github.com/golang-jwt/jwt"
 )
 
-func GenerateJWT(user models.User, hours int) (string, error) {
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+
+	AccessTokenType = "access"
+
+	TwoFactorChallengeTTL  = 5 * time.Minute
+	TwoFactorChallengeType = "2fa_challenge"
+)
+
+// GenerateJWT génère un access token de courte durée rattaché à une session (claim "sid")
+func GenerateJWT(user models.User, sessionID string, ttl time.Duration) (string, error) {
 	var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
 
 	claims := jwt.MapClaims{
 		"user_id": user.ID,
 		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * time.Duration(hours)).Unix(),
+		"sid":     sessionID,
+		"type":    AccessTokenType,
+		"exp":     time.Now().Add(ttl).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
+// GenerateTwoFactorChallenge génère le token intermédiaire renvoyé par le login quand la 2FA est active.
+// Il ne donne accès à aucune route protégée (type différent de "access").
+func GenerateTwoFactorChallenge(user models.User) (string, error) {
+	var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
+
+	claims := jwt.MapClaims{
+		"user_id": user.ID,
+		"type":    TwoFactorChallengeType,
+		"exp":     time.Now().Add(TwoFactorChallengeTTL).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
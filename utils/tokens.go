@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"time"
+)
+
+const (
+	// Un ticket de flux est demandé juste avant d'ouvrir la connexion, il n'a pas besoin de vivre longtemps
+	StreamTicketTTL    = time.Minute
+	StreamTicketCookie = "stream_ticket"
+)
+
+// GenerateOpaqueToken renvoie un token aléatoire (256 bits) encodé en base64 URL
+func GenerateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken renvoie l'empreinte sha256 d'un token, seule valeur stockée en base
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashCode calcule l'empreinte HMAC d'un code court (validation d'email, reset du mot de passe).
+// Contrairement à HashToken, la clé serveur empêche de retrouver un code à 8 chiffres par force brute depuis la base.
+func HashCode(code string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CheckCode compare un code saisi à son empreinte en temps constant
+func CheckCode(code string, hash string) bool {
+	if hash == "" {
+		return false
+	}
+	return hmac.Equal([]byte(HashCode(code)), []byte(hash))
+}
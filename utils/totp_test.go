@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Secret ASCII "12345678901234567890" des vecteurs de test de la RFC 6238
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeAt_RFC6238Vectors(t *testing.T) {
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, expected := range vectors {
+		code, err := TOTPCodeAt(rfcSecret, TOTPStep(time.Unix(unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code, "time %d", unix)
+	}
+}
+
+func TestValidateTOTP_Window(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	previous, _ := TOTPCodeAt(rfcSecret, TOTPStep(now)-1)
+	tooOld, _ := TOTPCodeAt(rfcSecret, TOTPStep(now)-2)
+
+	step, ok := ValidateTOTP(rfcSecret, previous, now)
+	assert.True(t, ok)
+	assert.Equal(t, TOTPStep(now)-1, step)
+
+	_, ok = ValidateTOTP(rfcSecret, tooOld, now)
+	assert.False(t, ok)
+
+	_, ok = ValidateTOTP(rfcSecret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("user@example.com", rfcSecret)
+	assert.Contains(t, uri, "otpauth://totp/OnlyFlick:user@example.com?")
+	assert.Contains(t, uri, "secret="+rfcSecret)
+	assert.Contains(t, uri, "issuer=OnlyFlick")
+}